@@ -2,10 +2,10 @@ package account
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/sha256"
 	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/signal"
 	"sort"
@@ -40,6 +40,8 @@ type ClientImpl struct {
 	path      string
 	iv        []byte
 	masterKey []byte
+	seed      []byte
+	version   string
 
 	mainAccount common.Uint160
 	accounts    map[common.Uint160]*Account
@@ -187,19 +189,13 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 
 	go client.ProcessSignals()
 
-	passwordKey := crypto.ToAesKey(password)
 	if create {
 		//create new client
-		client.iv = make([]byte, 16)
+		client.version = KeystoreVersion
 		client.masterKey = make([]byte, 32)
-
-		//generate random number for iv/masterkey
-		r := rand.New(rand.NewSource(time.Now().UnixNano()))
-		for i := 0; i < 16; i++ {
-			client.iv[i] = byte(r.Intn(256))
-		}
-		for i := 0; i < 32; i++ {
-			client.masterKey[i] = byte(r.Intn(256))
+		if _, err := rand.Read(client.masterKey); err != nil {
+			log.Error(err)
+			return nil
 		}
 
 		//new client store (build DB)
@@ -210,54 +206,104 @@ func NewClient(path string, password []byte, create bool) *ClientImpl {
 			return nil
 		}
 
-		pwdhash := sha256.Sum256(passwordKey)
-		if err := client.SaveStoredData("PasswordHash", pwdhash[:]); err != nil {
+		kdf, err := NewKDFParams(KeystoreKDF)
+		if err != nil {
 			log.Error(err)
 			return nil
 		}
-		if err := client.SaveStoredData("IV", client.iv[:]); err != nil {
+		if err := client.SaveKDFParams(kdf); err != nil {
 			log.Error(err)
 			return nil
 		}
-
-		aesmk, err := crypto.AesEncrypt(client.masterKey[:], passwordKey, client.iv)
+		passwordKey, err := kdf.DeriveKey(password)
 		if err != nil {
 			log.Error(err)
 			return nil
 		}
-		if err := client.SaveStoredData("MasterKey", aesmk); err != nil {
+		encryptedMasterKey, err := crypto.AesGcmEncrypt(client.masterKey, passwordKey)
+		common.ClearBytes(passwordKey)
+		if err != nil {
+			log.Error(err)
+			return nil
+		}
+		if err := client.SaveStoredData("MasterKey", encryptedMasterKey); err != nil {
 			log.Error(err)
 			return nil
 		}
 
 	} else {
-		if ok := client.verifyPasswordKey(passwordKey); !ok {
+		if err := client.loadMasterKey(password); err != nil {
+			fmt.Println("error:", err)
 			return nil
 		}
-		var err error
-		client.iv, err = client.LoadStoredData("IV")
-		if err != nil {
-			fmt.Println("error: failed to load iv")
+		if err := client.loadSeed(); err != nil {
+			fmt.Println("error: failed to load seed")
 			return nil
 		}
-		encryptedMasterKey, err := client.LoadStoredData("MasterKey")
+	}
+
+	return client
+}
+
+// loadMasterKey decrypts the master key with password. The keystore of version
+// 1.0.0 derives the password key by double SHA-256 and encrypts with AES-CBC,
+// the current version derives it by the stored KDF and encrypts with AES-GCM.
+func (cl *ClientImpl) loadMasterKey(password []byte) error {
+	version, err := cl.LoadStoredData("Version")
+	if err != nil {
+		return errors.New("failed to load version")
+	}
+	cl.version = string(version)
+	encryptedMasterKey, err := cl.LoadStoredData("MasterKey")
+	if err != nil {
+		return errors.New("failed to load master key")
+	}
+
+	switch cl.version {
+	case KeystoreVersionV1:
+		passwordKey := crypto.ToAesKey(password)
+		defer common.ClearBytes(passwordKey)
+		if err := cl.verifyPasswordKey(passwordKey); err != nil {
+			return err
+		}
+		cl.iv, err = cl.LoadStoredData("IV")
 		if err != nil {
-			fmt.Println("error: failed to load master key")
-			return nil
+			return errors.New("failed to load iv")
 		}
-		client.masterKey, err = crypto.AesDecrypt(encryptedMasterKey, passwordKey, client.iv)
+		cl.masterKey, err = crypto.AesDecrypt(encryptedMasterKey, passwordKey, cl.iv)
 		if err != nil {
-			fmt.Println("error: failed to decrypt master key")
-			return nil
+			return errors.New("failed to decrypt master key")
 		}
+
+	case KeystoreVersion:
+		kdf, err := cl.LoadKDFParams()
+		if err != nil {
+			return errors.New("failed to load kdf parameters")
+		}
+		passwordKey, err := kdf.DeriveKey(password)
+		if err != nil {
+			return err
+		}
+		defer common.ClearBytes(passwordKey)
+		cl.masterKey, err = crypto.AesGcmDecrypt(encryptedMasterKey, passwordKey)
+		if err != nil {
+			return errors.New("password wrong")
+		}
+
+	default:
+		return errors.New("unsupported keystore version " + cl.version)
 	}
-	common.ClearBytes(passwordKey)
 
-	return client
+	return nil
 }
 
-// CreateAccount create a new Account then save it
+// CreateAccount create a new Account then save it, the accounts of a HD
+// wallet are derived from the seed.
 func (cl *ClientImpl) CreateAccount() (*Account, error) {
+	if cl.IsHD() {
+		return cl.createHDAccount()
+	}
+
 	account, err := NewAccount()
 	if err != nil {
 		return nil, err
@@ -271,6 +317,10 @@ func (cl *ClientImpl) CreateAccount() (*Account, error) {
 
 // SaveAccount saves a Account to memory and db
 func (cl *ClientImpl) SaveAccount(ac *Account) error {
+	return cl.saveAccount(ac, "")
+}
+
+func (cl *ClientImpl) saveAccount(ac *Account, path string) error {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
 
@@ -295,7 +345,7 @@ func (cl *ClientImpl) SaveAccount(ac *Account) error {
 	common.ClearBytes(decryptedPrivateKey)
 
 	// save Account keys to db
-	err = cl.SaveAccountData(ac.ProgramHash.Bytes(), encryptedPrivateKey)
+	err = cl.SaveHDAccountData(ac.ProgramHash.Bytes(), encryptedPrivateKey, path)
 	if err != nil {
 		return err
 	}
@@ -349,47 +399,49 @@ func (cl *ClientImpl) LoadAccounts() error {
 }
 
 func (cl *ClientImpl) EncryptPrivateKey(prikey []byte) ([]byte, error) {
-	enc, err := crypto.AesEncrypt(prikey, cl.masterKey, cl.iv)
-	if err != nil {
-		return nil, err
-	}
-
-	return enc, nil
+	return cl.encrypt(prikey)
 }
 
 func (cl *ClientImpl) DecryptPrivateKey(prikey []byte) ([]byte, error) {
 	if prikey == nil {
 		return nil, errors.New("The PriKey is nil")
 	}
-	if len(prikey) != 96 {
+	if cl.version == KeystoreVersionV1 && len(prikey) != 96 {
 		return nil, errors.New("The len of PriKeyEnc is not 96bytes")
 	}
 
-	dec, err := crypto.AesDecrypt(prikey, cl.masterKey, cl.iv)
-	if err != nil {
-		return nil, err
+	return cl.decrypt(prikey)
+}
+
+// encrypt encrypts the secret data stored in keystore with the master key.
+func (cl *ClientImpl) encrypt(data []byte) ([]byte, error) {
+	if cl.version == KeystoreVersionV1 {
+		return crypto.AesEncrypt(data, cl.masterKey, cl.iv)
 	}
+	return crypto.AesGcmEncrypt(data, cl.masterKey)
+}
 
-	return dec, nil
+// decrypt decrypts the secret data stored in keystore with the master key.
+func (cl *ClientImpl) decrypt(data []byte) ([]byte, error) {
+	if cl.version == KeystoreVersionV1 {
+		return crypto.AesDecrypt(data, cl.masterKey, cl.iv)
+	}
+	return crypto.AesGcmDecrypt(data, cl.masterKey)
 }
 
-func (cl *ClientImpl) verifyPasswordKey(passwordKey []byte) bool {
+func (cl *ClientImpl) verifyPasswordKey(passwordKey []byte) error {
 	savedPasswordHash, err := cl.LoadStoredData("PasswordHash")
 	if err != nil {
-		fmt.Println("error: failed to load password hash")
-		return false
+		return errors.New("failed to load password hash")
 	}
 	if savedPasswordHash == nil {
-		fmt.Println("error: saved password hash is nil")
-		return false
+		return errors.New("saved password hash is nil")
 	}
 	passwordHash := sha256.Sum256(passwordKey)
-	///ClearBytes(passwordKey, len(passwordKey))
 	if !bytes.Equal(savedPasswordHash, passwordHash[:]) {
-		fmt.Println("error: password wrong")
-		return false
+		return errors.New("password wrong")
 	}
-	return true
+	return nil
 }
 
 func (cl *ClientImpl) ProcessSignals() {
@@ -12,7 +12,11 @@ const (
 	MAINACCOUNT      = "main-account"
 	SUBACCOUNT       = "sub-account"
 	KeystoreFileName = "keystore.dat"
-	KeystoreVersion  = "1.0.0"
+	KeystoreVersion  = "2.0.0"
+
+	// KeystoreVersionV1 is the keystore version encrypted by AES-CBC with the
+	// key of double SHA-256 password hash.
+	KeystoreVersionV1 = "1.0.0"
 
 	MaxSignalQueueLen = 5
 )
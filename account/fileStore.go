@@ -16,14 +16,19 @@ type AccountData struct {
 	ProgramHash         string
 	PrivateKeyEncrypted string
 	Type                string
+	Path                string `json:",omitempty"`
 }
 
 type FileData struct {
 	Version      string
+	KDF          *KDFParams `json:",omitempty"`
 	PasswordHash string
 	IV           string
 	MasterKey    string
+	Seed         string `json:",omitempty"`
 	Account      []AccountData
+	Watch        []WatchData       `json:",omitempty"`
+	AddressBook  []AddressBookData `json:",omitempty"`
 }
 
 type FileStore struct {
@@ -98,6 +103,11 @@ func (cs *FileStore) BuildDatabase(path string) {
 }
 
 func (cs *FileStore) SaveAccountData(programHash []byte, encryptedPrivateKey []byte) error {
+	return cs.SaveHDAccountData(programHash, encryptedPrivateKey, "")
+}
+
+// SaveHDAccountData saves the account derived from the wallet seed along path.
+func (cs *FileStore) SaveHDAccountData(programHash []byte, encryptedPrivateKey []byte, path string) error {
 	JSONData, err := cs.readDB()
 	if err != nil {
 		return errors.New("error: reading db")
@@ -126,6 +136,7 @@ func (cs *FileStore) SaveAccountData(programHash []byte, encryptedPrivateKey []b
 		ProgramHash:         BytesToHexString(programHash),
 		PrivateKeyEncrypted: BytesToHexString(encryptedPrivateKey),
 		Type:                accountType,
+		Path:                path,
 	}
 	cs.data.Account = append(cs.data.Account, a)
 
@@ -176,6 +187,156 @@ func (cs *FileStore) LoadAccountData() ([]AccountData, error) {
 	return cs.data.Account, nil
 }
 
+// SaveWatchData adds the watch-only address, or updates it if the address is
+// already watched.
+func (cs *FileStore) SaveWatchData(watch *WatchData) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	for _, v := range cs.data.Account {
+		if v.ProgramHash == watch.ProgramHash {
+			return errors.New(watch.Address + " is already an account of the wallet")
+		}
+	}
+	updated := false
+	for i, v := range cs.data.Watch {
+		if v.ProgramHash == watch.ProgramHash {
+			cs.data.Watch[i] = *watch
+			updated = true
+		}
+	}
+	if !updated {
+		cs.data.Watch = append(cs.data.Watch, *watch)
+	}
+
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) DeleteWatchData(address string) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	deleted := false
+	for i, v := range cs.data.Watch {
+		if v.Address == address {
+			cs.data.Watch = append(cs.data.Watch[:i], cs.data.Watch[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	if !deleted {
+		return errors.New(address + " is not watched")
+	}
+
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadWatchData() ([]WatchData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+	return cs.data.Watch, nil
+}
+
+// SaveAddressBookData adds the labelled address to the address book, the
+// address of an existing label is replaced.
+func (cs *FileStore) SaveAddressBookData(label, address string) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	updated := false
+	for i, v := range cs.data.AddressBook {
+		if v.Label == label {
+			cs.data.AddressBook[i].Address = address
+			updated = true
+		}
+	}
+	if !updated {
+		cs.data.AddressBook = append(cs.data.AddressBook,
+			AddressBookData{Label: label, Address: address})
+	}
+
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) DeleteAddressBookData(label string) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	deleted := false
+	for i, v := range cs.data.AddressBook {
+		if v.Label == label {
+			cs.data.AddressBook = append(cs.data.AddressBook[:i], cs.data.AddressBook[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	if !deleted {
+		return errors.New(label + " is not in the address book")
+	}
+
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadAddressBookData() ([]AddressBookData, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+	return cs.data.AddressBook, nil
+}
+
 func (cs *FileStore) SaveStoredData(name string, value []byte) error {
 	JSONData, err := cs.readDB()
 	if err != nil {
@@ -195,7 +356,8 @@ func (cs *FileStore) SaveStoredData(name string, value []byte) error {
 		cs.data.MasterKey = hexValue
 	case "PasswordHash":
 		cs.data.PasswordHash = hexValue
-
+	case "Seed":
+		cs.data.Seed = hexValue
 	}
 	JSONBlob, err := json.Marshal(cs.data)
 	if err != nil {
@@ -223,11 +385,46 @@ func (cs *FileStore) LoadStoredData(name string) ([]byte, error) {
 		return HexStringToBytes(cs.data.MasterKey)
 	case "PasswordHash":
 		return HexStringToBytes(cs.data.PasswordHash)
+	case "Seed":
+		return HexStringToBytes(cs.data.Seed)
 	}
 
 	return nil, errors.New("can't find the key: " + name)
 }
 
+func (cs *FileStore) SaveKDFParams(params *KDFParams) error {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return errors.New("error: unmarshal db")
+	}
+
+	cs.data.KDF = params
+	JSONBlob, err := json.Marshal(cs.data)
+	if err != nil {
+		return errors.New("error: marshal db")
+	}
+	cs.writeDB(JSONBlob)
+
+	return nil
+}
+
+func (cs *FileStore) LoadKDFParams() (*KDFParams, error) {
+	JSONData, err := cs.readDB()
+	if err != nil {
+		return nil, errors.New("error: reading db")
+	}
+	if err := json.Unmarshal(JSONData, &cs.data); err != nil {
+		return nil, errors.New("error: unmarshal db")
+	}
+	if cs.data.KDF == nil {
+		return nil, errors.New("can't find the kdf parameters")
+	}
+	return cs.data.KDF, nil
+}
+
 func (cs *FileStore) SetPath(path string) {
 	cs.Lock()
 	defer cs.Unlock()
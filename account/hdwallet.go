@@ -0,0 +1,171 @@
+package account
+
+import (
+	"errors"
+
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	// MnemonicEntropyBits is the entropy size of a new mnemonic, which makes
+	// a mnemonic of 12 words.
+	MnemonicEntropyBits = 128
+
+	// HDAccountIndex is the BIP44 account the wallet addresses derived from.
+	HDAccountIndex = 0
+)
+
+// NewMnemonic generates a random BIP39 mnemonic for a new HD wallet.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(MnemonicEntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// CreateFromMnemonic creates a HD wallet with the seed of the mnemonic and the
+// passphrase. The main account is the first external address of the ELA
+// account along the BIP44 path, so the same wallet can be restored from the
+// mnemonic.
+func CreateFromMnemonic(path string, password []byte, mnemonic, passphrase string) (*ClientImpl, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+	seed := bip39.NewSeed(mnemonic, passphrase)
+
+	client := NewClient(path, password, true)
+	if client == nil {
+		return nil, errors.New("client nil")
+	}
+	if err := client.saveSeed(seed); err != nil {
+		return nil, err
+	}
+	account, err := client.CreateAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	client.mainAccount = account.ProgramHash.ToCodeHash()
+
+	return client, nil
+}
+
+// IsHD returns if the wallet is created from a mnemonic.
+func (cl *ClientImpl) IsHD() bool {
+	return len(cl.seed) > 0
+}
+
+// ExportXPub returns the extended public key of the ELA account of the HD
+// wallet, all addresses of the wallet can be derived from it without the
+// private keys.
+func (cl *ClientImpl) ExportXPub() (string, error) {
+	if !cl.IsHD() {
+		return "", errors.New("wallet is not created from a mnemonic")
+	}
+	master, err := crypto.NewMasterKey(cl.seed)
+	if err != nil {
+		return "", err
+	}
+	key, err := master.Derive(crypto.AccountPath(HDAccountIndex))
+	if err != nil {
+		return "", err
+	}
+	xpub, err := key.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return xpub.String(), nil
+}
+
+// createHDAccount derives the next external address after the ones already
+// in the wallet.
+func (cl *ClientImpl) createHDAccount() (*Account, error) {
+	storeAccounts, err := cl.LoadAccountData()
+	if err != nil {
+		return nil, err
+	}
+	var index uint32
+	for _, a := range storeAccounts {
+		if a.Path == "" {
+			continue
+		}
+		indexes, err := crypto.ParseDerivationPath(a.Path)
+		if err != nil || len(indexes) == 0 {
+			continue
+		}
+		if last := indexes[len(indexes)-1]; last >= index {
+			index = last + 1
+		}
+	}
+
+	account, path, err := cl.deriveHDAccount(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := cl.saveAccount(account, path); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// HDAddress returns the external address at index of the HD wallet without
+// adding it to the wallet.
+func (cl *ClientImpl) HDAddress(index uint32) (string, error) {
+	if !cl.IsHD() {
+		return "", errors.New("wallet is not created from a mnemonic")
+	}
+	account, _, err := cl.deriveHDAccount(index)
+	if err != nil {
+		return "", err
+	}
+	return account.Address, nil
+}
+
+func (cl *ClientImpl) deriveHDAccount(index uint32) (*Account, string, error) {
+	master, err := crypto.NewMasterKey(cl.seed)
+	if err != nil {
+		return nil, "", err
+	}
+	path := crypto.AddressPath(HDAccountIndex, 0, index)
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, "", err
+	}
+	privateKey, err := key.PrivateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	account, err := NewAccountWithPrivateKey(privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return account, path, nil
+}
+
+func (cl *ClientImpl) saveSeed(seed []byte) error {
+	encryptedSeed, err := cl.encrypt(seed)
+	if err != nil {
+		return err
+	}
+	if err := cl.SaveStoredData("Seed", encryptedSeed); err != nil {
+		return err
+	}
+	cl.seed = seed
+	return nil
+}
+
+func (cl *ClientImpl) loadSeed() error {
+	encryptedSeed, err := cl.LoadStoredData("Seed")
+	if err != nil {
+		return err
+	}
+	if len(encryptedSeed) == 0 {
+		return nil
+	}
+	cl.seed, err = cl.decrypt(encryptedSeed)
+	return err
+}
@@ -0,0 +1,94 @@
+package account
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+
+	// kdfKeyLen is the length of the key derived from the password.
+	kdfKeyLen = 32
+
+	// kdfSaltLen is the length of the random salt.
+	kdfSaltLen = 32
+)
+
+var (
+	// KeystoreKDF is the key derivation function of new keystores.
+	KeystoreKDF = KDFScrypt
+
+	// ScryptN, ScryptR and ScryptP are the scrypt parameters of new
+	// keystores, which takes 32MB memory.
+	ScryptN = 1 << 15
+	ScryptR = 8
+	ScryptP = 1
+
+	// Argon2Time, Argon2Memory (in KiB) and Argon2Threads are the argon2id
+	// parameters of new keystores.
+	Argon2Time    uint32 = 3
+	Argon2Memory  uint32 = 64 * 1024
+	Argon2Threads uint8  = 4
+)
+
+// KDFParams is the key derivation function and its parameters stored in the
+// keystore, so the parameters of new keystores can be tuned without breaking
+// the existing ones.
+type KDFParams struct {
+	Name    string
+	Salt    string
+	N       int    `json:",omitempty"`
+	R       int    `json:",omitempty"`
+	P       int    `json:",omitempty"`
+	Time    uint32 `json:",omitempty"`
+	Memory  uint32 `json:",omitempty"`
+	Threads uint8  `json:",omitempty"`
+}
+
+// NewKDFParams creates the parameters of the key derivation function name with
+// the current settings and a random salt.
+func NewKDFParams(name string) (*KDFParams, error) {
+	salt := make([]byte, kdfSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	params := &KDFParams{Name: name, Salt: common.BytesToHexString(salt)}
+	switch name {
+	case KDFScrypt:
+		params.N, params.R, params.P = ScryptN, ScryptR, ScryptP
+	case KDFArgon2id:
+		params.Time, params.Memory, params.Threads =
+			Argon2Time, Argon2Memory, Argon2Threads
+	default:
+		return nil, errors.New("unknown key derivation function " + name)
+	}
+	return params, nil
+}
+
+// DeriveKey derives the key encrypting the master key from password.
+func (p *KDFParams) DeriveKey(password []byte) ([]byte, error) {
+	salt, err := common.HexStringToBytes(p.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Name {
+	case KDFScrypt:
+		return scrypt.Key(password, salt, p.N, p.R, p.P, kdfKeyLen)
+	case KDFArgon2id:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		return argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads,
+			kdfKeyLen), nil
+	}
+	return nil, errors.New("unknown key derivation function " + p.Name)
+}
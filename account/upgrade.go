@@ -0,0 +1,110 @@
+package account
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+// BackupSuffix is appended to the keystore path to name the backup file
+// created before upgrading.
+const BackupSuffix = ".bak"
+
+// UpgradeKeystore re-encrypts the keystore at path in place with the current
+// keystore version and the key derivation function kdf, with a new master
+// key. The original file is copied to the returned backup path first.
+func UpgradeKeystore(path string, password []byte, kdf string) (string, error) {
+	client, err := Open(path, password)
+	if err != nil {
+		return "", err
+	}
+
+	JSONData, err := client.readDB()
+	if err != nil {
+		return "", errors.New("error: reading db")
+	}
+	var data FileData
+	if err := json.Unmarshal(JSONData, &data); err != nil {
+		return "", errors.New("error: unmarshal db")
+	}
+
+	// decrypt all private keys with the original master key
+	privateKeys := make([][]byte, 0, len(data.Account))
+	defer func() {
+		for _, key := range privateKeys {
+			common.ClearBytes(key)
+		}
+	}()
+	for _, a := range data.Account {
+		encryptedKey, err := common.HexStringToBytes(a.PrivateKeyEncrypted)
+		if err != nil {
+			return "", err
+		}
+		key, err := client.DecryptPrivateKey(encryptedKey)
+		if err != nil {
+			return "", errors.New("failed to decrypt private key of " + a.Address)
+		}
+		privateKeys = append(privateKeys, key)
+	}
+
+	backup := path + BackupSuffix
+	if _, err := os.Stat(backup); err == nil || os.IsExist(err) {
+		return "", errors.New(backup + " file already exist")
+	}
+	if err := ioutil.WriteFile(backup, JSONData, 0600); err != nil {
+		return "", err
+	}
+
+	params, err := NewKDFParams(kdf)
+	if err != nil {
+		return "", err
+	}
+	passwordKey, err := params.DeriveKey(password)
+	if err != nil {
+		return "", err
+	}
+	defer common.ClearBytes(passwordKey)
+	masterKey := make([]byte, 32)
+	if _, err := rand.Read(masterKey); err != nil {
+		return "", err
+	}
+	encryptedMasterKey, err := crypto.AesGcmEncrypt(masterKey, passwordKey)
+	if err != nil {
+		return "", err
+	}
+
+	client.version = KeystoreVersion
+	client.masterKey = masterKey
+	client.iv = nil
+
+	data.Version = KeystoreVersion
+	data.KDF = params
+	data.PasswordHash = ""
+	data.IV = ""
+	data.MasterKey = common.BytesToHexString(encryptedMasterKey)
+	for i, key := range privateKeys {
+		encryptedKey, err := client.EncryptPrivateKey(key)
+		if err != nil {
+			return "", err
+		}
+		data.Account[i].PrivateKeyEncrypted = common.BytesToHexString(encryptedKey)
+	}
+	if client.IsHD() {
+		encryptedSeed, err := client.encrypt(client.seed)
+		if err != nil {
+			return "", err
+		}
+		data.Seed = common.BytesToHexString(encryptedSeed)
+	}
+
+	JSONBlob, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.New("error: marshal db")
+	}
+	return backup, client.writeDB(JSONBlob)
+}
@@ -0,0 +1,211 @@
+package account
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon " +
+	"abandon abandon abandon abandon abandon about"
+
+// setupKeystoreTest returns a temporary directory for keystores, the KDF
+// parameters are lowered to keep the tests fast.
+func setupKeystoreTest(t *testing.T) (string, func()) {
+	log.Init(0, 20, 100)
+	dir, err := ioutil.TempDir("", "keystore")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	scryptN, argon2Memory := ScryptN, Argon2Memory
+	ScryptN, Argon2Memory = 1<<10, 1024
+	return dir, func() {
+		ScryptN, Argon2Memory = scryptN, argon2Memory
+		os.RemoveAll(dir)
+	}
+}
+
+// createV1Keystore creates a keystore of version 1.0.0 with one account, as
+// the ones created before the KDF was introduced.
+func createV1Keystore(t *testing.T, path string, password []byte) *Account {
+	client := &ClientImpl{
+		path:      path,
+		accounts:  map[common.Uint160]*Account{},
+		FileStore: FileStore{path: path},
+		version:   KeystoreVersionV1,
+		masterKey: make([]byte, 32),
+		iv:        make([]byte, 16),
+	}
+	rand.Read(client.masterKey)
+	rand.Read(client.iv)
+	client.BuildDatabase(path)
+
+	passwordKey := crypto.ToAesKey(password)
+	passwordHash := sha256.Sum256(passwordKey)
+	encryptedMasterKey, err := crypto.AesEncrypt(client.masterKey,
+		passwordKey, client.iv)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SaveStoredData("Version", []byte(KeystoreVersionV1)))
+	assert.NoError(t, client.SaveStoredData("IV", client.iv))
+	assert.NoError(t, client.SaveStoredData("PasswordHash", passwordHash[:]))
+	assert.NoError(t, client.SaveStoredData("MasterKey", encryptedMasterKey))
+
+	account, err := NewAccount()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, client.saveAccount(account, ""))
+	return account
+}
+
+func TestOpen_KeystoreV1(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	path := filepath.Join(dir, "keystore.dat")
+	password := []byte("123")
+	account := createV1Keystore(t, path, password)
+
+	client, err := Open(path, password)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, KeystoreVersionV1, client.version)
+	main, err := client.GetDefaultAccount()
+	assert.NoError(t, err)
+	if assert.NotNil(t, main) {
+		assert.Equal(t, account.PrivateKey, main.PrivateKey)
+	}
+
+	_, err = Open(path, []byte("456"))
+	assert.Error(t, err)
+}
+
+func TestUpgradeKeystore(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	password := []byte("123")
+
+	for _, kdf := range []string{KDFScrypt, KDFArgon2id} {
+		path := filepath.Join(dir, kdf+".dat")
+		account := createV1Keystore(t, path, password)
+		original, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+
+		backup, err := UpgradeKeystore(path, password, kdf)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		// the original keystore is kept as the backup
+		assert.Equal(t, path+BackupSuffix, backup)
+		saved, err := ioutil.ReadFile(backup)
+		assert.NoError(t, err)
+		assert.Equal(t, original, saved)
+
+		client, err := Open(path, password)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, KeystoreVersion, client.version)
+		kdfParams, err := client.LoadKDFParams()
+		assert.NoError(t, err)
+		assert.Equal(t, kdf, kdfParams.Name)
+		main, err := client.GetDefaultAccount()
+		assert.NoError(t, err)
+		if assert.NotNil(t, main) {
+			assert.Equal(t, account.PrivateKey, main.PrivateKey)
+		}
+
+		// the upgraded keystore still refuses a wrong password
+		_, err = Open(path, []byte("456"))
+		assert.Error(t, err)
+
+		// the backup opens with the original password
+		client, err = Open(backup, password)
+		if assert.NoError(t, err) {
+			assert.Equal(t, KeystoreVersionV1, client.version)
+		}
+	}
+}
+
+func TestUpgradeKeystore_HD(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	path := filepath.Join(dir, "keystore.dat")
+	password := []byte("123")
+
+	client, err := CreateFromMnemonic(path, password, testMnemonic, "passphrase")
+	if !assert.NoError(t, err) {
+		return
+	}
+	second, err := client.CreateAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	xpub, err := client.ExportXPub()
+	assert.NoError(t, err)
+
+	_, err = UpgradeKeystore(path, password, KDFArgon2id)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	upgraded, err := Open(path, password)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, upgraded.IsHD())
+	assert.Equal(t, client.seed, upgraded.seed)
+	upgradedXPub, err := upgraded.ExportXPub()
+	assert.NoError(t, err)
+	assert.Equal(t, xpub, upgradedXPub)
+	assert.Equal(t, len(client.GetAccounts()), len(upgraded.GetAccounts()))
+	account := upgraded.GetAccountByCodeHash(second.ProgramHash.ToCodeHash())
+	if assert.NotNil(t, account) {
+		assert.Equal(t, second.PrivateKey, account.PrivateKey)
+	}
+
+	// new accounts continue along the derivation path
+	third, err := upgraded.CreateAccount()
+	assert.NoError(t, err)
+	address, err := client.HDAddress(2)
+	assert.NoError(t, err)
+	assert.Equal(t, address, third.Address)
+
+	_, err = Open(path, []byte("456"))
+	assert.Error(t, err)
+}
+
+func TestUpgradeKeystore_BackupExists(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	path := filepath.Join(dir, "keystore.dat")
+	password := []byte("123")
+	createV1Keystore(t, path, password)
+	original, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	backup := []byte("previous backup")
+	assert.NoError(t, ioutil.WriteFile(path+BackupSuffix, backup, 0600))
+
+	_, err = UpgradeKeystore(path, password, KDFScrypt)
+	assert.Error(t, err)
+
+	// neither the keystore nor the existing backup is changed
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(original, data))
+	data, err = ioutil.ReadFile(path + BackupSuffix)
+	assert.NoError(t, err)
+	assert.Equal(t, backup, data)
+}
@@ -0,0 +1,77 @@
+package account
+
+import (
+	"errors"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+// WatchData is an address the wallet tracks without holding the private key,
+// like a cold storage address or a multi-sign address the wallet does not
+// control alone. The redeem script is kept if it is known, so unsigned
+// transactions spending from the address can be created.
+type WatchData struct {
+	Address      string
+	ProgramHash  string
+	RedeemScript string `json:",omitempty"`
+	Label        string `json:",omitempty"`
+}
+
+// AddressBookData is a labelled address of a frequent recipient.
+type AddressBookData struct {
+	Label   string
+	Address string
+}
+
+// NewWatchData creates the watch-only entry of address, the redeem script is
+// unknown so it can only be used to track balance.
+func NewWatchData(address, label string) (*WatchData, error) {
+	programHash, err := common.Uint168FromAddress(address)
+	if err != nil {
+		return nil, errors.New("invalid address " + address)
+	}
+	return &WatchData{
+		Address:     address,
+		ProgramHash: common.BytesToHexString(programHash.Bytes()),
+		Label:       label,
+	}, nil
+}
+
+// NewWatchDataByPublicKey creates the watch-only entry of the standard address
+// of the public key.
+func NewWatchDataByPublicKey(publicKey *crypto.PublicKey, label string) (*WatchData, error) {
+	c, err := contract.CreateStandardContractByPubKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newWatchDataByContract(c, label)
+}
+
+// NewMultiSignWatchData creates the watch-only entry of the M-of-N multi-sign
+// address of the public keys.
+func NewMultiSignWatchData(m int, publicKeys []*crypto.PublicKey, label string) (*WatchData, error) {
+	c, err := contract.CreateMultiSigContractByPubKey(m, publicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return newWatchDataByContract(c, label)
+}
+
+func newWatchDataByContract(c *contract.Contract, label string) (*WatchData, error) {
+	programHash, err := c.ToProgramHash()
+	if err != nil {
+		return nil, err
+	}
+	address, err := programHash.ToAddress()
+	if err != nil {
+		return nil, err
+	}
+	return &WatchData{
+		Address:      address,
+		ProgramHash:  common.BytesToHexString(programHash.Bytes()),
+		RedeemScript: common.BytesToHexString(c.Code),
+		Label:        label,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package account
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore_WatchData(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	path := filepath.Join(dir, "keystore.dat")
+	client, err := Create(path, []byte("123"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	main, err := client.GetDefaultAccount()
+	assert.NoError(t, err)
+
+	_, publicKey1, _ := crypto.GenerateKeyPair()
+	_, publicKey2, _ := crypto.GenerateKeyPair()
+	single, err := NewWatchDataByPublicKey(publicKey1, "cold")
+	assert.NoError(t, err)
+	multi, err := NewMultiSignWatchData(2,
+		[]*crypto.PublicKey{publicKey1, publicKey2}, "shared")
+	assert.NoError(t, err)
+	byAddress, err := NewWatchData(multi.Address, "")
+	assert.NoError(t, err)
+	assert.Equal(t, multi.ProgramHash, byAddress.ProgramHash)
+	assert.Equal(t, "", byAddress.RedeemScript)
+	_, err = NewWatchData("invalid", "")
+	assert.Error(t, err)
+
+	// the redeem script matches the address
+	script, err := common.HexStringToBytes(multi.RedeemScript)
+	assert.NoError(t, err)
+	programHash, err := common.Uint168FromAddress(multi.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, programHash.ToCodeHash(), *common.ToCodeHash(script))
+
+	assert.NoError(t, client.SaveWatchData(single))
+	assert.NoError(t, client.SaveWatchData(byAddress))
+
+	// watching an address again replaces the entry
+	assert.NoError(t, client.SaveWatchData(multi))
+	watches, err := client.LoadWatchData()
+	assert.NoError(t, err)
+	assert.Equal(t, []WatchData{*single, *multi}, watches)
+
+	// the accounts of the wallet can not be watched
+	own, err := NewWatchData(main.Address, "")
+	assert.NoError(t, err)
+	assert.Error(t, client.SaveWatchData(own))
+
+	// the entries are kept after reopening
+	var fileStore FileStore
+	fileStore.SetPath(path)
+	watches, err = fileStore.LoadWatchData()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(watches))
+
+	assert.NoError(t, fileStore.DeleteWatchData(single.Address))
+	assert.Error(t, fileStore.DeleteWatchData(single.Address))
+	assert.Error(t, fileStore.DeleteWatchData(main.Address))
+	watches, err = fileStore.LoadWatchData()
+	assert.NoError(t, err)
+	assert.Equal(t, []WatchData{*multi}, watches)
+}
+
+func TestFileStore_AddressBookData(t *testing.T) {
+	dir, teardown := setupKeystoreTest(t)
+	defer teardown()
+	path := filepath.Join(dir, "keystore.dat")
+	_, err := Create(path, []byte("123"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var fileStore FileStore
+	fileStore.SetPath(path)
+	assert.NoError(t, fileStore.SaveAddressBookData("alice", "address1"))
+	assert.NoError(t, fileStore.SaveAddressBookData("bob", "address2"))
+
+	// saving a label again replaces the address
+	assert.NoError(t, fileStore.SaveAddressBookData("alice", "address3"))
+	contacts, err := fileStore.LoadAddressBookData()
+	assert.NoError(t, err)
+	assert.Equal(t, []AddressBookData{
+		{Label: "alice", Address: "address3"},
+		{Label: "bob", Address: "address2"},
+	}, contacts)
+
+	assert.NoError(t, fileStore.DeleteAddressBookData("alice"))
+	assert.Error(t, fileStore.DeleteAddressBookData("alice"))
+	assert.Error(t, fileStore.DeleteAddressBookData("carol"))
+	contacts, err = fileStore.LoadAddressBookData()
+	assert.NoError(t, err)
+	assert.Equal(t, []AddressBookData{{Label: "bob", Address: "address2"}},
+		contacts)
+}
@@ -0,0 +1,239 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/log"
+	. "github.com/elastos/Elastos.ELA/core/types"
+)
+
+type HistoryDirection byte
+
+const (
+	// HistoryReceived means the address received coin from an output.
+	HistoryReceived HistoryDirection = 0x00
+
+	// HistorySent means the address spent coin with an input.
+	HistorySent HistoryDirection = 0x01
+)
+
+// AddressHistory is a record of an address receiving or spending coin in a
+// transaction. Index is the output index for received coin, or the input
+// index for sent coin.
+type AddressHistory struct {
+	Height    uint32
+	TxID      Uint256
+	Direction HistoryDirection
+	Index     uint16
+	Value     Fixed64
+}
+
+// addressHistoryKey builds the key of an address history record, the height
+// is big endian so the records of an address are ordered by height.
+func addressHistoryKey(programHash Uint168, h *AddressHistory) []byte {
+	key := new(bytes.Buffer)
+	key.WriteByte(byte(IXAddressHistory))
+	programHash.Serialize(key)
+	binary.Write(key, binary.BigEndian, h.Height)
+	h.TxID.Serialize(key)
+	key.WriteByte(byte(h.Direction))
+	binary.Write(key, binary.BigEndian, h.Index)
+	return key.Bytes()
+}
+
+func parseAddressHistory(key, value []byte) (*AddressHistory, error) {
+	r := bytes.NewReader(key[1+UINT168SIZE:])
+	h := new(AddressHistory)
+	if err := binary.Read(r, binary.BigEndian, &h.Height); err != nil {
+		return nil, err
+	}
+	if err := h.TxID.Deserialize(r); err != nil {
+		return nil, err
+	}
+	direction, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	h.Direction = HistoryDirection(direction)
+	if err := binary.Read(r, binary.BigEndian, &h.Index); err != nil {
+		return nil, err
+	}
+	if err := h.Value.Deserialize(bytes.NewReader(value)); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// blockAddressHistory returns the address history records created by block.
+func (c *ChainStore) blockAddressHistory(b *Block) (map[Uint168][]*AddressHistory, error) {
+	history := make(map[Uint168][]*AddressHistory)
+	blockTxs := make(map[Uint256]*Transaction)
+	for _, txn := range b.Transactions {
+		blockTxs[txn.Hash()] = txn
+		if txn.TxType == RegisterAsset {
+			continue
+		}
+		for index, output := range txn.Outputs {
+			history[output.ProgramHash] = append(history[output.ProgramHash],
+				&AddressHistory{
+					Height:    b.Height,
+					TxID:      txn.Hash(),
+					Direction: HistoryReceived,
+					Index:     uint16(index),
+					Value:     output.Value,
+				})
+		}
+		if txn.IsCoinBaseTx() {
+			continue
+		}
+		for index, input := range txn.Inputs {
+			// the referenced transaction may be an earlier one in this block
+			referTxn, ok := blockTxs[input.Previous.TxID]
+			if !ok {
+				var err error
+				referTxn, _, err = c.GetTransaction(input.Previous.TxID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if int(input.Previous.Index) >= len(referTxn.Outputs) {
+				return nil, errors.New("[addressHistory] refer index out of range")
+			}
+			output := referTxn.Outputs[input.Previous.Index]
+			history[output.ProgramHash] = append(history[output.ProgramHash],
+				&AddressHistory{
+					Height:    b.Height,
+					TxID:      txn.Hash(),
+					Direction: HistorySent,
+					Index:     uint16(index),
+					Value:     output.Value,
+				})
+		}
+	}
+	return history, nil
+}
+
+func (c *ChainStore) persistAddressHistory(b *Block) error {
+	history, err := c.blockAddressHistory(b)
+	if err != nil {
+		return err
+	}
+	for programHash, records := range history {
+		for _, h := range records {
+			value := new(bytes.Buffer)
+			if err := h.Value.Serialize(value); err != nil {
+				return err
+			}
+			c.BatchPut(addressHistoryKey(programHash, h), value.Bytes())
+		}
+	}
+	// the address index is complete up to this block
+	c.BatchPut([]byte{byte(SYSAddressIndexTip)}, b.Hash().Bytes())
+	return nil
+}
+
+func (c *ChainStore) RollbackAddressHistory(b *Block) error {
+	history, err := c.blockAddressHistory(b)
+	if err != nil {
+		return err
+	}
+	for programHash, records := range history {
+		for _, h := range records {
+			c.BatchDelete(addressHistoryKey(programHash, h))
+		}
+	}
+	c.BatchPut([]byte{byte(SYSAddressIndexTip)}, b.Previous.Bytes())
+	return nil
+}
+
+// GetAddressHistory returns the history records of the address from the
+// newest to the oldest, skipping the first skip records and returning at most
+// count records.
+func (c *ChainStore) GetAddressHistory(programHash Uint168, skip, count int) ([]*AddressHistory, error) {
+	prefix := new(bytes.Buffer)
+	prefix.WriteByte(byte(IXAddressHistory))
+	programHash.Serialize(prefix)
+
+	iter := c.NewIterator(prefix.Bytes())
+	defer iter.Release()
+
+	history := make([]*AddressHistory, 0)
+	for ok := iter.Last(); ok && len(history) < count; ok = iter.Prev() {
+		if skip > 0 {
+			skip--
+			continue
+		}
+		h, err := parseAddressHistory(iter.Key(), iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, nil
+}
+
+// RebuildAddressIndex removes the address history records and creates them
+// again from the blocks in store, so the index can be enabled on an existing
+// data directory.
+func (c *ChainStore) RebuildAddressIndex() error {
+	iter := c.NewIterator([]byte{byte(IXAddressHistory)})
+	c.NewBatch()
+	for iter.Next() {
+		c.BatchDelete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := c.BatchCommit(); err != nil {
+		return err
+	}
+
+	data, err := c.Get([]byte{byte(SYSCurrentBlock)})
+	if err != nil {
+		return err
+	}
+	currentHeight, err := ReadUint32(bytes.NewReader(data[UINT256SIZE:]))
+	if err != nil {
+		return err
+	}
+	for height := uint32(0); height <= currentHeight; height++ {
+		hash, err := c.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		block, err := c.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		c.NewBatch()
+		if err := c.persistAddressHistory(block); err != nil {
+			return err
+		}
+		if err := c.BatchCommit(); err != nil {
+			return err
+		}
+		if height%10000 == 0 {
+			log.Infof("address index rebuilt to height %d", height)
+		}
+	}
+	return nil
+}
+
+// catchUpAddressIndex rebuilds the address index from the blocks in store if
+// it has not been kept updated up to the current block, as when it is enabled
+// on an existing data directory, or blocks were saved or rolled back while it
+// was disabled.
+func (c *ChainStore) catchUpAddressIndex(currentHash Uint256) error {
+	tip, err := c.Get([]byte{byte(SYSAddressIndexTip)})
+	if err == nil && bytes.Equal(tip, currentHash.Bytes()) {
+		return nil
+	}
+
+	log.Info("address index is not up to date, rebuilding it, this may take a while...")
+	if err := c.RebuildAddressIndex(); err != nil {
+		return err
+	}
+	log.Info("address index rebuilt")
+	return nil
+}
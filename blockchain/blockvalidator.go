@@ -173,13 +173,23 @@ func PowCheckBlockSanity(block *Block, powLimit *big.Int, timeSource MedianTimeS
 func CheckBlockContext(block *Block) error {
 	var totalTxFee = Fixed64(0)
 
+	// transactions may spend outputs of the transactions before them in block
+	// since InBlockSpendHeight
+	var parents map[Uint256]*Transaction
+	if IsInBlockSpendAllowed(block.Height) {
+		parents = make(map[Uint256]*Transaction)
+	}
 	for i := 1; i < len(block.Transactions); i++ {
-		if errCode := CheckTransactionContext(block.Height, block.Transactions[i]); errCode != Success {
+		txn := block.Transactions[i]
+		if errCode := CheckTransactionContextWithParents(block.Height, txn, parents); errCode != Success {
 			return errors.New("CheckTransactionContext failed when verify block")
 		}
 
 		// Calculate transaction fee
-		totalTxFee += GetTxFee(block.Transactions[i], DefaultLedger.Blockchain.AssetID)
+		totalTxFee += GetTxFeeWithParents(txn, DefaultLedger.Blockchain.AssetID, parents)
+		if parents != nil {
+			parents[txn.Hash()] = txn
+		}
 	}
 
 	return checkCoinbaseTransactionContext(block.Height, block.Transactions[0], totalTxFee)
@@ -266,8 +276,20 @@ func IsFinalizedTransaction(msgTx *Transaction, blockHeight uint32) bool {
 	return true
 }
 
+// IsInBlockSpendAllowed returns if transactions in the block of the given
+// height can spend outputs of the transactions before them in the same block.
+func IsInBlockSpendAllowed(height uint32) bool {
+	return height >= config.Parameters.InBlockSpendHeight
+}
+
 func GetTxFee(tx *Transaction, assetId Uint256) Fixed64 {
-	feeMap, err := GetTxFeeMap(tx)
+	return GetTxFeeWithParents(tx, assetId, nil)
+}
+
+// GetTxFeeWithParents returns the fee of tx like GetTxFee, inputs spending
+// outputs of the given unconfirmed parent transactions are resolved from them.
+func GetTxFeeWithParents(tx *Transaction, assetId Uint256, parents map[Uint256]*Transaction) Fixed64 {
+	feeMap, err := getTxFeeMap(tx, parents)
 	if err != nil {
 		return 0
 	}
@@ -276,8 +298,12 @@ func GetTxFee(tx *Transaction, assetId Uint256) Fixed64 {
 }
 
 func GetTxFeeMap(tx *Transaction) (map[Uint256]Fixed64, error) {
+	return getTxFeeMap(tx, nil)
+}
+
+func getTxFeeMap(tx *Transaction, parents map[Uint256]*Transaction) (map[Uint256]Fixed64, error) {
 	feeMap := make(map[Uint256]Fixed64)
-	reference, err := DefaultLedger.Store.GetTxReference(tx)
+	reference, err := GetTxReferenceWithParents(tx, parents)
 	if err != nil {
 		return nil, err
 	}
@@ -318,6 +344,34 @@ func GetTxFeeMap(tx *Transaction) (map[Uint256]Fixed64, error) {
 	return feeMap, nil
 }
 
+// GetTxReferenceWithParents returns the outputs referenced by the inputs of tx.
+// Outputs of the given unconfirmed parent transactions are resolved from them,
+// others are looked up in the ledger.
+func GetTxReferenceWithParents(tx *Transaction, parents map[Uint256]*Transaction) (map[*Input]*Output, error) {
+	if len(parents) == 0 || tx.TxType == RegisterAsset {
+		return DefaultLedger.Store.GetTxReference(tx)
+	}
+
+	reference := make(map[*Input]*Output)
+	for _, input := range tx.Inputs {
+		txID := input.Previous.TxID
+		index := input.Previous.Index
+		transaction, ok := parents[txID]
+		if !ok {
+			var err error
+			transaction, _, err = DefaultLedger.Store.GetTransaction(txID)
+			if err != nil {
+				return nil, errors.New("GetTxReference failed, previous transaction not found")
+			}
+		}
+		if int(index) >= len(transaction.Outputs) {
+			return nil, errors.New("GetTxReference failed, refIdx out of range.")
+		}
+		reference[input] = transaction.Outputs[index]
+	}
+	return reference, nil
+}
+
 func checkCoinbaseTransactionContext(blockHeight uint32, coinbase *Transaction, totalTxFee Fixed64) error {
 	var rewardInCoinbase = Fixed64(0)
 	outputAddressMap := make(map[Uint168]Fixed64)
@@ -3,14 +3,18 @@ package blockchain
 import (
 	"bytes"
 	"encoding/hex"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/elastos/Elastos.ELA/blockchain/mock"
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
 	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -76,3 +80,83 @@ func TestCheckBlockSanity(t *testing.T) {
 	assert.Error(t, err, "[Error] block passed check with invalid hash")
 	assert.EqualError(t, err, "[PowCheckBlockSanity] block check aux pow failed")
 }
+
+func TestCheckBlockContext_InBlockSpend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inblockspend")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewChainStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	originLedger := DefaultLedger
+	defer func() { DefaultLedger = originLedger }()
+	if !assert.NoError(t, Init(store, mock.NewBlockHeightMock())) {
+		return
+	}
+	spendHeight := config.Parameters.InBlockSpendHeight
+	defer func() { config.Parameters.InBlockSpendHeight = spendHeight }()
+	config.Parameters.InBlockSpendHeight = 100
+
+	act := newAccount(t)
+	assetID := DefaultLedger.Blockchain.AssetID
+	fee := common.Fixed64(config.Parameters.PowConfiguration.MinTxFee)
+	newTransfer := func(prev common.Uint256, value common.Fixed64) *types.Transaction {
+		tx := &types.Transaction{
+			TxType:  types.TransferAsset,
+			Payload: &payload.PayloadTransferAsset{},
+			Outputs: []*types.Output{{AssetID: assetID, Value: value,
+				ProgramHash: *act.ProgramHash()}},
+		}
+		if prev != common.EmptyHash {
+			tx.Inputs = []*types.Input{{Previous: *types.NewOutPoint(prev, 0)}}
+		}
+		signature, err := act.Sign(getData(tx))
+		assert.NoError(t, err)
+		tx.Programs = []*program.Program{{Code: act.RedeemScript(),
+			Parameter: signature}}
+		return tx
+	}
+
+	// the output to spend is confirmed in ledger
+	funding := newTransfer(common.EmptyHash, 1000*fee)
+	assert.NoError(t, store.(*ChainStore).persist(&types.Block{
+		Header:       types.Header{Height: 1},
+		Transactions: []*types.Transaction{funding},
+	}))
+
+	parent := newTransfer(funding.Hash(), 999*fee)
+	child := newTransfer(parent.Hash(), 998*fee)
+	newBlock := func(height uint32, txs ...*types.Transaction) *types.Block {
+		coinbase := &types.Transaction{
+			TxType:  types.CoinBase,
+			Payload: &payload.PayloadCoinBase{},
+			Outputs: []*types.Output{{AssetID: assetID,
+				Value:       RewardAmountPerBlock + common.Fixed64(len(txs))*fee,
+				ProgramHash: FoundationAddress}},
+		}
+		return &types.Block{
+			Header:       types.Header{Height: height},
+			Transactions: append([]*types.Transaction{coinbase}, txs...),
+		}
+	}
+
+	// spending outputs of the transactions before in block is not allowed
+	// before InBlockSpendHeight
+	assert.NoError(t, CheckBlockContext(newBlock(99, parent)))
+	assert.Error(t, CheckBlockContext(newBlock(99, parent, child)))
+
+	block := newBlock(100, parent, child)
+	assert.NoError(t, CheckBlockContext(block))
+
+	// the output spent in block is not left unspent
+	assert.NoError(t, store.(*ChainStore).persist(block))
+	unspent, _ := store.ContainsUnspent(parent.Hash(), 0)
+	assert.False(t, unspent)
+	unspent, err = store.ContainsUnspent(child.Hash(), 0)
+	assert.NoError(t, err)
+	assert.True(t, unspent)
+}
@@ -0,0 +1,247 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	// BoltDBFile is the name of the database file under the store directory.
+	BoltDBFile = "bolt.db"
+)
+
+var (
+	boltBucket = []byte("ela")
+
+	// ErrBoltNotFound is returned by Get if the key does not exist.
+	ErrBoltNotFound = errors.New("bolt: not found")
+)
+
+func init() {
+	creator := func(path string) (IStore, error) {
+		return NewBoltDB(path)
+	}
+	RegisterStore("bolt", creator)
+	RegisterStore("bbolt", creator)
+}
+
+type boltOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// BoltDB is a store keeping all records in one bucket of a bbolt database.
+type BoltDB struct {
+	db    *bbolt.DB
+	batch []boltOp
+}
+
+// NewBoltDB opens the bolt database under the directory dir, so the same
+// directory layout can be used as LevelDB.
+func NewBoltDB(dir string) (*BoltDB, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(filepath.Join(dir, BoltDBFile), 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltDB{db: db}, nil
+}
+
+func (bdb *BoltDB) Put(key []byte, value []byte) error {
+	return bdb.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Put(key, value)
+	})
+}
+
+func (bdb *BoltDB) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get(key)
+		if v == nil {
+			return ErrBoltNotFound
+		}
+		// the value is only valid in the transaction
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+func (bdb *BoltDB) Delete(key []byte) error {
+	return bdb.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete(key)
+	})
+}
+
+func (bdb *BoltDB) NewBatch() {
+	bdb.batch = nil
+}
+
+func (bdb *BoltDB) BatchPut(key []byte, value []byte) {
+	bdb.batch = append(bdb.batch, boltOp{key: key, value: value})
+}
+
+func (bdb *BoltDB) BatchDelete(key []byte) {
+	bdb.batch = append(bdb.batch, boltOp{key: key, delete: true})
+}
+
+func (bdb *BoltDB) BatchCommit() error {
+	return bdb.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, op := range bdb.batch {
+			var err error
+			if op.delete {
+				err = bucket.Delete(op.key)
+			} else {
+				err = bucket.Put(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bdb *BoltDB) Close() error {
+	return bdb.db.Close()
+}
+
+// NewIterator returns an iterator over the records with the prefix, it holds
+// a read transaction until released.
+func (bdb *BoltDB) NewIterator(prefix []byte) IIterator {
+	tx, err := bdb.db.Begin(false)
+	if err != nil {
+		return &boltIterator{err: err}
+	}
+	return &boltIterator{
+		tx:     tx,
+		cursor: tx.Bucket(boltBucket).Cursor(),
+		prefix: prefix,
+	}
+}
+
+// boltIterator moves like a LevelDB iterator, which is positioned before the
+// first record when created.
+type boltIterator struct {
+	tx      *bbolt.Tx
+	cursor  *bbolt.Cursor
+	prefix  []byte
+	started bool
+	key     []byte
+	value   []byte
+	err     error
+}
+
+func (it *boltIterator) set(key, value []byte) bool {
+	it.started = true
+	if key == nil || !bytes.HasPrefix(key, it.prefix) {
+		it.key, it.value = nil, nil
+		return false
+	}
+	it.key, it.value = key, value
+	return true
+}
+
+func (it *boltIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		return it.First()
+	}
+	if it.key == nil {
+		return false
+	}
+	return it.set(it.cursor.Next())
+}
+
+func (it *boltIterator) Prev() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		return it.Last()
+	}
+	if it.key == nil {
+		return false
+	}
+	return it.set(it.cursor.Prev())
+}
+
+func (it *boltIterator) First() bool {
+	if it.err != nil {
+		return false
+	}
+	return it.set(it.cursor.Seek(it.prefix))
+}
+
+func (it *boltIterator) Last() bool {
+	if it.err != nil {
+		return false
+	}
+	// seek to the first key after the prefix range and step back
+	limit := prefixLimit(it.prefix)
+	if limit == nil {
+		return it.set(it.cursor.Last())
+	}
+	if key, _ := it.cursor.Seek(limit); key == nil {
+		return it.set(it.cursor.Last())
+	}
+	return it.set(it.cursor.Prev())
+}
+
+func (it *boltIterator) Seek(key []byte) bool {
+	if it.err != nil {
+		return false
+	}
+	if bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	}
+	return it.set(it.cursor.Seek(key))
+}
+
+func (it *boltIterator) Key() []byte {
+	return it.key
+}
+
+func (it *boltIterator) Value() []byte {
+	return it.value
+}
+
+func (it *boltIterator) Release() {
+	if it.tx != nil {
+		it.tx.Rollback()
+		it.tx = nil
+	}
+}
+
+// prefixLimit returns the smallest key greater than all keys with the prefix,
+// or nil if there is no such key.
+func prefixLimit(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			limit := append([]byte{}, prefix[:i+1]...)
+			limit[i]++
+			return limit
+		}
+	}
+	return nil
+}
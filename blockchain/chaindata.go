@@ -123,8 +123,10 @@ func (c *ChainStore) RollbackCurrentBlock(b *Block) error {
 func (c *ChainStore) persistUnspendUTXOs(b *Block) error {
 	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*UTXO)
 	curHeight := b.Header.Height
+	blockTxs := make(map[Uint256]*Transaction)
 
 	for _, txn := range b.Transactions {
+		blockTxs[txn.Hash()] = txn
 		if txn.TxType == RegisterAsset {
 			continue
 		}
@@ -161,9 +163,15 @@ func (c *ChainStore) persistUnspendUTXOs(b *Block) error {
 
 		if !txn.IsCoinBaseTx() {
 			for _, input := range txn.Inputs {
-				referTxn, height, err := c.GetTransaction(input.Previous.TxID)
-				if err != nil {
-					return err
+				// the referenced transaction may be an earlier one in this
+				// block, which is not committed yet
+				referTxn, height := blockTxs[input.Previous.TxID], curHeight
+				if referTxn == nil {
+					var err error
+					referTxn, height, err = c.GetTransaction(input.Previous.TxID)
+					if err != nil {
+						return err
+					}
 				}
 				index := input.Previous.Index
 				referTxnOutput := referTxn.Outputs[index]
@@ -178,6 +186,7 @@ func (c *ChainStore) persistUnspendUTXOs(b *Block) error {
 				}
 
 				if _, ok := unspendUTXOs[programHash][assetID][height]; !ok {
+					var err error
 					unspendUTXOs[programHash][assetID][height], err = c.GetUnspentElementFromProgramHash(programHash, assetID, height)
 
 					if err != nil {
@@ -221,6 +230,10 @@ func (c *ChainStore) persistUnspendUTXOs(b *Block) error {
 func (c *ChainStore) RollbackUnspendUTXOs(b *Block) error {
 	unspendUTXOs := make(map[Uint168]map[Uint256]map[uint32][]*UTXO)
 	height := b.Header.Height
+	blockTxs := make(map[Uint256]struct{})
+	for _, txn := range b.Transactions {
+		blockTxs[txn.Hash()] = struct{}{}
+	}
 	for _, txn := range b.Transactions {
 		if txn.TxType == RegisterAsset {
 			continue
@@ -247,18 +260,27 @@ func (c *ChainStore) RollbackUnspendUTXOs(b *Block) error {
 				Index: uint32(index),
 				Value: value,
 			}
-			var position int
+			// outputs spent by later transactions in this block are not in
+			// the unspent list
+			position := -1
 			for i, unspend := range unspendUTXOs[programHash][assetID][height] {
 				if unspend.TxID == u.TxID && unspend.Index == u.Index {
 					position = i
 					break
 				}
 			}
+			if position < 0 {
+				continue
+			}
 			unspendUTXOs[programHash][assetID][height] = append(unspendUTXOs[programHash][assetID][height][:position], unspendUTXOs[programHash][assetID][height][position+1:]...)
 		}
 
 		if !txn.IsCoinBaseTx() {
 			for _, input := range txn.Inputs {
+				// outputs of transactions in this block are rolled back too
+				if _, ok := blockTxs[input.Previous.TxID]; ok {
+					continue
+				}
 				referTxn, hh, err := c.GetTransaction(input.Previous.TxID)
 				if err != nil {
 					return err
@@ -448,6 +470,10 @@ func (c *ChainStore) persistUnspend(b *Block) error {
 func (c *ChainStore) RollbackUnspend(b *Block) error {
 	unspentPrefix := []byte{byte(IXUnspent)}
 	unspents := make(map[Uint256][]uint16)
+	blockTxs := make(map[Uint256]struct{})
+	for _, txn := range b.Transactions {
+		blockTxs[txn.Hash()] = struct{}{}
+	}
 	for _, txn := range b.Transactions {
 		if txn.TxType == RegisterAsset {
 			continue
@@ -460,6 +486,10 @@ func (c *ChainStore) RollbackUnspend(b *Block) error {
 			for _, input := range txn.Inputs {
 				referTxnHash := input.Previous.TxID
 				referTxnOutIndex := input.Previous.Index
+				// outputs of transactions in this block are rolled back too
+				if _, ok := blockTxs[referTxnHash]; ok {
+					continue
+				}
 				if _, ok := unspents[referTxnHash]; !ok {
 					var err error
 					unspentValue, _ := c.Get(append(unspentPrefix, referTxnHash.Bytes()...))
@@ -343,8 +343,15 @@ func (c *ChainStore) persistForVoteInputs(tx *Transaction) error {
 }
 
 func (c *ChainStore) rollbackForMempool(b *Block) error {
+	// the transactions of the block have been removed from the store already,
+	// so the inputs referencing earlier transactions in this block are looked
+	// up in the block
+	blockTxs := make(map[Uint256]*Transaction)
 	for _, txn := range b.Transactions {
-		if err := c.rollbackForVoteInputs(txn); err != nil {
+		blockTxs[txn.Hash()] = txn
+	}
+	for _, txn := range b.Transactions {
+		if err := c.rollbackForVoteInputs(txn, blockTxs); err != nil {
 			return err
 		}
 		switch txn.TxType {
@@ -376,18 +383,26 @@ func (c *ChainStore) rollbackForMempool(b *Block) error {
 	return nil
 }
 
-func (c *ChainStore) rollbackForVoteInputs(tx *Transaction) error {
+func (c *ChainStore) rollbackForVoteInputs(tx *Transaction,
+	blockTxs map[Uint256]*Transaction) error {
+	if tx.TxType == CoinBase {
+		return nil
+	}
 	for _, input := range tx.Inputs {
-		transaction, _, err := c.GetTransaction(input.Previous.TxID)
-		if err != nil {
-			return err
+		transaction, ok := blockTxs[input.Previous.TxID]
+		if !ok {
+			var err error
+			transaction, _, err = c.GetTransaction(input.Previous.TxID)
+			if err != nil {
+				return err
+			}
 		}
 		if transaction.Version < TxVersion09 {
 			continue
 		}
 		output := transaction.Outputs[input.Previous.Index]
 		if output.OutputType == VoteOutput {
-			if err = c.persistVoteOutputForMempool(output); err != nil {
+			if err := c.persistVoteOutputForMempool(output); err != nil {
 				return err
 			}
 		}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
 	. "github.com/elastos/Elastos.ELA/core/types"
 	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
@@ -65,7 +66,7 @@ type ChainStore struct {
 }
 
 func NewChainStore(filePath string) (IChainStore, error) {
-	st, err := NewLevelDB(filePath)
+	st, err := NewStore(config.Parameters.StoreBackend, filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -184,6 +185,17 @@ func (c *ChainStore) InitWithGenesisBlock(genesisBlock *Block) (uint32, error) {
 	c.currentBlockHeight, err = ReadUint32(r)
 	endHeight := c.currentBlockHeight
 
+	if config.Parameters.EnableAddressIndex {
+		if err := c.catchUpAddressIndex(blockHash); err != nil {
+			return 0, err
+		}
+	}
+	if config.Parameters.EnableSpendIndex {
+		if err := c.catchUpSpendIndex(blockHash, endHeight); err != nil {
+			return 0, err
+		}
+	}
+
 	startHeight := uint32(0)
 	if endHeight > MinMemoryNodes {
 		startHeight = endHeight - MinMemoryNodes
@@ -509,6 +521,12 @@ func (c *ChainStore) rollback(b *Block) error {
 	c.RollbackTransactions(b)
 	c.RollbackUnspendUTXOs(b)
 	c.RollbackUnspend(b)
+	if config.Parameters.EnableAddressIndex {
+		c.RollbackAddressHistory(b)
+	}
+	if config.Parameters.EnableSpendIndex {
+		c.RollbackSpendInfo(b)
+	}
 	c.RollbackCurrentBlock(b)
 	c.RollbackConfirm(b)
 	c.BatchCommit()
@@ -540,6 +558,16 @@ func (c *ChainStore) persist(b *Block) error {
 	if err := c.persistUnspend(b); err != nil {
 		return err
 	}
+	if config.Parameters.EnableAddressIndex {
+		if err := c.persistAddressHistory(b); err != nil {
+			return err
+		}
+	}
+	if config.Parameters.EnableSpendIndex {
+		if err := c.persistSpendInfo(b); err != nil {
+			return err
+		}
+	}
 	if err := c.persistCurrentBlock(b); err != nil {
 		return err
 	}
@@ -576,7 +604,10 @@ func (c *ChainStore) handleRollbackBlockTask(blockHash Uint256) {
 		log.Errorf("block %x can't be found", BytesToHexString(blockHash.Bytes()))
 		return
 	}
-	c.rollback(block)
+	if err := c.rollback(block); err != nil {
+		log.Errorf("[handleRollbackBlockTask] rollback block %s failed: %s",
+			blockHash.String(), err)
+	}
 }
 
 func (c *ChainStore) handlePersistBlockTask(b *Block) {
@@ -2,9 +2,12 @@ package blockchain
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/core/contract"
 	"github.com/elastos/Elastos.ELA/core/contract/program"
 	"github.com/elastos/Elastos.ELA/core/types"
@@ -727,6 +730,227 @@ func TestCheckAssetPrecision(t *testing.T) {
 	DefaultLedger.Store = originalStore
 }
 
+func TestChainStore_AddressHistory(t *testing.T) {
+	if testChainStore == nil {
+		t.Error("Chainstore init failed")
+	}
+
+	var from, to common.Uint168
+	from[0], to[0] = 0x21, 0x21
+	from[1], to[1] = 0x01, 0x02
+	coinbase := &types.Transaction{
+		TxType:  types.CoinBase,
+		Payload: &payload.PayloadCoinBase{},
+		Outputs: []*types.Output{{ProgramHash: from, Value: 100}},
+	}
+	transfer := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Inputs: []*types.Input{{Previous: types.OutPoint{
+			TxID: coinbase.Hash(), Index: 0}}},
+		Outputs: []*types.Output{
+			{ProgramHash: to, Value: 60},
+			{ProgramHash: from, Value: 39},
+		},
+	}
+	block := &types.Block{
+		Header:       types.Header{Height: 1000},
+		Transactions: []*types.Transaction{coinbase, transfer},
+	}
+
+	testChainStore.NewBatch()
+	assert.NoError(t, testChainStore.persistAddressHistory(block))
+	assert.NoError(t, testChainStore.BatchCommit())
+
+	history, err := testChainStore.GetAddressHistory(from, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(history))
+	history, err = testChainStore.GetAddressHistory(to, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*AddressHistory{{Height: 1000, TxID: transfer.Hash(),
+		Direction: HistoryReceived, Index: 0, Value: 60}}, history)
+
+	// records are paginated
+	history, err = testChainStore.GetAddressHistory(from, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(history))
+	history, err = testChainStore.GetAddressHistory(from, 3, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(history))
+
+	testChainStore.NewBatch()
+	assert.NoError(t, testChainStore.RollbackAddressHistory(block))
+	assert.NoError(t, testChainStore.BatchCommit())
+	history, err = testChainStore.GetAddressHistory(from, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(history))
+}
+
+func TestChainStore_SpendInfo(t *testing.T) {
+	if testChainStore == nil {
+		t.Error("Chainstore init failed")
+	}
+
+	var prev common.Uint256
+	prev[0] = 0x01
+	transfer := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Inputs: []*types.Input{
+			{Previous: types.OutPoint{TxID: prev, Index: 0}},
+			{Previous: types.OutPoint{TxID: prev, Index: 2}},
+		},
+	}
+	block := &types.Block{
+		Header:       types.Header{Height: 1001},
+		Transactions: []*types.Transaction{transfer},
+	}
+
+	testChainStore.NewBatch()
+	assert.NoError(t, testChainStore.persistSpendInfo(block))
+	assert.NoError(t, testChainStore.BatchCommit())
+
+	info, err := testChainStore.GetSpendInfo(prev, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &SpendInfo{TxID: transfer.Hash(), Index: 1, Height: 1001}, info)
+	_, err = testChainStore.GetSpendInfo(prev, 1)
+	assert.Error(t, err)
+
+	testChainStore.NewBatch()
+	assert.NoError(t, testChainStore.RollbackSpendInfo(block))
+	assert.NoError(t, testChainStore.BatchCommit())
+	_, err = testChainStore.GetSpendInfo(prev, 0)
+	assert.Error(t, err)
+}
+
+func TestChainStore_CatchUpSpendIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spendindex")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewChainStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	chainStore := store.(*ChainStore)
+
+	// blocks are saved before the spend index is enabled
+	enabled := config.Parameters.EnableSpendIndex
+	defer func() { config.Parameters.EnableSpendIndex = enabled }()
+	config.Parameters.EnableSpendIndex = false
+	funding := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Outputs: []*types.Output{{Value: 100}},
+	}
+	transfer := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Inputs: []*types.Input{{Previous: types.OutPoint{
+			TxID: funding.Hash(), Index: 0}}},
+		Outputs: []*types.Output{{Value: 90}},
+	}
+	block0 := &types.Block{
+		Header:       types.Header{Height: 0},
+		Transactions: []*types.Transaction{funding},
+	}
+	block1 := &types.Block{
+		Header:       types.Header{Height: 1, Previous: block0.Hash()},
+		Transactions: []*types.Transaction{transfer},
+	}
+	assert.NoError(t, chainStore.persist(block0))
+	assert.NoError(t, chainStore.persist(block1))
+	_, err = chainStore.GetSpendInfo(funding.Hash(), 0)
+	assert.Error(t, err)
+
+	// the index is rebuilt from the saved blocks
+	config.Parameters.EnableSpendIndex = true
+	assert.NoError(t, chainStore.catchUpSpendIndex(block1.Hash(), 1))
+	info, err := chainStore.GetSpendInfo(funding.Hash(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &SpendInfo{TxID: transfer.Hash(), Index: 0, Height: 1}, info)
+
+	// the index is kept up to date when blocks are rolled back while enabled
+	chainStore.NewBatch()
+	assert.NoError(t, chainStore.RollbackSpendInfo(block1))
+	assert.NoError(t, chainStore.BatchCommit())
+	tip, err := chainStore.Get([]byte{byte(SYSSpendIndexTip)})
+	assert.NoError(t, err)
+	assert.Equal(t, block0.Hash().Bytes(), tip)
+	assert.NoError(t, chainStore.catchUpSpendIndex(block0.Hash(), 0))
+	_, err = chainStore.GetSpendInfo(funding.Hash(), 0)
+	assert.Error(t, err)
+}
+
+func TestChainStore_CatchUpAddressIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "addressindex")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewChainStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer store.Close()
+	chainStore := store.(*ChainStore)
+
+	// blocks are saved before the address index is enabled
+	enabled := config.Parameters.EnableAddressIndex
+	defer func() { config.Parameters.EnableAddressIndex = enabled }()
+	config.Parameters.EnableAddressIndex = false
+	address := common.Uint168{1}
+	funding := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Outputs: []*types.Output{{Value: 100, ProgramHash: address}},
+	}
+	transfer := &types.Transaction{
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Inputs: []*types.Input{{Previous: types.OutPoint{
+			TxID: funding.Hash(), Index: 0}}},
+		Outputs: []*types.Output{{Value: 90}},
+	}
+	block0 := &types.Block{
+		Header:       types.Header{Height: 0},
+		Transactions: []*types.Transaction{funding},
+	}
+	block1 := &types.Block{
+		Header:       types.Header{Height: 1, Previous: block0.Hash()},
+		Transactions: []*types.Transaction{transfer},
+	}
+	assert.NoError(t, chainStore.persist(block0))
+	assert.NoError(t, chainStore.persist(block1))
+	history, err := chainStore.GetAddressHistory(address, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(history))
+
+	// the index is rebuilt from the saved blocks
+	config.Parameters.EnableAddressIndex = true
+	assert.NoError(t, chainStore.catchUpAddressIndex(block1.Hash()))
+	history, err = chainStore.GetAddressHistory(address, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*AddressHistory{
+		{Height: 1, TxID: transfer.Hash(), Direction: HistorySent, Value: 100},
+		{Height: 0, TxID: funding.Hash(), Direction: HistoryReceived, Value: 100},
+	}, history)
+
+	// the index is kept up to date when blocks are rolled back while enabled
+	chainStore.NewBatch()
+	assert.NoError(t, chainStore.RollbackAddressHistory(block1))
+	assert.NoError(t, chainStore.BatchCommit())
+	tip, err := chainStore.Get([]byte{byte(SYSAddressIndexTip)})
+	assert.NoError(t, err)
+	assert.Equal(t, block0.Hash().Bytes(), tip)
+	assert.NoError(t, chainStore.catchUpAddressIndex(block0.Hash()))
+	history, err = chainStore.GetAddressHistory(address, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(history))
+}
+
 func TestChainStoreDone(t *testing.T) {
 	if testChainStore == nil {
 		t.Error("Chainstore init failed")
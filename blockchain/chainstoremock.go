@@ -132,6 +132,14 @@ func (c *ChainStoreMock) GetUnspentsFromProgramHash(programHash common.Uint168)
 	panic("implement me")
 }
 
+func (c *ChainStoreMock) GetAddressHistory(programHash common.Uint168, skip, count int) ([]*AddressHistory, error) {
+	panic("implement me")
+}
+
+func (c *ChainStoreMock) GetSpendInfo(txID common.Uint256, index uint16) (*SpendInfo, error) {
+	panic("implement me")
+}
+
 func (c *ChainStoreMock) GetAssets() map[common.Uint256]*payload.Asset {
 	panic("implement me")
 }
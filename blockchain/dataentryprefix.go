@@ -13,12 +13,16 @@ const (
 	//SYSTEM
 	SYSCurrentBlock      DataEntryPrefix = 0x40
 	SYSCurrentBookKeeper DataEntryPrefix = 0x42
+	SYSSpendIndexTip     DataEntryPrefix = 0x43
+	SYSAddressIndexTip   DataEntryPrefix = 0x44
 
 	// INDEX
 	IXHeaderHashList DataEntryPrefix = 0x80
 	IXUnspent        DataEntryPrefix = 0x90
 	IXUnspentUTXO    DataEntryPrefix = 0x91
 	IXSideChainTx    DataEntryPrefix = 0x92
+	IXAddressHistory DataEntryPrefix = 0x93
+	IXSpentOutput    DataEntryPrefix = 0x94
 
 	// ASSET
 	STInfo DataEntryPrefix = 0xc0
@@ -0,0 +1,44 @@
+package interfaces
+
+// ArbiterStats is the performance and liveness statistics of an arbiter in an
+// election round.
+type ArbiterStats struct {
+	Arbiter string
+
+	// DutySlots is the count of the views the arbiter was on duty.
+	DutySlots uint32
+
+	// Proposals is the count of the proposals made while on duty.
+	Proposals uint32
+
+	// MissedDuties is the count of the views changed while the arbiter was on
+	// duty.
+	MissedDuties uint32
+
+	// ExpectedVotes is the count of the proposals the arbiter should vote on,
+	// Votes and RejectedVotes are the votes it cast and the rejects of them.
+	ExpectedVotes uint32
+	Votes         uint32
+	RejectedVotes uint32
+
+	// IllegalEvidences is the count of the illegal proposals, votes and blocks
+	// evidences attributed to the arbiter.
+	IllegalEvidences uint32
+}
+
+// ArbiterRoundStats is the statistics of the arbiters in an election round,
+// from the block at StartHeight to the block at EndHeight.
+type ArbiterRoundStats struct {
+	StartHeight uint32
+	EndHeight   uint32
+	Arbiters    []*ArbiterStats
+}
+
+// IArbiterStatsRecord persists the arbiter statistics of the election rounds.
+type IArbiterStatsRecord interface {
+	SaveArbiterStats(round *ArbiterRoundStats)
+
+	// GetArbiterStats returns the statistics of the election round the block
+	// at the height belongs to.
+	GetArbiterStats(height uint32) (*ArbiterRoundStats, error)
+}
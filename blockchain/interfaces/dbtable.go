@@ -327,25 +327,25 @@ func readElements(r io.Reader) (interface{}, error) {
 	switch fieldType[0] {
 	case FieldUint8:
 		var result uint8
-		if err := common.ReadElement(r, result); err != nil {
+		if err := common.ReadElement(r, &result); err != nil {
 			return nil, errors.New("[readElements] read uint8 failed")
 		}
 		return result, nil
 	case FieldUint16:
 		var result uint16
-		if err := common.ReadElement(r, result); err != nil {
+		if err := common.ReadElement(r, &result); err != nil {
 			return nil, err
 		}
 		return result, nil
 	case FieldUint32:
 		var result uint32
-		if err := common.ReadElement(r, result); err != nil {
+		if err := common.ReadElement(r, &result); err != nil {
 			return nil, err
 		}
 		return result, nil
 	case FieldUint64:
 		var result uint64
-		if err := common.ReadElement(r, result); err != nil {
+		if err := common.ReadElement(r, &result); err != nil {
 			return nil, err
 		}
 		return result, nil
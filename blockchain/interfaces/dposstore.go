@@ -45,5 +45,7 @@ type IArbitratorsRecord interface {
 type IDposStore interface {
 	IDBOperator
 	IEventRecord
+	IEventQuery
 	IArbitratorsRecord
+	IArbiterStatsRecord
 }
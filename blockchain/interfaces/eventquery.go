@@ -0,0 +1,77 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// EventFilter selects the recorded consensus events of the blocks from
+// StartHeight to EndHeight inclusive.
+type EventFilter struct {
+	StartHeight uint32
+	EndHeight   uint32
+
+	// Arbiter is the sponsor of the proposals, the signer of the votes or the
+	// on duty arbiter of the views, all arbiters are selected if empty.
+	Arbiter string
+
+	// ViewOffset selects the events in the view only if not nil.
+	ViewOffset *uint32
+}
+
+// ConsensusRecord is the recorded consensus of a block, the end time is zero
+// if the consensus has not finished.
+type ConsensusRecord struct {
+	Height    uint32
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// ProposalRecord is a recorded proposal, the end time is zero if the
+// proposal has not finished.
+type ProposalRecord struct {
+	Height       uint32
+	Sponsor      string
+	BlockHash    common.Uint256
+	ProposalHash common.Uint256
+	ViewOffset   uint32
+	ReceivedTime time.Time
+	EndTime      time.Time
+	Result       bool
+}
+
+// VoteRecord is a recorded vote on a proposal.
+type VoteRecord struct {
+	Height       uint32
+	Signer       string
+	ProposalHash common.Uint256
+	Accept       bool
+	ReceivedTime time.Time
+}
+
+// ViewRecord is a recorded view change.
+type ViewRecord struct {
+	Height           uint32
+	OnDutyArbitrator string
+	Offset           uint32
+	StartTime        time.Time
+}
+
+// ConsensusTimeline is the consensus of a block, with the view changes
+// occurred, the proposals and their votes in the order they arrived.
+type ConsensusTimeline struct {
+	Consensus *ConsensusRecord
+	Views     []*ViewRecord
+	Proposals []*ProposalRecord
+	Votes     []*VoteRecord
+}
+
+// IEventQuery reads back the consensus events recorded by IEventRecord.
+type IEventQuery interface {
+	GetConsensusEvents(startHeight, endHeight uint32) ([]*ConsensusRecord, error)
+	GetProposalEvents(filter *EventFilter) ([]*ProposalRecord, error)
+	GetVoteEvents(filter *EventFilter) ([]*VoteRecord, error)
+	GetViewEvents(filter *EventFilter) ([]*ViewRecord, error)
+	GetConsensusTimelines(startHeight, endHeight uint32) ([]*ConsensusTimeline, error)
+}
@@ -55,6 +55,8 @@ type IChainStore interface {
 	ContainsUnspent(txID Uint256, index uint16) (bool, error)
 	GetUnspentFromProgramHash(programHash Uint168, assetid Uint256) ([]*UTXO, error)
 	GetUnspentsFromProgramHash(programHash Uint168) (map[Uint256][]*UTXO, error)
+	GetAddressHistory(programHash Uint168, skip, count int) ([]*AddressHistory, error)
+	GetSpendInfo(txID Uint256, index uint16) (*SpendInfo, error)
 	GetAssets() map[Uint256]*Asset
 
 	IsTxHashDuplicate(txhash Uint256) bool
@@ -5,6 +5,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/errors"
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
@@ -17,6 +18,15 @@ type LevelDB struct {
 // too small will lead to high false positive rate.
 const BITSPERKEY = 10
 
+func init() {
+	RegisterStore("leveldb", func(path string) (IStore, error) {
+		return NewLevelDB(path)
+	})
+	RegisterStore("memory", func(path string) (IStore, error) {
+		return NewMemDB()
+	})
+}
+
 func NewLevelDB(file string) (*LevelDB, error) {
 	// default Options
 	o := opt.Options{
@@ -39,6 +49,20 @@ func NewLevelDB(file string) (*LevelDB, error) {
 	}, nil
 }
 
+// NewMemDB creates a LevelDB kept in memory, the data is lost when closed.
+// It is used by tests.
+func NewMemDB() (*LevelDB, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDB{
+		db:    db,
+		batch: nil,
+	}, nil
+}
+
 func (ldb *LevelDB) Put(key []byte, value []byte) error {
 	return ldb.db.Put(key, value, nil)
 }
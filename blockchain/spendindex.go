@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"bytes"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/log"
+	. "github.com/elastos/Elastos.ELA/core/types"
+)
+
+// SpendInfo tells which transaction input spent an output, and the height of
+// the block including the spending transaction.
+type SpendInfo struct {
+	TxID   Uint256
+	Index  uint16
+	Height uint32
+}
+
+func (s *SpendInfo) Serialize(w *bytes.Buffer) error {
+	if err := s.TxID.Serialize(w); err != nil {
+		return err
+	}
+	return WriteElements(w, s.Index, s.Height)
+}
+
+func (s *SpendInfo) Deserialize(r *bytes.Reader) error {
+	if err := s.TxID.Deserialize(r); err != nil {
+		return err
+	}
+	return ReadElements(r, &s.Index, &s.Height)
+}
+
+func spendInfoKey(op *OutPoint) []byte {
+	return append([]byte{byte(IXSpentOutput)}, op.Bytes()...)
+}
+
+func (c *ChainStore) persistSpendInfo(b *Block) error {
+	for _, txn := range b.Transactions {
+		if txn.IsCoinBaseTx() {
+			continue
+		}
+		for index, input := range txn.Inputs {
+			info := SpendInfo{
+				TxID:   txn.Hash(),
+				Index:  uint16(index),
+				Height: b.Height,
+			}
+			value := new(bytes.Buffer)
+			if err := info.Serialize(value); err != nil {
+				return err
+			}
+			c.BatchPut(spendInfoKey(&input.Previous), value.Bytes())
+		}
+	}
+	// the spend index is complete up to this block
+	c.BatchPut([]byte{byte(SYSSpendIndexTip)}, b.Hash().Bytes())
+	return nil
+}
+
+func (c *ChainStore) RollbackSpendInfo(b *Block) error {
+	for _, txn := range b.Transactions {
+		if txn.IsCoinBaseTx() {
+			continue
+		}
+		for _, input := range txn.Inputs {
+			c.BatchDelete(spendInfoKey(&input.Previous))
+		}
+	}
+	c.BatchPut([]byte{byte(SYSSpendIndexTip)}, b.Previous.Bytes())
+	return nil
+}
+
+// GetSpendInfo returns the input spending the output, an error is returned if
+// the output has not been spent.
+func (c *ChainStore) GetSpendInfo(txID Uint256, index uint16) (*SpendInfo, error) {
+	op := OutPoint{TxID: txID, Index: index}
+	data, err := c.Get(spendInfoKey(&op))
+	if err != nil {
+		return nil, err
+	}
+	info := new(SpendInfo)
+	if err := info.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// catchUpSpendIndex rebuilds the spend index from the blocks in store if it
+// has not been kept updated up to the current block, as when it is enabled on
+// an existing data directory, or blocks were saved or rolled back while it was
+// disabled.
+func (c *ChainStore) catchUpSpendIndex(currentHash Uint256, currentHeight uint32) error {
+	tip, err := c.Get([]byte{byte(SYSSpendIndexTip)})
+	if err == nil && bytes.Equal(tip, currentHash.Bytes()) {
+		return nil
+	}
+
+	log.Info("spend index is not up to date, rebuilding it, this may take a while...")
+	iter := c.NewIterator([]byte{byte(IXSpentOutput)})
+	c.NewBatch()
+	for iter.Next() {
+		c.BatchDelete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := c.BatchCommit(); err != nil {
+		return err
+	}
+
+	for height := uint32(0); height <= currentHeight; height++ {
+		hash, err := c.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		block, err := c.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		c.NewBatch()
+		if err := c.persistSpendInfo(block); err != nil {
+			return err
+		}
+		if err := c.BatchCommit(); err != nil {
+			return err
+		}
+		if height%10000 == 0 {
+			log.Infof("spend index rebuilt to height %d", height)
+		}
+	}
+	log.Info("spend index rebuilt")
+	return nil
+}
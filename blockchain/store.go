@@ -1,5 +1,21 @@
 package blockchain
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultStoreBackend is the store backend used if none is configured.
+	DefaultStoreBackend = "leveldb"
+
+	// copyBatchSize is the number of records written in one batch when
+	// copying a store.
+	copyBatchSize = 10000
+)
+
 type IIterator interface {
 	Next() bool
 	Prev() bool
@@ -22,3 +38,100 @@ type IStore interface {
 	Close() error
 	NewIterator(prefix []byte) IIterator
 }
+
+// StoreCreator opens the store at path, the store is created if not exist.
+type StoreCreator func(path string) (IStore, error)
+
+var storeCreators = make(map[string]StoreCreator)
+
+// RegisterStore makes a store backend available by name, it is called by the
+// init function of the backends.
+func RegisterStore(name string, creator StoreCreator) {
+	storeCreators[name] = creator
+}
+
+// StoreBackends returns the names of the registered store backends.
+func StoreBackends() []string {
+	names := make([]string, 0, len(storeCreators))
+	for name := range storeCreators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStore opens the store at path with the named backend, the default
+// backend is used if name is empty.
+func NewStore(name string, path string) (IStore, error) {
+	if name == "" {
+		name = DefaultStoreBackend
+	}
+	creator, ok := storeCreators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %s, available backends %v",
+			name, StoreBackends())
+	}
+	return creator(path)
+}
+
+// CopyStore copies all the records of src to dst, progress is called with the
+// number of records copied after every batch. It returns the number of
+// records copied.
+func CopyStore(dst, src IStore, progress func(count int)) (int, error) {
+	iter := src.NewIterator(nil)
+	defer iter.Release()
+
+	var count int
+	dst.NewBatch()
+	for iter.Next() {
+		// the iterator may reuse the slices on next move
+		key := append([]byte{}, iter.Key()...)
+		value := append([]byte{}, iter.Value()...)
+		dst.BatchPut(key, value)
+		count++
+		if count%copyBatchSize == 0 {
+			if err := dst.BatchCommit(); err != nil {
+				return count, err
+			}
+			dst.NewBatch()
+			if progress != nil {
+				progress(count)
+			}
+		}
+	}
+	if err := dst.BatchCommit(); err != nil {
+		return count, err
+	}
+	if progress != nil {
+		progress(count)
+	}
+	return count, nil
+}
+
+// VerifyStoreCopy checks the current block of dst is the same as src, and
+// dst has count records.
+func VerifyStoreCopy(dst, src IStore, count int) error {
+	key := []byte{byte(SYSCurrentBlock)}
+	srcTip, err := src.Get(key)
+	if err != nil {
+		return fmt.Errorf("get current block of source failed: %s", err)
+	}
+	dstTip, err := dst.Get(key)
+	if err != nil {
+		return fmt.Errorf("get current block of target failed: %s", err)
+	}
+	if !bytes.Equal(srcTip, dstTip) {
+		return errors.New("current block of target is different from source")
+	}
+
+	iter := dst.NewIterator(nil)
+	defer iter.Release()
+	var dstCount int
+	for iter.Next() {
+		dstCount++
+	}
+	if dstCount != count {
+		return fmt.Errorf("target has %d records, %d copied", dstCount, count)
+	}
+	return nil
+}
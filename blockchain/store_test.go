@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, store IStore) {
+	assert.NoError(t, store.Put([]byte{0x01, 0x01}, []byte("a")))
+	assert.NoError(t, store.Put([]byte{0x01, 0x02}, []byte{}))
+	value, err := store.Get([]byte{0x01, 0x01})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a"), value)
+	_, err = store.Get([]byte{0x01, 0x02})
+	assert.NoError(t, err)
+	_, err = store.Get([]byte{0x01, 0x03})
+	assert.Error(t, err)
+
+	store.NewBatch()
+	store.BatchPut([]byte{0x01, 0x03}, []byte("c"))
+	store.BatchPut([]byte{0x02, 0x01}, []byte("d"))
+	store.BatchPut([]byte{0xff, 0xff}, []byte("e"))
+	store.BatchDelete([]byte{0x01, 0x02})
+	assert.NoError(t, store.BatchCommit())
+	_, err = store.Get([]byte{0x01, 0x02})
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Delete([]byte{0x02, 0x01}))
+	_, err = store.Get([]byte{0x02, 0x01})
+	assert.Error(t, err)
+
+	// iterate records with prefix
+	iter := store.NewIterator([]byte{0x01})
+	var keys [][]byte
+	for iter.Next() {
+		keys = append(keys, append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	assert.Equal(t, [][]byte{{0x01, 0x01}, {0x01, 0x03}}, keys)
+
+	iter = store.NewIterator([]byte{0x01})
+	assert.True(t, iter.Last())
+	assert.Equal(t, []byte("c"), iter.Value())
+	assert.True(t, iter.Prev())
+	assert.Equal(t, []byte{0x01, 0x01}, iter.Key())
+	assert.False(t, iter.Prev())
+	assert.True(t, iter.Seek([]byte{0x01, 0x02}))
+	assert.Equal(t, []byte{0x01, 0x03}, iter.Key())
+	iter.Release()
+
+	iter = store.NewIterator([]byte{0xff})
+	assert.True(t, iter.Last())
+	assert.Equal(t, []byte{0xff, 0xff}, iter.Key())
+	iter.Release()
+
+	iter = store.NewIterator([]byte{0x03})
+	assert.False(t, iter.Next())
+	iter.Release()
+}
+
+func TestStoreBackends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ela-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range StoreBackends() {
+		store, err := NewStore(name, dir+"/"+name)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		testStore(t, store)
+		assert.NoError(t, store.Close())
+	}
+
+	_, err = NewStore("unknown", dir)
+	assert.Error(t, err)
+}
+
+func TestCopyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ela-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	src, err := NewStore("memory", "")
+	assert.NoError(t, err)
+	defer src.Close()
+	src.NewBatch()
+	src.BatchPut([]byte{byte(SYSCurrentBlock)}, []byte("tip"))
+	for i := 0; i < copyBatchSize+1; i++ {
+		src.BatchPut([]byte{byte(DATAHeader), byte(i >> 8), byte(i)}, []byte{byte(i)})
+	}
+	assert.NoError(t, src.BatchCommit())
+
+	dst, err := NewStore("bolt", dir)
+	assert.NoError(t, err)
+	defer dst.Close()
+
+	var progress []int
+	count, err := CopyStore(dst, src, func(count int) {
+		progress = append(progress, count)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, copyBatchSize+2, count)
+	assert.Equal(t, []int{copyBatchSize, copyBatchSize + 2}, progress)
+	assert.NoError(t, VerifyStoreCopy(dst, src, count))
+
+	assert.NoError(t, dst.Put([]byte{byte(SYSCurrentBlock)}, []byte("other")))
+	assert.Error(t, VerifyStoreCopy(dst, src, count))
+}
@@ -71,6 +71,14 @@ func CheckTransactionSanity(blockHeight uint32, txn *Transaction) ErrCode {
 
 // CheckTransactionContext verifys a transaction with history transaction in ledger
 func CheckTransactionContext(blockHeight uint32, txn *Transaction) ErrCode {
+	return CheckTransactionContextWithParents(blockHeight, txn, nil)
+}
+
+// CheckTransactionContextWithParents verifys a transaction like
+// CheckTransactionContext, but inputs spending outputs of the given unconfirmed
+// parent transactions are resolved from them instead of the ledger.
+func CheckTransactionContextWithParents(blockHeight uint32, txn *Transaction,
+	parents map[common.Uint256]*Transaction) ErrCode {
 	// check if duplicated with transaction in ledger
 	if exist := DefaultLedger.Store.IsTxHashDuplicate(txn.Hash()); exist {
 		log.Warn("[CheckTransactionContext] duplicate transaction check failed.")
@@ -142,13 +150,14 @@ func CheckTransactionContext(blockHeight uint32, txn *Transaction) ErrCode {
 		}
 	}
 
-	// check double spent transaction
-	if DefaultLedger.IsDoubleSpend(txn) {
+	// check double spent transaction, outputs of unconfirmed parents are not
+	// in the ledger and their double spend is checked by the caller
+	if DefaultLedger.IsDoubleSpend(&Transaction{Inputs: confirmedInputs(txn, parents)}) {
 		log.Warn("[CheckTransactionContext] IsDoubleSpend check faild.")
 		return ErrDoubleSpend
 	}
 
-	references, err := DefaultLedger.Store.GetTxReference(txn)
+	references, err := GetTxReferenceWithParents(txn, parents)
 	if err != nil {
 		log.Warn("[CheckTransactionContext] get transaction reference failed")
 		return ErrUnknownReferredTx
@@ -193,7 +202,7 @@ func CheckTransactionContext(blockHeight uint32, txn *Transaction) ErrCode {
 		return ErrTransactionSignature
 	}
 
-	if err := CheckTransactionCoinbaseOutputLock(txn); err != nil {
+	if err := CheckTransactionCoinbaseOutputLock(txn, parents); err != nil {
 		log.Warn("[CheckTransactionCoinbaseLock]", err)
 		return ErrIneffectiveCoinbase
 	}
@@ -228,7 +237,7 @@ func CheckDestructionAddress(references map[*Input]*Output) error {
 	return nil
 }
 
-func CheckTransactionCoinbaseOutputLock(txn *Transaction) error {
+func CheckTransactionCoinbaseOutputLock(txn *Transaction, parents map[common.Uint256]*Transaction) error {
 	type lockTxInfo struct {
 		isCoinbaseTx bool
 		locktime     uint32
@@ -236,7 +245,7 @@ func CheckTransactionCoinbaseOutputLock(txn *Transaction) error {
 	transactionCache := make(map[common.Uint256]lockTxInfo)
 	currentHeight := DefaultLedger.Store.GetHeight()
 	var referTxn *Transaction
-	for _, input := range txn.Inputs {
+	for _, input := range confirmedInputs(txn, parents) {
 		var lockHeight uint32
 		var isCoinbase bool
 		referHash := input.Previous.TxID
@@ -265,6 +274,20 @@ func CheckTransactionCoinbaseOutputLock(txn *Transaction) error {
 	return nil
 }
 
+// confirmedInputs returns the inputs of txn not spending outputs of parents.
+func confirmedInputs(txn *Transaction, parents map[common.Uint256]*Transaction) []*Input {
+	if len(parents) == 0 {
+		return txn.Inputs
+	}
+	inputs := make([]*Input, 0, len(txn.Inputs))
+	for _, input := range txn.Inputs {
+		if _, ok := parents[input.Previous.TxID]; !ok {
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs
+}
+
 //validate the transaction of duplicate UTXO input
 func CheckTransactionInput(txn *Transaction) error {
 	if txn.IsCoinBaseTx() {
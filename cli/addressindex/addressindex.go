@@ -0,0 +1,44 @@
+package addressindex
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA/blockchain"
+	cliCommon "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+
+	"github.com/urfave/cli"
+)
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "addressindex",
+		Usage:       "rebuild the address transaction history index",
+		Description: "With ela-cli addressindex command, you could build the address index of an existing blockchain data directory.",
+		Action:      rebuildAddressIndex,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			cliCommon.PrintError(c, err, "addressindex")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
+
+func rebuildAddressIndex(context *cli.Context) error {
+	store, err := blockchain.NewStore(config.Parameters.StoreBackend,
+		filepath.Join(config.DataPath, config.DataDir, config.ChainDir))
+	if err != nil {
+		fmt.Println("open database failed! Please check wether there is already a ela process running.", err)
+		return err
+	}
+	defer store.Close()
+
+	chain := blockchain.ChainStore{IStore: store}
+	fmt.Println("rebuilding address index, this may take a while...")
+	if err := chain.RebuildAddressIndex(); err != nil {
+		fmt.Println("rebuild address index failed:", err)
+		return err
+	}
+	fmt.Println("address index rebuilt, set EnableAddressIndex to true in config.json to keep it updated")
+	return nil
+}
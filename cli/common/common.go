@@ -3,17 +3,10 @@ package common
 import (
 	"fmt"
 	"os"
-	"strconv"
-
-	"github.com/elastos/Elastos.ELA/common/config"
 
 	"github.com/urfave/cli"
 )
 
-func LocalServer() string {
-	return "http://localhost" + ":" + strconv.Itoa(config.Parameters.HttpJsonPort)
-}
-
 func PrintError(c *cli.Context, err error, cmd string) {
 	fmt.Println("Incorrect Usage:", err)
 	fmt.Println("")
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/common/config"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+const (
+	// DefaultRPCHost is the host of the JSON-RPC server if not specified.
+	DefaultRPCHost = "localhost"
+
+	// rpcTimeout is the timeout of a JSON-RPC request.
+	rpcTimeout = 60 * time.Second
+)
+
+// RPCFlags are the global flags to specify the JSON-RPC server, the port and
+// credentials are loaded from the node config by default.
+var RPCFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "rpchost",
+		Usage: "the host of the JSON-RPC server",
+		Value: DefaultRPCHost,
+	},
+	cli.IntFlag{
+		Name:  "rpcport",
+		Usage: "the port of the JSON-RPC server, HttpJsonPort in config by default",
+	},
+	cli.StringFlag{
+		Name:  "rpcuser",
+		Usage: "the user name of the JSON-RPC server, RpcConfiguration.User in config by default",
+	},
+	cli.StringFlag{
+		Name:  "rpcpassword",
+		Usage: "the password of the JSON-RPC server, RpcConfiguration.Pass in config by default",
+	},
+}
+
+var rpcHost = DefaultRPCHost
+var rpcPort, rpcUser, rpcPassword = 0, "", ""
+
+// SetRPCConfig sets the JSON-RPC server by the global flags.
+func SetRPCConfig(c *cli.Context) error {
+	rpcHost = c.GlobalString("rpchost")
+	rpcPort = c.GlobalInt("rpcport")
+	if rpcPort < 0 || rpcPort > 65535 {
+		return errors.New("invalid rpc port " + strconv.Itoa(rpcPort))
+	}
+	rpcUser = c.GlobalString("rpcuser")
+	rpcPassword = c.GlobalString("rpcpassword")
+	return nil
+}
+
+// RPCServer returns the url of the JSON-RPC server.
+func RPCServer() string {
+	port := rpcPort
+	if port == 0 {
+		port = config.Parameters.HttpJsonPort
+	}
+	return "http://" + rpcHost + ":" + strconv.Itoa(port)
+}
+
+// CallRPC calls the method of the JSON-RPC server with the params, and returns
+// the result.
+func CallRPC(method string, params httputil.Params) (interface{}, error) {
+	user, password := rpcUser, rpcPassword
+	if user == "" && password == "" {
+		user = config.Parameters.RpcConfiguration.User
+		password = config.Parameters.RpcConfiguration.Pass
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, RPCServer(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" || password != "" {
+		req.SetBasicAuth(user, password)
+	}
+
+	client := http.Client{Timeout: rpcTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Result interface{} `json:"result"`
+		Error  *struct {
+			Code    int         `json:"code"`
+			Message interface{} `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("%s, %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("%v", response.Error.Message)
+	}
+	return response.Result, nil
+}
+
+// CallRPCResult calls the method like CallRPC, and unmarshals the result to v.
+func CallRPCResult(method string, params httputil.Params, v interface{}) error {
+	result, err := CallRPC(method, params)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
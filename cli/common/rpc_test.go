@@ -0,0 +1,137 @@
+package common
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common/config"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+// newTestRPCContext returns the context with the global RPC flags.
+func newTestRPCContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("ela-cli", flag.ContinueOnError)
+	for _, f := range RPCFlags {
+		f.Apply(set)
+	}
+	assert.NoError(t, set.Parse(args))
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestSetRPCConfig(t *testing.T) {
+	defer func() {
+		rpcHost, rpcPort, rpcUser, rpcPassword = DefaultRPCHost, 0, "", ""
+	}()
+
+	// the port of the config is used by default
+	assert.NoError(t, SetRPCConfig(newTestRPCContext(t)))
+	assert.Equal(t, "http://localhost:"+
+		strconv.Itoa(config.Parameters.HttpJsonPort), RPCServer())
+
+	assert.NoError(t, SetRPCConfig(newTestRPCContext(t, "--rpchost", "10.0.0.1",
+		"--rpcport", "20000", "--rpcuser", "user", "--rpcpassword", "pass")))
+	assert.Equal(t, "http://10.0.0.1:20000", RPCServer())
+	assert.Equal(t, "user", rpcUser)
+	assert.Equal(t, "pass", rpcPassword)
+
+	assert.Error(t, SetRPCConfig(newTestRPCContext(t, "--rpcport", "65536")))
+	assert.Error(t, SetRPCConfig(newTestRPCContext(t, "--rpcport", "-1")))
+}
+
+func TestCallRPC(t *testing.T) {
+	var user, password string
+	var authorized bool
+	var request struct {
+		JSONRPC string                 `json:"jsonrpc"`
+		Method  string                 `json:"method"`
+		Params  map[string]interface{} `json:"params"`
+	}
+	var reply string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		user, password, authorized = r.BasicAuth()
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	rpcPort, _ = strconv.Atoi(u.Port())
+	defer func() { rpcPort = 0 }()
+
+	// the result of the reply is returned
+	reply = `{"jsonrpc":"2.0","id":1,"result":{"height":100,"hash":"abc"},"error":null}`
+	result, err := CallRPC("getblock", httputil.Params{"hash": "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"height": float64(100), "hash": "abc"}, result)
+	assert.Equal(t, "2.0", request.JSONRPC)
+	assert.Equal(t, "getblock", request.Method)
+	assert.Equal(t, map[string]interface{}{"hash": "abc"}, request.Params)
+	assert.False(t, authorized)
+
+	// the result is unmarshalled to the value
+	var block struct {
+		Height uint32 `json:"height"`
+		Hash   string `json:"hash"`
+	}
+	assert.NoError(t, CallRPCResult("getblock", httputil.Params{}, &block))
+	assert.Equal(t, uint32(100), block.Height)
+	assert.Equal(t, "abc", block.Hash)
+	var mismatched []string
+	assert.Error(t, CallRPCResult("getblock", httputil.Params{}, &mismatched))
+
+	// the error of the reply is returned as error
+	reply = `{"jsonrpc":"2.0","id":1,"result":null,"error":{"code":-32602,"message":"invalid params"}}`
+	result, err = CallRPC("getblock", httputil.Params{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "invalid params", err.Error())
+	}
+	assert.Nil(t, result)
+	assert.Error(t, CallRPCResult("getblock", httputil.Params{}, &block))
+
+	// the replies not in JSON are returned as error with the status
+	status, reply = http.StatusUnauthorized, "Unauthorized\n"
+	_, err = CallRPC("getblock", httputil.Params{})
+	if assert.Error(t, err) {
+		assert.Equal(t, "401 Unauthorized, Unauthorized", err.Error())
+	}
+	status = http.StatusOK
+
+	// the credentials of the flags override the config
+	reply = `{"result":1}`
+	configUser := config.Parameters.RpcConfiguration.User
+	configPass := config.Parameters.RpcConfiguration.Pass
+	config.Parameters.RpcConfiguration.User = "configuser"
+	config.Parameters.RpcConfiguration.Pass = "configpass"
+	defer func() {
+		config.Parameters.RpcConfiguration.User = configUser
+		config.Parameters.RpcConfiguration.Pass = configPass
+	}()
+	_, err = CallRPC("getblockcount", httputil.Params{})
+	assert.NoError(t, err)
+	assert.True(t, authorized)
+	assert.Equal(t, "configuser", user)
+	assert.Equal(t, "configpass", password)
+
+	rpcUser, rpcPassword = "user", "pass"
+	defer func() { rpcUser, rpcPassword = "", "" }()
+	_, err = CallRPC("getblockcount", httputil.Params{})
+	assert.NoError(t, err)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", password)
+
+	// the server can not be connected after closed
+	server.Close()
+	_, err = CallRPC("getblockcount", httputil.Params{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package crosschain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/account"
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/cli/transfer"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+func crossChainAction(context *cli.Context) error {
+	if context.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	var action string
+	var err error
+	switch {
+	case context.Bool("lockaddress"):
+		action, err = "lockaddress", showLockAddress(context.String("genesis"))
+	case context.Bool("deposit"):
+		action, err = "deposit", depositTransaction(context)
+	case context.String("withdrawal") != "":
+		action, err = "withdrawal", showWithdrawal(context.String("withdrawal"))
+	default:
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	if err != nil {
+		fmt.Println("error:", err)
+		cli.ShowCommandHelpAndExit(context, action, 1)
+	}
+	return nil
+}
+
+// GetLockAddress returns the address locking the assets deposited to the side
+// chain of the genesis block hash, which is in the reversed hex string format
+// as shown by the side chain.
+func GetLockAddress(genesis string) (string, error) {
+	if genesis == "" {
+		return "", errors.New("use --genesis to specify the genesis block hash of the side chain")
+	}
+	hashBytes, err := common.HexStringToBytes(genesis)
+	if err != nil {
+		return "", errors.New("invalid genesis block hash " + genesis)
+	}
+	genesisHash, err := common.Uint256FromBytes(common.BytesReverse(hashBytes))
+	if err != nil {
+		return "", errors.New("invalid genesis block hash " + genesis)
+	}
+	redeemScript := crypto.CreateCrossChainRedeemScript(*genesisHash)
+	return common.ToProgramHash(common.PrefixCrossChain, redeemScript).ToAddress()
+}
+
+func showLockAddress(genesis string) error {
+	address, err := GetLockAddress(genesis)
+	if err != nil {
+		return err
+	}
+	fmt.Println(address)
+	return nil
+}
+
+// depositTransaction creates the deposit transaction by the account of the
+// wallet, signs it and sends it to the node if required.
+func depositTransaction(context *cli.Context) error {
+	pwd, err := password.GetPassword()
+	if err != nil {
+		return err
+	}
+	client, err := account.Open(context.String("name"), pwd)
+	if err != nil {
+		return err
+	}
+	acc, err := client.GetDefaultAccount()
+	if err != nil {
+		return err
+	}
+	if from := context.String("from"); from != "" && from != acc.Address {
+		programHash, err := common.Uint168FromAddress(from)
+		if err != nil {
+			return errors.New("invalid address " + from)
+		}
+		if acc = client.GetAccountByCodeHash(programHash.ToCodeHash()); acc == nil {
+			return errors.New(from + " is not local account")
+		}
+	}
+
+	txn, err := createDepositTransaction(context, acc)
+	if err != nil {
+		return err
+	}
+	if txn, err = client.Sign(txn); err != nil {
+		return err
+	}
+	return transfer.OutputOrSend(txn, context.Bool("send"))
+}
+
+// createDepositTransaction creates the transaction depositing the amounts to
+// the side chain addresses, each amount is put in an output to the lock
+// address with the minimum cross chain fee paid to the side chain.
+func createDepositTransaction(context *cli.Context, acc *account.Account) (*types.Transaction, error) {
+	lockAddress, err := GetLockAddress(context.String("genesis"))
+	if err != nil {
+		return nil, err
+	}
+
+	feeStr := context.String("fee")
+	if feeStr == "" {
+		return nil, errors.New("use --fee to specify transaction fee")
+	}
+	fee, err := common.StringToFixed64(feeStr)
+	if err != nil {
+		return nil, errors.New("invalid transaction fee")
+	}
+	minFee := common.Fixed64(config.Parameters.MinCrossChainTxFee)
+	if *fee < minFee {
+		return nil, errors.New("transaction fee can not be less than the minimum cross chain fee " + minFee.String())
+	}
+
+	addresses := strings.Split(context.String("to"), ",")
+	amounts := strings.Split(context.String("amount"), ",")
+	if context.String("to") == "" || context.String("amount") == "" {
+		return nil, errors.New("use --to and --amount to specify the side chain addresses and amounts separated by comma")
+	}
+	if len(addresses) != len(amounts) {
+		return nil, errors.New("the count of side chain addresses and amounts not match")
+	}
+
+	p := &payload.PayloadTransferCrossChainAsset{}
+	var outputs []*transfer.Transfer
+	deposited := make(map[string]struct{})
+	for i := range addresses {
+		address := strings.TrimSpace(addresses[i])
+		if address == "" {
+			return nil, errors.New("side chain address can not be empty")
+		}
+		if _, ok := deposited[address]; ok {
+			return nil, errors.New("duplicate side chain address " + address)
+		}
+		deposited[address] = struct{}{}
+		amount, err := common.StringToFixed64(strings.TrimSpace(amounts[i]))
+		if err != nil || *amount <= 0 {
+			return nil, errors.New("invalid amount " + amounts[i])
+		}
+
+		value := *amount + minFee
+		p.CrossChainAddresses = append(p.CrossChainAddresses, address)
+		p.OutputIndexes = append(p.OutputIndexes, uint64(i))
+		p.CrossChainAmounts = append(p.CrossChainAmounts, *amount)
+		outputs = append(outputs, &transfer.Transfer{Address: lockAddress, Amount: &value})
+	}
+
+	txn, _, err := transfer.CreatePayloadTransaction(acc.Address, acc.Contract.Code,
+		fee, 0, types.TransferCrossChainAsset, p, outputs...)
+	return txn, err
+}
+
+// showWithdrawal shows if the side chain transactions separated by comma have
+// been withdrawn to the main chain, in blocks or the transaction pool.
+func showWithdrawal(hashes string) error {
+	var txHashes []string
+	for _, hash := range strings.Split(hashes, ",") {
+		hash = strings.TrimSpace(hash)
+		if _, err := common.Uint256FromHexString(hash); err != nil {
+			return errors.New("invalid side chain transaction hash " + hash)
+		}
+		txHashes = append(txHashes, hash)
+	}
+	txs, err := json.Marshal(txHashes)
+	if err != nil {
+		return err
+	}
+
+	var exists []string
+	if err := clicom.CallRPCResult("getexistwithdrawtransactions", httputil.Params{
+		"txs": common.BytesToHexString(txs),
+	}, &exists); err != nil {
+		return err
+	}
+	withdrawn := make(map[string]struct{})
+	for _, hash := range exists {
+		withdrawn[hash] = struct{}{}
+	}
+
+	fmt.Printf("%-64s %-10s\n", "SIDE CHAIN TRANSACTION", "STATUS")
+	fmt.Println(strings.Repeat("-", 64), strings.Repeat("-", 10))
+	for _, hash := range txHashes {
+		status := "pending"
+		if _, ok := withdrawn[hash]; ok {
+			status = "withdrawn"
+		}
+		fmt.Printf("%-64s %-10s\n", hash, status)
+	}
+	fmt.Println(strings.Repeat("-", 64), strings.Repeat("-", 10))
+	return nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "crosschain",
+		Usage:       "cross chain deposit and withdrawal operation",
+		Description: "With ela-cli crosschain, you could deposit assets to side chains and check the withdrawals from side chains.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "lockaddress",
+				Usage: "show the address locking the deposited assets of the side chain, use --genesis",
+			},
+			cli.BoolFlag{
+				Name: "deposit",
+				Usage: "deposit assets to the side chain addresses\n" +
+					"\tuse --genesis --to --amount --fee [--from], the minimum cross chain fee is added to each amount\n" +
+					"\tas the side chain fee",
+			},
+			cli.StringFlag{
+				Name:  "withdrawal",
+				Usage: "show if the side chain transactions separated by comma have been withdrawn to the main chain",
+			},
+			cli.StringFlag{
+				Name:  "genesis",
+				Usage: "the genesis block hash of the side chain",
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Usage: "the side chain addresses to deposit separated by comma",
+			},
+			cli.StringFlag{
+				Name:  "amount",
+				Usage: "the amounts to deposit to each side chain address separated by comma",
+			},
+			cli.StringFlag{
+				Name:  "fee",
+				Usage: "the transfer fee of the transaction, not less than the minimum cross chain fee",
+			},
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "the spend address of the transaction in the wallet, the main account by default",
+			},
+			cli.BoolFlag{
+				Name:  "send",
+				Usage: "send the signed transaction to the node instead of writing it to file",
+			},
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "wallet name",
+				Value: account.KeystoreFileName,
+			},
+		},
+		Action: crossChainAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			clicom.PrintError(c, err, "crosschain")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
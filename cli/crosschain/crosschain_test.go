@@ -0,0 +1,166 @@
+package crosschain
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+// newTestRPCServer serves the JSON-RPC requests of the cli by the results of
+// the methods, until the returned function is called.
+func newTestRPCServer(t *testing.T, results map[string]interface{}) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": results[request.Method],
+		})
+	}))
+	u, _ := url.Parse(server.URL)
+	port := config.Parameters.HttpJsonPort
+	config.Parameters.HttpJsonPort, _ = strconv.Atoi(u.Port())
+	return func() {
+		server.Close()
+		config.Parameters.HttpJsonPort = port
+	}
+}
+
+// newTestContext returns the context of the crosschain command with the args.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("crosschain", flag.ContinueOnError)
+	for _, f := range NewCommand().Flags {
+		f.Apply(set)
+	}
+	assert.NoError(t, set.Parse(args))
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestGetLockAddress(t *testing.T) {
+	genesisHash := common.Uint256{1, 2, 3}
+	redeemScript := crypto.CreateCrossChainRedeemScript(genesisHash)
+	expected, err := common.ToProgramHash(common.PrefixCrossChain, redeemScript).ToAddress()
+	assert.NoError(t, err)
+
+	// the genesis block hash is in the reversed format shown by side chains
+	genesis := common.BytesToHexString(common.BytesReverse(genesisHash.Bytes()))
+	address, err := GetLockAddress(genesis)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, address)
+	address, err = GetLockAddress(common.BytesToHexString(genesisHash.Bytes()))
+	assert.NoError(t, err)
+	assert.NotEqual(t, expected, address)
+
+	_, err = GetLockAddress("")
+	assert.Error(t, err)
+	_, err = GetLockAddress("0102")
+	assert.Error(t, err)
+	_, err = GetLockAddress("xyz")
+	assert.Error(t, err)
+}
+
+func TestCreateDepositTransaction(t *testing.T) {
+	acc, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	closeServer := newTestRPCServer(t, map[string]interface{}{
+		"listunspent": []servers.UTXOInfo{{
+			TxType:        byte(types.TransferAsset),
+			TxID:          "a3d0eaa466df74983b5d7c543de6904f4c9418ead5ffd6d25814234a96db37b0",
+			Address:       acc.Address,
+			Amount:        "10",
+			Confirmations: 10,
+		}},
+	})
+	defer closeServer()
+
+	genesisHash := common.Uint256{1, 2, 3}
+	genesis := common.BytesToHexString(common.BytesReverse(genesisHash.Bytes()))
+	lockAddress, err := GetLockAddress(genesis)
+	assert.NoError(t, err)
+	minFee := common.Fixed64(config.Parameters.MinCrossChainTxFee)
+	fee := minFee.String()
+
+	// each amount is deposited to the lock address with the minimum cross
+	// chain fee added
+	txn, err := createDepositTransaction(newTestContext(t, "--genesis", genesis,
+		"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw, EKn3UGyEoMRZaxDGcQPo1aXnQKUSkG7Ra4",
+		"--amount", "1, 2.5", "--fee", fee), acc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.TransferCrossChainAsset, txn.TxType)
+	if !assert.Equal(t, 3, len(txn.Outputs)) {
+		return
+	}
+	for i, amount := range []string{"1", "2.5"} {
+		address, _ := txn.Outputs[i].ProgramHash.ToAddress()
+		assert.Equal(t, lockAddress, address)
+		value, _ := common.StringToFixed64(amount)
+		assert.Equal(t, *value+minFee, txn.Outputs[i].Value)
+	}
+	change, _ := common.StringToFixed64("6.5")
+	assert.Equal(t, *change-3*minFee, txn.Outputs[2].Value)
+	assert.Equal(t, acc.ProgramHash, txn.Outputs[2].ProgramHash)
+
+	p, ok := txn.Payload.(*payload.PayloadTransferCrossChainAsset)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, []string{"EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw",
+		"EKn3UGyEoMRZaxDGcQPo1aXnQKUSkG7Ra4"}, p.CrossChainAddresses)
+	assert.Equal(t, []uint64{0, 1}, p.OutputIndexes)
+	one, _ := common.StringToFixed64("1")
+	twoAndHalf, _ := common.StringToFixed64("2.5")
+	assert.Equal(t, []common.Fixed64{*one, *twoAndHalf}, p.CrossChainAmounts)
+
+	// the fee can not be less than the minimum cross chain fee
+	_, err = createDepositTransaction(newTestContext(t, "--genesis", genesis,
+		"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "1",
+		"--fee", (minFee-1).String()), acc)
+	assert.Error(t, err)
+
+	// the addresses and amounts must match and be valid
+	for _, args := range [][]string{
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "1,2"},
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw"},
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw,", "--amount", "1,2"},
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw,EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw",
+			"--amount", "1,2"},
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "0"},
+		{"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "abc"},
+	} {
+		args = append(args, "--genesis", genesis, "--fee", fee)
+		_, err = createDepositTransaction(newTestContext(t, args...), acc)
+		assert.Error(t, err, args)
+	}
+
+	// the genesis block hash is required
+	_, err = createDepositTransaction(newTestContext(t,
+		"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "1",
+		"--fee", fee), acc)
+	assert.Error(t, err)
+
+	// the deposit must be paid by the balance
+	_, err = createDepositTransaction(newTestContext(t, "--genesis", genesis,
+		"--to", "EPzxJrHefvE7TCWmEGQ4rcFgxGeGBZFSHw", "--amount", "10",
+		"--fee", fee), acc)
+	assert.Error(t, err)
+}
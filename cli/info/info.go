@@ -0,0 +1,294 @@
+package info
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+var jsonFlag = cli.BoolFlag{
+	Name:  "json",
+	Usage: "print the result in JSON format",
+}
+
+// query calls the JSON-RPC method, and prints the result in JSON format if
+// required, or unmarshals it to v and prints it by show.
+func query(c *cli.Context, method string, params httputil.Params, v interface{}, show func()) error {
+	result, err := clicom.CallRPC(method, params)
+	if err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	show()
+	return nil
+}
+
+func line(widths ...int) {
+	var dashes []string
+	for _, w := range widths {
+		dashes = append(dashes, strings.Repeat("-", w))
+	}
+	fmt.Println(strings.Join(dashes, " "))
+}
+
+func blockAction(c *cli.Context) error {
+	method, params := "getblock", httputil.Params{"blockhash": c.String("hash"), "verbosity": 2}
+	if c.String("hash") == "" {
+		if !c.IsSet("height") {
+			fmt.Println("error: use --hash or --height to specify the block")
+			cli.ShowCommandHelpAndExit(c, "block", 1)
+		}
+		method, params = "getblockbyheight", httputil.Params{"height": c.Uint("height")}
+	}
+
+	var block servers.BlockInfo
+	return query(c, method, params, &block, func() {
+		fmt.Println("Hash:         ", block.Hash)
+		fmt.Println("Height:       ", block.Height)
+		fmt.Println("Confirmations:", block.Confirmations)
+		fmt.Println("Size:         ", block.Size)
+		fmt.Println("Version:      ", block.Version)
+		fmt.Println("Time:         ", block.Time)
+		fmt.Println("Bits:         ", block.Bits)
+		fmt.Println("Difficulty:   ", block.Difficulty)
+		fmt.Println("MerkleRoot:   ", block.MerkleRoot)
+		fmt.Println("Previous:     ", block.PreviousBlockHash)
+		fmt.Println("Next:         ", block.NextBlockHash)
+		fmt.Println("Transactions: ", len(block.Tx))
+		for i, tx := range block.Tx {
+			txID := tx
+			if info, ok := tx.(map[string]interface{}); ok {
+				txID = info["txid"]
+			}
+			fmt.Printf("%5d %v\n", i, txID)
+		}
+	})
+}
+
+func transactionAction(c *cli.Context) error {
+	txID := c.String("txid")
+	if txID == "" {
+		fmt.Println("error: use --txid to specify the transaction")
+		cli.ShowCommandHelpAndExit(c, "tx", 1)
+	}
+
+	var txn servers.TransactionInfo
+	return query(c, "getrawtransaction", httputil.Params{"txid": txID, "verbose": true}, &txn, func() {
+		showTransaction(&txn)
+	})
+}
+
+func showTransaction(txn *servers.TransactionInfo) {
+	fmt.Println("TXID:         ", txn.TxID)
+	fmt.Println("Type:         ", txn.TxType.Name())
+	fmt.Println("Version:      ", txn.Version)
+	fmt.Println("Size:         ", txn.Size)
+	fmt.Println("LockTime:     ", txn.LockTime)
+	fmt.Println("BlockHash:    ", txn.BlockHash)
+	fmt.Println("Confirmations:", txn.Confirmations)
+	fmt.Println("INPUTS:")
+	for i, input := range txn.Inputs {
+		fmt.Printf("%5d %s:%d\n", i, input.TxID, input.VOut)
+	}
+	fmt.Println("OUTPUTS:")
+	for _, output := range txn.Outputs {
+		fmt.Printf("%5d %34s %s\n", output.Index, output.Address, output.Value)
+	}
+}
+
+func mempoolAction(c *cli.Context) error {
+	var txs []servers.TransactionInfo
+	return query(c, "getrawmempool", httputil.Params{}, &txs, func() {
+		fmt.Printf("%-64s %-28s %8s\n", "TXID", "TYPE", "SIZE")
+		line(64, 28, 8)
+		for _, txn := range txs {
+			fmt.Printf("%-64s %-28s %8d\n", txn.TxID, txn.TxType.Name(), txn.Size)
+		}
+		line(64, 28, 8)
+	})
+}
+
+func neighborsAction(c *cli.Context) error {
+	var neighbors []string
+	return query(c, "getneighbors", httputil.Params{}, &neighbors, func() {
+		fmt.Println("ADDRESS")
+		line(40)
+		for _, n := range neighbors {
+			fmt.Println(n)
+		}
+		line(40)
+	})
+}
+
+func producersAction(c *cli.Context) error {
+	var producers servers.Producers
+	return query(c, "listproducers", httputil.Params{}, &producers, func() {
+		fmt.Printf("%5s %-20s %-66s %-20s %-6s\n", "INDEX", "NICKNAME", "OWNER PUBLIC KEY", "VOTES", "ACTIVE")
+		line(5, 20, 66, 20, 6)
+		for _, p := range producers.Producers {
+			fmt.Printf("%5d %-20s %-66s %-20s %-6t\n", p.Index, p.Nickname, p.OwnerPublicKey, p.Votes, p.Active)
+		}
+		line(5, 20, 66, 20, 6)
+		fmt.Println("Total votes:", producers.TotalVotes)
+		fmt.Println("Total count:", producers.TotalCounts)
+	})
+}
+
+func arbitratorsAction(c *cli.Context) error {
+	if !c.IsSet("height") {
+		fmt.Println("error: use --height to specify the block height")
+		cli.ShowCommandHelpAndExit(c, "arbiters", 1)
+	}
+
+	var group servers.ArbitratorGroupInfo
+	return query(c, "getarbitratorgroupbyheight", httputil.Params{"height": c.Uint("height")}, &group, func() {
+		fmt.Printf("%5s %-66s %-7s\n", "INDEX", "PUBLIC KEY", "ON DUTY")
+		line(5, 66, 7)
+		for i, a := range group.Arbitrators {
+			fmt.Printf("%5d %-66s %-7t\n", i, a, i == group.OnDutyArbitratorIndex)
+		}
+		line(5, 66, 7)
+	})
+}
+
+func unspentAction(c *cli.Context) error {
+	addresses := c.String("address")
+	if addresses == "" {
+		fmt.Println("error: use --address to specify the addresses separated by comma")
+		cli.ShowCommandHelpAndExit(c, "unspent", 1)
+	}
+	utxoType := c.String("utxotype")
+	switch utxoType {
+	case "mixed", "vote", "normal":
+	default:
+		return errors.New("invalid utxo type " + utxoType)
+	}
+
+	var utxos []servers.UTXOInfo
+	params := httputil.Params{
+		"addresses": strings.Split(addresses, ","),
+		"utxotype":  utxoType,
+	}
+	return query(c, "listunspent", params, &utxos, func() {
+		fmt.Printf("%-34s %-64s %5s %20s %13s\n", "ADDRESS", "TXID", "VOUT", "AMOUNT", "CONFIRMATIONS")
+		line(34, 64, 5, 20, 13)
+		for _, u := range utxos {
+			fmt.Printf("%-34s %-64s %5d %20s %13d\n", u.Address, u.TxID, u.VOut, u.Amount, u.Confirmations)
+		}
+		line(34, 64, 5, 20, 13)
+	})
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "info",
+		Usage:       "query blockchain information from the node",
+		Description: "With ela-cli info, you could query blocks, transactions, producers and other information from the node.",
+		ArgsUsage:   "[args]",
+		Subcommands: []cli.Command{
+			{
+				Name:  "block",
+				Usage: "show the block by hash or height",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "hash",
+						Usage: "the block hash",
+					},
+					cli.UintFlag{
+						Name:  "height",
+						Usage: "the block height",
+					},
+					jsonFlag,
+				},
+				Action: blockAction,
+			},
+			{
+				Name:  "tx",
+				Usage: "show the transaction in blocks or the transaction pool",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "txid",
+						Usage: "the transaction id",
+					},
+					jsonFlag,
+				},
+				Action: transactionAction,
+			},
+			{
+				Name:   "mempool",
+				Usage:  "show the transactions in the transaction pool",
+				Flags:  []cli.Flag{jsonFlag},
+				Action: mempoolAction,
+			},
+			{
+				Name:   "neighbors",
+				Usage:  "show the neighbor addresses of the node",
+				Flags:  []cli.Flag{jsonFlag},
+				Action: neighborsAction,
+			},
+			{
+				Name:   "producers",
+				Usage:  "show the registered producers",
+				Flags:  []cli.Flag{jsonFlag},
+				Action: producersAction,
+			},
+			{
+				Name:  "arbiters",
+				Usage: "show the arbiters at the block height",
+				Flags: []cli.Flag{
+					cli.UintFlag{
+						Name:  "height",
+						Usage: "the block height",
+					},
+					jsonFlag,
+				},
+				Action: arbitratorsAction,
+			},
+			{
+				Name:  "unspent",
+				Usage: "show the UTXOs of the addresses",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "address",
+						Usage: "the addresses separated by comma",
+					},
+					cli.StringFlag{
+						Name:  "utxotype",
+						Usage: "the UTXO type in [mixed, vote, normal]",
+						Value: "mixed",
+					},
+					jsonFlag,
+				},
+				Action: unspentAction,
+			},
+		},
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			clicom.PrintError(c, err, "info")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
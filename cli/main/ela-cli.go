@@ -7,8 +7,17 @@ import (
 	"sort"
 	"time"
 
+	"github.com/elastos/Elastos.ELA/cli/addressindex"
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/crosschain"
+	"github.com/elastos/Elastos.ELA/cli/info"
+	"github.com/elastos/Elastos.ELA/cli/migrate"
+	"github.com/elastos/Elastos.ELA/cli/producer"
 	"github.com/elastos/Elastos.ELA/cli/script"
+	"github.com/elastos/Elastos.ELA/cli/signledger"
+	"github.com/elastos/Elastos.ELA/cli/transaction"
 	"github.com/elastos/Elastos.ELA/cli/transfer"
+	"github.com/elastos/Elastos.ELA/cli/vote"
 	"github.com/elastos/Elastos.ELA/cli/wallet"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
@@ -38,12 +47,22 @@ func main() {
 	app.UsageText = "ela-cli [global options] command [command options] [args]"
 	app.HideHelp = false
 	app.HideVersion = false
+	app.Flags = clicom.RPCFlags
+	app.Before = clicom.SetRPCConfig
 	//commands
 	app.Commands = []cli.Command{
 		*wallet.NewCommand(),
 		*transfer.NewCommand(),
 		*script.NewCommand(),
 		*rollback.NewCommand(),
+		*addressindex.NewCommand(),
+		*migrate.NewCommand(),
+		*producer.NewCommand(),
+		*crosschain.NewCommand(),
+		*info.NewCommand(),
+		*transaction.NewCommand(),
+		*vote.NewCommand(),
+		*signledger.NewCommand(),
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 	sort.Sort(cli.FlagsByName(app.Flags))
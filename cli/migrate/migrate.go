@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA/blockchain"
+	cliCommon "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+
+	"github.com/urfave/cli"
+)
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "copy blockchain data to another database backend",
+		Description: "With ela-cli migrate command, you could copy an existing blockchain data directory " +
+			"to another database backend, and set StoreBackend in config.json to use it.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "the database backend of the source directory, default to StoreBackend in config.json",
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Usage: fmt.Sprintf("the database backend of the target directory, one of %v", blockchain.StoreBackends()),
+			},
+			cli.StringFlag{
+				Name:  "source",
+				Usage: "the source blockchain data directory",
+				Value: filepath.Join(config.DataPath, config.DataDir, config.ChainDir),
+			},
+			cli.StringFlag{
+				Name:  "target",
+				Usage: "the target blockchain data directory",
+			},
+		},
+		Action: migrateBlockchain,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			cliCommon.PrintError(c, err, "migrate")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
+
+func migrateBlockchain(context *cli.Context) error {
+	from := context.String("from")
+	if from == "" {
+		from = config.Parameters.StoreBackend
+	}
+	to := context.String("to")
+	source := context.String("source")
+	target := context.String("target")
+	if to == "" || target == "" {
+		return errors.New("both --to and --target are needed")
+	}
+	if filepath.Clean(source) == filepath.Clean(target) {
+		return errors.New("the target directory should be different from the source")
+	}
+
+	src, err := blockchain.NewStore(from, source)
+	if err != nil {
+		fmt.Println("open source database failed! Please check wether there is already a ela process running.", err)
+		return err
+	}
+	defer src.Close()
+
+	dst, err := blockchain.NewStore(to, target)
+	if err != nil {
+		fmt.Println("open target database failed!", err)
+		return err
+	}
+	defer dst.Close()
+
+	fmt.Printf("copying %s (%s) to %s (%s), this may take a while...\n", source, from, target, to)
+	count, err := blockchain.CopyStore(dst, src, func(count int) {
+		fmt.Printf("%d records copied\n", count)
+	})
+	if err != nil {
+		fmt.Println("copy blockchain data failed:", err)
+		return err
+	}
+
+	if err := blockchain.VerifyStoreCopy(dst, src, count); err != nil {
+		fmt.Println("verify blockchain data failed:", err)
+		return err
+	}
+	fmt.Printf("%d records migrated, replace %s with %s and set StoreBackend to \"%s\" in config.json to use it\n",
+		count, source, target, to)
+	return nil
+}
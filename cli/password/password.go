@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/howeyc/gopass"
 )
@@ -43,6 +44,46 @@ func GetConfirmedPassword() ([]byte, error) {
 	return first, nil
 }
 
+// GetMnemonic gets the BIP39 mnemonic from user input
+func GetMnemonic() (string, error) {
+	fmt.Printf("Mnemonic:")
+	mnemonic, err := gopass.GetPasswd()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(mnemonic)), " "), nil
+}
+
+// GetPassphrase gets the BIP39 passphrase of the mnemonic from user input,
+// an empty passphrase means none
+func GetPassphrase() (string, error) {
+	fmt.Printf("Passphrase (empty for none):")
+	passphrase, err := gopass.GetPasswd()
+	if err != nil {
+		return "", err
+	}
+	return string(passphrase), nil
+}
+
+// GetConfirmedPassphrase gets double confirmed BIP39 passphrase from user
+// input, an empty passphrase means none
+func GetConfirmedPassphrase() (string, error) {
+	first, err := GetPassphrase()
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Re-enter Passphrase:")
+	second, err := gopass.GetPasswd()
+	if err != nil {
+		return "", err
+	}
+	if first != string(second) {
+		fmt.Println("Unmatched Passphrase")
+		os.Exit(1)
+	}
+	return first, nil
+}
+
 // GetFlagPassword gets node's wallet password from command line or user input
 func GetFlagPassword() ([]byte, error) {
 	var password []byte
@@ -0,0 +1,462 @@
+package producer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/blockchain"
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/cli/transfer"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+// DefaultDepositAmount is the deposit amount of registering a producer.
+const DefaultDepositAmount = "5000"
+
+func producerAction(context *cli.Context) error {
+	if context.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	var action string
+	var build func(*cli.Context, *account.Account) (*types.Transaction, error)
+	switch {
+	case context.Bool("register"):
+		action, build = "register", createRegisterTransaction
+	case context.Bool("update"):
+		action, build = "update", createUpdateTransaction
+	case context.Bool("cancel"):
+		action, build = "cancel", createCancelTransaction
+	case context.Bool("returndeposit"):
+		action, build = "returndeposit", createReturnDepositTransaction
+	default:
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	if err := producerTransaction(context, build); err != nil {
+		fmt.Println("error:", err)
+		cli.ShowCommandHelpAndExit(context, action, 1)
+	}
+	return nil
+}
+
+// producerTransaction builds the transaction by the owner account, signs it
+// and sends it to the node if required.
+func producerTransaction(context *cli.Context,
+	build func(*cli.Context, *account.Account) (*types.Transaction, error)) error {
+	pwd, err := password.GetPassword()
+	if err != nil {
+		return err
+	}
+	client, err := account.Open(context.String("name"), pwd)
+	if err != nil {
+		return err
+	}
+	owner, err := getOwnerAccount(context, client)
+	if err != nil {
+		return err
+	}
+
+	txn, err := build(context, owner)
+	if err != nil {
+		return err
+	}
+	txn, err = client.Sign(txn)
+	if err != nil {
+		return err
+	}
+
+	return transfer.OutputOrSend(txn, context.Bool("send"))
+}
+
+// getOwnerAccount returns the wallet account of the owner public key, or the
+// main account if not specified.
+func getOwnerAccount(context *cli.Context, client *account.ClientImpl) (*account.Account, error) {
+	ownerPublicKey := context.String("ownerpublickey")
+	if ownerPublicKey == "" {
+		return client.GetDefaultAccount()
+	}
+	publicKey, err := parsePublicKey(ownerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := client.GetAccount(publicKey)
+	if err != nil {
+		return nil, errors.New("owner public key " + ownerPublicKey + " is not in the wallet")
+	}
+	return acc, nil
+}
+
+func createRegisterTransaction(context *cli.Context, owner *account.Account) (*types.Transaction, error) {
+	fee, err := getFee(context)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := common.StringToFixed64(context.String("amount"))
+	if err != nil {
+		return nil, errors.New("invalid deposit amount")
+	}
+	ownerPublicKey, err := owner.PublicKey.EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &payload.PayloadRegisterProducer{
+		OwnerPublicKey: ownerPublicKey,
+		NodePublicKey:  ownerPublicKey,
+		NickName:       context.String("nickname"),
+		Url:            context.String("url"),
+		NetAddress:     context.String("netaddress"),
+	}
+	if p.NickName == "" {
+		return nil, errors.New("use --nickname to specify the nick name of the producer")
+	}
+	if err := applyProducerFlags(context, &p.NodePublicKey, &p.Location); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := p.SerializeUnsigned(buf, payload.PayloadRegisterProducerVersion); err != nil {
+		return nil, err
+	}
+	if p.Signature, err = crypto.Sign(owner.PrivateKey, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	depositAddress, err := getDepositAddress(ownerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	txn, _, err := transfer.CreatePayloadTransaction(owner.Address, owner.Contract.Code,
+		fee, 0, types.RegisterProducer, p, &transfer.Transfer{
+			Address: depositAddress,
+			Amount:  amount,
+		})
+	return txn, err
+}
+
+func createUpdateTransaction(context *cli.Context, owner *account.Account) (*types.Transaction, error) {
+	fee, err := getFee(context)
+	if err != nil {
+		return nil, err
+	}
+	ownerPublicKey, err := owner.PublicKey.EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields not specified keep the registered values
+	producer, err := getProducer(common.BytesToHexString(ownerPublicKey))
+	if err != nil {
+		return nil, err
+	}
+	nodePublicKey, err := common.HexStringToBytes(producer.NodePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	p := &payload.PayloadUpdateProducer{
+		OwnerPublicKey: ownerPublicKey,
+		NodePublicKey:  nodePublicKey,
+		NickName:       producer.Nickname,
+		Url:            producer.Url,
+		Location:       producer.Location,
+		NetAddress:     producer.NetAddress,
+	}
+	if context.IsSet("nickname") {
+		p.NickName = context.String("nickname")
+	}
+	if context.IsSet("url") {
+		p.Url = context.String("url")
+	}
+	if context.IsSet("netaddress") {
+		p.NetAddress = context.String("netaddress")
+	}
+	if err := applyProducerFlags(context, &p.NodePublicKey, &p.Location); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := p.SerializeUnsigned(buf, payload.PayloadUpdateProducerVersion); err != nil {
+		return nil, err
+	}
+	if p.Signature, err = crypto.Sign(owner.PrivateKey, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return createFeeTransaction(owner, fee, types.UpdateProducer, p)
+}
+
+func createCancelTransaction(context *cli.Context, owner *account.Account) (*types.Transaction, error) {
+	fee, err := getFee(context)
+	if err != nil {
+		return nil, err
+	}
+	ownerPublicKey, err := owner.PublicKey.EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &payload.PayloadCancelProducer{OwnerPublicKey: ownerPublicKey}
+	buf := new(bytes.Buffer)
+	if err := p.SerializeUnsigned(buf, payload.PayloadCancelProducerVersion); err != nil {
+		return nil, err
+	}
+	if p.Signature, err = crypto.Sign(owner.PrivateKey, buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return createFeeTransaction(owner, fee, types.CancelProducer, p)
+}
+
+// createReturnDepositTransaction returns all the deposit of the owner except
+// the fee to the owner address, the deposit contract has the same redeem
+// script as the owner account, so it is signed by the owner.
+func createReturnDepositTransaction(context *cli.Context, owner *account.Account) (*types.Transaction, error) {
+	fee, err := getFee(context)
+	if err != nil {
+		return nil, err
+	}
+	ownerPublicKey, err := owner.PublicKey.EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDepositLockup(ownerPublicKey); err != nil {
+		return nil, err
+	}
+	depositAddress, err := getDepositAddress(ownerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	deposit, err := getBalance(depositAddress)
+	if err != nil {
+		return nil, err
+	}
+	if deposit <= *fee {
+		return nil, errors.New("deposit " + deposit.String() + " of " +
+			depositAddress + " is not enough to pay the fee")
+	}
+	amount := deposit - *fee
+	fmt.Println("Refundable amount:", amount.String())
+
+	txn, _, err := transfer.CreatePayloadTransaction(depositAddress, owner.Contract.Code,
+		fee, 0, types.ReturnDepositCoin, &payload.PayloadReturnDepositCoin{},
+		&transfer.Transfer{
+			Address: owner.Address,
+			Amount:  &amount,
+		})
+	return txn, err
+}
+
+// checkDepositLockup checks the producer of the owner is canceled and the
+// deposit is unlocked DepositLockupBlocks after the cancel, like the node
+// does before accepting the return deposit transaction.
+func checkDepositLockup(ownerPublicKey []byte) error {
+	var deposit servers.DepositCoin
+	if err := clicom.CallRPCResult("getdepositcoin", httputil.Params{
+		"ownerpublickey": common.BytesToHexString(ownerPublicKey),
+	}, &deposit); err != nil {
+		return err
+	}
+	if !deposit.Canceled {
+		return errors.New("producer is not canceled, cancel it before returning the deposit")
+	}
+
+	var height uint32
+	if err := clicom.CallRPCResult("getcurrentheight", httputil.Params{},
+		&height); err != nil {
+		return err
+	}
+	if passed := height - deposit.CancelHeight; passed < blockchain.DepositLockupBlocks {
+		return fmt.Errorf("producer canceled at height %d, the deposit is locked for %d more blocks",
+			deposit.CancelHeight, blockchain.DepositLockupBlocks-passed)
+	}
+	return nil
+}
+
+// createFeeTransaction creates the transaction carrying the payload, whose
+// only spend is the fee paid by the owner account.
+func createFeeTransaction(owner *account.Account, fee *common.Fixed64, txType types.TransactionType,
+	txPayload types.Payload) (*types.Transaction, error) {
+	txn, _, err := transfer.CreatePayloadTransaction(owner.Address, owner.Contract.Code,
+		fee, 0, txType, txPayload)
+	return txn, err
+}
+
+// applyProducerFlags overrides the node public key and location by the flags
+// if they are set.
+func applyProducerFlags(context *cli.Context, nodePublicKey *[]byte, location *uint64) error {
+	if str := context.String("nodepublickey"); str != "" {
+		publicKey, err := parsePublicKey(str)
+		if err != nil {
+			return err
+		}
+		if *nodePublicKey, err = publicKey.EncodePoint(true); err != nil {
+			return err
+		}
+	}
+	if str := context.String("location"); str != "" {
+		l, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return errors.New("invalid location " + str)
+		}
+		*location = l
+	}
+	return nil
+}
+
+func getFee(context *cli.Context) (*common.Fixed64, error) {
+	feeStr := context.String("fee")
+	if feeStr == "" {
+		return nil, errors.New("use --fee to specify transaction fee")
+	}
+	fee, err := common.StringToFixed64(feeStr)
+	if err != nil {
+		return nil, errors.New("invalid transaction fee")
+	}
+	return fee, nil
+}
+
+func parsePublicKey(str string) (*crypto.PublicKey, error) {
+	publicKey, err := common.HexStringToBytes(str)
+	if err != nil {
+		return nil, errors.New("invalid public key " + str)
+	}
+	return crypto.DecodePoint(publicKey)
+}
+
+func getDepositAddress(ownerPublicKey []byte) (string, error) {
+	programHash, err := contract.PublicKeyToDepositProgramHash(ownerPublicKey)
+	if err != nil {
+		return "", err
+	}
+	return programHash.ToAddress()
+}
+
+// ListProducers returns the registered producers from the node.
+func ListProducers() ([]servers.Producer, error) {
+	var producers servers.Producers
+	if err := clicom.CallRPCResult("listproducers", httputil.Params{}, &producers); err != nil {
+		return nil, err
+	}
+	return producers.Producers, nil
+}
+
+// getProducer returns the registered producer of the owner public key.
+func getProducer(ownerPublicKey string) (*servers.Producer, error) {
+	producers, err := ListProducers()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range producers {
+		if p.OwnerPublicKey == ownerPublicKey {
+			return &p, nil
+		}
+	}
+	return nil, errors.New("producer " + ownerPublicKey + " is not registered")
+}
+
+func getBalance(address string) (common.Fixed64, error) {
+	utxos, err := transfer.ListUnspent(address, "mixed")
+	if err != nil {
+		return 0, err
+	}
+	var balance common.Fixed64
+	for _, utxo := range utxos {
+		amount, err := common.StringToFixed64(utxo.Amount)
+		if err != nil {
+			return 0, err
+		}
+		balance += *amount
+	}
+	return balance, nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "producer",
+		Usage:       "producer register, update, cancel and deposit return operation",
+		Description: "With ela-cli producer, you could register a producer by the owner account in wallet and manage it.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name: "register",
+				Usage: "register a producer and pay the deposit by the owner account\n" +
+					"\tuse --nickname --fee [--ownerpublickey] [--nodepublickey] [--url] [--location] [--netaddress] [--amount]",
+			},
+			cli.BoolFlag{
+				Name: "update",
+				Usage: "update the registered producer, fields not specified keep the registered values\n" +
+					"\tuse --fee [--ownerpublickey] [--nodepublickey] [--nickname] [--url] [--location] [--netaddress]",
+			},
+			cli.BoolFlag{
+				Name:  "cancel",
+				Usage: "cancel the registered producer, use --fee [--ownerpublickey]",
+			},
+			cli.BoolFlag{
+				Name: "returndeposit",
+				Usage: "return the deposit except the fee to the owner address after the producer canceled\n" +
+					"\tand the deposit lockup passed, use --fee [--ownerpublickey]",
+			},
+			cli.StringFlag{
+				Name:  "ownerpublickey",
+				Usage: "the owner public key of the producer, which must be in the wallet, the main account by default",
+			},
+			cli.StringFlag{
+				Name:  "nodepublickey",
+				Usage: "the node public key of the producer, the owner public key by default",
+			},
+			cli.StringFlag{
+				Name:  "nickname",
+				Usage: "the nick name of the producer",
+			},
+			cli.StringFlag{
+				Name:  "url",
+				Usage: "the url of the producer",
+			},
+			cli.StringFlag{
+				Name:  "location",
+				Usage: "the location code of the producer",
+			},
+			cli.StringFlag{
+				Name:  "netaddress",
+				Usage: "the network address of the producer node",
+			},
+			cli.StringFlag{
+				Name:  "amount",
+				Usage: "the deposit amount of registering",
+				Value: DefaultDepositAmount,
+			},
+			cli.StringFlag{
+				Name:  "fee",
+				Usage: "the transfer fee of the transaction",
+			},
+			cli.BoolFlag{
+				Name:  "send",
+				Usage: "send the signed transaction to the node instead of writing it to file",
+			},
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "wallet name",
+				Value: account.KeystoreFileName,
+			},
+		},
+		Action: producerAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			clicom.PrintError(c, err, "producer")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package producer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+// newTestRPCServer serves the JSON-RPC requests of the cli by the results of
+// the methods, until the returned function is called.
+func newTestRPCServer(t *testing.T, results map[string]interface{}) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": results[request.Method],
+		})
+	}))
+	u, _ := url.Parse(server.URL)
+	port := config.Parameters.HttpJsonPort
+	config.Parameters.HttpJsonPort, _ = strconv.Atoi(u.Port())
+	return func() {
+		server.Close()
+		config.Parameters.HttpJsonPort = port
+	}
+}
+
+// newTestContext returns the context of the producer command with the args.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("producer", flag.ContinueOnError)
+	for _, f := range NewCommand().Flags {
+		f.Apply(set)
+	}
+	assert.NoError(t, set.Parse(args))
+	return cli.NewContext(nil, set, nil)
+}
+
+func newTestUTXO(address, amount string) servers.UTXOInfo {
+	return servers.UTXOInfo{
+		TxType:        byte(types.TransferAsset),
+		TxID:          "a3d0eaa466df74983b5d7c543de6904f4c9418ead5ffd6d25814234a96db37b0",
+		VOut:          1,
+		Address:       address,
+		Amount:        amount,
+		Confirmations: 10,
+	}
+}
+
+func TestCreateRegisterTransaction(t *testing.T) {
+	owner, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ownerPublicKey, _ := owner.PublicKey.EncodePoint(true)
+	_, nodeKey, _ := crypto.GenerateKeyPair()
+	nodePublicKey, _ := nodeKey.EncodePoint(true)
+	depositAddress, err := getDepositAddress(ownerPublicKey)
+	assert.NoError(t, err)
+
+	closeServer := newTestRPCServer(t, map[string]interface{}{
+		"listunspent": []servers.UTXOInfo{newTestUTXO(owner.Address, "6000")},
+	})
+	defer closeServer()
+
+	// the nick name is required
+	_, err = createRegisterTransaction(newTestContext(t, "--fee", "0.001"), owner)
+	assert.Error(t, err)
+
+	// the fee is required
+	_, err = createRegisterTransaction(newTestContext(t, "--nickname", "p1"), owner)
+	assert.Error(t, err)
+
+	txn, err := createRegisterTransaction(newTestContext(t, "--nickname", "p1",
+		"--fee", "0.001", "--nodepublickey", common.BytesToHexString(nodePublicKey),
+		"--url", "http://p1", "--location", "86", "--netaddress", "127.0.0.1:20338"),
+		owner)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.RegisterProducer, txn.TxType)
+
+	// the payload is signed by the owner
+	p, ok := txn.Payload.(*payload.PayloadRegisterProducer)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, ownerPublicKey, p.OwnerPublicKey)
+	assert.Equal(t, nodePublicKey, p.NodePublicKey)
+	assert.Equal(t, "p1", p.NickName)
+	assert.Equal(t, "http://p1", p.Url)
+	assert.Equal(t, uint64(86), p.Location)
+	assert.Equal(t, "127.0.0.1:20338", p.NetAddress)
+	buf := new(bytes.Buffer)
+	assert.NoError(t, p.SerializeUnsigned(buf, payload.PayloadRegisterProducerVersion))
+	assert.NoError(t, crypto.Verify(*owner.PublicKey, buf.Bytes(), p.Signature))
+
+	// the default deposit is paid to the deposit address of the owner, and
+	// the change is returned to the owner
+	if !assert.Equal(t, 2, len(txn.Outputs)) {
+		return
+	}
+	address, _ := txn.Outputs[0].ProgramHash.ToAddress()
+	assert.Equal(t, depositAddress, address)
+	deposit, _ := common.StringToFixed64(DefaultDepositAmount)
+	assert.Equal(t, *deposit, txn.Outputs[0].Value)
+	assert.Equal(t, owner.ProgramHash, txn.Outputs[1].ProgramHash)
+	fee, _ := common.StringToFixed64("0.001")
+	balance, _ := common.StringToFixed64("6000")
+	assert.Equal(t, *balance-*deposit-*fee, txn.Outputs[1].Value)
+
+	// the deposit amount can be specified, and must be paid by the balance
+	txn, err = createRegisterTransaction(newTestContext(t, "--nickname", "p1",
+		"--fee", "0.001", "--amount", "5500"), owner)
+	if assert.NoError(t, err) {
+		amount, _ := common.StringToFixed64("5500")
+		assert.Equal(t, *amount, txn.Outputs[0].Value)
+	}
+	_, err = createRegisterTransaction(newTestContext(t, "--nickname", "p1",
+		"--fee", "0.001", "--amount", "6000"), owner)
+	assert.Error(t, err)
+	_, err = createRegisterTransaction(newTestContext(t, "--nickname", "p1",
+		"--fee", "0.001", "--amount", "abc"), owner)
+	assert.Error(t, err)
+}
+
+func TestCheckDepositLockup(t *testing.T) {
+	ownerPublicKey := make([]byte, 33)
+	results := map[string]interface{}{
+		"getdepositcoin": servers.DepositCoin{
+			Available: "5000",
+			Canceled:  false,
+		},
+		"getcurrentheight": 100 + blockchain.DepositLockupBlocks,
+	}
+	closeServer := newTestRPCServer(t, results)
+	defer closeServer()
+
+	// the deposit of the producer not canceled is locked
+	assert.Error(t, checkDepositLockup(ownerPublicKey))
+
+	// the deposit is locked DepositLockupBlocks after the cancel
+	results["getdepositcoin"] = servers.DepositCoin{
+		Available:    "5000",
+		Canceled:     true,
+		CancelHeight: 101,
+	}
+	assert.Error(t, checkDepositLockup(ownerPublicKey))
+	results["getdepositcoin"] = servers.DepositCoin{
+		Available:    "5000",
+		Canceled:     true,
+		CancelHeight: 100,
+	}
+	assert.NoError(t, checkDepositLockup(ownerPublicKey))
+}
+
+func TestCreateReturnDepositTransaction(t *testing.T) {
+	owner, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ownerPublicKey, _ := owner.PublicKey.EncodePoint(true)
+	depositAddress, err := getDepositAddress(ownerPublicKey)
+	assert.NoError(t, err)
+
+	results := map[string]interface{}{
+		"getdepositcoin": servers.DepositCoin{
+			Available:    "5000",
+			Canceled:     true,
+			CancelHeight: 100,
+		},
+		"getcurrentheight": 100 + blockchain.DepositLockupBlocks,
+		"listunspent": []servers.UTXOInfo{
+			newTestUTXO(depositAddress, "4000"),
+			newTestUTXO(depositAddress, "1000"),
+		},
+	}
+	closeServer := newTestRPCServer(t, results)
+	defer closeServer()
+
+	// all the deposit except the fee is returned to the owner
+	txn, err := createReturnDepositTransaction(newTestContext(t, "--fee", "0.001"), owner)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.ReturnDepositCoin, txn.TxType)
+	assert.Equal(t, 2, len(txn.Inputs))
+	if assert.Equal(t, 1, len(txn.Outputs)) {
+		assert.Equal(t, owner.ProgramHash, txn.Outputs[0].ProgramHash)
+		amount, _ := common.StringToFixed64("4999.999")
+		assert.Equal(t, *amount, txn.Outputs[0].Value)
+	}
+
+	// the deposit must be enough to pay the fee
+	results["listunspent"] = []servers.UTXOInfo{newTestUTXO(depositAddress, "0.001")}
+	_, err = createReturnDepositTransaction(newTestContext(t, "--fee", "0.001"), owner)
+	assert.Error(t, err)
+
+	// the deposit can not be returned before the lockup passed
+	results["getcurrentheight"] = 99 + blockchain.DepositLockupBlocks
+	_, err = createReturnDepositTransaction(newTestContext(t, "--fee", "0.001"), owner)
+	assert.Error(t, err)
+}
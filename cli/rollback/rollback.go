@@ -40,9 +40,10 @@ func rollbackBlockchain(context *cli.Context) error {
 		fmt.Println("get height error:", err)
 		return err
 	}
-	store, err := blockchain.NewLevelDB(filepath.Join(config.DataPath, config.DataDir, config.ChainDir))
+	store, err := blockchain.NewStore(config.Parameters.StoreBackend,
+		filepath.Join(config.DataPath, config.DataDir, config.ChainDir))
 	if err != nil {
-		fmt.Println("connect leveldb failed! Please check wether there is already a ela process running.", err)
+		fmt.Println("open database failed! Please check wether there is already a ela process running.", err)
 	}
 
 	chain := blockchain.ChainStore{IStore: store}
@@ -66,6 +67,12 @@ func rollbackBlockchain(context *cli.Context) error {
 		chain.RollbackTransactions(block)
 		chain.RollbackUnspendUTXOs(block)
 		chain.RollbackUnspend(block)
+		if config.Parameters.EnableAddressIndex {
+			chain.RollbackAddressHistory(block)
+		}
+		if config.Parameters.EnableSpendIndex {
+			chain.RollbackSpendInfo(block)
+		}
 		chain.RollbackCurrentBlock(block)
 		chain.RollbackConfirm(block)
 		chain.BatchCommit()
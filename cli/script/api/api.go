@@ -17,7 +17,6 @@ import (
 	"github.com/elastos/Elastos.ELA/servers"
 	"github.com/elastos/Elastos.ELA/version/verconfig"
 
-	"github.com/elastos/Elastos.ELA.Utility/http/jsonrpc"
 	"github.com/elastos/Elastos.ELA.Utility/http/util"
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/yuin/gopher-lua"
@@ -83,7 +82,7 @@ func sendTx(L *lua.LState) int {
 	}
 	txHex := hex.EncodeToString(buffer.Bytes())
 
-	result, err := jsonrpc.CallParams(clicom.LocalServer(), "sendrawtransaction", util.Params{
+	result, err := clicom.CallRPC("sendrawtransaction", util.Params{
 		"data": txHex,
 	})
 	if err != nil {
@@ -103,7 +102,7 @@ func getAssetID(L *lua.LState) int {
 
 func getUTXO(L *lua.LState) int {
 	from := L.ToString(1)
-	result, err := jsonrpc.CallParams(clicom.LocalServer(), "listunspent", util.Params{
+	result, err := clicom.CallRPC("listunspent", util.Params{
 		"addresses": []string{from},
 	})
 	if err != nil {
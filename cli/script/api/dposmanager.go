@@ -66,7 +66,7 @@ func newDposManager(L *lua.LState) int {
 	mockManager.Account = account.NewDposAccountFromExisting(&account2.Account{
 		PrivateKey: priKey,
 		PublicKey:  pubKey,
-	})
+	}, nil)
 
 	mockManager.EventMonitor = log.NewEventMoniter()
 	mockManager.EventMonitor.RegisterListener(&log.EventLogs{})
@@ -249,9 +249,10 @@ func dposManagerCheckStatusRunning(L *lua.LState) int {
 func dposManagerSignProposal(L *lua.LState) int {
 	m := checkDposManager(L, 1)
 	p := checkProposal(L, 2)
+	height := uint32(L.OptInt(3, 0))
 
 	result := false
-	if sign, err := m.Account.SignProposal(p); err == nil {
+	if sign, err := m.Account.SignProposal(&types.Header{Height: height}, p); err == nil {
 		p.Sign = sign
 		result = true
 	}
@@ -263,9 +264,14 @@ func dposManagerSignProposal(L *lua.LState) int {
 func dposManagerSignVote(L *lua.LState) int {
 	m := checkDposManager(L, 1)
 	v := checkVote(L, 2)
+	p := &types.DPosProposal{}
+	if L.GetTop() >= 3 {
+		p = checkProposal(L, 3)
+	}
+	height := uint32(L.OptInt(4, 0))
 
 	result := false
-	if sign, err := m.Account.SignVote(v); err == nil {
+	if sign, err := m.Account.SignVote(&types.Header{Height: height}, p, v); err == nil {
 		v.Sign = sign
 		result = true
 	}
@@ -197,6 +197,26 @@ func (n *nodeMock) UnregisterTxPoolListener(listener protocol.TxnPoolListener) {
 	panic("implement me")
 }
 
+func (n *nodeMock) SaveTxPool() error {
+	panic("implement me")
+}
+
+func (n *nodeMock) LoadTxPool() {
+	panic("implement me")
+}
+
+func (n *nodeMock) SaveFeeEstimator() error {
+	panic("implement me")
+}
+
+func (n *nodeMock) LoadFeeEstimator() {
+	panic("implement me")
+}
+
+func (n *nodeMock) EstimateFee(confirmations uint32, confidence float64) (common.Fixed64, error) {
+	panic("implement me")
+}
+
 func (n *nodeMock) IsDuplicateSidechainTx(sidechainTxHash common.Uint256) bool {
 	panic("implement me")
 }
@@ -0,0 +1,119 @@
+package signledger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cliCommon "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/dpos/account"
+
+	"github.com/urfave/cli"
+)
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "signledger",
+		Usage: "export or import the proposals and votes signed by the arbiter",
+		Description: "With ela-cli signledger command, you could export the sign ledger of an arbiter " +
+			"and import it on the new machine when migrating the arbiter, so that it will not sign " +
+			"conflicting proposals or votes. The node should be stopped while running this command.",
+		ArgsUsage: "[args]",
+		Subcommands: []cli.Command{
+			{
+				Name:  "export",
+				Usage: "export the sign ledger to a file",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "ledger",
+						Usage: "the sign ledger directory",
+						Value: filepath.Join(config.DataPath, config.DataDir, config.SignLedgerDir),
+					},
+					cli.StringFlag{
+						Name:  "file, f",
+						Usage: "the file to export the sign ledger to",
+					},
+				},
+				Action: exportLedger,
+			},
+			{
+				Name:  "import",
+				Usage: "import the sign ledger from a file",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "ledger",
+						Usage: "the sign ledger directory",
+						Value: filepath.Join(config.DataPath, config.DataDir, config.SignLedgerDir),
+					},
+					cli.StringFlag{
+						Name:  "file, f",
+						Usage: "the file exported by the export command",
+					},
+				},
+				Action: importLedger,
+			},
+		},
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			cliCommon.PrintError(c, err, "signledger")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
+
+func exportLedger(c *cli.Context) error {
+	fileName := c.String("file")
+	if fileName == "" {
+		return errors.New("use --file to specify the file to export to")
+	}
+
+	ledger, err := account.NewSignLedger(c.String("ledger"))
+	if err != nil {
+		fmt.Println("open sign ledger failed! Please check wether there is already a ela process running.", err)
+		return err
+	}
+	defer ledger.Close()
+
+	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	count, err := ledger.Export(file)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+	fmt.Printf("Exported %d records to %s\n", count, fileName)
+	return nil
+}
+
+func importLedger(c *cli.Context) error {
+	fileName := c.String("file")
+	if fileName == "" {
+		return errors.New("use --file to specify the file to import from")
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ledger, err := account.NewSignLedger(c.String("ledger"))
+	if err != nil {
+		fmt.Println("open sign ledger failed! Please check wether there is already a ela process running.", err)
+		return err
+	}
+	defer ledger.Close()
+
+	count, err := ledger.Import(file)
+	if err != nil {
+		fmt.Println("error:", err)
+		return err
+	}
+	fmt.Printf("Imported %d records from %s\n", count, fileName)
+	return nil
+}
@@ -0,0 +1,198 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/transfer"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+func decodeAction(c *cli.Context) error {
+	if c.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(c)
+		return nil
+	}
+
+	if err := decodeTransaction(c); err != nil {
+		fmt.Println("error:", err)
+		cli.ShowCommandHelpAndExit(c, "decode", 1)
+	}
+	return nil
+}
+
+// decodeTransaction decodes the transaction and shows it.
+func decodeTransaction(c *cli.Context) error {
+	content, err := transfer.GetTransactionContent(c)
+	if err != nil {
+		return err
+	}
+	txn, info, err := decodeTransactionInfo(content, c.Bool("resolve"))
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	return showTransaction(txn, info)
+}
+
+// decodeTransactionInfo decodes the transaction offline, or by the node to
+// resolve the outputs referenced by the inputs if required.
+func decodeTransactionInfo(content string, resolve bool) (*types.Transaction,
+	*servers.DecodedTransactionInfo, error) {
+	rawData, err := common.HexStringToBytes(content)
+	if err != nil {
+		return nil, nil, errors.New("decode transaction content failed")
+	}
+	var txn types.Transaction
+	if err := txn.Deserialize(bytes.NewReader(rawData)); err != nil {
+		return nil, nil, errors.New("deserialize transaction failed, " + err.Error())
+	}
+
+	if !resolve {
+		return &txn, servers.GetDecodedTransactionInfo(&txn, nil), nil
+	}
+	info := new(servers.DecodedTransactionInfo)
+	if err := clicom.CallRPCResult("decoderawtransaction", httputil.Params{
+		"data": content,
+	}, info); err != nil {
+		return nil, nil, err
+	}
+	if info.TransactionInfo == nil || len(info.References) != len(txn.Inputs) ||
+		len(info.SignStatus) != len(txn.Programs) {
+		return nil, nil, errors.New("invalid decoded transaction from the node")
+	}
+	return &txn, info, nil
+}
+
+func showTransaction(txn *types.Transaction, info *servers.DecodedTransactionInfo) error {
+	fmt.Println("TXID:          ", info.TxID)
+	fmt.Println("Type:          ", txn.TxType.Name())
+	fmt.Println("Version:       ", info.Version)
+	fmt.Println("PayloadVersion:", info.PayloadVersion)
+	fmt.Println("Size:          ", info.Size)
+	fmt.Println("LockTime:      ", info.LockTime)
+
+	if info.Payload != nil {
+		payload, err := json.MarshalIndent(info.Payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println("PAYLOAD:")
+		fmt.Println(string(payload))
+	}
+
+	fmt.Println("ATTRIBUTES:")
+	for i, attr := range txn.Attributes {
+		fmt.Printf("%5d %-12s %s\n", i, attr.Usage.Name(), common.BytesToHexString(attr.Data))
+	}
+
+	fmt.Println("INPUTS:")
+	for i, input := range info.Inputs {
+		reference := "unknown"
+		if r := info.References[i]; r != nil {
+			reference = fmt.Sprintf("%34s %s", r.Address, r.Value)
+		}
+		fmt.Printf("%5d %s:%d %s\n", i, input.TxID, input.VOut, reference)
+	}
+
+	fmt.Println("OUTPUTS:")
+	for _, output := range info.Outputs {
+		fmt.Printf("%5d %34s %s\n", output.Index, output.Address, output.Value)
+		if txn.Outputs[output.Index].OutputType != types.DefaultOutput {
+			payload, err := json.Marshal(output.OutputPayload)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%5s %s\n", "", payload)
+		}
+	}
+
+	fmt.Println("PROGRAMS:")
+	for i, program := range txn.Programs {
+		address := "unknown"
+		if hash, err := programHash(program.Code); err == nil {
+			address, _ = hash.ToAddress()
+		}
+		status := "unknown"
+		if s := info.SignStatus[i]; s != nil {
+			status = fmt.Sprintf("[ %d / %d ]", s.HaveSign, s.NeedSign)
+		}
+		fmt.Printf("%5d %34s %s\n", i, address, status)
+	}
+
+	if info.Fee != "" {
+		fmt.Println("FEE:", info.Fee)
+	} else {
+		fmt.Println("FEE: unknown, use --resolve to resolve the inputs by the node")
+	}
+	return nil
+}
+
+// programHash returns the program hash of the standard or multi-sign redeem
+// script.
+func programHash(code []byte) (*common.Uint168, error) {
+	if len(code) == 0 {
+		return nil, errors.New("empty redeem script")
+	}
+	switch code[len(code)-1] {
+	case common.STANDARD:
+		return common.ToProgramHash(common.PrefixStandard, code), nil
+	case common.MULTISIG:
+		return common.ToProgramHash(common.PrefixMultisig, code), nil
+	}
+	return nil, errors.New("unknown redeem script type")
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "transaction",
+		Usage:       "inspect transactions",
+		Description: "With ela-cli transaction, you could decode and inspect raw transactions.",
+		ArgsUsage:   "[args]",
+		Subcommands: []cli.Command{
+			{
+				Name:  "decode",
+				Usage: "decode the raw transaction offline, use --hex or --file",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "hex",
+						Usage: "the transaction content in hex string format",
+					},
+					cli.StringFlag{
+						Name:  "file, f",
+						Usage: "the transaction file path with the hex string content",
+					},
+					cli.BoolFlag{
+						Name:  "resolve",
+						Usage: "decode by the node to resolve the outputs referenced by the inputs and the fee",
+					},
+					cli.BoolFlag{
+						Name:  "json",
+						Usage: "print the result in JSON format",
+					},
+				},
+				Action: decodeAction,
+			},
+		},
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			clicom.PrintError(c, err, "transaction")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
@@ -0,0 +1,173 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRPCServer serves the JSON-RPC requests of the cli by the replies of
+// the methods, until the returned function is called.
+func newTestRPCServer(t *testing.T, replies map[string]interface{}) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(replies[request.Method])
+	}))
+	u, _ := url.Parse(server.URL)
+	port := config.Parameters.HttpJsonPort
+	config.Parameters.HttpJsonPort, _ = strconv.Atoi(u.Port())
+	return func() {
+		server.Close()
+		config.Parameters.HttpJsonPort = port
+	}
+}
+
+// newTestTransaction returns the transaction spending an output of the
+// account, which is signed by the account.
+func newTestTransaction(t *testing.T, acc *account.Account) *types.Transaction {
+	txn := &types.Transaction{
+		Version: types.TxVersion09,
+		TxType:  types.TransferAsset,
+		Payload: &payload.PayloadTransferAsset{},
+		Inputs: []*types.Input{{
+			Previous: types.OutPoint{TxID: common.Uint256{1}, Index: 1},
+			Sequence: 0xffffffff,
+		}},
+		Outputs: []*types.Output{{
+			AssetID:       *account.SystemAssetID,
+			Value:         100,
+			ProgramHash:   acc.ProgramHash,
+			OutputType:    types.DefaultOutput,
+			OutputPayload: &outputpayload.DefaultOutput{},
+		}},
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, txn.SerializeUnsigned(buf))
+	signature, err := crypto.Sign(acc.PrivateKey, buf.Bytes())
+	assert.NoError(t, err)
+	txn.Programs = []*program.Program{{
+		Code:      acc.Contract.Code,
+		Parameter: append([]byte{byte(len(signature))}, signature...),
+	}}
+	return txn
+}
+
+func transactionHex(txn *types.Transaction) string {
+	buf := new(bytes.Buffer)
+	txn.Serialize(buf)
+	return common.BytesToHexString(buf.Bytes())
+}
+
+func TestProgramHash(t *testing.T) {
+	acc, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	hash, err := programHash(acc.Contract.Code)
+	assert.NoError(t, err)
+	assert.Equal(t, acc.ProgramHash, *hash)
+
+	_, publicKey1, _ := crypto.GenerateKeyPair()
+	_, publicKey2, _ := crypto.GenerateKeyPair()
+	multi, err := account.NewMultiSignWatchData(2,
+		[]*crypto.PublicKey{publicKey1, publicKey2}, "")
+	assert.NoError(t, err)
+	script, _ := common.HexStringToBytes(multi.RedeemScript)
+	hash, err = programHash(script)
+	if assert.NoError(t, err) {
+		address, _ := hash.ToAddress()
+		assert.Equal(t, multi.Address, address)
+	}
+
+	_, err = programHash(nil)
+	assert.Error(t, err)
+	_, err = programHash([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestDecodeTransactionInfo(t *testing.T) {
+	acc, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	txn := newTestTransaction(t, acc)
+	content := transactionHex(txn)
+
+	// the transaction is decoded offline without the references and fee
+	decoded, info, err := decodeTransactionInfo(content, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, txn.Hash(), decoded.Hash())
+	assert.Equal(t, common.BytesToHexString(common.BytesReverse(
+		txn.Hash().Bytes())), info.TxID)
+	assert.Equal(t, []*servers.ReferenceInfo{nil}, info.References)
+	assert.Equal(t, "", info.Fee)
+	assert.Equal(t, []*servers.SignStatusInfo{{HaveSign: 1, NeedSign: 1}},
+		info.SignStatus)
+	assert.NoError(t, showTransaction(decoded, info))
+
+	_, _, err = decodeTransactionInfo("xyz", false)
+	assert.Error(t, err)
+	_, _, err = decodeTransactionInfo(content[:len(content)-2], false)
+	assert.Error(t, err)
+
+	// the references and fee are resolved by the node
+	references := []*types.Output{{Value: 150, ProgramHash: acc.ProgramHash}}
+	replies := map[string]interface{}{
+		"decoderawtransaction": map[string]interface{}{
+			"result": servers.GetDecodedTransactionInfo(txn, references),
+		},
+	}
+	closeServer := newTestRPCServer(t, replies)
+	defer closeServer()
+	decoded, info, err = decodeTransactionInfo(content, true)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, txn.Hash(), decoded.Hash())
+	assert.Equal(t, []*servers.ReferenceInfo{{Address: acc.Address,
+		Value: common.Fixed64(150).String()}}, info.References)
+	assert.Equal(t, common.Fixed64(50).String(), info.Fee)
+	assert.NoError(t, showTransaction(decoded, info))
+
+	// the error replied by the node is returned
+	replies["decoderawtransaction"] = map[string]interface{}{
+		"error": map[string]interface{}{"code": 45002, "message": "unknown transaction"},
+	}
+	_, _, err = decodeTransactionInfo(content, true)
+	if assert.Error(t, err) {
+		assert.Equal(t, "unknown transaction", err.Error())
+	}
+
+	// the decoded transaction not matching the transaction is refused
+	another := newTestTransaction(t, acc)
+	another.Inputs = append(another.Inputs, another.Inputs[0])
+	replies["decoderawtransaction"] = map[string]interface{}{
+		"result": servers.GetDecodedTransactionInfo(another, nil),
+	}
+	_, _, err = decodeTransactionInfo(content, true)
+	assert.Error(t, err)
+	replies["decoderawtransaction"] = map[string]interface{}{"result": nil}
+	_, _, err = decodeTransactionInfo(content, true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,313 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/urfave/cli"
+)
+
+const (
+	// LargestFirst spends the largest UTXOs first, so the transaction has the
+	// least inputs.
+	LargestFirst = "largest"
+
+	// BranchAndBound searches the UTXOs matching the amount exactly to create
+	// no change, and falls back to LargestFirst if no match found.
+	BranchAndBound = "bnb"
+
+	// OldestFirst spends the UTXOs with the most confirmations first.
+	OldestFirst = "oldest"
+
+	// Privacy spends a single UTXO covering the amount if possible, so that
+	// the UTXOs of the wallet are not linked together, or the UTXOs in random
+	// order otherwise.
+	Privacy = "privacy"
+
+	// DefaultConsolidateBatch is the count of UTXOs swept by a consolidate
+	// transaction if not specified.
+	DefaultConsolidateBatch = 100
+
+	// maxSelectIterations is the times to select UTXOs again to pay the fee
+	// increased by the size of the transaction.
+	maxSelectIterations = 10
+
+	// maxBranchAndBoundTries is the limit of the branches searched by the
+	// branch and bound selection.
+	maxBranchAndBoundTries = 100000
+)
+
+// Strategies are the coin selection strategies can be specified by --strategy.
+var Strategies = []string{LargestFirst, BranchAndBound, OldestFirst, Privacy}
+
+// CoinSelection is the options to select the UTXOs spent by a transaction.
+type CoinSelection struct {
+	// Strategy is one of the Strategies.
+	Strategy string
+
+	// FeePerKB is the fee paid for each 1000 bytes of the transaction. The
+	// fee is calculated by the estimated size of the signed transaction if it
+	// is not nil, and the fee specified is the minimum then.
+	FeePerKB *common.Fixed64
+}
+
+// DefaultCoinSelection spends the largest UTXOs first with the fixed fee.
+var DefaultCoinSelection = CoinSelection{Strategy: LargestFirst}
+
+// coin is an UTXO with the parsed amount.
+type coin struct {
+	utxo   servers.UTXOInfo
+	amount common.Fixed64
+}
+
+func newCoins(utxos []servers.UTXOInfo) ([]*coin, error) {
+	coins := make([]*coin, 0, len(utxos))
+	for _, utxo := range utxos {
+		amount, err := common.StringToFixed64(utxo.Amount)
+		if err != nil {
+			return nil, errors.New("invalid amount of UTXO " + utxo.TxID)
+		}
+		coins = append(coins, &coin{utxo: utxo, amount: *amount})
+	}
+	return coins, nil
+}
+
+func sumCoins(coins []*coin) common.Fixed64 {
+	var total common.Fixed64
+	for _, c := range coins {
+		total += c.amount
+	}
+	return total
+}
+
+// getCoinSelection returns the coin selection specified by --strategy and
+// --feeperkb.
+func getCoinSelection(c *cli.Context) (*CoinSelection, error) {
+	selection := DefaultCoinSelection
+	if strategy := c.String("strategy"); strategy != "" {
+		if !isStrategy(strategy) {
+			return nil, errors.New("invalid coin selection strategy " + strategy +
+				", use one of [" + strings.Join(Strategies, ", ") + "]")
+		}
+		selection.Strategy = strategy
+	}
+	if feeRate := c.String("feeperkb"); feeRate != "" {
+		rate, err := common.StringToFixed64(feeRate)
+		if err != nil || *rate <= 0 {
+			return nil, errors.New("invalid fee per KB")
+		}
+		selection.FeePerKB = rate
+	}
+	return &selection, nil
+}
+
+func isStrategy(strategy string) bool {
+	for _, s := range Strategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSpendable returns if the UTXO can be spent in the next block, and a
+// locked output must wait until its lock height. The block count is the
+// height of the next block. The node unlocks the output of a coinbase
+// transaction once its best height exceeds the coinbase height by
+// CoinbaseLockTime, which is CoinbaseLockTime+1 confirmations since the
+// coinbase block is counted as one.
+func IsSpendable(utxo servers.UTXOInfo, blockCount uint32) bool {
+	if types.TransactionType(utxo.TxType) == types.CoinBase &&
+		utxo.Confirmations <= config.Parameters.ChainParam.CoinbaseLockTime {
+		return false
+	}
+	return utxo.OutputLock == 0 || utxo.OutputLock < blockCount
+}
+
+// SpendableUTXOs returns the UTXOs can be spent in the next block.
+func SpendableUTXOs(utxos []servers.UTXOInfo) ([]servers.UTXOInfo, error) {
+	var blockCount uint32
+	for _, utxo := range utxos {
+		if utxo.OutputLock > 0 {
+			count, err := getBlockCount()
+			if err != nil {
+				return nil, err
+			}
+			blockCount = count
+			break
+		}
+	}
+
+	var spendable []servers.UTXOInfo
+	for _, utxo := range utxos {
+		if IsSpendable(utxo, blockCount) {
+			spendable = append(spendable, utxo)
+		}
+	}
+	return spendable, nil
+}
+
+// selectCoins selects the coins to pay the target amount by the strategy. The
+// input fee is deducted from the amount of each coin selected, and a change
+// costs the change fee, which are zero for the fixed fee.
+func selectCoins(strategy string, coins []*coin, target, inputFee, changeFee common.Fixed64) []*coin {
+	if target <= 0 {
+		return nil
+	}
+	sorted := make([]*coin, len(coins))
+	copy(sorted, coins)
+
+	switch strategy {
+	case BranchAndBound:
+		sortByAmount(sorted)
+		if selected := branchAndBound(sorted, target, inputFee, changeFee); selected != nil {
+			return selected
+		}
+	case OldestFirst:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].utxo.Confirmations == sorted[j].utxo.Confirmations {
+				return sorted[i].amount > sorted[j].amount
+			}
+			return sorted[i].utxo.Confirmations > sorted[j].utxo.Confirmations
+		})
+		return accumulate(sorted, target, inputFee)
+	case Privacy:
+		sortByAmount(sorted)
+		// The smallest coin covers the target alone
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if sorted[i].amount-inputFee >= target {
+				return []*coin{sorted[i]}
+			}
+		}
+		rand.Shuffle(len(sorted), func(i, j int) {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		})
+		return accumulate(sorted, target, inputFee)
+	}
+
+	sortByAmount(sorted)
+	return accumulate(sorted, target, inputFee)
+}
+
+// sortByAmount sorts the coins by amount in descending order.
+func sortByAmount(coins []*coin) {
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].amount > coins[j].amount
+	})
+}
+
+// accumulate selects the coins in order until the target is paid, all the
+// coins are returned if they are not enough.
+func accumulate(coins []*coin, target, inputFee common.Fixed64) []*coin {
+	var selected []*coin
+	var total common.Fixed64
+	for _, c := range coins {
+		if total >= target {
+			break
+		}
+		selected = append(selected, c)
+		total += c.amount - inputFee
+	}
+	return selected
+}
+
+// branchAndBound searches the coins sorted by amount in descending order by
+// depth first, for the coins whose effective amount is not less than the
+// target, and exceeds it less than the change fee. Nil is returned if no
+// match found.
+func branchAndBound(coins []*coin, target, inputFee, changeFee common.Fixed64) []*coin {
+	var remaining common.Fixed64
+	effective := make([]common.Fixed64, len(coins))
+	for i, c := range coins {
+		effective[i] = c.amount - inputFee
+		if effective[i] > 0 {
+			remaining += effective[i]
+		}
+	}
+	if remaining < target {
+		return nil
+	}
+
+	tries := 0
+	selected := make([]bool, len(coins))
+	var search func(index int, total, remaining common.Fixed64) bool
+	search = func(index int, total, remaining common.Fixed64) bool {
+		tries++
+		if total >= target {
+			return total <= target+changeFee
+		}
+		if index >= len(coins) || total+remaining < target || tries > maxBranchAndBoundTries {
+			return false
+		}
+		if effective[index] <= 0 {
+			return search(index+1, total, remaining)
+		}
+
+		remaining -= effective[index]
+		selected[index] = true
+		if search(index+1, total+effective[index], remaining) {
+			return true
+		}
+		selected[index] = false
+		return search(index+1, total, remaining)
+	}
+	if !search(0, 0, remaining) {
+		return nil
+	}
+
+	var result []*coin
+	for i, c := range coins {
+		if selected[i] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// feeOfSize returns the fee of the size in bytes by the fee per KB.
+func feeOfSize(size int, feePerKB common.Fixed64) common.Fixed64 {
+	return (common.Fixed64(size)*feePerKB + 999) / 1000
+}
+
+// estimateSize returns the size of the transaction signed by the redeem
+// script, which is signed by the required count of signers.
+func estimateSize(txn *types.Transaction, redeemScript []byte) (int, error) {
+	_, needSign, err := crypto.GetSignStatus(redeemScript, nil)
+	if err != nil {
+		return 0, err
+	}
+	program := txn.Programs[0]
+	parameter := program.Parameter
+	program.Parameter = make([]byte, needSign*crypto.SignatureScriptLength)
+	size := txn.GetSize()
+	program.Parameter = parameter
+	return size, nil
+}
+
+// inputSize returns the serialized size of an input.
+func inputSize() int {
+	buf := new(bytes.Buffer)
+	new(types.Input).Serialize(buf)
+	return buf.Len()
+}
+
+// changeSize returns the serialized size of a change output.
+func changeSize() int {
+	buf := new(bytes.Buffer)
+	output := types.Output{
+		OutputType:    types.DefaultOutput,
+		OutputPayload: &outputpayload.DefaultOutput{},
+	}
+	output.Serialize(buf, types.TxVersion09)
+	return buf.Len()
+}
@@ -0,0 +1,201 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCoins returns the coins of the amounts, the confirmations of each
+// coin are the given ones or zero.
+func newTestCoins(amounts []common.Fixed64, confirmations ...uint32) []*coin {
+	coins := make([]*coin, 0, len(amounts))
+	for i, amount := range amounts {
+		c := &coin{amount: amount}
+		if i < len(confirmations) {
+			c.utxo.Confirmations = confirmations[i]
+		}
+		coins = append(coins, c)
+	}
+	return coins
+}
+
+func amountsOf(coins []*coin) []common.Fixed64 {
+	var amounts []common.Fixed64
+	for _, c := range coins {
+		amounts = append(amounts, c.amount)
+	}
+	return amounts
+}
+
+func TestSelectCoins(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      string
+		amounts       []common.Fixed64
+		confirmations []uint32
+		target        common.Fixed64
+		inputFee      common.Fixed64
+		changeFee     common.Fixed64
+		expected      []common.Fixed64
+	}{
+		{"no target", LargestFirst, []common.Fixed64{1, 5}, nil,
+			0, 0, 0, nil},
+		{"largest", LargestFirst, []common.Fixed64{1, 5, 3, 8}, nil,
+			9, 0, 0, []common.Fixed64{8, 5}},
+		{"largest with input fee", LargestFirst, []common.Fixed64{5, 5, 5}, nil,
+			9, 1, 0, []common.Fixed64{5, 5, 5}},
+		{"unknown strategy", "unknown", []common.Fixed64{1, 5, 3, 8}, nil,
+			9, 0, 0, []common.Fixed64{8, 5}},
+		{"bnb exact match", BranchAndBound, []common.Fixed64{1, 5, 3, 8}, nil,
+			9, 0, 0, []common.Fixed64{8, 1}},
+		{"bnb within change fee", BranchAndBound, []common.Fixed64{5, 8}, nil,
+			12, 0, 1, []common.Fixed64{8, 5}},
+		{"bnb fallback", BranchAndBound, []common.Fixed64{5, 8}, nil,
+			10, 0, 0, []common.Fixed64{8, 5}},
+		{"oldest", OldestFirst, []common.Fixed64{8, 1, 3, 5}, []uint32{1, 10, 10, 5},
+			6, 0, 0, []common.Fixed64{3, 1, 5}},
+		{"privacy single coin", Privacy, []common.Fixed64{1, 5, 3, 8}, nil,
+			4, 0, 0, []common.Fixed64{5}},
+		{"privacy single coin with input fee", Privacy, []common.Fixed64{1, 5, 3, 8}, nil,
+			5, 1, 0, []common.Fixed64{8}},
+	}
+
+	for _, test := range tests {
+		coins := newTestCoins(test.amounts, test.confirmations...)
+		selected := selectCoins(test.strategy, coins, test.target,
+			test.inputFee, test.changeFee)
+		assert.Equal(t, test.expected, amountsOf(selected), test.name)
+		assert.Equal(t, test.amounts, amountsOf(coins),
+			test.name+" changed the order of the coins")
+	}
+
+	// the coins are spent in random order if no single coin covers the target
+	coins := newTestCoins([]common.Fixed64{1, 5, 3, 8})
+	for i := 0; i < 10; i++ {
+		selected := selectCoins(Privacy, coins, 12, 0, 0)
+		assert.True(t, sumCoins(selected) >= 12)
+		last := selected[:len(selected)-1]
+		assert.True(t, sumCoins(last) < 12)
+	}
+}
+
+func TestBranchAndBound(t *testing.T) {
+	tests := []struct {
+		name      string
+		amounts   []common.Fixed64
+		target    common.Fixed64
+		inputFee  common.Fixed64
+		changeFee common.Fixed64
+		expected  []common.Fixed64
+	}{
+		{"single coin", []common.Fixed64{8, 5, 3}, 5, 0, 0,
+			[]common.Fixed64{5}},
+		{"multiple coins", []common.Fixed64{6, 4, 3}, 7, 0, 0,
+			[]common.Fixed64{4, 3}},
+		{"within change fee", []common.Fixed64{6, 4, 3}, 8, 0, 1,
+			[]common.Fixed64{6, 3}},
+		{"with input fee", []common.Fixed64{6, 4, 3}, 7, 1, 0,
+			[]common.Fixed64{6, 3}},
+		{"coins not worth the input fee", []common.Fixed64{6, 1, 1}, 5, 1, 0,
+			[]common.Fixed64{6}},
+		{"no match", []common.Fixed64{6, 4}, 5, 0, 0, nil},
+		{"insufficient", []common.Fixed64{6, 4}, 11, 0, 0, nil},
+		{"insufficient after input fee", []common.Fixed64{6, 4}, 10, 1, 0, nil},
+	}
+
+	for _, test := range tests {
+		selected := branchAndBound(newTestCoins(test.amounts), test.target,
+			test.inputFee, test.changeFee)
+		assert.Equal(t, test.expected, amountsOf(selected), test.name)
+	}
+}
+
+func TestAccumulate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amounts  []common.Fixed64
+		target   common.Fixed64
+		inputFee common.Fixed64
+		expected []common.Fixed64
+	}{
+		{"in order", []common.Fixed64{3, 8, 5}, 10, 0,
+			[]common.Fixed64{3, 8}},
+		{"first coin", []common.Fixed64{3, 8, 5}, 3, 0,
+			[]common.Fixed64{3}},
+		{"with input fee", []common.Fixed64{3, 8, 5}, 10, 1,
+			[]common.Fixed64{3, 8, 5}},
+		{"insufficient after input fee", []common.Fixed64{3, 8}, 10, 1,
+			[]common.Fixed64{3, 8}},
+		{"insufficient", []common.Fixed64{3, 8, 5}, 20, 0,
+			[]common.Fixed64{3, 8, 5}},
+		{"no coins", nil, 1, 0, nil},
+	}
+
+	for _, test := range tests {
+		selected := accumulate(newTestCoins(test.amounts), test.target,
+			test.inputFee)
+		assert.Equal(t, test.expected, amountsOf(selected), test.name)
+	}
+}
+
+func TestFeeOfSize(t *testing.T) {
+	tests := []struct {
+		size     int
+		feePerKB common.Fixed64
+		expected common.Fixed64
+	}{
+		{0, 1000, 0},
+		{250, 1000, 250},
+		{1000, 1, 1},
+		{1, 1, 1},
+		{999, 1, 1},
+		{1001, 1, 2},
+		{1500, 3, 5},
+		{2000, 10000, 20000},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, feeOfSize(test.size, test.feePerKB),
+			"size %d, fee per KB %d", test.size, test.feePerKB)
+	}
+}
+
+func TestIsSpendable(t *testing.T) {
+	lockTime := config.Parameters.ChainParam.CoinbaseLockTime
+	coinbase := byte(types.CoinBase)
+	transfer := byte(types.TransferAsset)
+
+	tests := []struct {
+		name     string
+		utxo     servers.UTXOInfo
+		expected bool
+	}{
+		{"transfer", servers.UTXOInfo{TxType: transfer, Confirmations: 1}, true},
+		{"coinbase locked", servers.UTXOInfo{TxType: coinbase,
+			Confirmations: 1}, false},
+		// the best height exceeds the coinbase height by lockTime-1
+		{"coinbase before lock time", servers.UTXOInfo{TxType: coinbase,
+			Confirmations: lockTime}, false},
+		// the best height exceeds the coinbase height by lockTime
+		{"coinbase at lock time", servers.UTXOInfo{TxType: coinbase,
+			Confirmations: lockTime + 1}, true},
+		{"output not locked", servers.UTXOInfo{TxType: transfer,
+			Confirmations: 1, OutputLock: 0}, true},
+		{"output locked", servers.UTXOInfo{TxType: transfer,
+			Confirmations: 1, OutputLock: 101}, false},
+		{"output locked until the next block", servers.UTXOInfo{TxType: transfer,
+			Confirmations: 1, OutputLock: 100}, false},
+		{"output unlocked", servers.UTXOInfo{TxType: transfer,
+			Confirmations: 1, OutputLock: 99}, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, IsSpendable(test.utxo, 100), test.name)
+	}
+}
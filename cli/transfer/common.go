@@ -2,7 +2,6 @@ package transfer
 
 import (
 	"bytes"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -12,14 +11,13 @@ import (
 	"github.com/elastos/Elastos.ELA/core/types"
 
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/elastos/Elastos.ELA.Utility/http/jsonrpc"
 	"github.com/elastos/Elastos.ELA.Utility/http/util"
 	"github.com/elastos/Elastos.ELA/crypto"
 	"github.com/urfave/cli"
 )
 
 func signTransaction(context *cli.Context, client *account.ClientImpl) error {
-	content, err := getTransactionContent(context)
+	content, err := GetTransactionContent(context)
 	if err != nil {
 		return err
 	}
@@ -49,18 +47,23 @@ func signTransaction(context *cli.Context, client *account.ClientImpl) error {
 	haveSign, needSign, _ = crypto.GetSignStatus(program.Code, program.Parameter)
 	fmt.Println("[", haveSign, "/", needSign, "] Transaction successfully signed")
 
-	output(haveSign, needSign, txnSigned)
+	Output(haveSign, needSign, txnSigned)
 
 	return nil
 }
 
 func SendTransaction(context *cli.Context) error {
-	content, err := getTransactionContent(context)
+	content, err := GetTransactionContent(context)
 	if err != nil {
 		return err
 	}
 
-	result, err := jsonrpc.CallParams(clicom.LocalServer(), "sendrawtransaction", util.Params{
+	return SendRawTransaction(content)
+}
+
+// SendRawTransaction sends the transaction in hex string format to the node.
+func SendRawTransaction(content string) error {
+	result, err := clicom.CallRPC("sendrawtransaction", util.Params{
 		"data": content,
 	})
 	if err != nil {
@@ -70,18 +73,21 @@ func SendTransaction(context *cli.Context) error {
 	return nil
 }
 
-func output(haveSign, needSign int, txn *types.Transaction) error {
-	// Serialise transaction content
+// OutputOrSend sends the signed transaction to the node if send is true, or
+// writes it to file.
+func OutputOrSend(txn *types.Transaction, send bool) error {
+	if !send {
+		return Output(1, 1, txn)
+	}
 	buf := new(bytes.Buffer)
-	err := txn.Serialize(buf)
-	if err != nil {
-		fmt.Println("serialize error", err)
+	if err := txn.Serialize(buf); err != nil {
+		return err
 	}
-	content := common.BytesToHexString(buf.Bytes())
-
-	// Print transaction hex string content to console
-	fmt.Println(content)
+	return SendRawTransaction(common.BytesToHexString(buf.Bytes()))
+}
 
+// Output prints the transaction and writes it to a file named by its sign status.
+func Output(haveSign, needSign int, txn *types.Transaction) error {
 	// Output to file
 	fileName := "to_be_signed" // Create transaction file name
 
@@ -94,6 +100,22 @@ func output(haveSign, needSign int, txn *types.Transaction) error {
 	}
 	fileName = fileName + ".txn"
 
+	return writeTransaction(fileName, txn)
+}
+
+// writeTransaction prints the transaction and writes it to the file.
+func writeTransaction(fileName string, txn *types.Transaction) error {
+	// Serialise transaction content
+	buf := new(bytes.Buffer)
+	err := txn.Serialize(buf)
+	if err != nil {
+		fmt.Println("serialize error", err)
+	}
+	content := common.BytesToHexString(buf.Bytes())
+
+	// Print transaction hex string content to console
+	fmt.Println(content)
+
 	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
@@ -104,10 +126,6 @@ func output(haveSign, needSign int, txn *types.Transaction) error {
 		return err
 	}
 
-	var tx types.Transaction
-	txBytes, _ := hex.DecodeString(content)
-	tx.Deserialize(bytes.NewReader(txBytes))
-
 	// Print output file to console
 	fmt.Println("File: ", fileName)
 
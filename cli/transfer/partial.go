@@ -0,0 +1,240 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/account"
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/core/types"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+// partiallySignedFile is the file the partially signed transaction is written
+// to after created, signed or combined.
+const partiallySignedFile = "partially_signed.pst"
+
+func partiallySignedAction(c *cli.Context, action string) error {
+	switch action {
+	case "create":
+		txn, references, err := buildTransaction(c)
+		if err != nil {
+			return err
+		}
+		pst, err := types.NewPartiallySignedTransaction(txn, references)
+		if err != nil {
+			return err
+		}
+		return outputPartiallySigned(pst)
+
+	case "sign":
+		pst, err := getPartiallySigned(c)
+		if err != nil {
+			return err
+		}
+		if err := signPartiallySigned(c.String("name"), pst); err != nil {
+			return err
+		}
+		return outputPartiallySigned(pst)
+
+	case "inspect":
+		pst, err := getPartiallySigned(c)
+		if err != nil {
+			return err
+		}
+		return inspectPartiallySigned(pst)
+
+	case "combine":
+		paths := strings.Split(c.String("file"), ",")
+		if len(paths) < 2 {
+			return errors.New("use --file to specify the files to combine separated by comma")
+		}
+		var pst *types.PartiallySignedTransaction
+		for _, path := range paths {
+			other, err := readPartiallySigned(strings.TrimSpace(path))
+			if err != nil {
+				return err
+			}
+			if pst == nil {
+				pst = other
+				continue
+			}
+			if err := pst.Combine(other); err != nil {
+				return err
+			}
+		}
+		return outputPartiallySigned(pst)
+
+	case "finalize":
+		pst, err := getPartiallySigned(c)
+		if err != nil {
+			return err
+		}
+		txn, err := pst.Finalize()
+		if err != nil {
+			return err
+		}
+		return Output(1, 1, txn)
+	}
+
+	return errors.New("unknown partially signed transaction action " + action)
+}
+
+func signPartiallySigned(name string, pst *types.PartiallySignedTransaction) error {
+	// the references are given by the creator, the fee signed is trusted
+	// only if they match the outputs spent on the node
+	if err := pst.VerifyReferences(getTransaction); err != nil {
+		return errors.New("verify references failed, " + err.Error())
+	}
+
+	pwd, err := password.GetPassword()
+	if err != nil {
+		return err
+	}
+	client, err := account.Open(name, pwd)
+	if err != nil {
+		return err
+	}
+
+	signed := 0
+	for _, acc := range client.GetAccounts() {
+		n, err := pst.Sign(acc.PrivateKey)
+		if err != nil {
+			return err
+		}
+		signed += n
+	}
+	if signed == 0 {
+		return errors.New("no available account in wallet to sign")
+	}
+	fmt.Println("Added", signed, "signatures")
+	return nil
+}
+
+func inspectPartiallySigned(pst *types.PartiallySignedTransaction) error {
+	txn := pst.Transaction
+	fmt.Println("TXID:", common.BytesToHexString(common.BytesReverse(txn.Hash().Bytes())))
+
+	fmt.Println("INPUTS:")
+	for i, input := range txn.Inputs {
+		reference := pst.References[i]
+		address, err := reference.ProgramHash.ToAddress()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%5d %s:%d %34s %s\n", i,
+			common.BytesToHexString(common.BytesReverse(input.Previous.TxID.Bytes())),
+			input.Previous.Index, address, reference.Value.String())
+	}
+
+	fmt.Println("OUTPUTS:")
+	for i, output := range txn.Outputs {
+		address, err := output.ProgramHash.ToAddress()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%5d %34s %s\n", i, address, output.Value.String())
+	}
+	if err := pst.VerifyReferences(getTransaction); err != nil {
+		fmt.Println("FEE: unknown, verify references failed,", err)
+	} else {
+		fmt.Println("FEE:", pst.Fee().String())
+	}
+
+	fmt.Println("SIGNATURES:")
+	for i, program := range txn.Programs {
+		prefix := contract.PrefixStandard
+		if contract.IsMultiSig(program.Code) {
+			prefix = contract.PrefixMultiSig
+		}
+		address, err := common.ToProgramHash(byte(prefix), program.Code).ToAddress()
+		if err != nil {
+			return err
+		}
+		have, need := pst.SignStatus(i)
+		fmt.Printf("%5d %34s [ %d / %d ]\n", i, address, have, need)
+		for _, s := range pst.Signatures[i] {
+			fmt.Printf("%5s %s\n", "", common.BytesToHexString(s.PublicKey))
+		}
+	}
+	fmt.Println("COMPLETE:", pst.IsComplete())
+
+	return nil
+}
+
+// getTransaction gets the transaction of txID from the node.
+func getTransaction(txID common.Uint256) (*types.Transaction, error) {
+	var content string
+	if err := clicom.CallRPCResult("getrawtransaction", httputil.Params{
+		"txid": common.BytesToHexString(common.BytesReverse(txID.Bytes())),
+	}, &content); err != nil {
+		return nil, err
+	}
+	rawData, err := common.HexStringToBytes(content)
+	if err != nil {
+		return nil, errors.New("decode transaction failed")
+	}
+	var txn types.Transaction
+	if err := txn.Deserialize(bytes.NewReader(rawData)); err != nil {
+		return nil, errors.New("deserialize transaction failed, " + err.Error())
+	}
+	return &txn, nil
+}
+
+func getPartiallySigned(c *cli.Context) (*types.PartiallySignedTransaction, error) {
+	content, err := GetTransactionContent(c)
+	if err != nil {
+		return nil, err
+	}
+	return parsePartiallySigned(content)
+}
+
+func readPartiallySigned(path string) (*types.PartiallySignedTransaction, error) {
+	rawData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("read partially signed transaction file failed")
+	}
+	return parsePartiallySigned(strings.TrimSpace(string(rawData)))
+}
+
+func parsePartiallySigned(content string) (*types.PartiallySignedTransaction, error) {
+	rawData, err := common.HexStringToBytes(content)
+	if err != nil {
+		return nil, errors.New("decode partially signed transaction failed")
+	}
+	var pst types.PartiallySignedTransaction
+	if err := pst.Deserialize(bytes.NewReader(rawData)); err != nil {
+		return nil, errors.New("deserialize partially signed transaction failed, " + err.Error())
+	}
+	return &pst, nil
+}
+
+func outputPartiallySigned(pst *types.PartiallySignedTransaction) error {
+	buf := new(bytes.Buffer)
+	if err := pst.Serialize(buf); err != nil {
+		return err
+	}
+	content := common.BytesToHexString(buf.Bytes())
+	fmt.Println(content)
+
+	file, err := os.OpenFile(partiallySignedFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write([]byte(content)); err != nil {
+		return err
+	}
+
+	fmt.Println("File: ", partiallySignedFile)
+	return nil
+}
@@ -28,6 +28,11 @@ func transferAction(context *cli.Context) error {
 				fmt.Println("error:", err)
 				os.Exit(1)
 			}
+		case "consolidate":
+			if err := consolidateTransactions(context); err != nil {
+				fmt.Println("error:", err)
+				os.Exit(1)
+			}
 		case "sign":
 			password, err := password.GetPassword()
 			if err != nil {
@@ -51,6 +56,14 @@ func transferAction(context *cli.Context) error {
 		}
 	}
 
+	// partially signed transaction actions
+	if param := context.String("pst"); param != "" {
+		if err := partiallySignedAction(context, param); err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+	}
+
 	return nil
 }
 
@@ -63,20 +76,35 @@ func NewCommand() *cli.Command {
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name: "transaction, t",
-				Usage: "use [create, sign, send], to create, sign or send a transaction\n" +
+				Usage: "use [create, consolidate, sign, send], to create, sign or send a transaction\n" +
 					"\tcreate:\n" +
 					"\t\tuse --to --amount --fee [--lock], or --file --fee [--lock]\n" +
 					"\t\tto create a standard transaction, or multi output transaction\n" +
+					"\t\tuse --feeperkb instead of --fee to calculate the fee by size, and --strategy to select UTXOs\n" +
+					"\tconsolidate:\n" +
+					"\t\tuse --fee or --feeperkb [--from] [--threshold] [--batch] to sweep the small UTXOs\n" +
+					"\t\tinto one output in batches, a transaction file is created for each batch\n" +
 					"\tsign, send:\n" +
 					"\t\tuse --file or --hex to specify the transaction file path or content\n",
 			},
+			cli.StringFlag{
+				Name: "pst",
+				Usage: "use [create, sign, inspect, combine, finalize] to handle a partially signed transaction\n" +
+					"\tcreate:\n" +
+					"\t\tuse --to --amount --fee [--from] [--lock] to create a partially signed transaction\n" +
+					"\tsign, inspect, finalize:\n" +
+					"\t\tuse --file or --hex to specify the partially signed transaction file path or content,\n" +
+					"\t\tthe finalized transaction can be sent by --transaction send\n" +
+					"\tcombine:\n" +
+					"\t\tuse --file to specify the partially signed transaction files separated by comma\n",
+			},
 			cli.StringFlag{
 				Name:  "from",
-				Usage: "the spend address of the transaction",
+				Usage: "the spend address of the transaction, which can be a watch-only address",
 			},
 			cli.StringFlag{
 				Name:  "to",
-				Usage: "the receive address of the transaction",
+				Usage: "the receive address of the transaction, or its label in the address book",
 			},
 			cli.StringFlag{
 				Name:  "amount",
@@ -86,6 +114,24 @@ func NewCommand() *cli.Command {
 				Name:  "fee",
 				Usage: "the transfer fee of the transaction",
 			},
+			cli.StringFlag{
+				Name:  "feeperkb",
+				Usage: "the fee for each 1000 bytes of the signed transaction, the fee is calculated by size if specified",
+			},
+			cli.StringFlag{
+				Name:  "strategy",
+				Usage: "the coin selection strategy in [largest, bnb, oldest, privacy]",
+				Value: LargestFirst,
+			},
+			cli.StringFlag{
+				Name:  "threshold",
+				Usage: "only the UTXOs less than the threshold amount are consolidated",
+			},
+			cli.IntFlag{
+				Name:  "batch",
+				Usage: "the count of UTXOs consolidated by each transaction",
+				Value: DefaultConsolidateBatch,
+			},
 			cli.StringFlag{
 				Name:  "lock",
 				Usage: "the lock time to specify when the received asset can be spent",
@@ -2,12 +2,13 @@ package transfer
 
 import (
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -15,13 +16,13 @@ import (
 	clicom "github.com/elastos/Elastos.ELA/cli/common"
 	"github.com/elastos/Elastos.ELA/cli/password"
 	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
 	pg "github.com/elastos/Elastos.ELA/core/contract/program"
 	"github.com/elastos/Elastos.ELA/core/types"
 	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
 	"github.com/elastos/Elastos.ELA/core/types/payload"
 	"github.com/elastos/Elastos.ELA/servers"
 
-	"github.com/elastos/Elastos.ELA.Utility/http/jsonrpc"
 	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
 	"github.com/urfave/cli"
 )
@@ -29,17 +30,102 @@ import (
 type Transfer struct {
 	Address string
 	Amount  *common.Fixed64
+
+	// OutputType and OutputPayload are the type and payload of the output,
+	// a default output is created if OutputPayload is nil.
+	OutputType    types.OutputType
+	OutputPayload types.OutputPayload
 }
 
 func createTransaction(c *cli.Context) error {
-	feeStr := c.String("fee")
-	if feeStr == "" {
-		return errors.New("use --fee to specify transfer fee")
+	txn, _, err := buildTransaction(c)
+	if err != nil {
+		return err
 	}
 
-	fee, err := common.StringToFixed64(feeStr)
+	Output(0, 0, txn)
+
+	return nil
+}
+
+// consolidateTransactions creates the transactions sweeping the UTXOs of the
+// spender less than --threshold into one output in batches, the smallest
+// UTXOs first. The vote UTXOs are not swept to keep the votes. Each
+// transaction is written to a file to be signed.
+func consolidateTransactions(c *cli.Context) error {
+	selection, fee, err := getSelectionAndFee(c)
+	if err != nil {
+		return err
+	}
+	batch := c.Int("batch")
+	if batch < 2 {
+		return errors.New("the batch size of consolidation can not be less than 2")
+	}
+	var threshold *common.Fixed64
+	if thresholdStr := c.String("threshold"); thresholdStr != "" {
+		if threshold, err = common.StringToFixed64(thresholdStr); err != nil {
+			return errors.New("invalid consolidation threshold")
+		}
+	}
+
+	fromAddress, redeemScript, err := getSpender(c.String("from"))
+	if err != nil {
+		return err
+	}
+	utxos, err := ListUnspent(fromAddress, "normal")
+	if err != nil {
+		return err
+	}
+	if utxos, err = SpendableUTXOs(utxos); err != nil {
+		return err
+	}
+	coins, err := newCoins(utxos)
+	if err != nil {
+		return err
+	}
+	var small []servers.UTXOInfo
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].amount < coins[j].amount
+	})
+	for _, c := range coins {
+		if threshold == nil || c.amount < *threshold {
+			small = append(small, c.utxo)
+		}
+	}
+
+	count, swept := 0, 0
+	for start := 0; len(small)-start >= 2; start += batch {
+		end := start + batch
+		if end > len(small) {
+			end = len(small)
+		}
+		txn, _, err := CreateTransactionWithSelection(fromAddress, redeemScript, fee, 0,
+			types.TransferAsset, &payload.PayloadTransferAsset{}, selection, small[start:end], nil)
+		if err != nil {
+			return err
+		}
+		if len(txn.Outputs) == 0 {
+			return errors.New("the UTXOs to consolidate are not enough to pay the fee")
+		}
+		count++
+		swept += end - start
+		if err := writeTransaction(fmt.Sprint("to_be_signed_consolidate_", count, ".txn"), txn); err != nil {
+			return err
+		}
+	}
+	if count == 0 {
+		return errors.New("no UTXOs of " + fromAddress + " need to be consolidated")
+	}
+	fmt.Println("Consolidated", swept, "UTXOs into", count, "transactions")
+	return nil
+}
+
+// buildTransaction creates the unsigned transaction by the command flags, and
+// returns the outputs referenced by the inputs.
+func buildTransaction(c *cli.Context) (*types.Transaction, []*types.Output, error) {
+	selection, fee, err := getSelectionAndFee(c)
 	if err != nil {
-		return errors.New("invalid transaction fee")
+		return nil, nil, err
 	}
 
 	from := c.String("from")
@@ -51,17 +137,24 @@ func createTransaction(c *cli.Context) error {
 
 	amountStr := c.String("amount")
 	if amountStr == "" {
-		return errors.New("use --amount to specify transfer amount")
+		return nil, nil, errors.New("use --amount to specify transfer amount")
 	}
 
 	amount, err := common.StringToFixed64(amountStr)
 	if err != nil {
-		return errors.New("invalid transaction amount")
+		return nil, nil, errors.New("invalid transaction amount")
+	}
+
+	var lock uint32
+	if lockStr := c.String("lock"); lockStr != "" {
+		l, err := strconv.ParseUint(lockStr, 10, 32)
+		if err != nil {
+			return nil, nil, errors.New("invalid lock height")
+		}
+		lock = uint32(l)
 	}
 
-	var txn *types.Transaction
-	var to string
-	standard := c.String("to")
+	standard := resolveAddress(c.String("name"), c.String("to"))
 	deposit := c.String("deposit")
 	if deposit != "" {
 		// TODO fix cross chain tx
@@ -70,34 +163,44 @@ func createTransaction(c *cli.Context) error {
 		//if err != nil {
 		//	return errors.New("create transaction failed: " + err.Error())
 		//}
-	} else if standard != "" {
-		to = standard
-		lockStr := c.String("lock")
-		if lockStr == "" {
-			txn, err = CreateTransaction(from, to, amount, fee)
-			if err != nil {
-				return errors.New("create transaction failed: " + err.Error())
-			}
-		} else {
-			lock, err := strconv.ParseUint(lockStr, 10, 32)
-			if err != nil {
-				return errors.New("invalid lock height")
-			}
-			txn, err = CreateLockedTransaction(from, to, amount, fee, uint32(lock))
-			if err != nil {
-				return errors.New("create transaction failed: " + err.Error())
-			}
-		}
-	} else {
-		return errors.New("use --to or --deposit to specify receiver address")
+		return nil, nil, errors.New("deposit transaction is not supported")
+	} else if standard == "" {
+		return nil, nil, errors.New("use --to or --deposit to specify receiver address")
 	}
 
-	output(0, 0, txn)
+	txn, references, err := createTransactionWithReferences(from, fee, lock, selection,
+		&Transfer{Address: standard, Amount: amount})
+	if err != nil {
+		return nil, nil, errors.New("create transaction failed: " + err.Error())
+	}
+	return txn, references, nil
+}
 
-	return nil
+// getSelectionAndFee returns the coin selection and the fee by the command
+// flags, the fee is optional if it is calculated by size.
+func getSelectionAndFee(c *cli.Context) (*CoinSelection, *common.Fixed64, error) {
+	selection, err := getCoinSelection(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feeStr := c.String("fee")
+	if feeStr == "" {
+		if selection.FeePerKB == nil {
+			return nil, nil, errors.New("use --fee or --feeperkb to specify transfer fee")
+		}
+		feeStr = "0"
+	}
+	fee, err := common.StringToFixed64(feeStr)
+	if err != nil {
+		return nil, nil, errors.New("invalid transaction fee")
+	}
+	return selection, fee, nil
 }
 
-func getTransactionContent(context *cli.Context) (string, error) {
+// GetTransactionContent returns the transaction hex string from the file by
+// --file, or by --hex.
+func GetTransactionContent(context *cli.Context) (string, error) {
 	// If parameter with file path is not empty, read content from file
 	if filePath := strings.TrimSpace(context.String("file")); filePath != "" {
 
@@ -135,7 +238,7 @@ func CreateTransaction(fromAddress, toAddress string, amount, fee *common.Fixed6
 }
 
 func CreateLockedTransaction(fromAddress, toAddress string, amount, fee *common.Fixed64, lockedUntil uint32) (*types.Transaction, error) {
-	return CreateLockedMultiOutputTransaction(fromAddress, fee, lockedUntil, &Transfer{toAddress, amount})
+	return CreateLockedMultiOutputTransaction(fromAddress, fee, lockedUntil, &Transfer{Address: toAddress, Amount: amount})
 }
 
 func CreateMultiOutputTransaction(fromAddress string, fee *common.Fixed64, outputs ...*Transfer) (*types.Transaction, error) {
@@ -147,51 +250,122 @@ func CreateLockedMultiOutputTransaction(fromAddress string, fee *common.Fixed64,
 }
 
 func createTransaction_(fromAddress string, fee *common.Fixed64, lockedUntil uint32, outputs ...*Transfer) (*types.Transaction, error) {
+	txn, _, err := createTransactionWithReferences(fromAddress, fee, lockedUntil,
+		&DefaultCoinSelection, outputs...)
+	return txn, err
+}
+
+func createTransactionWithReferences(fromAddress string, fee *common.Fixed64, lockedUntil uint32,
+	selection *CoinSelection, outputs ...*Transfer) (*types.Transaction, []*types.Output, error) {
 	// Check if output is valid
 	if len(outputs) == 0 {
-		return nil, errors.New("[Wallet], Invalid transaction target")
+		return nil, nil, errors.New("[Wallet], Invalid transaction target")
+	}
+
+	fromAddress, redeemScript, err := getSpender(fromAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	return createPayloadTransaction(fromAddress, redeemScript, fee, lockedUntil,
+		types.TransferAsset, &payload.PayloadTransferAsset{}, selection, outputs...)
+}
+
+// getSpender returns the address and redeem script of the spender, which is
+// the main account if fromAddress is empty.
+func getSpender(fromAddress string) (string, []byte, error) {
+	// Check from address, a watch-only address needs no password
+	redeemScript, err := getWatchRedeemScript(account.KeystoreFileName, fromAddress)
+	if err != nil {
+		return "", nil, err
+	}
+	if redeemScript != nil {
+		return fromAddress, redeemScript, nil
 	}
 
-	// Check from address
 	password, err := password.GetPassword()
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	client, err := account.Open(account.KeystoreFileName, password)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	acc, err := client.GetDefaultAccount()
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	if fromAddress != "" && fromAddress != acc.Address {
 		programHash, err := common.Uint168FromAddress(fromAddress)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		acc = client.GetAccountByCodeHash(programHash.ToCodeHash())
 		if acc == nil {
-			return nil, errors.New(fromAddress + " is not local account")
+			return "", nil, errors.New(fromAddress + " is not local account")
 		}
 	}
-	fromAddress = acc.Address
+	return acc.Address, acc.Contract.Code, nil
+}
+
+// CreatePayloadTransaction creates an unsigned transaction of the type and
+// payload, spending the UTXOs of fromAddress whose redeem script is given, and
+// returns the outputs referenced by the inputs. The vote UTXOs are not spent to
+// keep the votes. The outputs can be empty if only the fee is paid.
+func CreatePayloadTransaction(fromAddress string, redeemScript []byte, fee *common.Fixed64, lockedUntil uint32,
+	txType types.TransactionType, txPayload types.Payload, outputs ...*Transfer) (*types.Transaction, []*types.Output, error) {
+	return createPayloadTransaction(fromAddress, redeemScript, fee, lockedUntil, txType, txPayload,
+		&DefaultCoinSelection, outputs...)
+}
+
+func createPayloadTransaction(fromAddress string, redeemScript []byte, fee *common.Fixed64, lockedUntil uint32,
+	txType types.TransactionType, txPayload types.Payload, selection *CoinSelection,
+	outputs ...*Transfer) (*types.Transaction, []*types.Output, error) {
+	utxos, err := ListUnspent(fromAddress, "normal")
+	if err != nil {
+		return nil, nil, err
+	}
+	availableUTXOs, err := SpendableUTXOs(utxos)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	return CreateTransactionWithSelection(fromAddress, redeemScript, fee, lockedUntil,
+		txType, txPayload, selection, nil, availableUTXOs, outputs...)
+}
+
+// CreateTransactionWithUTXOs creates an unsigned transaction like
+// CreatePayloadTransaction, which spends all the required UTXOs, and then the
+// available UTXOs selected by DefaultCoinSelection until the outputs and fee
+// are paid. The amount exceeded is returned to fromAddress as change.
+func CreateTransactionWithUTXOs(fromAddress string, redeemScript []byte, fee *common.Fixed64, lockedUntil uint32,
+	txType types.TransactionType, txPayload types.Payload, required, available []servers.UTXOInfo,
+	outputs ...*Transfer) (*types.Transaction, []*types.Output, error) {
+	return CreateTransactionWithSelection(fromAddress, redeemScript, fee, lockedUntil, txType, txPayload,
+		&DefaultCoinSelection, required, available, outputs...)
+}
+
+// CreateTransactionWithSelection creates an unsigned transaction like
+// CreateTransactionWithUTXOs, the available UTXOs are selected by the coin
+// selection. If the fee is calculated by size, it is increased until the
+// estimated size of the signed transaction is paid, and a change not worth
+// its own fee is paid as fee too.
+func CreateTransactionWithSelection(fromAddress string, redeemScript []byte, fee *common.Fixed64, lockedUntil uint32,
+	txType types.TransactionType, txPayload types.Payload, selection *CoinSelection, required, available []servers.UTXOInfo,
+	outputs ...*Transfer) (*types.Transaction, []*types.Output, error) {
 	// Check if from address is valid
 	spender, err := common.Uint168FromAddress(fromAddress)
 	if err != nil {
-		return nil, errors.New(fmt.Sprint("[Wallet], Invalid spender address: ", fromAddress, ", error: ", err))
+		return nil, nil, errors.New(fmt.Sprint("[Wallet], Invalid spender address: ", fromAddress, ", error: ", err))
 	}
 	// Create transaction outputs
 	var totalOutputAmount = common.Fixed64(0) // The total amount will be spend
 	var txOutputs []*types.Output             // The outputs in transaction
-	totalOutputAmount += *fee                 // Add transaction fee
 
 	for _, output := range outputs {
 		receiver, err := common.Uint168FromAddress(output.Address)
 		if err != nil {
-			return nil, errors.New(fmt.Sprint("[Wallet], Invalid receiver address: ", output.Address, ", error: ", err))
+			return nil, nil, errors.New(fmt.Sprint("[Wallet], Invalid receiver address: ", output.Address, ", error: ", err))
 		}
 
 		txOutput := &types.Output{
@@ -202,73 +376,190 @@ func createTransaction_(fromAddress string, fee *common.Fixed64, lockedUntil uin
 			OutputType:    types.DefaultOutput,
 			OutputPayload: &outputpayload.DefaultOutput{},
 		}
+		if output.OutputPayload != nil {
+			txOutput.OutputType = output.OutputType
+			txOutput.OutputPayload = output.OutputPayload
+		}
 		totalOutputAmount += *output.Amount
 		txOutputs = append(txOutputs, txOutput)
 	}
 
-	result, err := jsonrpc.CallParams(clicom.LocalServer(), "listunspent", httputil.Params{
-		"addresses": []string{fromAddress},
-	})
+	requiredCoins, err := newCoins(required)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	data, err := json.Marshal(result)
+	availableCoins, err := newCoins(available)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var utxos []servers.UTXOInfo
-	err = json.Unmarshal(data, &utxos)
 
-	var availabelUtxos []servers.UTXOInfo
-	for _, utxo := range utxos {
-		if types.TransactionType(utxo.TxType) == types.CoinBase && utxo.Confirmations < 100 {
-			continue
+	// The fee of an input and a change, which are zero for the fixed fee
+	var inputFee, changeFee common.Fixed64
+	txFee := *fee
+	if selection.FeePerKB != nil {
+		inputFee = feeOfSize(inputSize(), *selection.FeePerKB)
+		changeFee = feeOfSize(changeSize(), *selection.FeePerKB)
+		if minFee := common.Fixed64(config.Parameters.PowConfiguration.MinTxFee); txFee < minFee {
+			txFee = minFee
 		}
-		availabelUtxos = append(availabelUtxos, utxo)
 	}
 
-	// Create transaction inputs
-	var txInputs []*types.Input // The inputs in transaction
-	for _, utxo := range availabelUtxos {
-		txIDReverse, _ := hex.DecodeString(utxo.TxID)
-		txID, _ := common.Uint256FromBytes(common.BytesReverse(txIDReverse))
-		input := &types.Input{
-			Previous: types.OutPoint{
-				TxID:  *txID,
-				Index: uint16(utxo.VOut),
-			},
-			Sequence: 4294967295,
+	for i := 0; i < maxSelectIterations; i++ {
+		target := totalOutputAmount + txFee - sumCoins(requiredCoins)
+		coins := append(requiredCoins, selectCoins(selection.Strategy, availableCoins,
+			target, inputFee, changeFee)...)
+
+		totalInputAmount := sumCoins(coins)
+		if totalInputAmount < totalOutputAmount+txFee {
+			return nil, nil, errors.New("[Wallet], Available token is not enough")
 		}
-		txInputs = append(txInputs, input)
-		amount, _ := common.StringToFixed64(utxo.Amount)
-		if *amount < totalOutputAmount {
-			totalOutputAmount -= *amount
-		} else if *amount == totalOutputAmount {
-			totalOutputAmount = 0
-			break
-		} else if *amount > totalOutputAmount {
-			change := &types.Output{
+
+		txInputs, references, lockTime := createInputs(spender, coins)
+		changeOutputs := txOutputs
+		change := totalInputAmount - totalOutputAmount - txFee
+		// The change not worth its own fee is paid as fee
+		if change > changeFee {
+			changeOutputs = append(txOutputs[:len(txOutputs):len(txOutputs)], &types.Output{
 				AssetID:       *account.SystemAssetID,
-				Value:         *amount - totalOutputAmount,
+				Value:         change,
 				OutputLock:    uint32(0),
 				ProgramHash:   *spender,
 				OutputType:    types.DefaultOutput,
 				OutputPayload: &outputpayload.DefaultOutput{},
+			})
+		}
+
+		txn := newTransaction(redeemScript, txInputs, changeOutputs, txType, txPayload)
+		txn.LockTime = lockTime
+		size, err := estimateSize(txn, redeemScript)
+		if err != nil {
+			return nil, nil, err
+		}
+		if size > config.Parameters.MaxBlockSize {
+			return nil, nil, fmt.Errorf("[Wallet], Transaction size %d exceeds the max block size, "+
+				"consolidate the UTXOs first", size)
+		}
+		if selection.FeePerKB == nil {
+			return txn, references, nil
+		}
+
+		// Select again if the fee by size is not paid
+		paid := txFee
+		if change <= changeFee {
+			paid += change
+		}
+		sizeFee := feeOfSize(size, *selection.FeePerKB)
+		if paid >= sizeFee {
+			return txn, references, nil
+		}
+		txFee = sizeFee
+	}
+	return nil, nil, errors.New("[Wallet], Calculate transaction fee by size failed")
+}
+
+// createInputs creates the inputs spending the coins of the spender, and
+// returns the outputs referenced and the lock time to unlock the locked
+// outputs.
+func createInputs(spender *common.Uint168, coins []*coin) ([]*types.Input, []*types.Output, uint32) {
+	var txInputs []*types.Input    // The inputs in transaction
+	var references []*types.Output // The outputs referenced by inputs
+	var lockTime uint32
+	for _, c := range coins {
+		txIDReverse, _ := hex.DecodeString(c.utxo.TxID)
+		txID, _ := common.Uint256FromBytes(common.BytesReverse(txIDReverse))
+		input := &types.Input{
+			Previous: types.OutPoint{
+				TxID:  *txID,
+				Index: uint16(c.utxo.VOut),
+			},
+			Sequence: math.MaxUint32,
+		}
+		// A locked output is spent by the transaction whose lock time is
+		// not less than the output lock
+		if c.utxo.OutputLock > 0 {
+			input.Sequence = math.MaxUint32 - 1
+			if c.utxo.OutputLock > lockTime {
+				lockTime = c.utxo.OutputLock
 			}
-			txOutputs = append(txOutputs, change)
-			totalOutputAmount = 0
-			break
 		}
+		txInputs = append(txInputs, input)
+		references = append(references, &types.Output{
+			AssetID:       *account.SystemAssetID,
+			Value:         c.amount,
+			OutputLock:    c.utxo.OutputLock,
+			ProgramHash:   *spender,
+			OutputType:    types.DefaultOutput,
+			OutputPayload: &outputpayload.DefaultOutput{},
+		})
+	}
+	return txInputs, references, lockTime
+}
+
+// getBlockCount returns the count of blocks, which is the height of the next
+// block.
+func getBlockCount() (uint32, error) {
+	var count uint32
+	err := clicom.CallRPCResult("getblockcount", httputil.Params{}, &count)
+	return count, err
+}
+
+// ListUnspent returns the UTXOs of the address by the UTXO type, which is one
+// of mixed, vote and normal.
+func ListUnspent(address, utxoType string) ([]servers.UTXOInfo, error) {
+	var utxos []servers.UTXOInfo
+	err := clicom.CallRPCResult("listunspent", httputil.Params{
+		"addresses": []string{address},
+		"utxotype":  utxoType,
+	}, &utxos)
+	return utxos, err
+}
+
+// getWatchRedeemScript returns the redeem script of the watch-only address in
+// the wallet, or nil if the address is not watched.
+func getWatchRedeemScript(name, address string) ([]byte, error) {
+	if address == "" {
+		return nil, nil
 	}
-	if totalOutputAmount > 0 {
-		return nil, errors.New("[Wallet], Available token is not enough")
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	watches, err := fileStore.LoadWatchData()
+	if err != nil {
+		return nil, err
 	}
+	for _, w := range watches {
+		if w.Address != address {
+			continue
+		}
+		if w.RedeemScript == "" {
+			return nil, errors.New(address + " is watched by address only, watch its public key to create transactions")
+		}
+		return common.HexStringToBytes(w.RedeemScript)
+	}
+	return nil, nil
+}
 
-	return newTransaction(acc.Contract.Code, txInputs, txOutputs, types.TransferAsset), nil
+// resolveAddress returns the address of the label in the address book, or
+// the given string if it is not a label.
+func resolveAddress(name, addressOrLabel string) string {
+	if _, err := common.Uint168FromAddress(addressOrLabel); err == nil {
+		return addressOrLabel
+	}
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	contacts, err := fileStore.LoadAddressBookData()
+	if err != nil {
+		return addressOrLabel
+	}
+	for _, c := range contacts {
+		if c.Label == addressOrLabel {
+			return c.Address
+		}
+	}
+	return addressOrLabel
 }
 
-func newTransaction(redeemScript []byte, inputs []*types.Input, outputs []*types.Output, txType types.TransactionType) *types.Transaction {
-	txPayload := &payload.PayloadTransferAsset{}
+func newTransaction(redeemScript []byte, inputs []*types.Input, outputs []*types.Output,
+	txType types.TransactionType, txPayload types.Payload) *types.Transaction {
 	txAttr := types.NewAttribute(types.Nonce, []byte(strconv.FormatInt(rand.Int63(), 10)))
 	attributes := make([]*types.Attribute, 0)
 	attributes = append(attributes, &txAttr)
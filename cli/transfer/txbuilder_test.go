@@ -0,0 +1,95 @@
+package transfer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransaction_WatchOnlySpender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transfer")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	scryptN := account.ScryptN
+	account.ScryptN = 1 << 10
+	defer func() { account.ScryptN = scryptN }()
+
+	path := filepath.Join(dir, "keystore.dat")
+	client, err := account.Create(path, []byte("123"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	main, err := client.GetDefaultAccount()
+	assert.NoError(t, err)
+
+	_, publicKey1, _ := crypto.GenerateKeyPair()
+	_, publicKey2, _ := crypto.GenerateKeyPair()
+	multi, err := account.NewMultiSignWatchData(2,
+		[]*crypto.PublicKey{publicKey1, publicKey2}, "shared")
+	assert.NoError(t, err)
+	single, err := account.NewWatchDataByPublicKey(publicKey1, "")
+	assert.NoError(t, err)
+	addressOnly, err := account.NewWatchData(single.Address, "")
+	assert.NoError(t, err)
+	assert.NoError(t, client.SaveWatchData(multi))
+	assert.NoError(t, client.SaveWatchData(addressOnly))
+
+	// the redeem script of a watch-only address is used without the keystore
+	script, err := getWatchRedeemScript(path, multi.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, multi.RedeemScript, common.BytesToHexString(script))
+
+	// an address watched without the public key can not be spent from
+	_, err = getWatchRedeemScript(path, addressOnly.Address)
+	assert.Error(t, err)
+
+	// the accounts of the keystore are not watch-only
+	script2, err := getWatchRedeemScript(path, main.Address)
+	assert.NoError(t, err)
+	assert.Nil(t, script2)
+	script2, err = getWatchRedeemScript(path, "")
+	assert.NoError(t, err)
+	assert.Nil(t, script2)
+
+	// the unsigned transaction spends from and returns the change to the
+	// watch-only address
+	fee := common.Fixed64(100)
+	amount := common.Fixed64(1e8)
+	available := []servers.UTXOInfo{{
+		TxID:          "a3d0eaa466df74983b5d7c543de6904f4c9418ead5ffd6d25814234a96db37b0",
+		VOut:          1,
+		Address:       multi.Address,
+		Amount:        "10",
+		Confirmations: 10,
+	}}
+	txn, references, err := CreateTransactionWithUTXOs(multi.Address, script,
+		&fee, 0, types.TransferAsset, &payload.PayloadTransferAsset{}, nil,
+		available, &Transfer{Address: main.Address, Amount: &amount})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, script, txn.Programs[0].Code)
+	assert.Nil(t, txn.Programs[0].Parameter)
+	assert.Equal(t, 1, len(txn.Inputs))
+	assert.Equal(t, uint16(1), txn.Inputs[0].Previous.Index)
+	assert.Equal(t, 1, len(references))
+	spender, err := common.Uint168FromAddress(multi.Address)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(txn.Outputs)) {
+		assert.Equal(t, amount, txn.Outputs[0].Value)
+		assert.Equal(t, *spender, txn.Outputs[1].ProgramHash)
+		assert.Equal(t, 10*amount-amount-fee, txn.Outputs[1].Value)
+	}
+}
@@ -0,0 +1,389 @@
+package vote
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/account"
+	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/cli/producer"
+	"github.com/elastos/Elastos.ELA/cli/transfer"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	httputil "github.com/elastos/Elastos.ELA.Utility/http/util"
+	"github.com/urfave/cli"
+)
+
+func voteAction(context *cli.Context) error {
+	if context.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	var action string
+	var err error
+	switch {
+	case context.Bool("list"):
+		action, err = "list", listProducers()
+	case context.Bool("status"):
+		action, err = "status", showVoteStatus(context)
+	case context.Bool("vote"):
+		action, err = "vote", voteTransaction(context, createVoteTransaction)
+	case context.Bool("cancel"):
+		action, err = "cancel", voteTransaction(context, createCancelTransaction)
+	default:
+		cli.ShowSubcommandHelp(context)
+		return nil
+	}
+
+	if err != nil {
+		fmt.Println("error:", err)
+		cli.ShowCommandHelpAndExit(context, action, 1)
+	}
+	return nil
+}
+
+func listProducers() error {
+	producers, err := producer.ListProducers()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%5s %-20s %-66s %-20s %-6s\n", "INDEX", "NICKNAME", "OWNER PUBLIC KEY", "VOTES", "ACTIVE")
+	fmt.Println(strings.Repeat("-", 5), strings.Repeat("-", 20), strings.Repeat("-", 66),
+		strings.Repeat("-", 20), strings.Repeat("-", 6))
+	for _, p := range producers {
+		fmt.Printf("%5d %-20s %-66s %-20s %-6t\n", p.Index, p.Nickname, p.OwnerPublicKey, p.Votes, p.Active)
+	}
+	fmt.Println(strings.Repeat("-", 5), strings.Repeat("-", 20), strings.Repeat("-", 66),
+		strings.Repeat("-", 20), strings.Repeat("-", 6))
+	return nil
+}
+
+// showVoteStatus shows the voting amount of the wallet address and the
+// candidates voted by each vote output.
+func showVoteStatus(context *cli.Context) error {
+	address, err := getVoterAddress(context)
+	if err != nil {
+		return err
+	}
+
+	var status struct {
+		Total   string `json:"total"`
+		Voting  string `json:"voting"`
+		Pending bool   `json:"pending"`
+	}
+	if err := clicom.CallRPCResult("votestatus", httputil.Params{
+		"address": address,
+	}, &status); err != nil {
+		return err
+	}
+	fmt.Println("Address:", address)
+	fmt.Println("Total:  ", status.Total)
+	fmt.Println("Voting: ", status.Voting)
+	fmt.Println("Pending:", status.Pending)
+
+	utxos, err := transfer.ListUnspent(address, "vote")
+	if err != nil {
+		return err
+	}
+	for _, utxo := range utxos {
+		var txn struct {
+			Outputs []struct {
+				Payload servers.VoteOutputInfo `json:"payload"`
+			} `json:"vout"`
+		}
+		if err := clicom.CallRPCResult("getrawtransaction", httputil.Params{
+			"txid":    utxo.TxID,
+			"verbose": true,
+		}, &txn); err != nil {
+			return err
+		}
+		if int(utxo.VOut) >= len(txn.Outputs) {
+			return errors.New("invalid vote output " + utxo.TxID)
+		}
+
+		fmt.Printf("VOTE %s:%d %s\n", utxo.TxID, utxo.VOut, utxo.Amount)
+		for _, content := range txn.Outputs[utxo.VOut].Payload.Contents {
+			for _, candidate := range content.CandidatesInfo {
+				fmt.Printf("%5s %s\n", "", candidate)
+			}
+		}
+	}
+	return nil
+}
+
+// voteTransaction builds the transaction by the voter account, signs it and
+// sends it to the node if required.
+func voteTransaction(context *cli.Context,
+	build func(*cli.Context, *account.Account, *common.Fixed64) (*types.Transaction, error)) error {
+	feeStr := context.String("fee")
+	if feeStr == "" {
+		return errors.New("use --fee to specify transaction fee")
+	}
+	fee, err := common.StringToFixed64(feeStr)
+	if err != nil {
+		return errors.New("invalid transaction fee")
+	}
+
+	pwd, err := password.GetPassword()
+	if err != nil {
+		return err
+	}
+	client, err := account.Open(context.String("name"), pwd)
+	if err != nil {
+		return err
+	}
+	voter, err := getVoterAccount(context, client)
+	if err != nil {
+		return err
+	}
+
+	txn, err := build(context, voter, fee)
+	if err != nil {
+		return err
+	}
+	txn, err = client.Sign(txn)
+	if err != nil {
+		return err
+	}
+
+	return transfer.OutputOrSend(txn, context.Bool("send"))
+}
+
+// createVoteTransaction votes the candidates by the voter, the previous vote
+// outputs are spent so the new vote replaces them. All the balance except the
+// fee is voted if the amount is not specified.
+func createVoteTransaction(context *cli.Context, voter *account.Account, fee *common.Fixed64) (*types.Transaction, error) {
+	candidates, err := getCandidates(context.String("candidates"))
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := transfer.ListUnspent(voter.Address, "vote")
+	if err != nil {
+		return nil, err
+	}
+	normal, err := getNormalUTXOs(voter.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	required, available := votes, normal
+	var amount *common.Fixed64
+	if amountStr := context.String("amount"); amountStr != "" {
+		if amount, err = common.StringToFixed64(amountStr); err != nil {
+			return nil, errors.New("invalid vote amount")
+		}
+	} else {
+		required, available = append(votes, normal...), nil
+		balance, err := getAmount(required)
+		if err != nil {
+			return nil, err
+		}
+		if balance <= *fee {
+			return nil, errors.New("balance " + balance.String() + " is not enough to pay the fee")
+		}
+		balance -= *fee
+		amount = &balance
+	}
+
+	txn, _, err := transfer.CreateTransactionWithUTXOs(voter.Address, voter.Contract.Code,
+		fee, 0, types.TransferAsset, &payload.PayloadTransferAsset{}, required, available,
+		&transfer.Transfer{
+			Address:    voter.Address,
+			Amount:     amount,
+			OutputType: types.VoteOutput,
+			OutputPayload: &outputpayload.VoteOutput{
+				Version: 0,
+				Contents: []outputpayload.VoteContent{{
+					VoteType:   outputpayload.Delegate,
+					Candidates: candidates,
+				}},
+			},
+		})
+	return txn, err
+}
+
+// createCancelTransaction cancels the votes by spending all the vote outputs
+// of the voter to a normal output.
+func createCancelTransaction(context *cli.Context, voter *account.Account, fee *common.Fixed64) (*types.Transaction, error) {
+	votes, err := transfer.ListUnspent(voter.Address, "vote")
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) == 0 {
+		return nil, errors.New("no vote of " + voter.Address + " to cancel")
+	}
+	normal, err := getNormalUTXOs(voter.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, _, err := transfer.CreateTransactionWithUTXOs(voter.Address, voter.Contract.Code,
+		fee, 0, types.TransferAsset, &payload.PayloadTransferAsset{}, votes, normal)
+	return txn, err
+}
+
+// getCandidates returns the candidates from the owner public keys separated
+// by comma, which must be registered producers.
+func getCandidates(publicKeys string) ([][]byte, error) {
+	if publicKeys == "" {
+		return nil, errors.New("use --candidates to specify the owner public keys of the producers separated by comma")
+	}
+	keys := strings.Split(publicKeys, ",")
+	if len(keys) > outputpayload.MaxVoteProducersPerTransaction {
+		return nil, fmt.Errorf("can not vote more than %d producers",
+			outputpayload.MaxVoteProducersPerTransaction)
+	}
+
+	producers, err := producer.ListProducers()
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]struct{})
+	for _, p := range producers {
+		registered[p.OwnerPublicKey] = struct{}{}
+	}
+
+	var candidates [][]byte
+	voted := make(map[string]struct{})
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if _, ok := registered[key]; !ok {
+			return nil, errors.New("producer " + key + " is not registered")
+		}
+		if _, ok := voted[key]; ok {
+			return nil, errors.New("duplicate candidate " + key)
+		}
+		voted[key] = struct{}{}
+		candidate, err := common.HexStringToBytes(key)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// getNormalUTXOs returns the UTXOs of the address can be spent except votes.
+func getNormalUTXOs(address string) ([]servers.UTXOInfo, error) {
+	utxos, err := transfer.ListUnspent(address, "normal")
+	if err != nil {
+		return nil, err
+	}
+	return transfer.SpendableUTXOs(utxos)
+}
+
+func getAmount(utxos []servers.UTXOInfo) (common.Fixed64, error) {
+	var total common.Fixed64
+	for _, utxo := range utxos {
+		amount, err := common.StringToFixed64(utxo.Amount)
+		if err != nil {
+			return 0, err
+		}
+		total += *amount
+	}
+	return total, nil
+}
+
+// getVoterAddress returns the address to show the vote status, which is the
+// main account of the wallet if not specified.
+func getVoterAddress(context *cli.Context) (string, error) {
+	if from := context.String("from"); from != "" {
+		return from, nil
+	}
+	var fileStore account.FileStore
+	fileStore.SetPath(context.String("name"))
+	accounts, err := fileStore.LoadAccountData()
+	if err != nil {
+		return "", err
+	}
+	for _, a := range accounts {
+		if a.Type == account.MAINACCOUNT {
+			return a.Address, nil
+		}
+	}
+	return "", errors.New("no main account in wallet")
+}
+
+func getVoterAccount(context *cli.Context, client *account.ClientImpl) (*account.Account, error) {
+	from := context.String("from")
+	if from == "" {
+		return client.GetDefaultAccount()
+	}
+	programHash, err := common.Uint168FromAddress(from)
+	if err != nil {
+		return nil, errors.New("invalid address " + from)
+	}
+	acc := client.GetAccountByCodeHash(programHash.ToCodeHash())
+	if acc == nil {
+		return nil, errors.New(from + " is not local account")
+	}
+	return acc, nil
+}
+
+func NewCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "vote",
+		Usage:       "vote producers by the wallet account",
+		Description: "With ela-cli vote, you could vote producers, change or cancel the votes and show the voting state.",
+		ArgsUsage:   "[args]",
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "list",
+				Usage: "list the registered producers",
+			},
+			cli.BoolFlag{
+				Name:  "status",
+				Usage: "show the voting state of the wallet address, use [--from]",
+			},
+			cli.BoolFlag{
+				Name: "vote",
+				Usage: "vote the candidates, the previous votes are replaced\n" +
+					"\tuse --candidates --fee [--amount] [--from], all the balance is voted if --amount is not specified",
+			},
+			cli.BoolFlag{
+				Name:  "cancel",
+				Usage: "cancel the votes, use --fee [--from]",
+			},
+			cli.StringFlag{
+				Name: "candidates",
+				Usage: fmt.Sprintf("the owner public keys of the producers to vote separated by comma, at most %d",
+					outputpayload.MaxVoteProducersPerTransaction),
+			},
+			cli.StringFlag{
+				Name:  "amount",
+				Usage: "the vote amount",
+			},
+			cli.StringFlag{
+				Name:  "fee",
+				Usage: "the transfer fee of the transaction",
+			},
+			cli.StringFlag{
+				Name:  "from",
+				Usage: "the voter address in the wallet, the main account by default",
+			},
+			cli.BoolFlag{
+				Name:  "send",
+				Usage: "send the signed transaction to the node instead of writing it to file",
+			},
+			cli.StringFlag{
+				Name:  "name, n",
+				Usage: "wallet name",
+				Value: account.KeystoreFileName,
+			},
+		},
+		Action: voteAction,
+		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
+			clicom.PrintError(c, err, "vote")
+			return cli.NewExitError("", 1)
+		},
+	}
+}
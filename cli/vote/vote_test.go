@@ -0,0 +1,249 @@
+package vote
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+// newTestRPCServer serves the JSON-RPC requests of the cli by the results of
+// the methods, the UTXOs listed are looked up by the UTXO type.  The server
+// is closed when the returned function is called.
+func newTestRPCServer(t *testing.T, results map[string]interface{}) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		method := request.Method
+		if utxoType, ok := request.Params["utxotype"].(string); ok {
+			method += ":" + utxoType
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": results[method],
+		})
+	}))
+	u, _ := url.Parse(server.URL)
+	port := config.Parameters.HttpJsonPort
+	config.Parameters.HttpJsonPort, _ = strconv.Atoi(u.Port())
+	return func() {
+		server.Close()
+		config.Parameters.HttpJsonPort = port
+	}
+}
+
+// newTestContext returns the context of the vote command with the args.
+func newTestContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("vote", flag.ContinueOnError)
+	for _, f := range NewCommand().Flags {
+		f.Apply(set)
+	}
+	assert.NoError(t, set.Parse(args))
+	return cli.NewContext(nil, set, nil)
+}
+
+func newTestUTXO(txID, address, amount string) servers.UTXOInfo {
+	return servers.UTXOInfo{
+		TxType:        byte(types.TransferAsset),
+		TxID:          txID,
+		Address:       address,
+		Amount:        amount,
+		Confirmations: 10,
+	}
+}
+
+func randomOwnerPublicKey() string {
+	_, publicKey, _ := crypto.GenerateKeyPair()
+	key, _ := publicKey.EncodePoint(true)
+	return common.BytesToHexString(key)
+}
+
+// newTestProducers returns the registered producers of the count.
+func newTestProducers(count int) []servers.Producer {
+	producers := make([]servers.Producer, 0, count)
+	for i := 0; i < count; i++ {
+		producers = append(producers, servers.Producer{
+			OwnerPublicKey: randomOwnerPublicKey(),
+		})
+	}
+	return producers
+}
+
+const (
+	voteTxID   = "a3d0eaa466df74983b5d7c543de6904f4c9418ead5ffd6d25814234a96db37b0"
+	normalTxID = "b3d0eaa466df74983b5d7c543de6904f4c9418ead5ffd6d25814234a96db37b0"
+)
+
+func TestGetCandidates(t *testing.T) {
+	producers := newTestProducers(outputpayload.MaxVoteProducersPerTransaction + 1)
+	closeServer := newTestRPCServer(t, map[string]interface{}{
+		"listproducers": servers.Producers{Producers: producers},
+	})
+	defer closeServer()
+
+	candidates, err := getCandidates(producers[0].OwnerPublicKey + ", " +
+		producers[1].OwnerPublicKey)
+	if assert.NoError(t, err) && assert.Equal(t, 2, len(candidates)) {
+		assert.Equal(t, producers[0].OwnerPublicKey, common.BytesToHexString(candidates[0]))
+		assert.Equal(t, producers[1].OwnerPublicKey, common.BytesToHexString(candidates[1]))
+	}
+
+	// the candidates are required
+	_, err = getCandidates("")
+	assert.Error(t, err)
+
+	// the candidates must be registered producers
+	_, err = getCandidates(randomOwnerPublicKey())
+	assert.Error(t, err)
+
+	// the candidates can not be duplicated
+	_, err = getCandidates(producers[0].OwnerPublicKey + "," +
+		producers[0].OwnerPublicKey)
+	assert.Error(t, err)
+
+	// at most MaxVoteProducersPerTransaction candidates can be voted
+	var keys []string
+	for _, p := range producers {
+		keys = append(keys, p.OwnerPublicKey)
+	}
+	_, err = getCandidates(strings.Join(keys[:len(keys)-1], ","))
+	assert.NoError(t, err)
+	_, err = getCandidates(strings.Join(keys, ","))
+	assert.Error(t, err)
+}
+
+func TestCreateVoteTransaction(t *testing.T) {
+	voter, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	producers := newTestProducers(2)
+	closeServer := newTestRPCServer(t, map[string]interface{}{
+		"listproducers": servers.Producers{Producers: producers},
+		"listunspent:vote": []servers.UTXOInfo{
+			newTestUTXO(voteTxID, voter.Address, "10"),
+		},
+		"listunspent:normal": []servers.UTXOInfo{
+			newTestUTXO(normalTxID, voter.Address, "20"),
+		},
+	})
+	defer closeServer()
+	fee, _ := common.StringToFixed64("0.001")
+	candidates := producers[0].OwnerPublicKey + "," + producers[1].OwnerPublicKey
+
+	// all the balance except the fee is voted by default
+	txn, err := createVoteTransaction(newTestContext(t, "--candidates",
+		candidates), voter, fee)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.TxVersion09, txn.Version)
+	assert.Equal(t, types.TransferAsset, txn.TxType)
+	assert.Equal(t, 2, len(txn.Inputs))
+	if !assert.Equal(t, 1, len(txn.Outputs)) {
+		return
+	}
+	output := txn.Outputs[0]
+	assert.Equal(t, types.VoteOutput, output.OutputType)
+	assert.Equal(t, voter.ProgramHash, output.ProgramHash)
+	amount, _ := common.StringToFixed64("29.999")
+	assert.Equal(t, *amount, output.Value)
+	payload, ok := output.OutputPayload.(*outputpayload.VoteOutput)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.NoError(t, payload.Validate())
+	if assert.Equal(t, 1, len(payload.Contents)) {
+		content := payload.Contents[0]
+		assert.Equal(t, outputpayload.Delegate, content.VoteType)
+		if assert.Equal(t, 2, len(content.Candidates)) {
+			assert.Equal(t, producers[0].OwnerPublicKey,
+				common.BytesToHexString(content.Candidates[0]))
+			assert.Equal(t, producers[1].OwnerPublicKey,
+				common.BytesToHexString(content.Candidates[1]))
+		}
+	}
+
+	// the previous vote is spent to be replaced when the amount is specified
+	txn, err = createVoteTransaction(newTestContext(t, "--candidates",
+		producers[0].OwnerPublicKey, "--amount", "5"), voter, fee)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Equal(t, 1, len(txn.Inputs)) {
+		assert.Equal(t, voteTxID, common.BytesToHexString(
+			common.BytesReverse(txn.Inputs[0].Previous.TxID.Bytes())))
+	}
+	if assert.Equal(t, 2, len(txn.Outputs)) {
+		amount, _ := common.StringToFixed64("5")
+		assert.Equal(t, *amount, txn.Outputs[0].Value)
+		assert.Equal(t, types.VoteOutput, txn.Outputs[0].OutputType)
+		change, _ := common.StringToFixed64("4.999")
+		assert.Equal(t, *change, txn.Outputs[1].Value)
+		assert.Equal(t, types.DefaultOutput, txn.Outputs[1].OutputType)
+	}
+
+	_, err = createVoteTransaction(newTestContext(t, "--candidates",
+		candidates, "--amount", "abc"), voter, fee)
+	assert.Error(t, err)
+	_, err = createVoteTransaction(newTestContext(t, "--candidates",
+		randomOwnerPublicKey()), voter, fee)
+	assert.Error(t, err)
+}
+
+func TestCreateCancelTransaction(t *testing.T) {
+	voter, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	results := map[string]interface{}{
+		"listunspent:vote": []servers.UTXOInfo{
+			newTestUTXO(voteTxID, voter.Address, "10"),
+		},
+		"listunspent:normal": []servers.UTXOInfo{
+			newTestUTXO(normalTxID, voter.Address, "20"),
+		},
+	}
+	closeServer := newTestRPCServer(t, results)
+	defer closeServer()
+	fee, _ := common.StringToFixed64("0.001")
+
+	// the vote outputs are spent to a normal output of the voter
+	txn, err := createCancelTransaction(newTestContext(t), voter, fee)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, types.TxVersion09, txn.Version)
+	if assert.Equal(t, 1, len(txn.Inputs)) {
+		assert.Equal(t, voteTxID, common.BytesToHexString(
+			common.BytesReverse(txn.Inputs[0].Previous.TxID.Bytes())))
+	}
+	if assert.Equal(t, 1, len(txn.Outputs)) {
+		assert.Equal(t, types.DefaultOutput, txn.Outputs[0].OutputType)
+		assert.Equal(t, voter.ProgramHash, txn.Outputs[0].ProgramHash)
+		amount, _ := common.StringToFixed64("9.999")
+		assert.Equal(t, *amount, txn.Outputs[0].Value)
+	}
+
+	// there must be votes to cancel
+	results["listunspent:vote"] = []servers.UTXOInfo{}
+	_, err = createCancelTransaction(newTestContext(t), voter, fee)
+	assert.Error(t, err)
+}
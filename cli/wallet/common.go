@@ -10,10 +10,10 @@ import (
 
 	"github.com/elastos/Elastos.ELA/account"
 	clicom "github.com/elastos/Elastos.ELA/cli/common"
+	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/core/types"
 	"github.com/elastos/Elastos.ELA/servers"
 
-	"github.com/elastos/Elastos.ELA.Utility/http/jsonrpc"
 	"github.com/elastos/Elastos.ELA.Utility/http/util"
 	"github.com/elastos/Elastos.ELA/common"
 )
@@ -63,39 +63,65 @@ func ShowAccountBalance(name string) error {
 		return err
 	}
 
-	for _, a := range storeAccounts {
-		result, err := jsonrpc.CallParams(clicom.LocalServer(), "listunspent", util.Params{
-			"addresses": []string{a.Address},
-		})
+	for i, a := range storeAccounts {
+		available, locked, err := getBalance(a.Address)
 		if err != nil {
 			return err
 		}
-		data, err := json.Marshal(result)
+
+		fmt.Printf("%5d %34s %-20s%22s \n", i, a.Address, available.String(), "("+locked.String()+")")
+		fmt.Println("-----", strings.Repeat("-", 34), strings.Repeat("-", 42))
+	}
+
+	watches, err := fileStore.LoadWatchData()
+	if err != nil {
+		return err
+	}
+	for _, w := range watches {
+		available, locked, err := getBalance(w.Address)
 		if err != nil {
 			return err
 		}
-		var utxos []servers.UTXOInfo
-		err = json.Unmarshal(data, &utxos)
-
-		//var availabelUtxos []servers.UTXOInfo
-		availableAmount := common.Fixed64(0)
-		lockedAmount := common.Fixed64(0)
-		for _, utxo := range utxos {
-			amount, err := common.StringToFixed64(utxo.Amount)
-			if err != nil {
-				return err
-			}
-
-			if types.TransactionType(utxo.TxType) == types.CoinBase && utxo.Confirmations < 100 {
-				lockedAmount += *amount
-				continue
-			}
-			availableAmount += *amount
-		}
 
-		fmt.Printf("%5d %34s %-20s%22s \n", 0, a.Address, availableAmount.String(), "("+lockedAmount.String()+")")
+		fmt.Printf("%5s %34s %-20s%22s %s\n", "WATCH", w.Address, available.String(), "("+locked.String()+")", w.Label)
 		fmt.Println("-----", strings.Repeat("-", 34), strings.Repeat("-", 42))
 	}
 
 	return nil
 }
+
+// getBalance returns the available and locked amount of address.
+func getBalance(address string) (common.Fixed64, common.Fixed64, error) {
+	result, err := clicom.CallRPC("listunspent", util.Params{
+		"addresses": []string{address},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return 0, 0, err
+	}
+	var utxos []servers.UTXOInfo
+	if err := json.Unmarshal(data, &utxos); err != nil {
+		return 0, 0, err
+	}
+
+	availableAmount := common.Fixed64(0)
+	lockedAmount := common.Fixed64(0)
+	for _, utxo := range utxos {
+		amount, err := common.StringToFixed64(utxo.Amount)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if types.TransactionType(utxo.TxType) == types.CoinBase &&
+			utxo.Confirmations <= config.Parameters.ChainParam.CoinbaseLockTime {
+			lockedAmount += *amount
+			continue
+		}
+		availableAmount += *amount
+	}
+
+	return availableAmount, lockedAmount, nil
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/elastos/Elastos.ELA/account"
 	clicom "github.com/elastos/Elastos.ELA/cli/common"
 	pwd "github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/servers"
+
+	"github.com/elastos/Elastos.ELA.Utility/http/util"
 	"github.com/urfave/cli"
 )
 
@@ -50,18 +53,166 @@ func createWallet(name string, password string) error {
 	return ShowAccountInfo(client)
 }
 
-func importAccount(name, password, privateKeyHexStr string) error {
-	privateKeyBytes, err := hex.DecodeString(privateKeyHexStr)
+// hdGapLimit is the number of consecutive unused addresses after which a
+// restore stops scanning the addresses of the HD wallet.
+const hdGapLimit = 20
+
+func createHDWallet(name, password string, restore bool, count int) error {
+	var mnemonic, passphrase string
+	var err error
+	if restore {
+		if mnemonic, err = pwd.GetMnemonic(); err != nil {
+			return err
+		}
+		if passphrase, err = pwd.GetPassphrase(); err != nil {
+			return err
+		}
+	} else {
+		// a new mnemonic is generated if not restoring from one
+		if mnemonic, err = account.NewMnemonic(); err != nil {
+			return err
+		}
+		if passphrase, err = pwd.GetConfirmedPassphrase(); err != nil {
+			return err
+		}
+	}
+	p := getConfirmedPassword(password)
+
+	client, err := account.CreateFromMnemonic(name, p, mnemonic, passphrase)
+	if err != nil {
+		return err
+	}
+	if restore {
+		used, err := scanUsedAddresses(client)
+		if err != nil {
+			fmt.Println("warning: scan used addresses failed,", err)
+		} else if used > count {
+			count = used
+		}
+	}
+	for i := 1; i < count; i++ {
+		if _, err := client.CreateAccount(); err != nil {
+			return err
+		}
+	}
+
+	if !restore {
+		fmt.Println("Please write down the mnemonic and keep it safe, the wallet can be restored from it:")
+		fmt.Println(mnemonic)
+	}
+
+	return ShowAccountInfo(client)
+}
+
+// scanUsedAddresses returns the number of addresses of the HD wallet up to
+// the last used one, the scan stops after hdGapLimit unused addresses.
+func scanUsedAddresses(client *account.ClientImpl) (int, error) {
+	var count int
+	for index, unused := uint32(0), 0; unused < hdGapLimit; index++ {
+		address, err := client.HDAddress(index)
+		if err != nil {
+			return 0, err
+		}
+		used, err := isAddressUsed(address)
+		if err != nil {
+			return 0, err
+		}
+		if used {
+			count = int(index) + 1
+			unused = 0
+		} else {
+			unused++
+		}
+	}
+	return count, nil
+}
+
+// isAddressUsed returns if the address is in any transaction. Without the
+// address index on the node, only the addresses holding unspent outputs are
+// found used.
+func isAddressUsed(address string) (bool, error) {
+	var history []servers.AddressHistoryInfo
+	err := clicom.CallRPCResult("getaddresshistory", util.Params{
+		"addr":  address,
+		"count": 1,
+	}, &history)
+	if err == nil {
+		return len(history) > 0, nil
+	}
+
+	available, locked, err := getBalance(address)
+	if err != nil {
+		return false, err
+	}
+	return available+locked > 0, nil
+}
+
+func getPassword(passwd string) ([]byte, error) {
+	if passwd != "" {
+		return []byte(passwd), nil
+	}
+	return pwd.GetPassword()
+}
+
+func addAccount(name, password string, count int) error {
+	passwd, err := getPassword(password)
 	if err != nil {
 		return err
 	}
-	var passwd []byte
-	if len(password) == 0 {
-		passwd, err = pwd.GetConfirmedPassword()
+	client, err := account.Open(name, passwd)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		acc, err := client.CreateAccount()
 		if err != nil {
 			return err
 		}
+		fmt.Println(acc.Address)
+	}
+	return nil
+}
+
+func exportXPub(name, password string) error {
+	passwd, err := getPassword(password)
+	if err != nil {
+		return err
+	}
+	client, err := account.Open(name, passwd)
+	if err != nil {
+		return err
+	}
+
+	xpub, err := client.ExportXPub()
+	if err != nil {
+		return err
+	}
+	fmt.Println(xpub)
+	return nil
+}
+
+func upgradeWallet(name, password, kdf string) error {
+	passwd, err := getPassword(password)
+	if err != nil {
+		return err
+	}
+
+	backup, err := account.UpgradeKeystore(name, passwd, kdf)
+	if err != nil {
+		return err
 	}
+	fmt.Printf("%s upgraded to version %s, the original file is saved as %s\n",
+		name, account.KeystoreVersion, backup)
+	return nil
+}
+
+func importAccount(name, password, privateKeyHexStr string) error {
+	privateKeyBytes, err := hex.DecodeString(privateKeyHexStr)
+	if err != nil {
+		return err
+	}
+	passwd := getConfirmedPassword(password)
 
 	var client *account.ClientImpl
 	if _, err := os.Open(name); os.IsNotExist(err) {
@@ -90,13 +241,9 @@ func importAccount(name, password, privateKeyHexStr string) error {
 }
 
 func exportAccount(name, password string) error {
-	var err error
-	var passwd []byte
-	if password == "" {
-		passwd, err = pwd.GetPassword()
-		if err != nil {
-			return err
-		}
+	passwd, err := getPassword(password)
+	if err != nil {
+		return err
 	}
 
 	client, err := account.Open(name, passwd)
@@ -167,6 +314,41 @@ func walletAction(context *cli.Context) error {
 	// wallet name is keystore.dat by default
 	name := context.String("name")
 	passwd := context.String("password")
+	kdf := context.String("kdf")
+	account.KeystoreKDF = kdf
+
+	// upgrade keystore to the current version
+	if context.Bool("upgrade") {
+		if exist := clicom.FileExisted(name); !exist {
+			fmt.Println(fmt.Sprintf("error: %s is not found.", name))
+			cli.ShowCommandHelpAndExit(context, "upgrade", 1)
+		}
+		if err := upgradeWallet(name, passwd, kdf); err != nil {
+			fmt.Println("error: upgrade wallet failed,", err)
+			cli.ShowCommandHelpAndExit(context, "upgrade", 1)
+		}
+		return nil
+	}
+
+	// restore HD wallet from mnemonic
+	if context.Bool("restore") {
+		if err := createHDWallet(name, passwd, true,
+			context.Int("count")); err != nil {
+			fmt.Println("error: restore wallet failed,", err)
+			cli.ShowCommandHelpAndExit(context, "restore", 1)
+		}
+		return nil
+	}
+
+	// create HD wallet with a new mnemonic
+	if context.Bool("create") && context.Bool("mnemonic") {
+		if err := createHDWallet(name, passwd, false,
+			context.Int("count")); err != nil {
+			fmt.Println("error: create wallet failed,", err)
+			cli.ShowCommandHelpAndExit(context, "create", 1)
+		}
+		return nil
+	}
 
 	// create wallet
 	if context.Bool("create") {
@@ -208,6 +390,62 @@ func walletAction(context *cli.Context) error {
 		}
 	}
 
+	// add new accounts, derived from the seed for HD wallet
+	if count := context.Int("addaccount"); count > 0 {
+		if err := addAccount(name, passwd, count); err != nil {
+			fmt.Println("error: add account failed,", err)
+			cli.ShowCommandHelpAndExit(context, "addaccount", 1)
+		}
+	}
+
+	// export extended public key of HD wallet
+	if context.Bool("xpub") {
+		if err := exportXPub(name, passwd); err != nil {
+			fmt.Println("error: export extended public key failed,", err)
+			cli.ShowCommandHelpAndExit(context, "xpub", 1)
+		}
+	}
+
+	// watch-only addresses
+	if str := context.String("watch"); len(str) > 0 {
+		if err := addWatchAddress(name, str, context.String("label")); err != nil {
+			fmt.Println("error: add watch-only address failed,", err)
+			cli.ShowCommandHelpAndExit(context, "watch", 1)
+		}
+	}
+	if str := context.String("addmultisigaccount"); len(str) > 0 {
+		if err := addWatchMultiSign(name, str, context.Int("m"), context.String("label")); err != nil {
+			fmt.Println("error: add multi-sign account failed,", err)
+			cli.ShowCommandHelpAndExit(context, "addmultisigaccount", 1)
+		}
+	}
+	if str := context.String("unwatch"); len(str) > 0 {
+		if err := removeWatchAddress(name, str); err != nil {
+			fmt.Println("error: remove watch-only address failed,", err)
+			cli.ShowCommandHelpAndExit(context, "unwatch", 1)
+		}
+	}
+
+	// address book
+	if str := context.String("addcontact"); len(str) > 0 {
+		if err := addContact(name, str, context.String("label")); err != nil {
+			fmt.Println("error: add contact failed,", err)
+			cli.ShowCommandHelpAndExit(context, "addcontact", 1)
+		}
+	}
+	if str := context.String("delcontact"); len(str) > 0 {
+		if err := removeContact(name, str); err != nil {
+			fmt.Println("error: delete contact failed,", err)
+			cli.ShowCommandHelpAndExit(context, "delcontact", 1)
+		}
+	}
+	if context.Bool("addressbook") {
+		if err := showAddressBook(name); err != nil {
+			fmt.Println("error: show address book failed,", err)
+			cli.ShowCommandHelpAndExit(context, "addressbook", 1)
+		}
+	}
+
 	// import account by private key
 	if str := context.String("import"); len(str) > 0 {
 		if err := importAccount(name, passwd, str); err != nil {
@@ -253,13 +491,58 @@ func NewCommand() *cli.Command {
 				Name:  "list, l",
 				Usage: "list wallet information [account, balance, verbose]",
 			},
+			cli.BoolFlag{
+				Name:  "mnemonic",
+				Usage: "create a HD wallet from a new BIP39 mnemonic, used with --create",
+			},
+			cli.BoolFlag{
+				Name:  "restore",
+				Usage: "restore the HD wallet from the BIP39 mnemonic and passphrase entered interactively",
+			},
+			cli.IntFlag{
+				Name:  "count",
+				Usage: "the number of accounts to derive when creating a HD wallet, a restore derives at least the used ones",
+				Value: 1,
+			},
+			cli.BoolFlag{
+				Name:  "xpub",
+				Usage: "export the extended public key of the HD wallet for watch-only use",
+			},
 			cli.IntFlag{
 				Name:  "addaccount",
 				Usage: "add new account address",
 			},
 			cli.StringFlag{
 				Name:  "addmultisigaccount",
-				Usage: "add new multi-sign account address",
+				Usage: "add a watch-only multi-sign account by the public keys separated by comma, use with --m",
+			},
+			cli.IntFlag{
+				Name:  "m",
+				Usage: "the minimum number of signatures of the multi-sign account",
+			},
+			cli.StringFlag{
+				Name:  "watch",
+				Usage: "add a watch-only account by the address, or the public key to create transactions from it",
+			},
+			cli.StringFlag{
+				Name:  "unwatch",
+				Usage: "remove the watch-only account of the address",
+			},
+			cli.StringFlag{
+				Name:  "label",
+				Usage: "the label of the watch-only account or the address book contact",
+			},
+			cli.BoolFlag{
+				Name:  "addressbook",
+				Usage: "show the labelled addresses in the address book",
+			},
+			cli.StringFlag{
+				Name:  "addcontact",
+				Usage: "add the address to the address book, use with --label",
+			},
+			cli.StringFlag{
+				Name:  "delcontact",
+				Usage: "delete the contact of the label from the address book",
 			},
 			cli.BoolFlag{
 				Name:  "account, a",
@@ -269,6 +552,15 @@ func NewCommand() *cli.Command {
 				Name:  "changepassword",
 				Usage: "change wallet password",
 			},
+			cli.BoolFlag{
+				Name:  "upgrade",
+				Usage: "re-encrypt the wallet with the current keystore version, the original file is backed up",
+			},
+			cli.StringFlag{
+				Name:  "kdf",
+				Usage: "key derivation function of the wallet password [scrypt, argon2id]",
+				Value: account.KDFScrypt,
+			},
 			cli.BoolFlag{
 				Name:  "reset",
 				Usage: "reset wallet",
@@ -0,0 +1,117 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+func parsePublicKey(str string) (*crypto.PublicKey, error) {
+	publicKey, err := common.HexStringToBytes(strings.TrimSpace(str))
+	if err != nil {
+		return nil, errors.New("invalid public key " + str)
+	}
+	return crypto.DecodePoint(publicKey)
+}
+
+// addWatchAddress watches the address, or the standard address of the public
+// key so the redeem script is known.
+func addWatchAddress(name, addressOrPublicKey, label string) error {
+	var watch *account.WatchData
+	var err error
+	if _, e := common.Uint168FromAddress(addressOrPublicKey); e == nil {
+		watch, err = account.NewWatchData(addressOrPublicKey, label)
+	} else {
+		publicKey, e := parsePublicKey(addressOrPublicKey)
+		if e != nil {
+			return errors.New("invalid address or public key " + addressOrPublicKey)
+		}
+		watch, err = account.NewWatchDataByPublicKey(publicKey, label)
+	}
+	if err != nil {
+		return err
+	}
+
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	if err := fileStore.SaveWatchData(watch); err != nil {
+		return err
+	}
+	fmt.Println(watch.Address)
+	return nil
+}
+
+// addWatchMultiSign watches the M-of-N multi-sign address of the public keys
+// separated by comma.
+func addWatchMultiSign(name, publicKeys string, m int, label string) error {
+	var keys []*crypto.PublicKey
+	for _, str := range strings.Split(publicKeys, ",") {
+		publicKey, err := parsePublicKey(str)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, publicKey)
+	}
+	if m <= 0 || m > len(keys) {
+		return errors.New("use --m to specify the minimum signatures between 1 and the number of public keys")
+	}
+
+	watch, err := account.NewMultiSignWatchData(m, keys, label)
+	if err != nil {
+		return err
+	}
+
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	if err := fileStore.SaveWatchData(watch); err != nil {
+		return err
+	}
+	fmt.Println(watch.Address)
+	return nil
+}
+
+func removeWatchAddress(name, address string) error {
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	return fileStore.DeleteWatchData(address)
+}
+
+func addContact(name, address, label string) error {
+	if label == "" {
+		return errors.New("use --label to specify the label of the address")
+	}
+	if _, err := common.Uint168FromAddress(address); err != nil {
+		return errors.New("invalid address " + address)
+	}
+
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	return fileStore.SaveAddressBookData(label, address)
+}
+
+func removeContact(name, label string) error {
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	return fileStore.DeleteAddressBookData(label)
+}
+
+func showAddressBook(name string) error {
+	var fileStore account.FileStore
+	fileStore.SetPath(name)
+	contacts, err := fileStore.LoadAddressBookData()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-20s %-34s\n", "LABEL", "ADDRESS")
+	fmt.Println(strings.Repeat("-", 20), strings.Repeat("-", 34))
+	for _, c := range contacts {
+		fmt.Printf("%-20s %-34s\n", c.Label, c.Address)
+	}
+	fmt.Println(strings.Repeat("-", 20), strings.Repeat("-", 34))
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
+	"math"
 	"math/big"
 	"os"
 	"time"
@@ -18,13 +19,14 @@ const (
 	MINGENBLOCKTIME       = 2
 	DefaultGenBlockTime   = 6
 
-	DataPath   = "elastos"
-	DataDir    = "data"
-	ChainDir   = "chain"
-	DposDir    = "dpos"
-	LogDir     = "logs"
-	NodeDir    = "node"
-	ArbiterDir = "arbiter"
+	DataPath      = "elastos"
+	DataDir       = "data"
+	ChainDir      = "chain"
+	DposDir       = "dpos"
+	SignLedgerDir = "signledger"
+	LogDir        = "logs"
+	NodeDir       = "node"
+	ArbiterDir    = "arbiter"
 
 	MajorityCount    = 3
 	ArbitratorsCount = 5
@@ -77,25 +79,39 @@ type Configuration struct {
 	MaxPerLogSize        int64                `json:"MaxPerLogSize"`
 	MaxTxsInBlock        int                  `json:"MaxTransactionInBlock"`
 	MaxBlockSize         int                  `json:"MaxBlockSize"`
+	MaxTxsInPool         int                  `json:"MaxTransactionInPool"`
+	MaxTxPoolSize        int                  `json:"MaxTxPoolSize"`
+	MaxTxAncestorCount   int                  `json:"MaxTxAncestorCount"`
+	MaxTxAncestorSize    int                  `json:"MaxTxAncestorSize"`
+	TxPoolExpiry         time.Duration        `json:"TxPoolExpiry"`
+	MaxOrphanTxs         int                  `json:"MaxOrphanTransactions"`
+	DisableTxPoolPersist bool                 `json:"DisableTxPoolPersist"`
+	EnableAddressIndex   bool                 `json:"EnableAddressIndex"`
+	EnableSpendIndex     bool                 `json:"EnableSpendIndex"`
+	HeadersFirstSync     bool                 `json:"HeadersFirstSync"`
+	StoreBackend         string               `json:"StoreBackend"`
 	PowConfiguration     PowConfiguration     `json:"PowConfiguration"`
 	VoteHeight           uint32               `json:"VoteHeight"`
+	InBlockSpendHeight   uint32               `json:"InBlockSpendHeight"`
 	Arbiters             []string             `json:"Arbiters"`
 	ArbiterConfiguration ArbiterConfiguration `json:"ArbiterConfiguration"`
 	RpcConfiguration     RpcConfiguration     `json:"RpcConfiguration"`
 }
 
 type ArbiterConfiguration struct {
-	Name            string `json:"Name"`
-	Magic           uint32 `json:"Magic"`
-	NodePort        uint16 `json:"NodePort"`
-	ProtocolVersion uint32 `json:"ProtocolVersion"`
-	Services        uint64 `json:"Services"`
-	PrintLevel      uint8  `json:"PrintLevel"`
-	SignTolerance   uint64 `json:"SignTolerance"`
-	MaxLogsSize     int64  `json:"MaxLogsSize"`
-	MaxPerLogSize   int64  `json:"MaxPerLogSize"`
-	MaxConnections  int    `json:"MaxConnections"`
-	CandidatesCount uint32 `json:"CandidatesCount"`
+	Name              string `json:"Name"`
+	Magic             uint32 `json:"Magic"`
+	NodePort          uint16 `json:"NodePort"`
+	ProtocolVersion   uint32 `json:"ProtocolVersion"`
+	Services          uint64 `json:"Services"`
+	PrintLevel        uint8  `json:"PrintLevel"`
+	SignTolerance     uint64 `json:"SignTolerance"`
+	MaxLogsSize       int64  `json:"MaxLogsSize"`
+	MaxPerLogSize     int64  `json:"MaxPerLogSize"`
+	MaxConnections    int    `json:"MaxConnections"`
+	CandidatesCount   uint32 `json:"CandidatesCount"`
+	RequireEncryption bool   `json:"RequireEncryption"`
+	Signer            string `json:"Signer"`
 }
 
 type Seed struct {
@@ -127,6 +143,10 @@ type configParams struct {
 func init() {
 	var config ConfigFile
 	config.ConfigFile.VoteHeight = heights.HeightVersion1
+	// spending the outputs of earlier transactions in the same block is a
+	// consensus change, it stays disabled until an activation height is
+	// configured for the network
+	config.ConfigFile.InBlockSpendHeight = math.MaxUint32
 
 	if _, err := os.Stat(DefaultConfigFilename); os.IsNotExist(err) {
 		config.ConfigFile = configTemplate
@@ -23,6 +23,13 @@ var configTemplate = Configuration{
 	MultiCoreNum:        4,
 	MaxTxsInBlock:       10000,
 	MaxBlockSize:        8000000,
+	MaxTxsInPool:        100000,
+	MaxTxPoolSize:       80000000,
+	MaxTxAncestorCount:  25,
+	MaxTxAncestorSize:   100000,
+	TxPoolExpiry:        1209600,
+	MaxOrphanTxs:        100,
+	StoreBackend:        "leveldb",
 	MinCrossChainTxFee:  10000,
 	PowConfiguration: PowConfiguration{
 		PayToAddr:  "8VYXVxKKSAxkmRrfmGpQR2Kc66XhG6m3ta",
@@ -31,7 +38,8 @@ var configTemplate = Configuration{
 		MinTxFee:   100,
 		ActiveNet:  "RegNet",
 	},
-	VoteHeight: 100,
+	VoteHeight:         100,
+	InBlockSpendHeight: 100,
 	ArbiterConfiguration: ArbiterConfiguration{
 		Name:            "test",
 		Magic:           7630403,
@@ -4,10 +4,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/elastos/Elastos.ELA/common"
 )
 
+// ReplaceableSequence is the input sequence signalling that the transaction
+// can be replaced in transaction pool by a conflicting one paying higher fee.
+const ReplaceableSequence = math.MaxUint32 - 2
+
 type Input struct {
 	// Reference outpoint of this input
 	Previous OutPoint
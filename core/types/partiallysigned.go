@@ -0,0 +1,474 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+const (
+	// PartiallySignedVersion is the version of the partially signed
+	// transaction serialization.
+	PartiallySignedVersion = 0
+
+	// maxProgramSignatures limits the count of signatures of a program when
+	// deserializing.
+	maxProgramSignatures = 10000
+)
+
+// partiallySignedMagic is put in front of a serialized partially signed
+// transaction, so it will not be mistaken for a raw transaction.
+var partiallySignedMagic = [4]byte{'e', 'p', 's', 't'}
+
+// KeySignature is the signature of a public key in a redeem script.
+type KeySignature struct {
+	PublicKey []byte
+	Signature []byte
+}
+
+// PartiallySignedTransaction carries an unsigned transaction with what the
+// signers need to check and sign it, the outputs referenced by the inputs and
+// the signatures collected for each redeem script. Signatures can be added by
+// several signers in any order, and the transaction is finalized once each
+// redeem script has enough signatures. The references are given by the
+// creator, they must be checked by VerifyReferences before the fee is trusted.
+type PartiallySignedTransaction struct {
+	// Transaction is the unsigned transaction, whose programs carry the
+	// redeem scripts without parameters.
+	Transaction *Transaction
+
+	// References are the outputs referenced by the inputs in order.
+	References []*Output
+
+	// Signatures are the signatures of each program in order.
+	Signatures [][]*KeySignature
+}
+
+// NewPartiallySignedTransaction creates the partially signed transaction of
+// txn, the signatures already in the programs are kept.
+func NewPartiallySignedTransaction(txn *Transaction, references []*Output) (*PartiallySignedTransaction, error) {
+	if len(references) != len(txn.Inputs) {
+		return nil, errors.New("references count not match inputs count")
+	}
+	if len(txn.Programs) == 0 {
+		return nil, errors.New("transaction has no redeem script")
+	}
+
+	unsigned := *txn
+	unsigned.Programs = make([]*program.Program, 0, len(txn.Programs))
+	for _, p := range txn.Programs {
+		if _, _, err := scriptPublicKeys(p.Code); err != nil {
+			return nil, err
+		}
+		unsigned.Programs = append(unsigned.Programs, &program.Program{Code: p.Code})
+	}
+	pst := &PartiallySignedTransaction{
+		Transaction: &unsigned,
+		References:  references,
+		Signatures:  make([][]*KeySignature, len(txn.Programs)),
+	}
+
+	// match the signatures in parameters to public keys
+	data := pst.unsignedData()
+	for i, p := range txn.Programs {
+		keys, _, _ := scriptPublicKeys(p.Code)
+		param := p.Parameter
+		for len(param) >= crypto.SignatureScriptLength {
+			signature := param[1:crypto.SignatureScriptLength]
+			param = param[crypto.SignatureScriptLength:]
+			for _, key := range keys {
+				if verifySignature(key, data, signature) == nil {
+					pst.addSignature(i, key, signature)
+					break
+				}
+			}
+		}
+	}
+
+	return pst, nil
+}
+
+func (p *PartiallySignedTransaction) Serialize(w io.Writer) error {
+	if _, err := w.Write(partiallySignedMagic[:]); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, PartiallySignedVersion); err != nil {
+		return err
+	}
+	if err := p.Transaction.Serialize(w); err != nil {
+		return err
+	}
+
+	if err := common.WriteVarUint(w, uint64(len(p.References))); err != nil {
+		return err
+	}
+	for _, reference := range p.References {
+		if err := reference.Serialize(w, p.Transaction.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := common.WriteVarUint(w, uint64(len(p.Signatures))); err != nil {
+		return err
+	}
+	for _, signatures := range p.Signatures {
+		if err := common.WriteVarUint(w, uint64(len(signatures))); err != nil {
+			return err
+		}
+		for _, s := range signatures {
+			if err := common.WriteVarBytes(w, s.PublicKey); err != nil {
+				return err
+			}
+			if err := common.WriteVarBytes(w, s.Signature); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *PartiallySignedTransaction) Deserialize(r io.Reader) error {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return err
+	}
+	if magic != partiallySignedMagic {
+		return errors.New("not a partially signed transaction")
+	}
+	version, err := common.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	if version != PartiallySignedVersion {
+		return fmt.Errorf("unknown partially signed transaction version %d", version)
+	}
+
+	p.Transaction = new(Transaction)
+	if err := p.Transaction.Deserialize(r); err != nil {
+		return err
+	}
+
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count != uint64(len(p.Transaction.Inputs)) {
+		return errors.New("references count not match inputs count")
+	}
+	p.References = make([]*Output, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var reference Output
+		if err := reference.Deserialize(r, p.Transaction.Version); err != nil {
+			return err
+		}
+		p.References = append(p.References, &reference)
+	}
+
+	count, err = common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count != uint64(len(p.Transaction.Programs)) {
+		return errors.New("signatures count not match programs count")
+	}
+	p.Signatures = make([][]*KeySignature, 0, count)
+	for i := uint64(0); i < count; i++ {
+		n, err := common.ReadVarUint(r, 0)
+		if err != nil {
+			return err
+		}
+		if n > maxProgramSignatures {
+			return errors.New("too many signatures")
+		}
+		signatures := make([]*KeySignature, 0, n)
+		for j := uint64(0); j < n; j++ {
+			publicKey, err := common.ReadVarBytes(r, crypto.PublicKeyScriptLength,
+				"public key")
+			if err != nil {
+				return err
+			}
+			signature, err := common.ReadVarBytes(r, crypto.SignatureLength,
+				"signature")
+			if err != nil {
+				return err
+			}
+			signatures = append(signatures,
+				&KeySignature{PublicKey: publicKey, Signature: signature})
+		}
+		p.Signatures = append(p.Signatures, signatures)
+	}
+
+	return p.verifySignatures()
+}
+
+// verifySignatures checks each signature collected is of a public key in the
+// redeem script and signs the transaction.
+func (p *PartiallySignedTransaction) verifySignatures() error {
+	data := p.unsignedData()
+	for i, prog := range p.Transaction.Programs {
+		keys, _, err := scriptPublicKeys(prog.Code)
+		if err != nil {
+			return err
+		}
+		for j, s := range p.Signatures[i] {
+			found := false
+			for _, key := range keys {
+				if bytes.Equal(key, s.PublicKey) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return errors.New("public key " + common.BytesToHexString(s.PublicKey) +
+					" is not a signer of the transaction")
+			}
+			for _, other := range p.Signatures[i][:j] {
+				if bytes.Equal(other.PublicKey, s.PublicKey) {
+					return errors.New("duplicated signature of public key " +
+						common.BytesToHexString(s.PublicKey))
+				}
+			}
+			if err := verifySignature(s.PublicKey, data, s.Signature); err != nil {
+				return errors.New("invalid signature of public key " +
+					common.BytesToHexString(s.PublicKey))
+			}
+		}
+	}
+	return nil
+}
+
+// VerifyReferences checks the references are the outputs referenced by the
+// inputs, getTransaction returns the transaction of the txid from a trusted
+// source like the node.
+func (p *PartiallySignedTransaction) VerifyReferences(getTransaction func(common.Uint256) (*Transaction, error)) error {
+	for i, input := range p.Transaction.Inputs {
+		txID := input.Previous.TxID
+		txn, err := getTransaction(txID)
+		if err != nil {
+			return err
+		}
+		if txn.Hash() != txID {
+			return fmt.Errorf("transaction of input %d not match", i)
+		}
+		index := int(input.Previous.Index)
+		if index >= len(txn.Outputs) {
+			return fmt.Errorf("output of input %d not found", i)
+		}
+
+		var expected, given bytes.Buffer
+		if err := txn.Outputs[index].Serialize(&expected, p.Transaction.Version); err != nil {
+			return err
+		}
+		if err := p.References[i].Serialize(&given, p.Transaction.Version); err != nil {
+			return err
+		}
+		if !bytes.Equal(expected.Bytes(), given.Bytes()) {
+			return fmt.Errorf("reference of input %d not match the output spent", i)
+		}
+	}
+	return nil
+}
+
+// AddSignature adds the signature of the public key to the programs whose
+// redeem script contains the key, a signature already collected is ignored.
+func (p *PartiallySignedTransaction) AddSignature(publicKey, signature []byte) error {
+	data := p.unsignedData()
+	found := false
+	for i, prog := range p.Transaction.Programs {
+		keys, _, err := scriptPublicKeys(prog.Code)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if !bytes.Equal(key, publicKey) {
+				continue
+			}
+			if err := verifySignature(key, data, signature); err != nil {
+				return errors.New("invalid signature of public key " +
+					common.BytesToHexString(publicKey))
+			}
+			p.addSignature(i, key, signature)
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("public key " + common.BytesToHexString(publicKey) +
+			" is not a signer of the transaction")
+	}
+	return nil
+}
+
+// Sign signs the transaction by the private key for each redeem script
+// containing its public key, and returns the count of signatures added.
+func (p *PartiallySignedTransaction) Sign(privateKey []byte) (int, error) {
+	publicKey, err := crypto.NewPubKey(privateKey).EncodePoint(true)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for i, prog := range p.Transaction.Programs {
+		keys, _, err := scriptPublicKeys(prog.Code)
+		if err != nil {
+			return added, err
+		}
+		for _, key := range keys {
+			if !bytes.Equal(key, publicKey) || p.hasSignature(i, key) {
+				continue
+			}
+			signature, err := crypto.Sign(privateKey, p.unsignedData())
+			if err != nil {
+				return added, err
+			}
+			p.addSignature(i, key, signature)
+			added++
+		}
+	}
+	return added, nil
+}
+
+// Combine adds the signatures collected in other, which must be the same
+// transaction.
+func (p *PartiallySignedTransaction) Combine(other *PartiallySignedTransaction) error {
+	if p.Transaction.Hash() != other.Transaction.Hash() {
+		return errors.New("can not combine different transactions")
+	}
+	for _, signatures := range other.Signatures {
+		for _, s := range signatures {
+			if err := p.AddSignature(s.PublicKey, s.Signature); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SignStatus returns the count of signatures collected and needed by the
+// program at index.
+func (p *PartiallySignedTransaction) SignStatus(index int) (int, int) {
+	_, m, err := scriptPublicKeys(p.Transaction.Programs[index].Code)
+	if err != nil {
+		return 0, 0
+	}
+	return len(p.Signatures[index]), m
+}
+
+// IsComplete returns if all programs have enough signatures.
+func (p *PartiallySignedTransaction) IsComplete() bool {
+	for i := range p.Transaction.Programs {
+		have, need := p.SignStatus(i)
+		if need == 0 || have < need {
+			return false
+		}
+	}
+	return true
+}
+
+// Fee returns the amount of the references exceeding the outputs.
+func (p *PartiallySignedTransaction) Fee() common.Fixed64 {
+	var fee common.Fixed64
+	for _, reference := range p.References {
+		fee += reference.Value
+	}
+	for _, output := range p.Transaction.Outputs {
+		fee -= output.Value
+	}
+	return fee
+}
+
+// Finalize returns the signed transaction with the collected signatures put
+// into the program parameters in the order of public keys in the redeem
+// scripts.
+func (p *PartiallySignedTransaction) Finalize() (*Transaction, error) {
+	if !p.IsComplete() {
+		return nil, errors.New("transaction has not enough signatures")
+	}
+
+	txn := *p.Transaction
+	txn.Programs = make([]*program.Program, 0, len(p.Transaction.Programs))
+	for i, prog := range p.Transaction.Programs {
+		keys, m, _ := scriptPublicKeys(prog.Code)
+		param := new(bytes.Buffer)
+		count := 0
+		for _, key := range keys {
+			for _, s := range p.Signatures[i] {
+				if count < m && bytes.Equal(s.PublicKey, key) {
+					param.WriteByte(byte(len(s.Signature)))
+					param.Write(s.Signature)
+					count++
+				}
+			}
+		}
+		txn.Programs = append(txn.Programs,
+			&program.Program{Code: prog.Code, Parameter: param.Bytes()})
+	}
+	return &txn, nil
+}
+
+func (p *PartiallySignedTransaction) unsignedData() []byte {
+	buf := new(bytes.Buffer)
+	p.Transaction.SerializeUnsigned(buf)
+	return buf.Bytes()
+}
+
+func (p *PartiallySignedTransaction) hasSignature(index int, publicKey []byte) bool {
+	for _, s := range p.Signatures[index] {
+		if bytes.Equal(s.PublicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PartiallySignedTransaction) addSignature(index int, publicKey, signature []byte) {
+	if p.hasSignature(index, publicKey) {
+		return
+	}
+	p.Signatures[index] = append(p.Signatures[index],
+		&KeySignature{PublicKey: publicKey, Signature: signature})
+}
+
+// scriptPublicKeys returns the public keys in a standard or multi-sign redeem
+// script, and the count of signatures needed.
+func scriptPublicKeys(code []byte) ([][]byte, int, error) {
+	scriptType, err := crypto.GetScriptType(code)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch scriptType {
+	case common.STANDARD:
+		if len(code) != crypto.PublicKeyScriptLength {
+			return nil, 0, errors.New("invalid standard redeem script")
+		}
+		return [][]byte{code[1 : len(code)-1]}, 1, nil
+	case common.MULTISIG:
+		scripts, err := crypto.ParseMultisigScript(code)
+		if err != nil {
+			return nil, 0, err
+		}
+		keys := make([][]byte, 0, len(scripts))
+		for _, script := range scripts {
+			keys = append(keys, script[1:])
+		}
+		m, err := crypto.GetM(code)
+		if err != nil {
+			return nil, 0, err
+		}
+		return keys, int(m), nil
+	}
+	return nil, 0, errors.New("redeem script not a standard or multi sign type")
+}
+
+func verifySignature(publicKey, data, signature []byte) error {
+	pubKey, err := crypto.DecodePoint(publicKey)
+	if err != nil {
+		return err
+	}
+	return crypto.Verify(*pubKey, data, signature)
+}
@@ -0,0 +1,241 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartiallySignedTransaction(t *testing.T) {
+	var privateKeys [][]byte
+	var publicKeys []*crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		privateKey, publicKey, err := crypto.GenerateKeyPair()
+		assert.NoError(t, err)
+		privateKeys = append(privateKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	redeemScript, err := crypto.CreateMultiSignRedeemScript(2, publicKeys)
+	assert.NoError(t, err)
+
+	txn := randomOldVersionTransaction(false, byte(TransferAsset), 2, 2, 1, 0)
+	txn.Payload = &payload.PayloadTransferAsset{}
+	txn.Programs = []*program.Program{{Code: redeemScript}}
+	var references []*Output
+	for range txn.Inputs {
+		references = append(references, &Output{
+			Value:         txn.Outputs[0].Value,
+			OutputType:    DefaultOutput,
+			OutputPayload: &outputpayload.DefaultOutput{},
+		})
+	}
+
+	_, err = NewPartiallySignedTransaction(txn, references[1:])
+	assert.Error(t, err)
+	pst, err := NewPartiallySignedTransaction(txn, references)
+	assert.NoError(t, err)
+	assert.Equal(t, references[0].Value+references[1].Value-
+		txn.Outputs[0].Value-txn.Outputs[1].Value, pst.Fee())
+
+	// two signers sign their own copies
+	copy1 := serializeAndDeserialize(t, pst)
+	copy2 := serializeAndDeserialize(t, pst)
+	added, err := copy1.Sign(privateKeys[2])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+	added, err = copy2.Sign(privateKeys[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+	added, err = copy2.Sign(privateKeys[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 0, added)
+
+	have, need := copy1.SignStatus(0)
+	assert.Equal(t, 1, have)
+	assert.Equal(t, 2, need)
+	assert.False(t, copy1.IsComplete())
+	_, err = copy1.Finalize()
+	assert.Error(t, err)
+
+	// combine in any order
+	copy1 = serializeAndDeserialize(t, copy1)
+	assert.NoError(t, copy1.Combine(copy2))
+	assert.NoError(t, copy1.Combine(copy2))
+	have, _ = copy1.SignStatus(0)
+	assert.Equal(t, 2, have)
+	assert.True(t, copy1.IsComplete())
+
+	signed, err := copy1.Finalize()
+	assert.NoError(t, err)
+	assert.Equal(t, txn.Hash(), signed.Hash())
+	param := signed.Programs[0].Parameter
+	assert.Equal(t, 2*crypto.SignatureScriptLength, len(param))
+	data := new(bytes.Buffer)
+	signed.SerializeUnsigned(data)
+	// signatures are in the order of public keys in the redeem script
+	keys, _, err := scriptPublicKeys(redeemScript)
+	assert.NoError(t, err)
+	var signers []int
+	for i := 0; i < len(param); i += crypto.SignatureScriptLength {
+		for j, key := range keys {
+			signature := param[i+1 : i+crypto.SignatureScriptLength]
+			if verifySignature(key, data.Bytes(), signature) == nil {
+				signers = append(signers, j)
+			}
+		}
+	}
+	assert.Equal(t, 2, len(signers))
+	assert.True(t, signers[0] < signers[1])
+
+	// the signatures in a signed transaction are kept
+	recreated, err := NewPartiallySignedTransaction(signed, references)
+	assert.NoError(t, err)
+	assert.True(t, recreated.IsComplete())
+
+	// a different transaction can not be combined
+	other := serializeAndDeserialize(t, pst)
+	other.Transaction.LockTime++
+	assert.Error(t, other.Combine(copy1))
+
+	// a signature of other key is rejected
+	_, publicKey, _ := crypto.GenerateKeyPair()
+	key, _ := publicKey.EncodePoint(true)
+	assert.Error(t, pst.AddSignature(key, make([]byte, crypto.SignatureLength)))
+	key, _ = publicKeys[1].EncodePoint(true)
+	assert.Error(t, pst.AddSignature(key, make([]byte, crypto.SignatureLength)))
+
+	// a raw transaction is not a partially signed transaction
+	buf := new(bytes.Buffer)
+	txn.Serialize(buf)
+	assert.Error(t, new(PartiallySignedTransaction).Deserialize(buf))
+}
+
+func serializeAndDeserialize(t *testing.T, pst *PartiallySignedTransaction) *PartiallySignedTransaction {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, pst.Serialize(buf))
+	var result PartiallySignedTransaction
+	assert.NoError(t, result.Deserialize(buf))
+	assert.Equal(t, common.Sha256D(pst.unsignedData()),
+		common.Sha256D(result.unsignedData()))
+	return &result
+}
+
+func TestPartiallySignedTransaction_DeserializeSignatures(t *testing.T) {
+	var privateKeys [][]byte
+	var publicKeys []*crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		privateKey, publicKey, err := crypto.GenerateKeyPair()
+		assert.NoError(t, err)
+		privateKeys = append(privateKeys, privateKey)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	redeemScript, err := crypto.CreateMultiSignRedeemScript(2, publicKeys[:2])
+	assert.NoError(t, err)
+
+	txn := randomOldVersionTransaction(false, byte(TransferAsset), 1, 1, 1, 0)
+	txn.Payload = &payload.PayloadTransferAsset{}
+	txn.Programs = []*program.Program{{Code: redeemScript}}
+	references := []*Output{{
+		Value:         txn.Outputs[0].Value,
+		OutputType:    DefaultOutput,
+		OutputPayload: &outputpayload.DefaultOutput{},
+	}}
+	pst, err := NewPartiallySignedTransaction(txn, references)
+	assert.NoError(t, err)
+	_, err = pst.Sign(privateKeys[0])
+	assert.NoError(t, err)
+	serializeAndDeserialize(t, pst)
+
+	deserialize := func(signatures []*KeySignature) error {
+		forged := *pst
+		forged.Signatures = [][]*KeySignature{signatures}
+		buf := new(bytes.Buffer)
+		assert.NoError(t, forged.Serialize(buf))
+		return new(PartiallySignedTransaction).Deserialize(buf)
+	}
+	signed := pst.Signatures[0][0]
+
+	// a signature not signing the transaction is rejected
+	invalid := make([]byte, len(signed.Signature))
+	copy(invalid, signed.Signature)
+	invalid[0]++
+	assert.Error(t, deserialize([]*KeySignature{
+		{PublicKey: signed.PublicKey, Signature: invalid}}))
+
+	// a signature of the transaction by other than the signers is rejected
+	signature, err := crypto.Sign(privateKeys[2], pst.unsignedData())
+	assert.NoError(t, err)
+	key, err := publicKeys[2].EncodePoint(true)
+	assert.NoError(t, err)
+	assert.Error(t, deserialize([]*KeySignature{
+		signed, {PublicKey: key, Signature: signature}}))
+
+	// a signature can not be counted twice
+	assert.Error(t, deserialize([]*KeySignature{signed, signed}))
+	assert.NoError(t, deserialize([]*KeySignature{signed}))
+}
+
+func TestPartiallySignedTransaction_VerifyReferences(t *testing.T) {
+	previous := make(map[common.Uint256]*Transaction)
+	txn := randomOldVersionTransaction(false, byte(TransferAsset), 2, 1, 1, 0)
+	var references []*Output
+	for i, input := range txn.Inputs {
+		prev := randomOldVersionTransaction(false, byte(TransferAsset), 1, 2, 1, 0)
+		prev.Payload = &payload.PayloadTransferAsset{}
+		previous[prev.Hash()] = prev
+		input.Previous = *NewOutPoint(prev.Hash(), uint16(i))
+		references = append(references, prev.Outputs[i])
+	}
+	_, publicKey1, _ := crypto.GenerateKeyPair()
+	_, publicKey2, _ := crypto.GenerateKeyPair()
+	redeemScript, err := crypto.CreateMultiSignRedeemScript(1,
+		[]*crypto.PublicKey{publicKey1, publicKey2})
+	assert.NoError(t, err)
+	txn.Payload = &payload.PayloadTransferAsset{}
+	txn.Programs = []*program.Program{{Code: redeemScript}}
+
+	getTransaction := func(txID common.Uint256) (*Transaction, error) {
+		if prev, ok := previous[txID]; ok {
+			return prev, nil
+		}
+		return nil, errors.New("transaction not found")
+	}
+	pst, err := NewPartiallySignedTransaction(txn, references)
+	assert.NoError(t, err)
+	assert.NoError(t, pst.VerifyReferences(getTransaction))
+	pst = serializeAndDeserialize(t, pst)
+	assert.NoError(t, pst.VerifyReferences(getTransaction))
+
+	// a reference with a forged value is rejected
+	forged := *references[1]
+	forged.Value++
+	pst.References[1] = &forged
+	assert.Error(t, pst.VerifyReferences(getTransaction))
+	pst.References[1] = references[1]
+
+	// the transaction spent must be found
+	delete(previous, txn.Inputs[0].Previous.TxID)
+	assert.Error(t, pst.VerifyReferences(getTransaction))
+	assert.Error(t, pst.VerifyReferences(
+		func(common.Uint256) (*Transaction, error) {
+			for _, prev := range previous {
+				return prev, nil
+			}
+			return nil, nil
+		}))
+
+	// the output spent must exist
+	pst.Transaction.Inputs = pst.Transaction.Inputs[1:]
+	pst.References = pst.References[1:]
+	assert.NoError(t, pst.VerifyReferences(getTransaction))
+	pst.Transaction.Inputs[0].Previous.Index = 2
+	assert.Error(t, pst.VerifyReferences(getTransaction))
+}
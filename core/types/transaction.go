@@ -362,6 +362,17 @@ func (tx *Transaction) IsCoinBaseTx() bool {
 	return tx.TxType == CoinBase
 }
 
+// IsReplaceable returns if any input of the transaction signals replace-by-fee
+// with ReplaceableSequence.
+func (tx *Transaction) IsReplaceable() bool {
+	for _, input := range tx.Inputs {
+		if input.Sequence == ReplaceableSequence {
+			return true
+		}
+	}
+	return false
+}
+
 // Payload define the func for loading the payload data
 // base on payload type which have different structure
 type Payload interface {
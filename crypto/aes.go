@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"errors"
 )
@@ -50,3 +51,43 @@ func AesDecrypt(cipherText []byte, key []byte, iv []byte) ([]byte, error) {
 
 	return plaintext, nil
 }
+
+// AesGcmEncrypt encrypts and authenticates plaintext with AES-GCM, the random
+// nonce is put in front of the returned cipher text.
+func AesGcmEncrypt(plaintext []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("invalid encrypt key")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// AesGcmDecrypt decrypts the cipher text created by AesGcmEncrypt, an error is
+// returned if the key is wrong or the cipher text has been modified.
+func AesGcmDecrypt(cipherText []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("invalid decrypt key")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cipherText) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("cipherText too short")
+	}
+	nonce := cipherText[:aead.NonceSize()]
+
+	return aead.Open(nil, nonce, cipherText[aead.NonceSize():], nil)
+}
@@ -76,6 +76,10 @@ func Sign(priKey []byte, data []byte) ([]byte, error) {
 	privateKey.Curve = algSet.Curve
 	privateKey.D = big.NewInt(0)
 	privateKey.D.SetBytes(priKey)
+	// ecdsa.Sign of recent Go versions uses the public key of the private
+	// key, and panics if it is not set
+	privateKey.PublicKey.X, privateKey.PublicKey.Y =
+		algSet.Curve.ScalarBaseMult(priKey)
 
 	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
 	if err != nil {
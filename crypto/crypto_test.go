@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"crypto/rand"
 	"sort"
 	"testing"
 
@@ -39,3 +40,44 @@ func (p pubKeySlice) Less(i, j int) bool {
 func (p pubKeySlice) Swap(i, j int) {
 	p[i], p[j] = p[j], p[i]
 }
+
+func TestAesGcmEncrypt(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	plaintext := []byte("the private key encrypted in keystore")
+
+	cipherText, err := AesGcmEncrypt(plaintext, key)
+	assert.NoError(t, err)
+	decrypted, err := AesGcmDecrypt(cipherText, key)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	// the same plaintext is encrypted with different nonces
+	cipherText2, err := AesGcmEncrypt(plaintext, key)
+	assert.NoError(t, err)
+	assert.NotEqual(t, cipherText, cipherText2)
+
+	// modified cipher text or wrong key can not be decrypted
+	cipherText[len(cipherText)-1] ^= 1
+	_, err = AesGcmDecrypt(cipherText, key)
+	assert.Error(t, err)
+	key[0] ^= 1
+	_, err = AesGcmDecrypt(cipherText2, key)
+	assert.Error(t, err)
+}
+
+func TestSign(t *testing.T) {
+	// only the private key is given, Sign derives the public key itself
+	privateKey, publicKey, err := GenerateKeyPair()
+	if !assert.NoError(t, err) {
+		return
+	}
+	data := []byte("data to sign")
+	signature, err := Sign(privateKey, data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, SignatureLength, len(signature))
+	assert.NoError(t, Verify(*publicKey, data, signature))
+	assert.Error(t, Verify(*publicKey, []byte("other data"), signature))
+}
@@ -0,0 +1,339 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/itchyny/base58-go"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = 0x80000000
+
+	// ELACoinType is the registered coin type of ELA in BIP44 paths.
+	ELACoinType = 2305
+
+	// extendedKeyLength is the length of a serialized extended key without
+	// checksum.
+	extendedKeyLength = 78
+)
+
+var (
+	// masterKeySeed is the HMAC key deriving the master key from a seed,
+	// defined by SLIP-0010 for the NIST P-256 curve.
+	masterKeySeed = []byte("Nist256p1 seed")
+
+	// extended key versions, the same as BIP32 so they are shown as xprv and
+	// xpub.
+	xprvVersion = []byte{0x04, 0x88, 0xad, 0xe4}
+	xpubVersion = []byte{0x04, 0x88, 0xb2, 0x1e}
+
+	ErrDeriveHardenedFromPublic = errors.New("can not derive a hardened key from a public key")
+	ErrInvalidExtendedKey       = errors.New("invalid extended key")
+)
+
+// ExtendedKey is a BIP32 hierarchical deterministic key on the curve ELA
+// uses, with the derivation following SLIP-0010. It holds either a private
+// key or only a public key.
+type ExtendedKey struct {
+	key         []byte // 32 bytes private key, or 33 bytes compressed public key
+	chainCode   []byte
+	depth       uint8
+	parentFP    []byte
+	childNumber uint32
+	isPrivate   bool
+}
+
+// NewMasterKey creates the master extended key from a seed, like the one
+// generated from a BIP39 mnemonic.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed length should be between 16 and 64 bytes")
+	}
+
+	data := seed
+	for {
+		mac := hmac.New(sha512.New, masterKeySeed)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		key := new(big.Int).SetBytes(sum[:32])
+		if key.Sign() != 0 && key.Cmp(algSet.EccParams.N) < 0 {
+			return &ExtendedKey{
+				key:       sum[:32],
+				chainCode: sum[32:],
+				parentFP:  []byte{0, 0, 0, 0},
+				isPrivate: true,
+			}, nil
+		}
+		data = sum
+	}
+}
+
+// IsPrivate returns if the extended key holds a private key.
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// PrivateKey returns the 32 bytes private key.
+func (k *ExtendedKey) PrivateKey() ([]byte, error) {
+	if !k.isPrivate {
+		return nil, errors.New("extended key has no private key")
+	}
+	return k.key, nil
+}
+
+// PublicKey returns the public key of the extended key.
+func (k *ExtendedKey) PublicKey() (*PublicKey, error) {
+	if k.isPrivate {
+		return NewPubKey(k.key), nil
+	}
+	return DecodePoint(k.key)
+}
+
+func (k *ExtendedKey) publicKeyBytes() ([]byte, error) {
+	if !k.isPrivate {
+		return k.key, nil
+	}
+	return NewPubKey(k.key).EncodePoint(true)
+}
+
+// Child derives the child extended key with index, the index not lower than
+// HardenedKeyStart derives a hardened child which needs the private key.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	hardened := index >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrDeriveHardenedFromPublic
+	}
+
+	pubKey, err := k.publicKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0x00)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, pubKey...)
+	}
+	var indexBytes [4]byte
+	binary.BigEndian.PutUint32(indexBytes[:], index)
+	data = append(data, indexBytes[:]...)
+
+	curve := algSet.Curve
+	n := algSet.EccParams.N
+	for {
+		mac := hmac.New(sha512.New, k.chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		il := new(big.Int).SetBytes(sum[:32])
+		chainCode := sum[32:]
+
+		// SLIP-0010 derives again from the chain code if the key is invalid
+		var childKey []byte
+		valid := il.Cmp(n) < 0
+		if valid && k.isPrivate {
+			key := new(big.Int).Add(il, new(big.Int).SetBytes(k.key))
+			key.Mod(key, n)
+			if key.Sign() != 0 {
+				childKey = paddedBytes(key, 32)
+			}
+		} else if valid {
+			parent, err := DecodePoint(k.key)
+			if err != nil {
+				return nil, err
+			}
+			x, y := curve.ScalarBaseMult(paddedBytes(il, 32))
+			x, y = curve.Add(x, y, parent.X, parent.Y)
+			if x.Sign() != 0 || y.Sign() != 0 {
+				childKey, err = (&PublicKey{X: x, Y: y}).EncodePoint(true)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if childKey != nil {
+			return &ExtendedKey{
+				key:         childKey,
+				chainCode:   chainCode,
+				depth:       k.depth + 1,
+				parentFP:    fingerprint(pubKey),
+				childNumber: index,
+				isPrivate:   k.isPrivate,
+			}, nil
+		}
+
+		data = append([]byte{0x01}, chainCode...)
+		data = append(data, indexBytes[:]...)
+	}
+}
+
+// Derive derives the descendant extended key along path, like
+// "m/44'/2305'/0'/0/0". The path must start with "m" if k is the master key.
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := k
+	for _, index := range indexes {
+		key, err = key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Neuter returns the extended key with only the public key.
+func (k *ExtendedKey) Neuter() (*ExtendedKey, error) {
+	if !k.isPrivate {
+		return k, nil
+	}
+	pubKey, err := k.publicKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	return &ExtendedKey{
+		key:         pubKey,
+		chainCode:   k.chainCode,
+		depth:       k.depth,
+		parentFP:    k.parentFP,
+		childNumber: k.childNumber,
+		isPrivate:   false,
+	}, nil
+}
+
+// String returns the base58 encoded extended key, shown as xprv or xpub.
+func (k *ExtendedKey) String() string {
+	buf := new(bytes.Buffer)
+	if k.isPrivate {
+		buf.Write(xprvVersion)
+	} else {
+		buf.Write(xpubVersion)
+	}
+	buf.WriteByte(k.depth)
+	buf.Write(k.parentFP)
+	binary.Write(buf, binary.BigEndian, k.childNumber)
+	buf.Write(k.chainCode)
+	if k.isPrivate {
+		buf.WriteByte(0x00)
+	}
+	buf.Write(k.key)
+
+	checksum := common.Sha256D(buf.Bytes())
+	buf.Write(checksum[:4])
+
+	bi := new(big.Int).SetBytes(buf.Bytes()).String()
+	encoded, _ := base58.BitcoinEncoding.Encode([]byte(bi))
+	return string(encoded)
+}
+
+// ParseExtendedKey parses an extended key encoded by String.
+func ParseExtendedKey(str string) (*ExtendedKey, error) {
+	decoded, err := base58.BitcoinEncoding.Decode([]byte(str))
+	if err != nil {
+		return nil, err
+	}
+	bi, ok := new(big.Int).SetString(string(decoded), 10)
+	if !ok {
+		return nil, ErrInvalidExtendedKey
+	}
+	data := paddedBytes(bi, extendedKeyLength+4)
+	if len(data) != extendedKeyLength+4 {
+		return nil, ErrInvalidExtendedKey
+	}
+	payload := data[:extendedKeyLength]
+	checksum := common.Sha256D(payload)
+	if !bytes.Equal(checksum[:4], data[extendedKeyLength:]) {
+		return nil, errors.New("extended key checksum mismatch")
+	}
+
+	k := &ExtendedKey{
+		depth:       payload[4],
+		parentFP:    payload[5:9],
+		childNumber: binary.BigEndian.Uint32(payload[9:13]),
+		chainCode:   payload[13:45],
+	}
+	switch {
+	case bytes.Equal(payload[:4], xprvVersion) && payload[45] == 0x00:
+		k.key = payload[46:]
+		k.isPrivate = true
+	case bytes.Equal(payload[:4], xpubVersion):
+		k.key = payload[45:]
+		if _, err := DecodePoint(k.key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidExtendedKey
+	}
+	return k, nil
+}
+
+// ParseDerivationPath parses a path like "m/44'/2305'/0'/0/0" to child
+// indexes, a hardened index is marked by ' or h.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) > 0 && parts[0] == "m" {
+		parts = parts[1:]
+	}
+	indexes := make([]uint32, 0, len(parts))
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = HardenedKeyStart
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || index >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid derivation path %s", path)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
+
+// AccountPath returns the BIP44 path of an ELA account, whose children are
+// the external and change chains.
+func AccountPath(account uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'", ELACoinType, account)
+}
+
+// AddressPath returns the BIP44 path of an address key, change is 0 for the
+// external chain and 1 for the change chain.
+func AddressPath(account, change, index uint32) string {
+	return fmt.Sprintf("%s/%d/%d", AccountPath(account), change, index)
+}
+
+func fingerprint(pubKey []byte) []byte {
+	hash := sha256.Sum256(pubKey)
+	md160 := ripemd160.New()
+	md160.Write(hash[:])
+	return md160.Sum(nil)[:4]
+}
+
+func paddedBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
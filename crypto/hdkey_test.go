@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test vector 1 of SLIP-0010 for the nist256p1 curve
+func TestExtendedKey_Child(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	vectors := []struct {
+		path      string
+		chainCode string
+		private   string
+		public    string
+	}{
+		{
+			path:      "m",
+			chainCode: "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea",
+			private:   "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+			public:    "0266874dc6ade47b3ecd096745ca09bcd29638dd52c2c12117b11ed3e458cfa9e8",
+		},
+		{
+			path:      "m/0'",
+			chainCode: "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11",
+			private:   "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+			public:    "0384610f5ecffe8fda089363a41f56a5c7ffc1d81b59a612d0d649b2d22355590c",
+		},
+	}
+
+	master, err := NewMasterKey(seed)
+	assert.NoError(t, err)
+	for _, v := range vectors {
+		key, err := master.Derive(v.path)
+		assert.NoError(t, err)
+		assert.Equal(t, v.chainCode, hex.EncodeToString(key.chainCode))
+		private, err := key.PrivateKey()
+		assert.NoError(t, err)
+		assert.Equal(t, v.private, hex.EncodeToString(private))
+		public, err := key.PublicKey()
+		assert.NoError(t, err)
+		publicBytes, err := public.EncodePoint(true)
+		assert.NoError(t, err)
+		assert.Equal(t, v.public, hex.EncodeToString(publicBytes))
+	}
+}
+
+func TestExtendedKey_Neuter(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	assert.NoError(t, err)
+
+	account, err := master.Derive(AccountPath(0))
+	assert.NoError(t, err)
+	xpub, err := account.Neuter()
+	assert.NoError(t, err)
+
+	// non-hardened children derived from xpub are the same as from xprv
+	for i := uint32(0); i < 5; i++ {
+		private, err := account.Derive(fmt.Sprintf("0/%d", i))
+		assert.NoError(t, err)
+		public, err := xpub.Derive(fmt.Sprintf("0/%d", i))
+		assert.NoError(t, err)
+		privatePub, _ := private.PublicKey()
+		publicPub, _ := public.PublicKey()
+		assert.True(t, Equal(privatePub, publicPub))
+
+		neutered, _ := private.Neuter()
+		assert.Equal(t, neutered.String(), public.String())
+	}
+
+	_, err = xpub.Child(HardenedKeyStart)
+	assert.Equal(t, ErrDeriveHardenedFromPublic, err)
+}
+
+func TestParseExtendedKey(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	assert.NoError(t, err)
+	assert.Equal(t, "xprv9s21ZrQH143K3xbxu53vDH2NWbLKw5edQ3BCSX12Pknr1EA7QjAZPnd2jYvGvZ9RSwbcfeCZ5v2qZTTESRMTiAizzfQ1GUDeMWPyaXGcMfF", master.String())
+
+	key, err := master.Derive(AddressPath(0, 0, 1))
+	assert.NoError(t, err)
+	xpub, err := key.Neuter()
+	assert.NoError(t, err)
+	for _, k := range []*ExtendedKey{master, key, xpub} {
+		parsed, err := ParseExtendedKey(k.String())
+		assert.NoError(t, err)
+		assert.Equal(t, k, parsed)
+	}
+
+	_, err = ParseExtendedKey(master.String()[:len(master.String())-1] + "1")
+	assert.Error(t, err)
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	indexes, err := ParseDerivationPath("m/44'/2305'/0'/0/3")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{HardenedKeyStart + 44, HardenedKeyStart + 2305,
+		HardenedKeyStart, 0, 3}, indexes)
+
+	indexes, err = ParseDerivationPath("1h/2")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{HardenedKeyStart + 1, 2}, indexes)
+
+	_, err = ParseDerivationPath("m/a'")
+	assert.Error(t, err)
+	_, err = ParseDerivationPath("m/2147483648")
+	assert.Error(t, err)
+}
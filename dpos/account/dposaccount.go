@@ -2,22 +2,35 @@ package account
 
 import (
 	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/core/types"
 
 	"github.com/elastos/Elastos.ELA/crypto"
 )
 
+// DposAccount signs the proposals, votes and peer nonces of the arbiter, by
+// the keystore in process or by a remote signer.
 type DposAccount interface {
-	SignProposal(proposal *types.DPosProposal) ([]byte, error)
-	SignVote(vote *types.DPosProposalVote) ([]byte, error)
+	PubKey() *crypto.PublicKey
+	SignProposal(header *types.Header, proposal *types.DPosProposal) ([]byte, error)
+	SignVote(header *types.Header, proposal *types.DPosProposal, vote *types.DPosProposalVote) ([]byte, error)
 	SignPeerNonce(nonce []byte) (signature [64]byte)
 }
 
 type dposAccount struct {
 	*account.Account
+	ledger *SignLedger
 }
 
-func (a *dposAccount) SignProposal(proposal *types.DPosProposal) ([]byte, error) {
+// SignProposal signs the proposal of the block of the header, it is refused
+// if another proposal has been signed at the same height and view offset.
+func (a *dposAccount) SignProposal(header *types.Header, proposal *types.DPosProposal) ([]byte, error) {
+	if a.ledger != nil {
+		err := a.ledger.RecordProposal(header.Height, proposal.ViewOffset, proposal.Hash())
+		if err != nil {
+			return []byte{0}, err
+		}
+	}
 	privateKey := a.PrivKey()
 
 	signature, err := crypto.Sign(privateKey, proposal.Data())
@@ -28,7 +41,21 @@ func (a *dposAccount) SignProposal(proposal *types.DPosProposal) ([]byte, error)
 	return signature, nil
 }
 
-func (a *dposAccount) SignVote(vote *types.DPosProposalVote) ([]byte, error) {
+// SignVote signs the vote on the proposal of the block of the header, it is
+// refused if another vote has been signed on the proposals of the same
+// sponsor at the same height and view offset.
+func (a *dposAccount) SignVote(header *types.Header, proposal *types.DPosProposal,
+	vote *types.DPosProposalVote) ([]byte, error) {
+	if a.ledger != nil {
+		sponsor, err := common.HexStringToBytes(proposal.Sponsor)
+		if err != nil {
+			return []byte{0}, err
+		}
+		err = a.ledger.RecordVote(header.Height, proposal.ViewOffset, sponsor, vote.Hash())
+		if err != nil {
+			return []byte{0}, err
+		}
+	}
 	privateKey := a.PrivKey()
 
 	signature, err := crypto.Sign(privateKey, vote.Data())
@@ -52,7 +79,9 @@ func (a *dposAccount) SignPeerNonce(nonce []byte) (signature [64]byte) {
 	return signature
 }
 
-func NewDposAccount(password []byte) (DposAccount, error) {
+// NewDposAccount opens the default account of the keystore, the proposals and
+// votes signed are checked against and recorded in the ledger if not nil.
+func NewDposAccount(password []byte, ledger *SignLedger) (DposAccount, error) {
 	client, err := account.Open(account.KeystoreFileName, password)
 	if err != nil {
 		return nil, err
@@ -62,9 +91,12 @@ func NewDposAccount(password []byte) (DposAccount, error) {
 		return nil, err
 	}
 
-	return &dposAccount{acc}, nil
+	return &dposAccount{Account: acc, ledger: ledger}, nil
 }
 
-func NewDposAccountFromExisting(a *account.Account) DposAccount {
-	return &dposAccount{a}
+// NewDposAccountFromExisting returns the DposAccount of the account, the
+// proposals and votes signed are checked against and recorded in the ledger
+// if not nil.
+func NewDposAccountFromExisting(a *account.Account, ledger *SignLedger) DposAccount {
+	return &dposAccount{Account: a, ledger: ledger}
 }
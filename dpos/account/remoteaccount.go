@@ -0,0 +1,158 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/dpos/log"
+)
+
+const (
+	// signerDialTimeout is the timeout to connect the remote signer.
+	signerDialTimeout = 5 * time.Second
+
+	// signerCallTimeout is the timeout of a request to the remote signer.
+	signerCallTimeout = 5 * time.Second
+)
+
+// remoteAccount is the DposAccount signs by the remote signer, so that the
+// private key is never loaded into the process of the arbiter.  The signer
+// is connected again if the connection is lost.
+type remoteAccount struct {
+	network   string
+	address   string
+	publicKey *crypto.PublicKey
+
+	mtx    sync.Mutex
+	client *rpc.Client
+}
+
+func (a *remoteAccount) PubKey() *crypto.PublicKey {
+	return a.publicKey
+}
+
+func (a *remoteAccount) SignProposal(header *types.Header, proposal *types.DPosProposal) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := header.Serialize(buf); err != nil {
+		return []byte{0}, err
+	}
+	var reply SignReply
+	err := a.call("SignProposal", &SignProposalArgs{
+		Header:   buf.Bytes(),
+		Proposal: proposal.Data(),
+	}, &reply)
+	if err != nil {
+		return []byte{0}, err
+	}
+	return reply.Signature, nil
+}
+
+func (a *remoteAccount) SignVote(header *types.Header, proposal *types.DPosProposal,
+	vote *types.DPosProposalVote) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := header.Serialize(buf); err != nil {
+		return []byte{0}, err
+	}
+	var reply SignReply
+	err := a.call("SignVote", &SignVoteArgs{
+		Header:   buf.Bytes(),
+		Proposal: proposal.Data(),
+		Vote:     vote.Data(),
+	}, &reply)
+	if err != nil {
+		return []byte{0}, err
+	}
+	return reply.Signature, nil
+}
+
+func (a *remoteAccount) SignPeerNonce(nonce []byte) (signature [64]byte) {
+	var reply SignReply
+	err := a.call("SignPeerNonce", &SignPeerNonceArgs{Nonce: nonce}, &reply)
+	if err != nil {
+		log.Error("[SignPeerNonce] remote signer error:", err)
+		return signature
+	}
+	if len(reply.Signature) != len(signature) {
+		log.Error("[SignPeerNonce] invalid signature length from remote signer")
+		return signature
+	}
+	copy(signature[:], reply.Signature)
+	return signature
+}
+
+// call sends the request to the remote signer, and connects it again if the
+// connection has been shut down.
+func (a *remoteAccount) call(method string, args interface{}, reply interface{}) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.client == nil {
+		if err := a.connect(); err != nil {
+			return err
+		}
+	}
+	err := a.callTimeout(method, args, reply)
+	if err == rpc.ErrShutdown {
+		if err = a.connect(); err != nil {
+			return err
+		}
+		err = a.callTimeout(method, args, reply)
+	}
+	return err
+}
+
+func (a *remoteAccount) callTimeout(method string, args interface{}, reply interface{}) error {
+	call := a.client.Go(SignerServiceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-time.After(signerCallTimeout):
+		// The reply can not be matched any more, drop the connection.
+		a.client.Close()
+		a.client = nil
+		return errors.New("remote signer request timeout")
+	}
+}
+
+func (a *remoteAccount) connect() error {
+	if a.client != nil {
+		a.client.Close()
+		a.client = nil
+	}
+	conn, err := net.DialTimeout(a.network, a.address, signerDialTimeout)
+	if err != nil {
+		return err
+	}
+	a.client = jsonrpc.NewClient(conn)
+	return nil
+}
+
+// NewRemoteAccount connects the remote signer at the address parsed by
+// ParseSignerAddress, and returns the DposAccount signs by it.  The public key of the signer must be the arbiter's.
+func NewRemoteAccount(address string, publicKey []byte) (DposAccount, error) {
+	network, addr, err := ParseSignerAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	a := &remoteAccount{network: network, address: addr}
+
+	var reply PublicKeyReply
+	if err := a.call("PublicKey", &PublicKeyArgs{}, &reply); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(reply.PublicKey, publicKey) {
+		return nil, errors.New("public key of the remote signer mismatch")
+	}
+	a.publicKey, err = crypto.DecodePoint(reply.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,238 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+)
+
+// SignerServiceName is the name of the remote signer service registered to
+// the RPC server.
+const SignerServiceName = "DposSigner"
+
+// peerNonceLength is the length of the nonces signed for the peers, which
+// are the version nonces and the key exchange hashes.
+const peerNonceLength = 32
+
+// SignProposalArgs is the request to sign the proposal of the block of the
+// header, the proposal is serialized without signature.  The height of the
+// proposal is taken from the header, which must be of the proposed block.
+type SignProposalArgs struct {
+	Header   []byte
+	Proposal []byte
+}
+
+// SignVoteArgs is the request to sign the vote on the proposal of the block
+// of the header, both serialized without signature.  The height of the vote
+// is taken from the header, which must be of the proposed block.
+type SignVoteArgs struct {
+	Header   []byte
+	Proposal []byte
+	Vote     []byte
+}
+
+// SignPeerNonceArgs is the request to sign the nonce for a peer.
+type SignPeerNonceArgs struct {
+	Nonce []byte
+}
+
+// PublicKeyArgs is the request of the public key of the signer.
+type PublicKeyArgs struct{}
+
+// SignReply is the signature replied by the signer.
+type SignReply struct {
+	Signature []byte
+}
+
+// PublicKeyReply is the compressed public key replied by the signer.
+type PublicKeyReply struct {
+	PublicKey []byte
+}
+
+// SignerService signs the proposals, votes and peer nonces requested by the
+// remote account of an arbiter by the account it serves.  The requests are
+// checked against the policy of the signer before signing: the proposals and
+// votes must be sponsored or signed by the account itself, and the account
+// refuses double signing if it is backed by a sign ledger.
+type SignerService struct {
+	account   DposAccount
+	publicKey []byte
+}
+
+// NewSignerService creates the signer service of the account.
+func NewSignerService(account DposAccount) (*SignerService, error) {
+	publicKey, err := account.PubKey().EncodePoint(true)
+	if err != nil {
+		return nil, err
+	}
+	return &SignerService{account: account, publicKey: publicKey}, nil
+}
+
+// PublicKey replies the public key of the account.
+func (s *SignerService) PublicKey(args *PublicKeyArgs, reply *PublicKeyReply) error {
+	reply.PublicKey = s.publicKey
+	return nil
+}
+
+// SignProposal signs the proposal sponsored by the account.
+func (s *SignerService) SignProposal(args *SignProposalArgs, reply *SignReply) error {
+	var proposal types.DPosProposal
+	if err := proposal.DeserializeUnSigned(bytes.NewReader(args.Proposal)); err != nil {
+		return errors.New("invalid proposal: " + err.Error())
+	}
+	if !s.isSelf(proposal.Sponsor) {
+		return errors.New("proposal not sponsored by the signer")
+	}
+	header, err := proposedHeader(args.Header, &proposal)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.account.SignProposal(header, &proposal)
+	if err != nil {
+		return err
+	}
+	reply.Signature = signature
+	return nil
+}
+
+// SignVote signs the vote of the account on the proposal.
+func (s *SignerService) SignVote(args *SignVoteArgs, reply *SignReply) error {
+	var proposal types.DPosProposal
+	if err := proposal.DeserializeUnSigned(bytes.NewReader(args.Proposal)); err != nil {
+		return errors.New("invalid proposal: " + err.Error())
+	}
+	var vote types.DPosProposalVote
+	if err := vote.DeserializeUnsigned(bytes.NewReader(args.Vote)); err != nil {
+		return errors.New("invalid vote: " + err.Error())
+	}
+	if !s.isSelf(vote.Signer) {
+		return errors.New("vote not signed by the signer")
+	}
+	if !vote.ProposalHash.IsEqual(proposal.Hash()) {
+		return errors.New("vote not on the proposal")
+	}
+	header, err := proposedHeader(args.Header, &proposal)
+	if err != nil {
+		return err
+	}
+
+	signature, err := s.account.SignVote(header, &proposal, &vote)
+	if err != nil {
+		return err
+	}
+	reply.Signature = signature
+	return nil
+}
+
+// SignPeerNonce signs the nonce for a peer.
+func (s *SignerService) SignPeerNonce(args *SignPeerNonceArgs, reply *SignReply) error {
+	if len(args.Nonce) != peerNonceLength {
+		return errors.New("invalid peer nonce length")
+	}
+	signature := s.account.SignPeerNonce(args.Nonce)
+	reply.Signature = signature[:]
+	return nil
+}
+
+// proposedHeader deserializes the header of the proposed block, so that the
+// height checked by the sign ledger is the one of the block signed rather
+// than a height given by the client.
+func proposedHeader(data []byte, proposal *types.DPosProposal) (*types.Header, error) {
+	var header types.Header
+	if err := header.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, errors.New("invalid header: " + err.Error())
+	}
+	if !header.Hash().IsEqual(proposal.BlockHash) {
+		return nil, errors.New("header not of the proposed block")
+	}
+	return &header, nil
+}
+
+func (s *SignerService) isSelf(publicKey string) bool {
+	key, err := common.HexStringToBytes(publicKey)
+	return err == nil && bytes.Equal(key, s.publicKey)
+}
+
+// ServeSigner serves the signer service of the account on the listener until
+// the listener is closed.
+func ServeSigner(listener net.Listener, account DposAccount) error {
+	service, err := NewSignerService(account)
+	if err != nil {
+		return err
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName(SignerServiceName, service); err != nil {
+		return err
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// ParseSignerAddress parses the address of the remote signer in the form of
+// unix:path/to/socket or unix:///path/to/socket, and returns the network and
+// the address to dial or listen.  Only unix sockets are supported, since the
+// signer service has no authentication other than the permission of the
+// socket.
+func ParseSignerAddress(address string) (network, addr string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "unix" {
+		return "", "", errors.New("unsupported signer address " + address +
+			", use unix:path/to/socket or unix:///path/to/socket")
+	}
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if path == "" {
+		return "", "", errors.New("missing socket path of signer address " + address)
+	}
+	return u.Scheme, path, nil
+}
+
+// ListenSigner listens on the unix socket at the address parsed by
+// ParseSignerAddress.  The socket is created in a directory accessible by the
+// owner only, which is created if not exists, so no other user can connect
+// the socket from the moment it is created.
+func ListenSigner(address string) (net.Listener, error) {
+	network, path, err := ParseSignerAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("directory %s of the signer socket is accessible"+
+			" by other users, change its mode to 0700", dir)
+	}
+
+	// Remove the socket left by the last run.
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen(network, path)
+}
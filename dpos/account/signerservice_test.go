@@ -0,0 +1,284 @@
+package account
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/crypto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSigner returns the signer service of a new account backed by the
+// ledger, and the public key of the account in hex.
+func newTestSigner(t *testing.T, ledger *SignLedger) (*SignerService, string) {
+	acc, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	service, err := NewSignerService(NewDposAccountFromExisting(acc, ledger))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return service, common.BytesToHexString(service.publicKey)
+}
+
+func randomPublicKey() string {
+	_, publicKey, _ := crypto.GenerateKeyPair()
+	key, _ := publicKey.EncodePoint(true)
+	return common.BytesToHexString(key)
+}
+
+// newTestHeader returns the header of a block at the height, the blocks of
+// different nonces at the same height are conflicting.
+func newTestHeader(height, nonce uint32) (*types.Header, []byte) {
+	header := &types.Header{Height: height, Nonce: nonce}
+	buf := new(bytes.Buffer)
+	header.Serialize(buf)
+	return header, buf.Bytes()
+}
+
+func TestSignerService_SignProposal(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger"))
+	defer ledger.Close()
+	service, self := newTestSigner(t, ledger)
+
+	header, headerData := newTestHeader(10, 1)
+	proposal := &types.DPosProposal{Sponsor: self, BlockHash: header.Hash()}
+	var reply SignReply
+	assert.NoError(t, service.SignProposal(&SignProposalArgs{
+		Header: headerData, Proposal: proposal.Data()}, &reply))
+	pk, _ := crypto.DecodePoint(service.publicKey)
+	assert.NoError(t, crypto.Verify(*pk, proposal.Data(), reply.Signature))
+
+	// the same proposal can be signed again
+	assert.NoError(t, service.SignProposal(&SignProposalArgs{
+		Header: headerData, Proposal: proposal.Data()}, &reply))
+
+	// the proposals sponsored by others are refused
+	otherHeader, otherHeaderData := newTestHeader(11, 1)
+	other := &types.DPosProposal{Sponsor: randomPublicKey(),
+		BlockHash: otherHeader.Hash()}
+	assert.Error(t, service.SignProposal(&SignProposalArgs{
+		Header: otherHeaderData, Proposal: other.Data()}, &reply))
+
+	// the invalid proposals and headers are refused
+	assert.Error(t, service.SignProposal(&SignProposalArgs{
+		Header: headerData, Proposal: []byte{1, 2, 3}}, &reply))
+	assert.Error(t, service.SignProposal(&SignProposalArgs{
+		Header: []byte{1, 2, 3}, Proposal: proposal.Data()}, &reply))
+
+	// another proposal at the same height and view offset is refused by the
+	// sign ledger
+	conflictingHeader, conflictingHeaderData := newTestHeader(10, 2)
+	conflicting := &types.DPosProposal{Sponsor: self,
+		BlockHash: conflictingHeader.Hash()}
+	assert.Equal(t, ErrDoubleSign, service.SignProposal(&SignProposalArgs{
+		Header: conflictingHeaderData, Proposal: conflicting.Data()}, &reply))
+
+	// the height can not be changed by a header of another block
+	assert.Error(t, service.SignProposal(&SignProposalArgs{
+		Header: otherHeaderData, Proposal: conflicting.Data()}, &reply))
+
+	conflicting.ViewOffset = 1
+	assert.NoError(t, service.SignProposal(&SignProposalArgs{
+		Header: conflictingHeaderData, Proposal: conflicting.Data()}, &reply))
+}
+
+func TestSignerService_SignVote(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger"))
+	defer ledger.Close()
+	service, self := newTestSigner(t, ledger)
+
+	header, headerData := newTestHeader(10, 1)
+	proposal := &types.DPosProposal{Sponsor: randomPublicKey(),
+		BlockHash: header.Hash()}
+	vote := &types.DPosProposalVote{ProposalHash: proposal.Hash(),
+		Signer: self, Accept: true}
+	var reply SignReply
+	assert.NoError(t, service.SignVote(&SignVoteArgs{Header: headerData,
+		Proposal: proposal.Data(), Vote: vote.Data()}, &reply))
+	pk, _ := crypto.DecodePoint(service.publicKey)
+	assert.NoError(t, crypto.Verify(*pk, vote.Data(), reply.Signature))
+
+	// the same vote can be signed again
+	assert.NoError(t, service.SignVote(&SignVoteArgs{Header: headerData,
+		Proposal: proposal.Data(), Vote: vote.Data()}, &reply))
+
+	// the votes of others are refused
+	other := &types.DPosProposalVote{ProposalHash: proposal.Hash(),
+		Signer: randomPublicKey(), Accept: true}
+	assert.Error(t, service.SignVote(&SignVoteArgs{Header: headerData,
+		Proposal: proposal.Data(), Vote: other.Data()}, &reply))
+
+	// the votes not on the proposal are refused
+	anotherHeader, anotherHeaderData := newTestHeader(10, 2)
+	another := &types.DPosProposal{Sponsor: proposal.Sponsor,
+		BlockHash: anotherHeader.Hash()}
+	assert.Error(t, service.SignVote(&SignVoteArgs{Header: anotherHeaderData,
+		Proposal: another.Data(), Vote: vote.Data()}, &reply))
+
+	// the invalid votes and headers are refused
+	assert.Error(t, service.SignVote(&SignVoteArgs{Header: headerData,
+		Proposal: proposal.Data(), Vote: []byte{1, 2, 3}}, &reply))
+	assert.Error(t, service.SignVote(&SignVoteArgs{Header: []byte{1, 2, 3},
+		Proposal: proposal.Data(), Vote: vote.Data()}, &reply))
+
+	// another vote on the proposals of the same sponsor at the same height
+	// and view offset is refused by the sign ledger
+	reject := &types.DPosProposalVote{ProposalHash: proposal.Hash(),
+		Signer: self, Accept: false}
+	assert.Equal(t, ErrDoubleSign, service.SignVote(&SignVoteArgs{
+		Header: headerData, Proposal: proposal.Data(), Vote: reject.Data()},
+		&reply))
+	voteAnother := &types.DPosProposalVote{ProposalHash: another.Hash(),
+		Signer: self, Accept: true}
+	assert.Equal(t, ErrDoubleSign, service.SignVote(&SignVoteArgs{
+		Header: anotherHeaderData, Proposal: another.Data(),
+		Vote: voteAnother.Data()}, &reply))
+
+	// the height can not be changed by a header of another block
+	_, otherHeaderData := newTestHeader(11, 2)
+	assert.Error(t, service.SignVote(&SignVoteArgs{Header: otherHeaderData,
+		Proposal: another.Data(), Vote: voteAnother.Data()}, &reply))
+}
+
+func TestSignerService_SignPeerNonce(t *testing.T) {
+	service, _ := newTestSigner(t, nil)
+
+	var reply SignReply
+	nonce := make([]byte, peerNonceLength)
+	assert.NoError(t, service.SignPeerNonce(&SignPeerNonceArgs{Nonce: nonce}, &reply))
+	pk, _ := crypto.DecodePoint(service.publicKey)
+	assert.NoError(t, crypto.Verify(*pk, nonce, reply.Signature))
+
+	assert.Error(t, service.SignPeerNonce(&SignPeerNonceArgs{
+		Nonce: make([]byte, peerNonceLength+1)}, &reply))
+}
+
+func TestParseSignerAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		path    string
+		valid   bool
+	}{
+		{"unix:ela-signer/signer.sock", "ela-signer/signer.sock", true},
+		{"unix:///tmp/signer.sock", "/tmp/signer.sock", true},
+		{"unix:", "", false},
+		{"tcp://127.0.0.1:20339", "", false},
+		{"/tmp/signer.sock", "", false},
+	}
+
+	for _, test := range tests {
+		network, path, err := ParseSignerAddress(test.address)
+		if !test.valid {
+			assert.Error(t, err, test.address)
+			continue
+		}
+		assert.NoError(t, err, test.address)
+		assert.Equal(t, "unix", network)
+		assert.Equal(t, test.path, path)
+	}
+}
+
+func TestListenSigner(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+
+	// the directory of the socket is created accessible by the owner only
+	path := filepath.Join(dir, "signer", "signer.sock")
+	listener, err := ListenSigner("unix://" + path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	listener.Close()
+	info, err := os.Stat(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// the socket left by the last run is replaced
+	assert.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	listener, err = ListenSigner("unix://" + path)
+	if assert.NoError(t, err) {
+		listener.Close()
+	}
+
+	// the directory accessible by other users is refused
+	shared := filepath.Join(dir, "shared")
+	assert.NoError(t, os.Mkdir(shared, 0700))
+	assert.NoError(t, os.Chmod(shared, 0755))
+	_, err = ListenSigner("unix://" + filepath.Join(shared, "signer.sock"))
+	assert.Error(t, err)
+}
+
+func TestRemoteAccount_Reconnect(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger"))
+	defer ledger.Close()
+
+	acc, err := account.NewAccount()
+	if !assert.NoError(t, err) {
+		return
+	}
+	address := "unix://" + filepath.Join(dir, "signer", "signer.sock")
+	listener, err := ListenSigner(address)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	go ServeSigner(listener, NewDposAccountFromExisting(acc, ledger))
+
+	publicKey, _ := acc.PubKey().EncodePoint(true)
+	_, err = NewRemoteAccount(address, []byte{1, 2, 3})
+	assert.Error(t, err)
+	dposAccount, err := NewRemoteAccount(address, publicKey)
+	if !assert.NoError(t, err) {
+		return
+	}
+	remote := dposAccount.(*remoteAccount)
+
+	nonce := make([]byte, peerNonceLength)
+	signature := remote.SignPeerNonce(nonce)
+	assert.NoError(t, crypto.Verify(*acc.PubKey(), nonce, signature[:]))
+
+	// the signer is connected again after the connection is shut down
+	client := remote.client
+	client.Close()
+	signature = remote.SignPeerNonce(nonce)
+	assert.NoError(t, crypto.Verify(*acc.PubKey(), nonce, signature[:]))
+	assert.True(t, remote.client != client)
+
+	// the policy of the signer applies to the remote account
+	self := common.BytesToHexString(publicKey)
+	header, _ := newTestHeader(10, 1)
+	proposal := &types.DPosProposal{Sponsor: self, BlockHash: header.Hash()}
+	_, err = remote.SignProposal(header, proposal)
+	assert.NoError(t, err)
+	conflictingHeader, _ := newTestHeader(10, 2)
+	conflicting := &types.DPosProposal{Sponsor: self,
+		BlockHash: conflictingHeader.Hash()}
+	_, err = remote.SignProposal(conflictingHeader, conflicting)
+	if assert.Error(t, err) {
+		assert.Equal(t, ErrDoubleSign.Error(), err.Error())
+	}
+
+	// the signer can not be connected after it stopped
+	listener.Close()
+	remote.client.Close()
+	_, err = remote.SignProposal(header, proposal)
+	assert.Error(t, err)
+	_, err = net.Dial("unix", filepath.Join(dir, "signer", "signer.sock"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+package account
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+const (
+	// ProposalRecord is the type of the records of the signed proposals.
+	ProposalRecord = "proposal"
+
+	// VoteRecord is the type of the records of the signed votes.
+	VoteRecord = "vote"
+
+	proposalPrefix byte = 0x01
+	votePrefix     byte = 0x02
+)
+
+// ErrDoubleSign is the error returned when signing a proposal or vote
+// conflicting with the one already signed at the same height and view offset.
+var ErrDoubleSign = errors.New("conflicting with the message already signed at the same height and view offset")
+
+// SignRecord is a signed proposal or vote in the sign ledger, the sponsor is
+// the sponsor of the proposal voted.
+type SignRecord struct {
+	Type       string
+	Height     uint32
+	ViewOffset uint32
+	Sponsor    string `json:",omitempty"`
+	Hash       string
+}
+
+// SignLedger is the persistent record of the proposals and votes signed by
+// the arbiter, which is consulted before signing to refuse signing two
+// different proposals or votes at the same height and view offset.  Every
+// record is written to disk before the message is signed, so the protection
+// survives crashes and restarts.
+type SignLedger struct {
+	mtx sync.Mutex
+	db  *leveldb.DB
+}
+
+// NewSignLedger opens the sign ledger stored in the directory, it is created
+// if not exists.  The ledger is locked by the process opened it, so the same
+// arbiter can not be run twice on the same data directory.
+func NewSignLedger(file string) (*SignLedger, error) {
+	db, err := leveldb.OpenFile(file, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SignLedger{db: db}, nil
+}
+
+// Close closes the sign ledger.
+func (l *SignLedger) Close() error {
+	return l.db.Close()
+}
+
+// RecordProposal records the hash of the proposal to sign at the height and
+// view offset, ErrDoubleSign is returned if a different proposal has been
+// signed there.  Recording the same proposal again is allowed.
+func (l *SignLedger) RecordProposal(height, viewOffset uint32, hash common.Uint256) error {
+	return l.record(recordKey(proposalPrefix, height, viewOffset, nil), hash)
+}
+
+// RecordVote records the hash of the vote to sign for the proposal of the
+// sponsor at the height and view offset, ErrDoubleSign is returned if a
+// different vote has been signed for the proposals of the sponsor there.
+// Recording the same vote again is allowed.
+func (l *SignLedger) RecordVote(height, viewOffset uint32, sponsor []byte,
+	hash common.Uint256) error {
+	return l.record(recordKey(votePrefix, height, viewOffset, sponsor), hash)
+}
+
+func (l *SignLedger) record(key []byte, hash common.Uint256) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	signed, err := l.db.Get(key, nil)
+	if err == nil {
+		if !bytes.Equal(signed, hash[:]) {
+			return ErrDoubleSign
+		}
+		return nil
+	}
+	if err != leveldb.ErrNotFound {
+		return err
+	}
+	return l.db.Put(key, hash[:], &opt.WriteOptions{Sync: true})
+}
+
+// Export writes all the records of the ledger to w in JSON, which can be
+// imported by the ledger of the new machine when migrating the arbiter.
+func (l *SignLedger) Export(w io.Writer) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	records := make([]SignRecord, 0)
+	iter := l.db.NewIterator(nil, nil)
+	for iter.Next() {
+		record, err := parseRecord(iter.Key(), iter.Value())
+		if err != nil {
+			iter.Release()
+			return 0, err
+		}
+		records = append(records, *record)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return len(records), encoder.Encode(records)
+}
+
+// Import reads the records exported by Export from r and merges them into
+// the ledger.  Nothing is imported if any of the records conflicts with the
+// one in the ledger.
+func (l *SignLedger) Import(r io.Reader) (int, error) {
+	var records []SignRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return 0, err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	batch := new(leveldb.Batch)
+	imported := make(map[string][]byte)
+	for _, record := range records {
+		key, hash, err := record.keyValue()
+		if err != nil {
+			return 0, err
+		}
+		signed, ok := imported[string(key)]
+		if !ok {
+			signed, err = l.db.Get(key, nil)
+		}
+		if err == nil {
+			if !bytes.Equal(signed, hash) {
+				return 0, fmt.Errorf("%s at height %d view offset %d: %s",
+					record.Type, record.Height, record.ViewOffset, ErrDoubleSign)
+			}
+			continue
+		}
+		if err != leveldb.ErrNotFound {
+			return 0, err
+		}
+		imported[string(key)] = hash
+		batch.Put(key, hash)
+	}
+	return batch.Len(), l.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// recordKey returns the key of the record, the height is big endian so that
+// the records are ordered by height.
+func recordKey(prefix byte, height, viewOffset uint32, sponsor []byte) []byte {
+	key := make([]byte, 9, 9+len(sponsor))
+	key[0] = prefix
+	binary.BigEndian.PutUint32(key[1:5], height)
+	binary.BigEndian.PutUint32(key[5:], viewOffset)
+	return append(key, sponsor...)
+}
+
+func parseRecord(key, value []byte) (*SignRecord, error) {
+	if len(key) < 9 || len(value) != common.UINT256SIZE {
+		return nil, errors.New("invalid sign ledger record")
+	}
+	record := &SignRecord{
+		Height:     binary.BigEndian.Uint32(key[1:5]),
+		ViewOffset: binary.BigEndian.Uint32(key[5:]),
+		Hash:       common.BytesToHexString(value),
+	}
+	switch key[0] {
+	case proposalPrefix:
+		record.Type = ProposalRecord
+	case votePrefix:
+		record.Type = VoteRecord
+		record.Sponsor = common.BytesToHexString(key[9:])
+	default:
+		return nil, errors.New("invalid sign ledger record type")
+	}
+	return record, nil
+}
+
+func (r *SignRecord) keyValue() ([]byte, []byte, error) {
+	var prefix byte
+	var sponsor []byte
+	switch r.Type {
+	case ProposalRecord:
+		prefix = proposalPrefix
+	case VoteRecord:
+		prefix = votePrefix
+		var err error
+		sponsor, err = common.HexStringToBytes(r.Sponsor)
+		if err != nil || len(sponsor) == 0 {
+			return nil, nil, errors.New("invalid sign record sponsor " + r.Sponsor)
+		}
+	default:
+		return nil, nil, errors.New("invalid sign record type " + r.Type)
+	}
+	hash, err := common.HexStringToBytes(r.Hash)
+	if err != nil || len(hash) != common.UINT256SIZE {
+		return nil, nil, errors.New("invalid sign record hash " + r.Hash)
+	}
+	return recordKey(prefix, r.Height, r.ViewOffset, sponsor), hash, nil
+}
@@ -0,0 +1,193 @@
+package account
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupSignLedger returns a temporary directory for sign ledgers, and the
+// function to remove it.
+func setupSignLedger(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "signledger")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func openSignLedger(t *testing.T, path string) *SignLedger {
+	ledger, err := NewSignLedger(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return ledger
+}
+
+func TestSignLedger_RecordProposal(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger"))
+	defer ledger.Close()
+
+	hash1 := common.Uint256{1}
+	hash2 := common.Uint256{2}
+	assert.NoError(t, ledger.RecordProposal(10, 0, hash1))
+
+	// the same proposal can be signed again
+	assert.NoError(t, ledger.RecordProposal(10, 0, hash1))
+
+	// a different proposal at the same height and view offset is refused
+	assert.Equal(t, ErrDoubleSign, ledger.RecordProposal(10, 0, hash2))
+
+	// a different proposal at another view offset or height is allowed
+	assert.NoError(t, ledger.RecordProposal(10, 1, hash2))
+	assert.NoError(t, ledger.RecordProposal(11, 0, hash2))
+
+	// the votes are recorded separately from the proposals
+	assert.NoError(t, ledger.RecordVote(10, 0, []byte{1}, hash2))
+}
+
+func TestSignLedger_RecordVote(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger"))
+	defer ledger.Close()
+
+	sponsor1 := []byte{1, 2, 3}
+	sponsor2 := []byte{4, 5, 6}
+	hash1 := common.Uint256{1}
+	hash2 := common.Uint256{2}
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor1, hash1))
+
+	// the same vote can be signed again
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor1, hash1))
+
+	// a different vote for the proposals of the same sponsor is refused
+	assert.Equal(t, ErrDoubleSign, ledger.RecordVote(10, 0, sponsor1, hash2))
+
+	// the votes for the proposals of another sponsor, view offset or height
+	// are allowed
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor2, hash2))
+	assert.NoError(t, ledger.RecordVote(10, 1, sponsor1, hash2))
+	assert.NoError(t, ledger.RecordVote(11, 0, sponsor1, hash2))
+
+	// the proposals are recorded separately from the votes
+	assert.NoError(t, ledger.RecordProposal(10, 0, hash2))
+}
+
+func TestSignLedger_Reopen(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	path := filepath.Join(dir, "ledger")
+	ledger := openSignLedger(t, path)
+
+	sponsor := []byte{1, 2, 3}
+	hash1 := common.Uint256{1}
+	hash2 := common.Uint256{2}
+	assert.NoError(t, ledger.RecordProposal(10, 0, hash1))
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor, hash1))
+
+	// the ledger is locked by the process opened it
+	_, err := NewSignLedger(path)
+	assert.Error(t, err)
+	assert.NoError(t, ledger.Close())
+
+	// the records survive a restart
+	ledger = openSignLedger(t, path)
+	defer ledger.Close()
+	assert.NoError(t, ledger.RecordProposal(10, 0, hash1))
+	assert.Equal(t, ErrDoubleSign, ledger.RecordProposal(10, 0, hash2))
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor, hash1))
+	assert.Equal(t, ErrDoubleSign, ledger.RecordVote(10, 0, sponsor, hash2))
+}
+
+func TestSignLedger_ExportImport(t *testing.T) {
+	dir, teardown := setupSignLedger(t)
+	defer teardown()
+	ledger := openSignLedger(t, filepath.Join(dir, "ledger1"))
+	defer ledger.Close()
+
+	sponsor := []byte{1, 2, 3}
+	hash1 := common.Uint256{1}
+	hash2 := common.Uint256{2}
+	assert.NoError(t, ledger.RecordProposal(11, 0, hash1))
+	assert.NoError(t, ledger.RecordProposal(10, 1, hash2))
+	assert.NoError(t, ledger.RecordVote(10, 0, sponsor, hash1))
+
+	buf := new(bytes.Buffer)
+	count, err := ledger.Export(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	exported := buf.Bytes()
+
+	// the records are ordered by type and height
+	var records []SignRecord
+	assert.NoError(t, json.Unmarshal(exported, &records))
+	assert.Equal(t, []SignRecord{
+		{Type: ProposalRecord, Height: 10, ViewOffset: 1,
+			Hash: common.BytesToHexString(hash2[:])},
+		{Type: ProposalRecord, Height: 11, ViewOffset: 0,
+			Hash: common.BytesToHexString(hash1[:])},
+		{Type: VoteRecord, Height: 10, ViewOffset: 0,
+			Sponsor: common.BytesToHexString(sponsor),
+			Hash:    common.BytesToHexString(hash1[:])},
+	}, records)
+
+	// the imported records protect the new ledger
+	ledger2 := openSignLedger(t, filepath.Join(dir, "ledger2"))
+	defer ledger2.Close()
+	assert.NoError(t, ledger2.RecordProposal(12, 0, hash1))
+	count, err = ledger2.Import(bytes.NewReader(exported))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, ErrDoubleSign, ledger2.RecordProposal(11, 0, hash2))
+	assert.Equal(t, ErrDoubleSign, ledger2.RecordVote(10, 0, sponsor, hash2))
+	assert.NoError(t, ledger2.RecordProposal(12, 0, hash1))
+
+	// importing the same records again imports nothing
+	count, err = ledger2.Import(bytes.NewReader(exported))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// nothing is imported if any record conflicts with the ledger
+	ledger3 := openSignLedger(t, filepath.Join(dir, "ledger3"))
+	defer ledger3.Close()
+	assert.NoError(t, ledger3.RecordVote(10, 0, sponsor, hash2))
+	_, err = ledger3.Import(bytes.NewReader(exported))
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), ErrDoubleSign.Error()))
+	}
+	count, err = ledger3.Export(new(bytes.Buffer))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, ledger3.RecordProposal(11, 0, hash2))
+
+	// the records conflicting with each other are refused
+	conflicting, _ := json.Marshal([]SignRecord{
+		{Type: ProposalRecord, Height: 20, Hash: common.BytesToHexString(hash1[:])},
+		{Type: ProposalRecord, Height: 20, Hash: common.BytesToHexString(hash2[:])},
+	})
+	_, err = ledger3.Import(bytes.NewReader(conflicting))
+	assert.Error(t, err)
+	assert.NoError(t, ledger3.RecordProposal(20, 0, hash2))
+
+	// invalid records are refused
+	for _, record := range []SignRecord{
+		{Type: "block", Height: 30, Hash: common.BytesToHexString(hash1[:])},
+		{Type: VoteRecord, Height: 30, Hash: common.BytesToHexString(hash1[:])},
+		{Type: ProposalRecord, Height: 30, Hash: "0102"},
+	} {
+		data, _ := json.Marshal([]SignRecord{record})
+		_, err = ledger3.Import(bytes.NewReader(data))
+		assert.Error(t, err)
+	}
+}
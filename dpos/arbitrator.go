@@ -13,20 +13,23 @@ import (
 	"github.com/elastos/Elastos.ELA/dpos/manager"
 	"github.com/elastos/Elastos.ELA/dpos/p2p/peer"
 	"github.com/elastos/Elastos.ELA/dpos/store"
+	"github.com/elastos/Elastos.ELA/errors"
 	"github.com/elastos/Elastos.ELA/node"
 	"github.com/elastos/Elastos.ELA/protocol"
 )
 
 type ArbitratorConfig struct {
-	EnableEventLog    bool
-	EnableEventRecord bool
-	Store             interfaces.IDposStore
+	EnableEventLog     bool
+	EnableEventRecord  bool
+	EnableArbiterStats bool
+	Store              interfaces.IDposStore
 }
 
 type Arbitrator interface {
 	interfaces.NewBlocksListener
 	interfaces.ArbitratorsListener
 	protocol.TxnPoolListener
+	protocol.TxnEvictedListener
 
 	Start()
 	Stop() error
@@ -65,6 +68,24 @@ func (a *arbitrator) OnIllegalBlockTxnReceived(txn *types.Transaction) {
 	}
 }
 
+func (a *arbitrator) OnTxnEvicted(txn *types.Transaction) {
+	if !txn.IsIllegalProposalTx() && !txn.IsIllegalVoteTx() &&
+		!txn.IsIllegalBlockTx() {
+		return
+	}
+	// arbiters do not accept blocks missing the evidences they know of, so
+	// the evicted evidence is sent to pool again. It is done outside of the
+	// pool operation evicting it, and fails if pool is still full.
+	log.Warn("[OnTxnEvicted] illegal evidence transaction evicted:",
+		txn.Hash().String())
+	go func() {
+		if code := a.dposManager.AppendToTxnPool(txn); code != errors.Success {
+			log.Warn("[OnTxnEvicted] resend illegal evidence transaction "+
+				"failed:", code)
+		}
+	}()
+}
+
 func (a *arbitrator) OnBlockReceived(b *types.Block, confirmed bool) {
 	log.Info("[OnBlockReceived] listener received block")
 	a.network.PostBlockReceivedTask(b, confirmed)
@@ -87,13 +108,7 @@ func (a *arbitrator) changeViewLoop() {
 	}
 }
 
-func NewArbitrator(password []byte, arConfig ArbitratorConfig) (Arbitrator, error) {
-	dposAccount, err := account.NewDposAccount(password)
-	if err != nil {
-		log.Error("Init dpos account error")
-		return nil, err
-	}
-
+func NewArbitrator(dposAccount account.DposAccount, arConfig ArbitratorConfig) (Arbitrator, error) {
 	dposManager := manager.NewManager(config.Parameters.ArbiterConfiguration.Name, blockchain.DefaultLedger.Arbitrators)
 	pk := config.Parameters.GetArbiterID()
 	var id peer.PID
@@ -118,6 +133,12 @@ func NewArbitrator(password []byte, arConfig ArbitratorConfig) (Arbitrator, erro
 		eventMonitor.RegisterListener(eventRecorder)
 	}
 
+	if arConfig.EnableArbiterStats {
+		arbiterStats := &store.ArbiterStatistics{}
+		arbiterStats.Initialize(arConfig.Store, blockchain.DefaultLedger.Arbitrators)
+		eventMonitor.RegisterListener(arbiterStats)
+	}
+
 	dposHandlerSwitch := manager.NewHandler(network, dposManager, eventMonitor)
 
 	consensus := manager.NewConsensus(dposManager, time.Duration(config.Parameters.ArbiterConfiguration.SignTolerance)*time.Second, dposHandlerSwitch)
@@ -412,17 +412,18 @@ func NewDposNetwork(pid peer.PID, listener manager.NetworkEventListener, dposAcc
 	notifier := p2p.NewNotifier(p2p.NFNetStabled|p2p.NFBadNetwork, network.notifyFlag)
 
 	server, err := p2p.NewServer(&p2p.Config{
-		PID:              pid,
-		MagicNumber:      config.Parameters.ArbiterConfiguration.Magic,
-		ProtocolVersion:  config.Parameters.ArbiterConfiguration.ProtocolVersion,
-		Services:         config.Parameters.ArbiterConfiguration.Services,
-		DefaultPort:      config.Parameters.ArbiterConfiguration.NodePort,
-		MakeEmptyMessage: makeEmptyMessage,
-		HandleMessage:    network.handleMessage,
-		PingNonce:        network.getCurrentHeight,
-		PongNonce:        network.getCurrentHeight,
-		SignNonce:        dposAccount.SignPeerNonce,
-		StateNotifier:    notifier,
+		PID:               pid,
+		MagicNumber:       config.Parameters.ArbiterConfiguration.Magic,
+		ProtocolVersion:   config.Parameters.ArbiterConfiguration.ProtocolVersion,
+		Services:          config.Parameters.ArbiterConfiguration.Services | peer.SFEncryption,
+		RequireEncryption: config.Parameters.ArbiterConfiguration.RequireEncryption,
+		DefaultPort:       config.Parameters.ArbiterConfiguration.NodePort,
+		MakeEmptyMessage:  makeEmptyMessage,
+		HandleMessage:     network.handleMessage,
+		PingNonce:         network.getCurrentHeight,
+		PongNonce:         network.getCurrentHeight,
+		SignNonce:         dposAccount.SignPeerNonce,
+		StateNotifier:     notifier,
 	})
 	if err != nil {
 		return nil, err
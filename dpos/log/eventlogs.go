@@ -29,3 +29,8 @@ func (e *EventLogs) OnConsensusStarted(cons *ConsensusEvent) {
 func (e *EventLogs) OnConsensusFinished(cons *ConsensusEvent) {
 	Info("[OnConsensusFinished], EndTime:", cons.EndTime, "Height:", cons.Height)
 }
+
+func (e *EventLogs) OnIllegalEvidenceFound(evidence *IllegalEvidenceEvent) {
+	Info("[OnIllegalEvidenceFound], Arbiters:", evidence.Arbiters,
+		"EvidenceHash:", evidence.EvidenceHash, "Height:", evidence.Height)
+}
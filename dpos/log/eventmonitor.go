@@ -14,6 +14,7 @@ type ProposalEvent struct {
 	Result       bool
 	ProposalHash common.Uint256
 	RawData      []byte
+	Height       uint32
 }
 
 type VoteEvent struct {
@@ -37,6 +38,13 @@ type ConsensusEvent struct {
 	RawData   []byte
 }
 
+type IllegalEvidenceEvent struct {
+	Arbiters     []string
+	EvidenceHash common.Uint256
+	Height       uint32
+	ReceivedTime time.Time
+}
+
 type EventListener interface {
 	OnProposalArrived(prop *ProposalEvent)
 	OnProposalFinished(prop *ProposalEvent)
@@ -44,6 +52,7 @@ type EventListener interface {
 	OnViewStarted(view *ViewEvent)
 	OnConsensusStarted(cons *ConsensusEvent)
 	OnConsensusFinished(cons *ConsensusEvent)
+	OnIllegalEvidenceFound(evidence *IllegalEvidenceEvent)
 }
 
 type EventMonitor struct {
@@ -101,3 +110,9 @@ func (e *EventMonitor) OnConsensusFinished(cons *ConsensusEvent) {
 		l.OnConsensusFinished(cons)
 	}
 }
+
+func (e *EventMonitor) OnIllegalEvidenceFound(evidence *IllegalEvidenceEvent) {
+	for _, l := range e.listeners {
+		l.OnIllegalEvidenceFound(evidence)
+	}
+}
@@ -100,6 +100,12 @@ func (h *dposHandlerSwitch) FinishConsensus() {
 func (h *dposHandlerSwitch) StartNewProposal(p types.DPosProposal) {
 	h.currentHandler.StartNewProposal(p)
 
+	// The block proposed is on the top of the chain if not received yet
+	height := blockchain.DefaultLedger.Blockchain.BlockHeight + 1
+	if b, ok := h.manager.GetBlockCache().TryGetValue(p.BlockHash); ok {
+		height = b.Height
+	}
+
 	rawData := new(bytes.Buffer)
 	p.Serialize(rawData)
 	proposalEvent := log.ProposalEvent{
@@ -109,6 +115,7 @@ func (h *dposHandlerSwitch) StartNewProposal(p types.DPosProposal) {
 		ProposalHash: p.Hash(),
 		RawData:      rawData.Bytes(),
 		Result:       false,
+		Height:       height,
 	}
 	h.eventMonitor.OnProposalArrived(&proposalEvent)
 }
@@ -1,10 +1,13 @@
 package manager
 
 import (
-	"github.com/elastos/Elastos.ELA/common"
+	"time"
+
 	"github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/core/contract/program"
 	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/dpos/log"
 	"github.com/elastos/Elastos.ELA/dpos/p2p/msg"
 	"github.com/elastos/Elastos.ELA/errors"
 )
@@ -38,14 +41,39 @@ type illegalBehaviorMonitor struct {
 
 func (i *illegalBehaviorMonitor) AddBlockEvidence(evidence *types.DposIllegalBlocks) {
 	i.evidenceCache.AddEvidence(evidence)
+
+	// Only the arbiters signed both blocks are illegal
+	signers := make(map[string]interface{})
+	for _, v := range evidence.Evidence.Signers {
+		signers[common.BytesToHexString(v)] = nil
+	}
+	arbiters := make([]string, 0)
+	for _, v := range evidence.CompareEvidence.Signers {
+		compareSigner := common.BytesToHexString(v)
+		if _, ok := signers[compareSigner]; ok {
+			arbiters = append(arbiters, compareSigner)
+		}
+	}
+	i.onIllegalEvidenceFound(evidence, arbiters)
 }
 
 func (i *illegalBehaviorMonitor) AddProposalEvidence(evidence *types.DposIllegalProposals) {
 	i.evidenceCache.AddEvidence(evidence)
+	i.onIllegalEvidenceFound(evidence, []string{evidence.Evidence.Proposal.Sponsor})
 }
 
 func (i *illegalBehaviorMonitor) AddVoteEvidence(evidence *types.DposIllegalVotes) {
 	i.evidenceCache.AddEvidence(evidence)
+	i.onIllegalEvidenceFound(evidence, []string{evidence.Evidence.Vote.Signer})
+}
+
+func (i *illegalBehaviorMonitor) onIllegalEvidenceFound(evidence types.DposIllegalData, arbiters []string) {
+	i.dispatcher.eventMonitor.OnIllegalEvidenceFound(&log.IllegalEvidenceEvent{
+		Arbiters:     arbiters,
+		EvidenceHash: evidence.Hash(),
+		Height:       evidence.GetBlockHeight(),
+		ReceivedTime: time.Now(),
+	})
 }
 
 func (i *illegalBehaviorMonitor) IsBlockValid(block *types.Block) bool {
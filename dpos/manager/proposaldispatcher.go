@@ -125,7 +125,7 @@ func (p *proposalDispatcher) StartProposal(b *types.Block) {
 	p.network.BroadcastMessage(msg2.NewInventory(b.Hash()))
 	proposal := types.DPosProposal{Sponsor: p.manager.GetPublicKey(), BlockHash: b.Hash(), ViewOffset: p.consensus.GetViewOffset()}
 	var err error
-	proposal.Sign, err = p.account.SignProposal(&proposal)
+	proposal.Sign, err = p.account.SignProposal(&b.Header, &proposal)
 	if err != nil {
 		log.Error("[StartProposal] start proposal failed:", err.Error())
 		return
@@ -149,6 +149,7 @@ func (p *proposalDispatcher) StartProposal(b *types.Block) {
 		ProposalHash: proposal.Hash(),
 		RawData:      rawData.Bytes(),
 		Result:       false,
+		Height:       b.Height,
 	}
 	p.eventMonitor.OnProposalArrived(&proposalEvent)
 
@@ -426,9 +427,9 @@ func (p *proposalDispatcher) acceptProposal(d types.DPosProposal) {
 	p.setProcessingProposal(d)
 	vote := types.DPosProposalVote{ProposalHash: d.Hash(), Signer: p.manager.GetPublicKey(), Accept: true}
 	var err error
-	vote.Sign, err = p.account.SignVote(&vote)
+	vote.Sign, err = p.account.SignVote(&p.processingBlock.Header, &d, &vote)
 	if err != nil {
-		log.Error("[acceptProposal] sign failed")
+		log.Error("[acceptProposal] sign failed:", err)
 		return
 	}
 	voteMsg := &msg2.Vote{Command: msg2.CmdAcceptVote, Vote: vote}
@@ -447,20 +448,20 @@ func (p *proposalDispatcher) rejectProposal(d types.DPosProposal) {
 	p.setProcessingProposal(d)
 
 	vote := types.DPosProposalVote{ProposalHash: d.Hash(), Signer: p.manager.GetPublicKey(), Accept: false}
+	b, ok := p.manager.GetBlockCache().TryGetValue(d.BlockHash)
+	if !ok {
+		log.Error("[rejectProposal] can't find block")
+		return
+	}
 	var err error
-	vote.Sign, err = p.account.SignVote(&vote)
+	vote.Sign, err = p.account.SignVote(&b.Header, &d, &vote)
 	if err != nil {
-		log.Error("[rejectProposal] sign failed")
+		log.Error("[rejectProposal] sign failed:", err)
 		return
 	}
 	msg := &msg2.Vote{Command: msg2.CmdRejectVote, Vote: vote}
 	log.Info("[rejectProposal] send rej_vote msg:", msg2.GetMessageHash(msg))
 
-	_, ok := p.manager.GetBlockCache().TryGetValue(d.BlockHash)
-	if !ok {
-		log.Error("[rejectProposal] can't find block")
-		return
-	}
 	p.ProcessVote(vote, false)
 	p.network.BroadcastMessage(msg)
 
@@ -42,10 +42,16 @@ type Config struct {
 	// messages.
 	PingInterval time.Duration
 
+	// RequireEncryption indicates to disconnect the peers do not support the
+	// encrypted transport.  The transport is encrypted with the peers both
+	// advertised the peer.SFEncryption service flag.
+	RequireEncryption bool
+
 	// SignNonce will be invoked when creating a version message to do the
 	// protocol negotiate.  The passed nonce is a 32 bytes length random value,
 	// and returns the signature of the nonce value to proof you have the right
-	// of the PID(public key) you've provided.
+	// of the PID(public key) you've provided.  It is also invoked to sign the
+	// 32 bytes hash of the ephemeral public key when negotiate encryption.
 	SignNonce func(nonce []byte) (signature [64]byte)
 
 	// PingNonce will be invoked before send a ping message to the connect peer
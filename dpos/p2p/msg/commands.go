@@ -13,6 +13,8 @@ const (
 	CmdPing    = "ping"
 	CmdPong    = "pong"
 
+	CmdKeyExchange = "keyexchange"
+
 	CmdInv      = "inventory"
 	CmdGetBlock = "getblock"
 
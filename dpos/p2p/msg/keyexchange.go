@@ -0,0 +1,49 @@
+package msg
+
+import (
+	"io"
+
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+// Ensure KeyExchange implement p2p.Message interface.
+var _ p2p.Message = (*KeyExchange)(nil)
+
+// KeyExchange is sent after the version messages by the peers both support
+// encryption, to exchange the ephemeral public keys to agree the session
+// keys.  The signature is signed by the PID of the sender to bind the
+// ephemeral public key to it.
+type KeyExchange struct {
+	PublicKey [33]byte
+	Signature [64]byte
+}
+
+func (msg *KeyExchange) CMD() string {
+	return CmdKeyExchange
+}
+
+func (msg *KeyExchange) MaxLength() uint32 {
+	return 97 // 33+64
+}
+
+func (msg *KeyExchange) Serialize(w io.Writer) error {
+	if _, err := w.Write(msg.PublicKey[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(msg.Signature[:])
+	return err
+}
+
+func (msg *KeyExchange) Deserialize(r io.Reader) error {
+	if _, err := io.ReadFull(r, msg.PublicKey[:]); err != nil {
+		return err
+	}
+
+	_, err := io.ReadFull(r, msg.Signature[:])
+	return err
+}
+
+func NewKeyExchange(publicKey [33]byte, signature [64]byte) *KeyExchange {
+	return &KeyExchange{PublicKey: publicKey, Signature: signature}
+}
@@ -64,6 +64,7 @@ type StatsSnap struct {
 	Inbound        bool
 	LastPingTime   time.Time
 	LastPingMicros int64
+	Encrypted      bool
 }
 
 // MessageFunc is a message handler in peer's configuration
@@ -71,16 +72,17 @@ type MessageFunc func(peer *Peer, msg p2p.Message)
 
 // Config is a descriptor which specifies the peer instance configuration.
 type Config struct {
-	PID              PID
-	Magic            uint32
-	ProtocolVersion  uint32
-	Services         uint64
-	PingInterval     time.Duration
-	SignNonce        func(nonce []byte) (signature [64]byte)
-	PingNonce        func(pid PID) uint64
-	PongNonce        func(pid PID) uint64
-	MakeEmptyMessage func(cmd string) (p2p.Message, error)
-	messageFuncs     []MessageFunc
+	PID               PID
+	Magic             uint32
+	ProtocolVersion   uint32
+	Services          uint64
+	PingInterval      time.Duration
+	RequireEncryption bool
+	SignNonce         func(nonce []byte) (signature [64]byte)
+	PingNonce         func(pid PID) uint64
+	PongNonce         func(pid PID) uint64
+	MakeEmptyMessage  func(cmd string) (p2p.Message, error)
+	messageFuncs      []MessageFunc
 }
 
 func (c *Config) AddMessageFunc(messageFunc MessageFunc) {
@@ -158,6 +160,9 @@ type Peer struct {
 	advertisedProtoVer uint32 // protocol version advertised by remote
 	protocolVersion    uint32 // negotiated protocol version
 	verAckReceived     bool
+	localNonce         [32]byte // nonce of the version message sent
+	remoteNonce        [32]byte // nonce of the version message received
+	session            *session // encrypted transport, nil if not encrypted
 
 	// These fields keep track of statistics for the peer and are protected
 	// by the statsMtx mutex.
@@ -198,6 +203,7 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 	addr := p.addr
 	services := p.services
 	protocolVersion := p.advertisedProtoVer
+	encrypted := p.session != nil
 	p.flagsMtx.Unlock()
 
 	// Get a copy of all relevant flags and stats.
@@ -213,6 +219,7 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		Inbound:        p.inbound,
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
+		Encrypted:      encrypted,
 	}
 
 	p.statsMtx.RUnlock()
@@ -336,6 +343,17 @@ func (p *Peer) ProtocolVersion() uint32 {
 	return protocolVersion
 }
 
+// Encrypted returns whether the messages with the peer are encrypted.
+//
+// This function is safe for concurrent access.
+func (p *Peer) Encrypted() bool {
+	p.flagsMtx.Lock()
+	encrypted := p.session != nil
+	p.flagsMtx.Unlock()
+
+	return encrypted
+}
+
 // LastSend returns the last send time of the peer.
 //
 // This function is safe for concurrent access.
@@ -403,6 +421,9 @@ func (p *Peer) makeEmptyMessage(cmd string) (p2p.Message, error) {
 	case p2p.CmdPong:
 		message = &msg.Pong{}
 
+	case msg.CmdKeyExchange:
+		message = &msg.KeyExchange{}
+
 	default:
 		return p.cfg.MakeEmptyMessage(cmd)
 	}
@@ -410,7 +431,13 @@ func (p *Peer) makeEmptyMessage(cmd string) (p2p.Message, error) {
 }
 
 func (p *Peer) readMessage() (p2p.Message, error) {
-	msg, err := p2p.ReadMessage(p.conn, p.cfg.Magic, p.makeEmptyMessage)
+	var msg p2p.Message
+	var err error
+	if p.session != nil {
+		msg, err = p.session.readMessage(p.conn, p.cfg.Magic, p.makeEmptyMessage)
+	} else {
+		msg, err = p2p.ReadMessage(p.conn, p.cfg.Magic, p.makeEmptyMessage)
+	}
 	// Use closures to log expensive operations so they are only run when
 	// the logging level requires it.
 	log.Debugf("%v", newLogClosure(func() string {
@@ -446,6 +473,9 @@ func (p *Peer) writeMessage(msg p2p.Message) error {
 		return fmt.Sprintf("Sending %v%s to %s", msg.CMD(), summary, p)
 	}))
 
+	if p.session != nil {
+		return p.session.writeMessage(p.conn, p.cfg.Magic, msg)
+	}
 	return p2p.WriteMessage(p.conn, p.cfg.Magic, msg)
 }
 
@@ -521,6 +551,15 @@ out:
 			<-doneChan
 			break out
 
+		case *msg.KeyExchange:
+
+			rejectMsg := msg.NewReject(m.CMD(), msg.RejectDuplicate, "unexpected key exchange message")
+			// Send the message and block until it has been sent before returning.
+			doneChan := make(chan error, 1)
+			p.SendMessage(rejectMsg, doneChan)
+			<-doneChan
+			break out
+
 		case *msg.VerAck:
 
 			// No read lock is necessary because verAckReceived is not written
@@ -712,6 +751,9 @@ func (p *Peer) readRemoteVersionMsg() error {
 
 	// Verify signature of the message nonce.
 	pk, err := crypto.DecodePoint(verMsg.PID[:])
+	if err != nil {
+		return errors.New("disconnecting peer with invalid PID")
+	}
 	err = crypto.Verify(*pk, verMsg.Nonce[:], verMsg.Signature[:])
 	if err != nil {
 		return errors.New("disconnecting peer verify signature failed")
@@ -728,6 +770,7 @@ func (p *Peer) readRemoteVersionMsg() error {
 
 	// Set the peer's ID.
 	p.pid = verMsg.PID
+	p.remoteNonce = verMsg.Nonce
 
 	// Set the supported services for the peer to what the remote peer advertised.
 	p.services = verMsg.Services
@@ -746,6 +789,7 @@ func (p *Peer) localVersionMsg() (*msg.Version, error) {
 	if err != nil {
 		return nil, errors.New("create local version nonce failed")
 	}
+	p.localNonce = nonce
 	// Version message.
 	msg := msg.NewVersion(p.cfg.ProtocolVersion, p.cfg.Services, p.cfg.PID,
 		nonce, p.cfg.SignNonce(nonce[:]))
@@ -769,6 +813,153 @@ func (p *Peer) writeLocalVersionMsg() error {
 	return p.writeMessage(localVerMsg)
 }
 
+// useEncryption returns whether the messages with the peer should be encrypted,
+// which requires both the local and remote peer advertise the SFEncryption
+// service flag.  An error is returned if encryption is required but the remote
+// peer does not support it.
+func (p *Peer) useEncryption() (bool, error) {
+	if p.cfg.Services&SFEncryption != SFEncryption {
+		return false, nil
+	}
+	if p.Services()&SFEncryption != SFEncryption {
+		if p.cfg.RequireEncryption {
+			return false, errors.New("disconnecting peer not support encryption")
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// versionInfos returns what this peer and the remote peer advertised in their
+// version messages.
+func (p *Peer) versionInfos() (local, remote *versionInfo) {
+	p.flagsMtx.Lock()
+	defer p.flagsMtx.Unlock()
+	local = &versionInfo{
+		version:  p.cfg.ProtocolVersion,
+		services: p.cfg.Services,
+		nonce:    p.localNonce,
+	}
+	remote = &versionInfo{
+		version:  p.advertisedProtoVer,
+		services: p.services,
+		nonce:    p.remoteNonce,
+	}
+	return local, remote
+}
+
+// localKeyExchangeMsg creates a key exchange message with a new ephemeral
+// public key, and returns the ephemeral private key.
+func (p *Peer) localKeyExchangeMsg() (*msg.KeyExchange, []byte, error) {
+	privateKey, publicKey, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return nil, nil, errors.New("create ephemeral key failed")
+	}
+	ePublicKey, err := publicKey.EncodePoint(true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var key [33]byte
+	copy(key[:], ePublicKey)
+	local, remote := p.versionInfos()
+	signature := p.cfg.SignNonce(keyExchangeHash(key[:], local, remote))
+	return msg.NewKeyExchange(key, signature), privateKey, nil
+}
+
+// readRemoteKeyExchangeMsg waits for the key exchange message from the remote
+// peer, and verifies the ephemeral public key is signed by the peer's PID.
+func (p *Peer) readRemoteKeyExchangeMsg() (*msg.KeyExchange, *crypto.PublicKey, error) {
+	remoteMsg, err := p.readMessage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyMsg, ok := remoteMsg.(*msg.KeyExchange)
+	if !ok {
+		reason := "A key exchange message must follow the version message"
+		rejectMsg := msg.NewReject(remoteMsg.CMD(), msg.RejectMalformed, reason)
+		p.writeMessage(rejectMsg)
+		return nil, nil, errors.New(reason)
+	}
+
+	// Verify signature of the ephemeral public key.
+	pk, err := crypto.DecodePoint(p.pid[:])
+	if err != nil {
+		return nil, nil, errors.New("disconnecting peer with invalid PID")
+	}
+	local, remote := p.versionInfos()
+	hash := keyExchangeHash(keyMsg.PublicKey[:], remote, local)
+	if err := crypto.Verify(*pk, hash, keyMsg.Signature[:]); err != nil {
+		return nil, nil, errors.New("disconnecting peer verify key exchange signature failed")
+	}
+
+	ephemeralKey, err := crypto.DecodePoint(keyMsg.PublicKey[:])
+	if err != nil {
+		return nil, nil, errors.New("disconnecting peer with invalid ephemeral key")
+	}
+	return keyMsg, ephemeralKey, nil
+}
+
+// negotiateEncryption exchanges the ephemeral public keys with the remote peer
+// if both support encryption, and encrypts the messages after that.  The
+// inbound peer waits for the key exchange message of the outbound peer before
+// sending its own.
+func (p *Peer) negotiateEncryption() error {
+	encrypt, err := p.useEncryption()
+	if err != nil || !encrypt {
+		return err
+	}
+
+	localMsg, privateKey, err := p.localKeyExchangeMsg()
+	if err != nil {
+		return err
+	}
+
+	var remoteMsg *msg.KeyExchange
+	var remoteKey *crypto.PublicKey
+	if p.inbound {
+		if remoteMsg, remoteKey, err = p.readRemoteKeyExchangeMsg(); err != nil {
+			return err
+		}
+		if err := p.writeMessage(localMsg); err != nil {
+			return err
+		}
+	} else {
+		if err := p.writeMessage(localMsg); err != nil {
+			return err
+		}
+		if remoteMsg, remoteKey, err = p.readRemoteKeyExchangeMsg(); err != nil {
+			return err
+		}
+	}
+
+	// The transcript is the ephemeral public keys and the version nonces of
+	// the outbound and inbound peer in order.
+	transcript := new(bytes.Buffer)
+	if p.inbound {
+		transcript.Write(remoteMsg.PublicKey[:])
+		transcript.Write(localMsg.PublicKey[:])
+		transcript.Write(p.remoteNonce[:])
+		transcript.Write(p.localNonce[:])
+	} else {
+		transcript.Write(localMsg.PublicKey[:])
+		transcript.Write(remoteMsg.PublicKey[:])
+		transcript.Write(p.localNonce[:])
+		transcript.Write(p.remoteNonce[:])
+	}
+
+	s, err := newSession(privateKey, remoteKey, !p.inbound, transcript.Bytes())
+	if err != nil {
+		return err
+	}
+	p.flagsMtx.Lock()
+	p.session = s
+	p.flagsMtx.Unlock()
+	log.Debugf("Encrypted connection with peer %s", p)
+	return nil
+}
+
 // negotiateInboundProtocol waits to receive a version message from the peer
 // then sends our version message. If the events do not occur in that order then
 // it returns an error.
@@ -777,7 +968,11 @@ func (p *Peer) negotiateInboundProtocol() error {
 		return err
 	}
 
-	return p.writeLocalVersionMsg()
+	if err := p.writeLocalVersionMsg(); err != nil {
+		return err
+	}
+
+	return p.negotiateEncryption()
 }
 
 // negotiateOutboundProtocol sends our version message then waits to receive a
@@ -788,7 +983,11 @@ func (p *Peer) negotiateOutboundProtocol() error {
 		return err
 	}
 
-	return p.readRemoteVersionMsg()
+	if err := p.readRemoteVersionMsg(); err != nil {
+		return err
+	}
+
+	return p.negotiateEncryption()
 }
 
 // start begins processing input and output messages.
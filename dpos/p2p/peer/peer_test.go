@@ -395,3 +395,216 @@ func TestUnsupportedVersionPeer(t *testing.T) {
 		t.Fatal("Timeout waiting for remote reader to close")
 	}
 }
+
+// TestPeerEncryption tests the encryption negotiated between inbound and
+// outbound peers by the SFEncryption service flag.
+func TestPeerEncryption(t *testing.T) {
+	tests := []struct {
+		name          string
+		inServices    uint64
+		outServices   uint64
+		inRequire     bool
+		wantEncrypted bool
+		wantConnected bool
+	}{
+		{"both support", peer.SFEncryption, peer.SFEncryption, false, true, true},
+		{"inbound only", peer.SFEncryption, 0, false, false, true},
+		{"outbound only", 0, peer.SFEncryption, false, false, true},
+		{"required", peer.SFEncryption, peer.SFEncryption, true, true, true},
+		{"required not supported", peer.SFEncryption, 0, true, false, false},
+	}
+
+	for i, test := range tests {
+		verack := make(chan struct{}, 2)
+		pong := make(chan struct{}, 2)
+		var messageFunc peer.MessageFunc = func(peer *peer.Peer, message p2p.Message) {
+			switch message.(type) {
+			case *msg.VerAck:
+				verack <- struct{}{}
+			case *msg.Pong:
+				pong <- struct{}{}
+			}
+		}
+
+		inCfg := peerConfig(123123, p2p.EIP001Version, test.inServices)
+		inCfg.RequireEncryption = test.inRequire
+		outCfg := peerConfig(123123, p2p.EIP001Version, test.outServices)
+		for _, cfg := range []*peer.Config{inCfg, outCfg} {
+			cfg.MakeEmptyMessage = makeEmptyMessage
+			cfg.PingNonce = func(pid peer.PID) uint64 { return 1 }
+			cfg.PongNonce = func(pid peer.PID) uint64 { return 1 }
+			cfg.AddMessageFunc(messageFunc)
+		}
+
+		inConn, outConn := pipe(
+			&conn{raddr: "10.0.0.1:8333"},
+			&conn{raddr: "10.0.0.2:8333"},
+		)
+		inPeer := peer.NewInboundPeer(inCfg)
+		inPeer.AssociateConnection(inConn)
+		outPeer, err := peer.NewOutboundPeer(outCfg, "10.0.0.2:8333")
+		if err != nil {
+			t.Fatalf("TestPeerEncryption #%d %s: unexpected err %v", i, test.name, err)
+		}
+		outPeer.AssociateConnection(outConn)
+
+		if !test.wantConnected {
+			select {
+			case <-inPeer.Quit():
+			case <-time.After(time.Second):
+				t.Errorf("TestPeerEncryption #%d %s: peer not disconnected", i, test.name)
+			}
+			outPeer.Disconnect()
+			continue
+		}
+
+		for j := 0; j < 2; j++ {
+			select {
+			case <-verack:
+			case <-time.After(time.Second):
+				t.Fatalf("TestPeerEncryption #%d %s: verack timeout", i, test.name)
+			}
+		}
+		if inPeer.Encrypted() != test.wantEncrypted || outPeer.Encrypted() != test.wantEncrypted {
+			t.Errorf("TestPeerEncryption #%d %s: wrong Encrypted - got %v and %v, want %v", i,
+				test.name, inPeer.Encrypted(), outPeer.Encrypted(), test.wantEncrypted)
+		}
+
+		// Messages can be exchanged in both directions.
+		inPeer.SendMessage(msg.NewPing(1), nil)
+		outPeer.SendMessage(msg.NewPing(1), nil)
+		for j := 0; j < 2; j++ {
+			select {
+			case <-pong:
+			case <-time.After(time.Second):
+				t.Fatalf("TestPeerEncryption #%d %s: pong timeout", i, test.name)
+			}
+		}
+
+		inPeer.Disconnect()
+		outPeer.Disconnect()
+		inPeer.WaitForDisconnect()
+		outPeer.WaitForDisconnect()
+	}
+}
+
+// TestPeerEncryptionTampered tests the key exchange fails if the services of
+// the version message are altered by a man in the middle.
+func TestPeerEncryptionTampered(t *testing.T) {
+	inCfg := peerConfig(123123, p2p.EIP001Version, peer.SFEncryption)
+	outCfg := peerConfig(123123, p2p.EIP001Version, peer.SFEncryption)
+	for _, cfg := range []*peer.Config{inCfg, outCfg} {
+		cfg.MakeEmptyMessage = makeEmptyMessage
+		cfg.PingNonce = func(pid peer.PID) uint64 { return 1 }
+		cfg.PongNonce = func(pid peer.PID) uint64 { return 1 }
+	}
+
+	// The relay forwards the messages between the peers, and alters the
+	// services of the version message of the outbound peer.
+	inConn, relayIn := pipe(
+		&conn{raddr: "10.0.0.1:8333"},
+		&conn{raddr: "10.0.0.2:8333"},
+	)
+	outConn, relayOut := pipe(
+		&conn{raddr: "10.0.0.2:8333"},
+		&conn{raddr: "10.0.0.1:8333"},
+	)
+	go func() {
+		m, err := p2p.ReadMessage(relayOut, outCfg.Magic, makeEmptyMessage)
+		if err != nil {
+			return
+		}
+		version := m.(*msg.Version)
+		version.Services |= 1
+		if err := p2p.WriteMessage(relayIn, inCfg.Magic, version); err != nil {
+			return
+		}
+		io.Copy(relayIn, relayOut)
+	}()
+	go io.Copy(relayOut, relayIn)
+
+	inPeer := peer.NewInboundPeer(inCfg)
+	inPeer.AssociateConnection(inConn)
+	outPeer, err := peer.NewOutboundPeer(outCfg, "10.0.0.2:8333")
+	if err != nil {
+		t.Fatalf("TestPeerEncryptionTampered: unexpected err %v", err)
+	}
+	outPeer.AssociateConnection(outConn)
+
+	select {
+	case <-inPeer.Quit():
+	case <-time.After(time.Second):
+		t.Errorf("TestPeerEncryptionTampered: peer not disconnected")
+	}
+	if inPeer.Encrypted() {
+		t.Errorf("TestPeerEncryptionTampered: key exchange of the altered " +
+			"version accepted")
+	}
+	outPeer.Disconnect()
+	inPeer.Disconnect()
+}
+
+// TestPeerOldVersionSignature tests the peer supports encryption connects to
+// the peers sign the nonce only in the version message and do not support
+// encryption, and its version message can be verified by them.
+func TestPeerOldVersionSignature(t *testing.T) {
+	verack := make(chan struct{}, 1)
+	inCfg := peerConfig(123123, p2p.EIP001Version, peer.SFEncryption)
+	inCfg.MakeEmptyMessage = makeEmptyMessage
+	inCfg.AddMessageFunc(func(peer *peer.Peer, message p2p.Message) {
+		if _, ok := message.(*msg.VerAck); ok {
+			verack <- struct{}{}
+		}
+	})
+	inConn, outConn := pipe(
+		&conn{raddr: "10.0.0.1:8333"},
+		&conn{raddr: "10.0.0.2:8333"},
+	)
+	inPeer := peer.NewInboundPeer(inCfg)
+	inPeer.AssociateConnection(inConn)
+	defer inPeer.Disconnect()
+
+	// The old peer signs the nonce of its version message.
+	outCfg := peerConfig(123123, p2p.EIP001Version, 0)
+	var nonce [32]byte
+	rand.Read(nonce[:])
+	version := msg.NewVersion(outCfg.ProtocolVersion, outCfg.Services,
+		outCfg.PID, nonce, outCfg.SignNonce(nonce[:]))
+	if err := p2p.WriteMessage(outConn, outCfg.Magic, version); err != nil {
+		t.Fatalf("TestPeerOldVersionSignature: write version failed %v", err)
+	}
+
+	m, err := p2p.ReadMessage(outConn, outCfg.Magic, makeEmptyMessage)
+	if err != nil {
+		t.Fatalf("TestPeerOldVersionSignature: read version failed %v", err)
+	}
+	remote := m.(*msg.Version)
+	pk, err := crypto.DecodePoint(remote.PID[:])
+	if err != nil {
+		t.Fatalf("TestPeerOldVersionSignature: invalid PID %v", err)
+	}
+	if err := crypto.Verify(*pk, remote.Nonce[:], remote.Signature[:]); err != nil {
+		t.Errorf("TestPeerOldVersionSignature: version signature of the " +
+			"nonce not verified")
+	}
+
+	if err := p2p.WriteMessage(outConn, outCfg.Magic, &msg.VerAck{}); err != nil {
+		t.Fatalf("TestPeerOldVersionSignature: write verack failed %v", err)
+	}
+	m, err = p2p.ReadMessage(outConn, outCfg.Magic, makeEmptyMessage)
+	if err != nil {
+		t.Fatalf("TestPeerOldVersionSignature: read verack failed %v", err)
+	}
+	if _, ok := m.(*msg.VerAck); !ok {
+		t.Errorf("TestPeerOldVersionSignature: got %s, want verack", m.CMD())
+	}
+	select {
+	case <-verack:
+	case <-time.After(time.Second):
+		t.Fatalf("TestPeerOldVersionSignature: verack timeout")
+	}
+	if inPeer.Encrypted() {
+		t.Errorf("TestPeerOldVersionSignature: connection to the old peer " +
+			"encrypted")
+	}
+}
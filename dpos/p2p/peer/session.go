@@ -0,0 +1,186 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+const (
+	// SFEncryption is the service flag advertised by the peers support the
+	// encrypted transport.  The traffic between two peers both advertised
+	// this flag is encrypted after the key exchange.
+	SFEncryption uint64 = 1 << 4
+
+	// frameHeaderSize is the size of an encrypted frame header, which is the
+	// sequence number and the length of the sealed message.
+	frameHeaderSize = 12 // 8+4
+
+	// maxFrameLength is the maximum length of the sealed message in a frame.
+	maxFrameLength = p2p.HeaderSize + p2p.MaxMessagePayload + 16
+)
+
+var (
+	// ErrUnexpectedSequence is the error returned when the sequence number of
+	// the received frame is not the expected one, which means a frame has
+	// been dropped, reordered or replayed.
+	ErrUnexpectedSequence = errors.New("unexpected frame sequence number")
+
+	// ErrFrameAuthentication is the error returned when the received frame
+	// can not be authenticated.
+	ErrFrameAuthentication = errors.New("frame authentication failed")
+)
+
+// session is the encrypted transport between two peers, each message is
+// sealed with AES-256-GCM by the key of its direction into a frame, and the
+// frames are numbered in sequence for each direction.
+//
+// The session is only used by the handler reads and writes messages of the
+// peer, so it is not safe for concurrent access.
+type session struct {
+	sendAEAD cipher.AEAD
+	recvAEAD cipher.AEAD
+	sendSeq  uint64
+	recvSeq  uint64
+}
+
+// versionInfo is what a peer advertised in its version message.
+type versionInfo struct {
+	version  uint32
+	services uint64
+	nonce    [32]byte
+}
+
+func (v *versionInfo) write(w io.Writer) {
+	binary.Write(w, binary.LittleEndian, v.version)
+	binary.Write(w, binary.LittleEndian, v.services)
+	w.Write(v.nonce[:])
+}
+
+// keyExchangeHash returns the hash signed by the PID of the peer to bind the
+// ephemeral public key to the connection, which is identified by the version
+// nonces of the peer and its remote peer.  The protocol versions and services
+// advertised are bound too, as the version message only signs the nonce, so a
+// man in the middle altering them fails the key exchange.
+func keyExchangeHash(publicKey []byte, local, remote *versionInfo) []byte {
+	hash := sha256.New()
+	hash.Write(publicKey)
+	local.write(hash)
+	remote.write(hash)
+	return hash.Sum(nil)
+}
+
+// newSession creates the session by the ephemeral private key of this peer
+// and the ephemeral public key of the remote peer.  The outbound peer is the
+// initiator of the connection, and the transcript is the ephemeral public
+// keys and version nonces of the initiator and responder in order.
+func newSession(privateKey []byte, remoteKey *crypto.PublicKey, initiator bool,
+	transcript []byte) (*session, error) {
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(remoteKey.X, remoteKey.Y) {
+		return nil, errors.New("remote ephemeral public key not on curve")
+	}
+	x, _ := curve.ScalarMult(remoteKey.X, remoteKey.Y, privateKey)
+	secret := make([]byte, 32)
+	xBytes := x.Bytes()
+	copy(secret[32-len(xBytes):], xBytes)
+
+	initiatorAEAD, err := newAEAD(secret, "initiator", transcript)
+	if err != nil {
+		return nil, err
+	}
+	responderAEAD, err := newAEAD(secret, "responder", transcript)
+	if err != nil {
+		return nil, err
+	}
+
+	if initiator {
+		return &session{sendAEAD: initiatorAEAD, recvAEAD: responderAEAD}, nil
+	}
+	return &session{sendAEAD: responderAEAD, recvAEAD: initiatorAEAD}, nil
+}
+
+// newAEAD derives the key of the direction labeled from the shared secret and
+// the transcript, and returns the AEAD cipher of the key.
+func newAEAD(secret []byte, label string, transcript []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	mac.Write(transcript)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// frameNonce returns the AEAD nonce of the frame by its sequence number.
+func frameNonce(seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
+
+// frameHeader returns the frame header, which is also the additional data
+// authenticated with the sealed message.
+func frameHeader(seq uint64, length uint32) []byte {
+	header := make([]byte, frameHeaderSize)
+	binary.LittleEndian.PutUint64(header[:8], seq)
+	binary.LittleEndian.PutUint32(header[8:], length)
+	return header
+}
+
+// writeMessage seals the message into a frame and writes it to w.
+func (s *session) writeMessage(w io.Writer, magic uint32, msg p2p.Message) error {
+	buf := new(bytes.Buffer)
+	if err := p2p.WriteMessage(buf, magic, msg); err != nil {
+		return err
+	}
+
+	length := uint32(buf.Len() + s.sendAEAD.Overhead())
+	header := frameHeader(s.sendSeq, length)
+	sealed := s.sendAEAD.Seal(header, frameNonce(s.sendSeq), buf.Bytes(), header)
+	s.sendSeq++
+
+	_, err := w.Write(sealed)
+	return err
+}
+
+// readMessage reads a frame from r, and returns the message opened from it.
+func (s *session) readMessage(r io.Reader, magic uint32,
+	makeEmptyMessage p2p.MakeEmptyMessage) (p2p.Message, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	seq := binary.LittleEndian.Uint64(header[:8])
+	length := binary.LittleEndian.Uint32(header[8:])
+	if seq != s.recvSeq {
+		return nil, ErrUnexpectedSequence
+	}
+	if length > maxFrameLength {
+		return nil, fmt.Errorf("frame length %d exceeds the maximum %d",
+			length, maxFrameLength)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r, sealed); err != nil {
+		return nil, err
+	}
+	plain, err := s.recvAEAD.Open(nil, frameNonce(seq), sealed, header)
+	if err != nil {
+		return nil, ErrFrameAuthentication
+	}
+	s.recvSeq++
+
+	return p2p.ReadMessage(bytes.NewReader(plain), magic, makeEmptyMessage)
+}
@@ -0,0 +1,81 @@
+package peer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/elastos/Elastos.ELA/dpos/p2p/msg"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+func makePingMessage(cmd string) (p2p.Message, error) {
+	return &msg.Ping{}, nil
+}
+
+// newTestSessions returns the sessions of the initiator and responder agreed
+// on the same transcript.
+func newTestSessions(t *testing.T) (*session, *session) {
+	priKey1, pubKey1, _ := crypto.GenerateKeyPair()
+	priKey2, pubKey2, _ := crypto.GenerateKeyPair()
+	transcript := []byte("transcript")
+
+	initiator, err := newSession(priKey1, pubKey2, true, transcript)
+	if err != nil {
+		t.Fatalf("newSession: unexpected err %v", err)
+	}
+	responder, err := newSession(priKey2, pubKey1, false, transcript)
+	if err != nil {
+		t.Fatalf("newSession: unexpected err %v", err)
+	}
+	return initiator, responder
+}
+
+func TestSession_ReadWriteMessage(t *testing.T) {
+	initiator, responder := newTestSessions(t)
+
+	buf := new(bytes.Buffer)
+	for i := uint64(1); i <= 3; i++ {
+		if err := initiator.writeMessage(buf, 123123, msg.NewPing(i)); err != nil {
+			t.Fatalf("writeMessage: unexpected err %v", err)
+		}
+	}
+	for i := uint64(1); i <= 3; i++ {
+		m, err := responder.readMessage(buf, 123123, makePingMessage)
+		if err != nil {
+			t.Fatalf("readMessage: unexpected err %v", err)
+		}
+		if m.(*msg.Ping).Nonce != i {
+			t.Errorf("readMessage: wrong nonce - got %d, want %d", m.(*msg.Ping).Nonce, i)
+		}
+	}
+
+}
+
+func TestSession_TamperedFrame(t *testing.T) {
+	initiator, responder := newTestSessions(t)
+
+	buf := new(bytes.Buffer)
+	initiator.writeMessage(buf, 123123, msg.NewPing(1))
+	frame := buf.Bytes()
+	frame[len(frame)-1] ^= 0xff
+
+	if _, err := responder.readMessage(bytes.NewReader(frame), 123123, makePingMessage); err != ErrFrameAuthentication {
+		t.Errorf("readMessage: wrong err - got %v, want %v", err, ErrFrameAuthentication)
+	}
+}
+
+func TestSession_ReplayedFrame(t *testing.T) {
+	initiator, responder := newTestSessions(t)
+
+	buf := new(bytes.Buffer)
+	initiator.writeMessage(buf, 123123, msg.NewPing(1))
+	frame := buf.Bytes()
+
+	if _, err := responder.readMessage(bytes.NewReader(frame), 123123, makePingMessage); err != nil {
+		t.Fatalf("readMessage: unexpected err %v", err)
+	}
+	if _, err := responder.readMessage(bytes.NewReader(frame), 123123, makePingMessage); err != ErrUnexpectedSequence {
+		t.Errorf("readMessage: wrong err - got %v, want %v", err, ErrUnexpectedSequence)
+	}
+}
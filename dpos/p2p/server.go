@@ -420,15 +420,16 @@ func (s *server) pongNonce(pid peer.PID) uint64 {
 // newPeerConfig returns the configuration for the given serverPeer.
 func newPeerConfig(sp *serverPeer) *peer.Config {
 	cfg := &peer.Config{
-		PID:              sp.server.cfg.PID,
-		Magic:            sp.server.cfg.MagicNumber,
-		ProtocolVersion:  sp.server.cfg.ProtocolVersion,
-		Services:         sp.server.cfg.Services,
-		PingInterval:     sp.server.cfg.PingInterval,
-		SignNonce:        sp.server.cfg.SignNonce,
-		PingNonce:        sp.server.pingNonce,
-		PongNonce:        sp.server.pongNonce,
-		MakeEmptyMessage: sp.server.cfg.MakeEmptyMessage,
+		PID:               sp.server.cfg.PID,
+		Magic:             sp.server.cfg.MagicNumber,
+		ProtocolVersion:   sp.server.cfg.ProtocolVersion,
+		Services:          sp.server.cfg.Services,
+		PingInterval:      sp.server.cfg.PingInterval,
+		RequireEncryption: sp.server.cfg.RequireEncryption,
+		SignNonce:         sp.server.cfg.SignNonce,
+		PingNonce:         sp.server.pingNonce,
+		PongNonce:         sp.server.pongNonce,
+		MakeEmptyMessage:  sp.server.cfg.MakeEmptyMessage,
 	}
 
 	// Add default message function for peer configuration.
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/dpos/log"
+)
+
+// ArbiterStatistics tracks the performance and liveness statistics of the
+// arbiters by the consensus events, and persists them to the store when the
+// consensus finished or the view changed.  An election round lasts while the
+// elected arbiters are unchanged, and is identified by the height of the first
+// block it made consensus on.
+type ArbiterStatistics struct {
+	store       interfaces.IArbiterStatsRecord
+	arbitrators interfaces.Arbitrators
+
+	round    *interfaces.ArbiterRoundStats
+	arbiters map[string]*interfaces.ArbiterStats
+
+	// onDuty and viewOffset are of the current view.
+	onDuty     string
+	viewOffset uint32
+
+	// proposals are the proposals counted in the current consensus, and
+	// pendingVotes are the votes arrived before their proposals.
+	proposals    map[common.Uint256]struct{}
+	votes        map[common.Uint256]struct{}
+	pendingVotes map[common.Uint256][]*log.VoteEvent
+
+	// evidences are the illegal evidences counted in the round.
+	evidences map[common.Uint256]struct{}
+
+	lock sync.Mutex
+
+	// saveLock keeps the rounds saved in the order they changed, it is taken
+	// before s.lock is released, so the store is not written under s.lock.
+	saveLock sync.Mutex
+}
+
+func (s *ArbiterStatistics) Initialize(store interfaces.IArbiterStatsRecord,
+	arbitrators interfaces.Arbitrators) {
+	s.store = store
+	s.arbitrators = arbitrators
+	s.resetConsensus()
+}
+
+func (s *ArbiterStatistics) OnProposalArrived(prop *log.ProposalEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Proposals not sponsored by the on duty arbiter will be rejected, they
+	// are not counted as duties.
+	if s.round == nil || prop.Proposal != s.onDuty {
+		return
+	}
+	if _, ok := s.proposals[prop.ProposalHash]; ok {
+		return
+	}
+	s.proposals[prop.ProposalHash] = struct{}{}
+
+	if a, ok := s.arbiters[prop.Proposal]; ok {
+		a.Proposals++
+	}
+	for _, a := range s.round.Arbiters {
+		a.ExpectedVotes++
+	}
+
+	for _, vote := range s.pendingVotes[prop.ProposalHash] {
+		s.countVote(vote)
+	}
+	delete(s.pendingVotes, prop.ProposalHash)
+}
+
+func (s *ArbiterStatistics) OnProposalFinished(prop *log.ProposalEvent) {
+}
+
+func (s *ArbiterStatistics) OnVoteArrived(vote *log.VoteEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.round == nil {
+		return
+	}
+
+	var v types.DPosProposalVote
+	if err := v.Deserialize(bytes.NewReader(vote.RawData)); err != nil {
+		log.Error("[ArbiterStatistics] invalid vote:", err.Error())
+		return
+	}
+	if _, ok := s.votes[v.Hash()]; ok {
+		return
+	}
+	s.votes[v.Hash()] = struct{}{}
+
+	if _, ok := s.proposals[v.ProposalHash]; !ok {
+		s.pendingVotes[v.ProposalHash] = append(s.pendingVotes[v.ProposalHash], vote)
+		return
+	}
+	s.countVote(vote)
+}
+
+func (s *ArbiterStatistics) OnViewStarted(view *log.ViewEvent) {
+	s.lock.Lock()
+	if s.round == nil || view.Offset <= s.viewOffset {
+		s.lock.Unlock()
+		return
+	}
+
+	// The view may skip several offsets if the changing of view was delayed,
+	// all the arbiters on duty during them missed their duties.
+	for offset := s.viewOffset; offset < view.Offset; offset++ {
+		arbiter := common.BytesToHexString(s.arbitrators.GetNextOnDutyArbitrator(offset))
+		if a, ok := s.arbiters[arbiter]; ok {
+			a.MissedDuties++
+		}
+	}
+	s.viewOffset = view.Offset
+	s.onDuty = view.OnDutyArbitrator
+	if a, ok := s.arbiters[s.onDuty]; ok {
+		a.DutySlots++
+	}
+
+	s.saveRoundAndUnlock()
+}
+
+func (s *ArbiterStatistics) OnConsensusStarted(cons *log.ConsensusEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.updateRound(cons.Height)
+	s.resetConsensus()
+	if s.round == nil {
+		return
+	}
+	s.round.EndHeight = cons.Height
+
+	s.onDuty = common.BytesToHexString(s.arbitrators.GetOnDutyArbitrator())
+	if a, ok := s.arbiters[s.onDuty]; ok {
+		a.DutySlots++
+	}
+}
+
+func (s *ArbiterStatistics) OnConsensusFinished(cons *log.ConsensusEvent) {
+	s.lock.Lock()
+	if s.round == nil {
+		s.lock.Unlock()
+		return
+	}
+	s.saveRoundAndUnlock()
+}
+
+func (s *ArbiterStatistics) OnIllegalEvidenceFound(evidence *log.IllegalEvidenceEvent) {
+	s.lock.Lock()
+	if s.round == nil {
+		s.lock.Unlock()
+		return
+	}
+	if _, ok := s.evidences[evidence.EvidenceHash]; ok {
+		s.lock.Unlock()
+		return
+	}
+	s.evidences[evidence.EvidenceHash] = struct{}{}
+
+	for _, arbiter := range evidence.Arbiters {
+		if a, ok := s.arbiters[arbiter]; ok {
+			a.IllegalEvidences++
+		}
+	}
+	s.saveRoundAndUnlock()
+}
+
+// saveRoundAndUnlock releases s.lock, which must be held by the caller, and
+// persists a copy of the round taken under it.
+func (s *ArbiterStatistics) saveRoundAndUnlock() {
+	round := &interfaces.ArbiterRoundStats{
+		StartHeight: s.round.StartHeight,
+		EndHeight:   s.round.EndHeight,
+		Arbiters:    make([]*interfaces.ArbiterStats, 0, len(s.round.Arbiters)),
+	}
+	for _, a := range s.round.Arbiters {
+		stats := *a
+		round.Arbiters = append(round.Arbiters, &stats)
+	}
+	s.saveLock.Lock()
+	s.lock.Unlock()
+	defer s.saveLock.Unlock()
+
+	s.store.SaveArbiterStats(round)
+}
+
+func (s *ArbiterStatistics) countVote(vote *log.VoteEvent) {
+	a, ok := s.arbiters[vote.Signer]
+	if !ok {
+		return
+	}
+	a.Votes++
+	if !vote.Result {
+		a.RejectedVotes++
+	}
+}
+
+func (s *ArbiterStatistics) resetConsensus() {
+	s.onDuty = ""
+	s.viewOffset = 0
+	s.proposals = make(map[common.Uint256]struct{})
+	s.votes = make(map[common.Uint256]struct{})
+	s.pendingVotes = make(map[common.Uint256][]*log.VoteEvent)
+}
+
+// updateRound starts a new round at the height if the arbiters changed, the
+// round recorded before is continued after restarting.
+func (s *ArbiterStatistics) updateRound(height uint32) {
+	arbiters := s.arbitrators.GetArbitrators()
+	if s.round != nil && s.isRoundOf(s.round, arbiters) {
+		return
+	}
+
+	if s.round == nil {
+		round, err := s.store.GetArbiterStats(height)
+		if err == nil && s.isRoundOf(round, arbiters) {
+			s.setRound(round)
+			return
+		}
+	}
+
+	if len(arbiters) == 0 {
+		s.setRound(nil)
+		return
+	}
+	round := &interfaces.ArbiterRoundStats{
+		StartHeight: height,
+		EndHeight:   height,
+		Arbiters:    make([]*interfaces.ArbiterStats, 0, len(arbiters)),
+	}
+	for _, a := range arbiters {
+		round.Arbiters = append(round.Arbiters, &interfaces.ArbiterStats{
+			Arbiter: common.BytesToHexString(a),
+		})
+	}
+	s.setRound(round)
+}
+
+func (s *ArbiterStatistics) setRound(round *interfaces.ArbiterRoundStats) {
+	s.round = round
+	s.arbiters = make(map[string]*interfaces.ArbiterStats)
+	s.evidences = make(map[common.Uint256]struct{})
+	if round == nil {
+		return
+	}
+	for _, a := range round.Arbiters {
+		s.arbiters[a.Arbiter] = a
+	}
+}
+
+func (s *ArbiterStatistics) isRoundOf(round *interfaces.ArbiterRoundStats,
+	arbiters [][]byte) bool {
+	if len(round.Arbiters) != len(arbiters) {
+		return false
+	}
+	stats := make(map[string]struct{}, len(round.Arbiters))
+	for _, a := range round.Arbiters {
+		stats[a.Arbiter] = struct{}{}
+	}
+	for _, a := range arbiters {
+		if _, ok := stats[common.BytesToHexString(a)]; !ok {
+			return false
+		}
+	}
+	return true
+}
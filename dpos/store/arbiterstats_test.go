@@ -0,0 +1,215 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
+	"github.com/elastos/Elastos.ELA/blockchain/mock"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/dpos/log"
+)
+
+var statsArbiters = [][]byte{{0x0a}, {0x0b}, {0x0c}, {0x0d}}
+
+func newStatsTestStore(t *testing.T) (*DposStore, func()) {
+	log.Init(0, 20, 100)
+
+	dir, err := ioutil.TempDir("", "arbiterstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &DposStore{}
+	if err := s.InitConnection(dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	s.StartRecordArbitrators()
+	return s, func() {
+		s.Disconnect()
+		os.RemoveAll(dir)
+	}
+}
+
+func statsProposalEvent(sponsor []byte, viewOffset uint32) (*log.ProposalEvent, *types.DPosProposal) {
+	proposal := &types.DPosProposal{
+		Sponsor:    common.BytesToHexString(sponsor),
+		BlockHash:  common.Uint256{1},
+		ViewOffset: viewOffset,
+	}
+	return &log.ProposalEvent{
+		Proposal:     proposal.Sponsor,
+		BlockHash:    proposal.BlockHash,
+		ReceivedTime: time.Now(),
+		ProposalHash: proposal.Hash(),
+	}, proposal
+}
+
+func statsVoteEvent(proposal *types.DPosProposal, signer []byte, accept bool) *log.VoteEvent {
+	vote := &types.DPosProposalVote{
+		ProposalHash: proposal.Hash(),
+		Signer:       common.BytesToHexString(signer),
+		Accept:       accept,
+		Sign:         []byte{1},
+	}
+	buf := new(bytes.Buffer)
+	vote.Serialize(buf)
+	return &log.VoteEvent{
+		Signer:       vote.Signer,
+		ReceivedTime: time.Now(),
+		Result:       accept,
+		RawData:      buf.Bytes(),
+	}
+}
+
+func checkArbiterStats(t *testing.T, stats *interfaces.ArbiterStats,
+	expected interfaces.ArbiterStats) {
+	expected.Arbiter = stats.Arbiter
+	if *stats != expected {
+		t.Errorf("arbiter %s got %+v, expected %+v", stats.Arbiter, *stats, expected)
+	}
+}
+
+func TestArbiterStatistics(t *testing.T) {
+	s, cleanup := newStatsTestStore(t)
+	defer cleanup()
+
+	arbitrators := mock.NewArbitratorsMock(statsArbiters, 0, 3)
+	stats := &ArbiterStatistics{}
+	stats.Initialize(s, arbitrators)
+
+	// A is on duty but does not propose, the view changed to C directly, and
+	// the proposal of C is accepted by A, B and C, and rejected by D.
+	stats.OnConsensusStarted(&log.ConsensusEvent{StartTime: time.Now(), Height: 10})
+	first, firstProposal := statsProposalEvent(statsArbiters[1], 1)
+	stats.OnProposalArrived(first)
+	stats.OnViewStarted(&log.ViewEvent{StartTime: time.Now(), Offset: 2,
+		OnDutyArbitrator: common.BytesToHexString(statsArbiters[2]), Height: 10})
+	second, secondProposal := statsProposalEvent(statsArbiters[2], 2)
+	// The vote of A arrived before the proposal
+	stats.OnVoteArrived(statsVoteEvent(secondProposal, statsArbiters[0], true))
+	stats.OnProposalArrived(second)
+	stats.OnProposalArrived(second)
+	stats.OnVoteArrived(statsVoteEvent(secondProposal, statsArbiters[1], true))
+	stats.OnVoteArrived(statsVoteEvent(secondProposal, statsArbiters[1], true))
+	stats.OnVoteArrived(statsVoteEvent(secondProposal, statsArbiters[2], true))
+	stats.OnVoteArrived(statsVoteEvent(secondProposal, statsArbiters[3], false))
+	// The vote on the proposal not sponsored by the on duty arbiter
+	stats.OnVoteArrived(statsVoteEvent(firstProposal, statsArbiters[3], false))
+
+	evidence := &log.IllegalEvidenceEvent{EvidenceHash: common.Uint256{1},
+		Arbiters: []string{common.BytesToHexString(statsArbiters[3])}}
+	stats.OnIllegalEvidenceFound(evidence)
+	stats.OnIllegalEvidenceFound(evidence)
+	stats.OnConsensusFinished(&log.ConsensusEvent{EndTime: time.Now(), Height: 10})
+
+	round, err := s.GetArbiterStats(10)
+	if err != nil {
+		t.Fatal("get arbiter stats failed:", err)
+	}
+	if round.StartHeight != 10 || round.EndHeight != 10 || len(round.Arbiters) != 4 {
+		t.Fatalf("unexpected round %+v", round)
+	}
+	checkArbiterStats(t, round.Arbiters[0], interfaces.ArbiterStats{
+		DutySlots: 1, MissedDuties: 1, ExpectedVotes: 1, Votes: 1})
+	checkArbiterStats(t, round.Arbiters[1], interfaces.ArbiterStats{
+		MissedDuties: 1, ExpectedVotes: 1, Votes: 1})
+	checkArbiterStats(t, round.Arbiters[2], interfaces.ArbiterStats{
+		DutySlots: 1, Proposals: 1, ExpectedVotes: 1, Votes: 1})
+	checkArbiterStats(t, round.Arbiters[3], interfaces.ArbiterStats{
+		ExpectedVotes: 1, Votes: 1, RejectedVotes: 1, IllegalEvidences: 1})
+
+	// The round is continued after restarting
+	arbitrators.SetDutyChangedCount(1)
+	restarted := &ArbiterStatistics{}
+	restarted.Initialize(s, arbitrators)
+	restarted.OnConsensusStarted(&log.ConsensusEvent{StartTime: time.Now(), Height: 11})
+	restarted.OnConsensusFinished(&log.ConsensusEvent{EndTime: time.Now(), Height: 11})
+
+	round, err = s.GetArbiterStats(11)
+	if err != nil {
+		t.Fatal("get arbiter stats failed:", err)
+	}
+	if round.StartHeight != 10 || round.EndHeight != 11 {
+		t.Errorf("unexpected round %d - %d", round.StartHeight, round.EndHeight)
+	}
+	checkArbiterStats(t, round.Arbiters[1], interfaces.ArbiterStats{
+		DutySlots: 1, MissedDuties: 1, ExpectedVotes: 1, Votes: 1})
+
+	// A new round is started after the arbiters changed
+	arbitrators.SetArbitrators([][]byte{{0x0a}, {0x0b}, {0x0c}, {0x0e}})
+	restarted.OnConsensusStarted(&log.ConsensusEvent{StartTime: time.Now(), Height: 12})
+	restarted.OnConsensusFinished(&log.ConsensusEvent{EndTime: time.Now(), Height: 12})
+
+	round, err = s.GetArbiterStats(20)
+	if err != nil {
+		t.Fatal("get arbiter stats failed:", err)
+	}
+	if round.StartHeight != 12 || round.Arbiters[3].Arbiter != "0e" {
+		t.Errorf("unexpected round %+v", round)
+	}
+	round, err = s.GetArbiterStats(11)
+	if err != nil || round.StartHeight != 10 {
+		t.Errorf("unexpected round %+v, err %v", round, err)
+	}
+	if _, err := s.GetArbiterStats(9); err == nil {
+		t.Error("expected error for height before recorded rounds")
+	}
+}
+
+// blockingStatsRecord holds SaveArbiterStats until release is closed.
+type blockingStatsRecord struct {
+	saving  chan *interfaces.ArbiterRoundStats
+	release chan struct{}
+}
+
+func (r *blockingStatsRecord) SaveArbiterStats(round *interfaces.ArbiterRoundStats) {
+	r.saving <- round
+	<-r.release
+}
+
+func (r *blockingStatsRecord) GetArbiterStats(height uint32) (*interfaces.ArbiterRoundStats, error) {
+	return nil, errors.New("not found")
+}
+
+func TestArbiterStatistics_SaveUnlocked(t *testing.T) {
+	log.Init(0, 20, 100)
+	record := &blockingStatsRecord{
+		saving:  make(chan *interfaces.ArbiterRoundStats, 1),
+		release: make(chan struct{}),
+	}
+	arbitrators := mock.NewArbitratorsMock(statsArbiters, 0, 3)
+	stats := &ArbiterStatistics{}
+	stats.Initialize(record, arbitrators)
+	stats.OnConsensusStarted(&log.ConsensusEvent{StartTime: time.Now(), Height: 10})
+
+	finished := make(chan struct{})
+	go func() {
+		stats.OnConsensusFinished(&log.ConsensusEvent{EndTime: time.Now(), Height: 10})
+		close(finished)
+	}()
+	saved := <-record.saving
+
+	// the events are counted while the round is being saved, and the saved
+	// copy of the round is not changed by them
+	proposal, _ := statsProposalEvent(statsArbiters[0], 0)
+	counted := make(chan struct{})
+	go func() {
+		stats.OnProposalArrived(proposal)
+		close(counted)
+	}()
+	select {
+	case <-counted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("statistics are locked while saving")
+	}
+	checkArbiterStats(t, saved.Arbiters[0], interfaces.ArbiterStats{DutySlots: 1})
+
+	close(record.release)
+	<-finished
+}
@@ -31,6 +31,11 @@ type persistDirectPeersTask struct {
 	reply chan bool
 }
 
+type persistArbiterStatsTask struct {
+	round *interfaces.ArbiterRoundStats
+	reply chan bool
+}
+
 func (s *DposStore) arbiterLoop() {
 	s.wg.Add(1)
 
@@ -60,6 +65,11 @@ out:
 				task.reply <- true
 				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
 				log.Debugf("handle persist current arbiters exetime: %g", tcall)
+			case *persistArbiterStatsTask:
+				s.handlePersistArbiterStats(task.round)
+				task.reply <- true
+				tcall := float64(time.Now().Sub(now)) / float64(time.Second)
+				log.Debugf("handle persist arbiter stats exetime: %g", tcall)
 			}
 
 		case <-s.quit:
@@ -75,19 +85,23 @@ func (s *DposStore) StartRecordArbitrators() {
 }
 
 func (s *DposStore) handlePersistDposDutyChangedCount(count uint32) {
-	s.SaveDposDutyChangedCount(count)
+	s.saveDposDutyChangedCount(count)
 }
 
 func (s *DposStore) handlePersistCurrentArbiters(a *Arbitrators) {
-	s.SaveCurrentArbitrators(a)
+	s.saveCurrentArbitrators(a)
 }
 
 func (s *DposStore) handlePersistNextArbiters(a *Arbitrators) {
-	s.SaveNextArbitrators(a)
+	s.saveNextArbitrators(a)
 }
 
 func (s *DposStore) handlePersistDirectPeers(p []*interfaces.DirectPeers) {
-	s.SaveDirectPeers(p)
+	s.saveDirectPeers(p)
+}
+
+func (s *DposStore) handlePersistArbiterStats(r *interfaces.ArbiterRoundStats) {
+	s.saveArbiterStats(r)
 }
 
 func (s *DposStore) SaveDposDutyChangedCount(c uint32) {
@@ -118,6 +132,12 @@ func (s *DposStore) SaveDirectPeers(p []*interfaces.DirectPeers) {
 	<-reply
 }
 
+func (s *DposStore) SaveArbiterStats(r *interfaces.ArbiterRoundStats) {
+	reply := make(chan bool)
+	s.taskCh <- &persistArbiterStatsTask{round: r, reply: reply}
+	<-reply
+}
+
 func (s *DposStore) GetArbitrators(a interfaces.Arbitrators) error {
 	arbiters, ok := a.(*Arbitrators)
 	if !ok {
@@ -146,6 +166,10 @@ func (s *DposStore) GetArbitrators(a interfaces.Arbitrators) error {
 	return nil
 }
 
+func (s *DposStore) GetArbiterStats(height uint32) (*interfaces.ArbiterRoundStats, error) {
+	return s.getArbiterStats(height)
+}
+
 func (s *DposStore) GetDirectPeers() ([]*interfaces.DirectPeers, error) {
 	key := []byte{byte(DPOSDirectPeers)}
 	data, err := s.Get(key)
@@ -234,3 +258,13 @@ func (s *DposStore) saveDirectPeers(p []*interfaces.DirectPeers) {
 	}
 	batch.Commit()
 }
+
+func (s *DposStore) saveArbiterStats(r *interfaces.ArbiterRoundStats) {
+	log.Debug("SaveArbiterStats()")
+	batch := s.NewBatch()
+	if err := s.persistArbiterStats(batch, r); err != nil {
+		log.Error("[persistArbiterStats]: error to persist arbiter stats:", err.Error())
+		return
+	}
+	batch.Commit()
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 
 	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
 	"github.com/elastos/Elastos.ELA/common"
@@ -189,3 +191,86 @@ func (s *DposStore) persistDirectPeers(batch Batch, peers []*interfaces.DirectPe
 	batch.Put(key.Bytes(), value.Bytes())
 	return nil
 }
+
+func (s *DposStore) persistArbiterStats(batch Batch, r *interfaces.ArbiterRoundStats) error {
+	value := new(bytes.Buffer)
+	if err := common.WriteUint32(value, r.StartHeight); err != nil {
+		return err
+	}
+
+	if err := common.WriteUint32(value, r.EndHeight); err != nil {
+		return err
+	}
+
+	if err := common.WriteVarUint(value, uint64(len(r.Arbiters))); err != nil {
+		return err
+	}
+
+	for _, a := range r.Arbiters {
+		if err := common.WriteVarString(value, a.Arbiter); err != nil {
+			return err
+		}
+
+		if err := common.WriteElements(value, a.DutySlots, a.Proposals,
+			a.MissedDuties, a.ExpectedVotes, a.Votes, a.RejectedVotes,
+			a.IllegalEvidences); err != nil {
+			return err
+		}
+	}
+
+	batch.Put(getArbiterStatsKey(r.StartHeight), value.Bytes())
+	return nil
+}
+
+// getArbiterStats returns the stats of the last election round started at or
+// before the height.
+func (s *DposStore) getArbiterStats(height uint32) (*interfaces.ArbiterRoundStats, error) {
+	iter := s.NewIterator([]byte{byte(DPOSArbiterStats)})
+	defer iter.Release()
+
+	if !iter.Seek(getArbiterStatsKey(height)) {
+		if !iter.Last() {
+			return nil, errors.New("no arbiter stats recorded")
+		}
+	} else if !bytes.Equal(iter.Key(), getArbiterStatsKey(height)) {
+		if !iter.Prev() {
+			return nil, errors.New("no arbiter stats recorded before the height")
+		}
+	}
+
+	r := bytes.NewReader(iter.Value())
+	round := &interfaces.ArbiterRoundStats{}
+	if err := common.ReadElements(r, &round.StartHeight, &round.EndHeight); err != nil {
+		return nil, err
+	}
+
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		a := &interfaces.ArbiterStats{}
+		if a.Arbiter, err = common.ReadVarString(r); err != nil {
+			return nil, err
+		}
+
+		if err := common.ReadElements(r, &a.DutySlots, &a.Proposals,
+			&a.MissedDuties, &a.ExpectedVotes, &a.Votes, &a.RejectedVotes,
+			&a.IllegalEvidences); err != nil {
+			return nil, err
+		}
+		round.Arbiters = append(round.Arbiters, a)
+	}
+
+	return round, nil
+}
+
+// getArbiterStatsKey returns the key of the stats of the election round
+// started at the height, in big endian to be iterated in order of height.
+func getArbiterStatsKey(startHeight uint32) []byte {
+	key := make([]byte, 5)
+	key[0] = byte(DPOSArbiterStats)
+	binary.BigEndian.PutUint32(key[1:], startHeight)
+	return key
+}
@@ -0,0 +1,304 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Ensure DposStore implements IEventQuery interface.
+var _ interfaces.IEventQuery = (*DposStore)(nil)
+
+func (s *DposStore) GetConsensusEvents(startHeight, endHeight uint32) ([]*interfaces.ConsensusRecord, error) {
+	if err := checkHeightRange(startHeight, endHeight); err != nil {
+		return nil, err
+	}
+
+	var result []*interfaces.ConsensusRecord
+	for height := startHeight; height <= endHeight; height++ {
+		rows, err := s.selectIndexed(ConsensusEventTable, &interfaces.Field{Name: "Height", Value: height})
+		if err != nil {
+			return nil, err
+		}
+		for _, fields := range rows {
+			values := fieldValues(fields)
+			result = append(result, &interfaces.ConsensusRecord{
+				Height:    height,
+				StartTime: timeValue(values["StartTime"]),
+				EndTime:   timeValue(values["EndTime"]),
+			})
+		}
+		if height == endHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *DposStore) GetProposalEvents(filter *interfaces.EventFilter) ([]*interfaces.ProposalRecord, error) {
+	if err := checkHeightRange(filter.StartHeight, filter.EndHeight); err != nil {
+		return nil, err
+	}
+
+	var result []*interfaces.ProposalRecord
+	for height := filter.StartHeight; height <= filter.EndHeight; height++ {
+		proposals, err := s.getProposals(height)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range proposals {
+			if filter.Arbiter != "" && p.Sponsor != filter.Arbiter {
+				continue
+			}
+			if filter.ViewOffset != nil && p.ViewOffset != *filter.ViewOffset {
+				continue
+			}
+			result = append(result, p)
+		}
+		if height == filter.EndHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *DposStore) GetVoteEvents(filter *interfaces.EventFilter) ([]*interfaces.VoteRecord, error) {
+	if err := checkHeightRange(filter.StartHeight, filter.EndHeight); err != nil {
+		return nil, err
+	}
+
+	var result []*interfaces.VoteRecord
+	for height := filter.StartHeight; height <= filter.EndHeight; height++ {
+		proposals, err := s.getProposals(height)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range proposals {
+			if filter.ViewOffset != nil && p.ViewOffset != *filter.ViewOffset {
+				continue
+			}
+			votes, err := s.getVotes(p)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range votes {
+				if filter.Arbiter != "" && v.Signer != filter.Arbiter {
+					continue
+				}
+				result = append(result, v)
+			}
+		}
+		if height == filter.EndHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *DposStore) GetViewEvents(filter *interfaces.EventFilter) ([]*interfaces.ViewRecord, error) {
+	if err := checkHeightRange(filter.StartHeight, filter.EndHeight); err != nil {
+		return nil, err
+	}
+
+	var result []*interfaces.ViewRecord
+	for height := filter.StartHeight; height <= filter.EndHeight; height++ {
+		views, err := s.getViews(height)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range views {
+			if filter.Arbiter != "" && v.OnDutyArbitrator != filter.Arbiter {
+				continue
+			}
+			if filter.ViewOffset != nil && v.Offset != *filter.ViewOffset {
+				continue
+			}
+			result = append(result, v)
+		}
+		if height == filter.EndHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (s *DposStore) GetConsensusTimelines(startHeight, endHeight uint32) ([]*interfaces.ConsensusTimeline, error) {
+	if err := checkHeightRange(startHeight, endHeight); err != nil {
+		return nil, err
+	}
+
+	var result []*interfaces.ConsensusTimeline
+	for height := startHeight; height <= endHeight; height++ {
+		consensus, err := s.GetConsensusEvents(height, height)
+		if err != nil {
+			return nil, err
+		}
+		views, err := s.getViews(height)
+		if err != nil {
+			return nil, err
+		}
+		proposals, err := s.getProposals(height)
+		if err != nil {
+			return nil, err
+		}
+		if len(consensus) == 0 && len(views) == 0 && len(proposals) == 0 {
+			if height == endHeight {
+				break
+			}
+			continue
+		}
+
+		timeline := &interfaces.ConsensusTimeline{
+			Consensus: &interfaces.ConsensusRecord{Height: height},
+			Views:     views,
+			Proposals: proposals,
+		}
+		// The consensus may be started again after restarting, the last one
+		// is the one finished.
+		if len(consensus) > 0 {
+			timeline.Consensus = consensus[len(consensus)-1]
+		}
+		for _, p := range proposals {
+			votes, err := s.getVotes(p)
+			if err != nil {
+				return nil, err
+			}
+			timeline.Votes = append(timeline.Votes, votes...)
+		}
+		sort.SliceStable(timeline.Votes, func(i, j int) bool {
+			return timeline.Votes[i].ReceivedTime.Before(timeline.Votes[j].ReceivedTime)
+		})
+		result = append(result, timeline)
+
+		if height == endHeight {
+			break
+		}
+	}
+	return result, nil
+}
+
+// getProposals returns the proposals of the block at the height.
+func (s *DposStore) getProposals(height uint32) ([]*interfaces.ProposalRecord, error) {
+	rows, err := s.selectIndexed(ProposalEventTable, &interfaces.Field{Name: "Height", Value: height})
+	if err != nil {
+		return nil, err
+	}
+
+	proposals := make([]*interfaces.ProposalRecord, 0, len(rows))
+	for _, fields := range rows {
+		values := fieldValues(fields)
+		p := &interfaces.ProposalRecord{
+			Height:       height,
+			ReceivedTime: timeValue(values["ReceivedTime"]),
+			EndTime:      timeValue(values["EndTime"]),
+		}
+		p.Sponsor, _ = values["Proposal"].(string)
+		p.Result, _ = values["Result"].(bool)
+		p.ProposalHash, _ = values["ProposalHash"].(common.Uint256)
+		if hash, ok := values["BlockHash"].([]byte); ok {
+			if blockHash, err := common.Uint256FromBytes(hash); err == nil {
+				p.BlockHash = *blockHash
+			}
+		}
+		if data, ok := values["RawData"].([]byte); ok {
+			var proposal types.DPosProposal
+			if err := proposal.Deserialize(bytes.NewReader(data)); err == nil {
+				p.ViewOffset = proposal.ViewOffset
+			}
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals, nil
+}
+
+// getVotes returns the votes on the proposal.
+func (s *DposStore) getVotes(proposal *interfaces.ProposalRecord) ([]*interfaces.VoteRecord, error) {
+	rows, err := s.selectIndexed(VoteEventTable, &interfaces.Field{
+		Name: "ProposalHash", Value: proposal.ProposalHash})
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]*interfaces.VoteRecord, 0, len(rows))
+	for _, fields := range rows {
+		values := fieldValues(fields)
+		v := &interfaces.VoteRecord{
+			Height:       proposal.Height,
+			ProposalHash: proposal.ProposalHash,
+			ReceivedTime: timeValue(values["ReceivedTime"]),
+		}
+		v.Signer, _ = values["Signer"].(string)
+		v.Accept, _ = values["Result"].(bool)
+		votes = append(votes, v)
+	}
+	return votes, nil
+}
+
+// getViews returns the view changes occurred in the consensus of the block at
+// the height.
+func (s *DposStore) getViews(height uint32) ([]*interfaces.ViewRecord, error) {
+	rows, err := s.selectIndexed(ViewEventTable, &interfaces.Field{Name: "Height", Value: height})
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*interfaces.ViewRecord, 0, len(rows))
+	for _, fields := range rows {
+		values := fieldValues(fields)
+		v := &interfaces.ViewRecord{
+			Height:    height,
+			StartTime: timeValue(values["StartTime"]),
+		}
+		v.OnDutyArbitrator, _ = values["OnDutyArbitrator"].(string)
+		v.Offset, _ = values["Offset"].(uint32)
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+// selectIndexed returns the rows of the table matching the indexed field in
+// the order they were inserted, no rows found is not an error.
+func (s *DposStore) selectIndexed(table *interfaces.DBTable,
+	field *interfaces.Field) ([][]*interfaces.Field, error) {
+	ids, err := s.selectRowsByField(table, field)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return s.selectValuesFromRowIDs(table, ids)
+}
+
+func checkHeightRange(startHeight, endHeight uint32) error {
+	if startHeight > endHeight {
+		return errors.New("start height is greater than end height")
+	}
+	return nil
+}
+
+func fieldValues(fields []*interfaces.Field) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		values[f.Name] = f.Value
+	}
+	return values
+}
+
+// timeValue returns the time of the field value in unix nanoseconds, or the
+// zero time if it is not set.
+func timeValue(value interface{}) time.Time {
+	nano, ok := value.(int64)
+	if !ok || nano <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
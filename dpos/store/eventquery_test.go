@@ -0,0 +1,196 @@
+package store
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/dpos/log"
+)
+
+func newQueryTestStore(t *testing.T) (*DposStore, func()) {
+	dir, err := ioutil.TempDir("", "eventquery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &DposStore{}
+	if err := s.InitConnection(dir); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	s.createConsensusEventTable()
+	s.createProposalEventTable()
+	s.createVoteEventTable()
+	s.createViewEventTable()
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func addQueryTestProposal(t *testing.T, s *DposStore, height uint32,
+	sponsor string, viewOffset uint32, receivedTime time.Time) *types.DPosProposal {
+	proposal := &types.DPosProposal{
+		Sponsor:    sponsor,
+		BlockHash:  common.Uint256{byte(height), byte(viewOffset)},
+		ViewOffset: viewOffset,
+		Sign:       []byte{1},
+	}
+	buf := new(bytes.Buffer)
+	proposal.Serialize(buf)
+	_, err := s.addProposalEvent(&log.ProposalEvent{
+		Proposal:     sponsor,
+		BlockHash:    proposal.BlockHash,
+		ReceivedTime: receivedTime,
+		ProposalHash: proposal.Hash(),
+		RawData:      buf.Bytes(),
+		Height:       height,
+	})
+	if err != nil {
+		t.Fatal("add proposal event failed:", err)
+	}
+	return proposal
+}
+
+func addQueryTestVote(t *testing.T, s *DposStore, proposal *types.DPosProposal,
+	signer string, accept bool, receivedTime time.Time) {
+	vote := &types.DPosProposalVote{
+		ProposalHash: proposal.Hash(),
+		Signer:       signer,
+		Accept:       accept,
+		Sign:         []byte{1},
+	}
+	buf := new(bytes.Buffer)
+	vote.Serialize(buf)
+	_, err := s.addVoteEvent(&log.VoteEvent{
+		Signer:       signer,
+		ReceivedTime: receivedTime,
+		Result:       accept,
+		RawData:      buf.Bytes(),
+	})
+	if err != nil {
+		t.Fatal("add vote event failed:", err)
+	}
+}
+
+func TestDposStore_GetConsensusTimelines(t *testing.T) {
+	s, cleanup := newQueryTestStore(t)
+	defer cleanup()
+
+	start := time.Unix(1000, 0)
+	if _, err := s.addConsensusEvent(&log.ConsensusEvent{
+		StartTime: start, Height: 10, RawData: []byte{10}}); err != nil {
+		t.Fatal("add consensus event failed:", err)
+	}
+
+	// The first proposal of A is rejected, and B is on duty after the view
+	// changed.
+	first := addQueryTestProposal(t, s, 10, "A", 0, start.Add(time.Second))
+	addQueryTestVote(t, s, first, "B", false, start.Add(2*time.Second))
+	addQueryTestVote(t, s, first, "C", false, start.Add(3*time.Second))
+	if _, err := s.addViewEvent(&log.ViewEvent{OnDutyArbitrator: "B",
+		StartTime: start.Add(5 * time.Second), Offset: 1, Height: 10}); err != nil {
+		t.Fatal("add view event failed:", err)
+	}
+	second := addQueryTestProposal(t, s, 10, "B", 1, start.Add(6*time.Second))
+	addQueryTestVote(t, s, second, "A", true, start.Add(7*time.Second))
+	addQueryTestVote(t, s, second, "C", true, start.Add(8*time.Second))
+
+	if _, err := s.updateConsensusEvent(&log.ConsensusEvent{
+		EndTime: start.Add(9 * time.Second), Height: 10}); err != nil {
+		t.Fatal("update consensus event failed:", err)
+	}
+
+	// A block without consensus recorded
+	addQueryTestProposal(t, s, 11, "C", 0, start.Add(10*time.Second))
+
+	timelines, err := s.GetConsensusTimelines(9, 11)
+	if err != nil {
+		t.Fatal("get consensus timelines failed:", err)
+	}
+	if len(timelines) != 2 {
+		t.Fatalf("got %d timelines, expected 2", len(timelines))
+	}
+
+	timeline := timelines[0]
+	if timeline.Consensus.Height != 10 ||
+		!timeline.Consensus.StartTime.Equal(start) ||
+		!timeline.Consensus.EndTime.Equal(start.Add(9*time.Second)) {
+		t.Errorf("unexpected consensus %+v", timeline.Consensus)
+	}
+	if len(timeline.Views) != 1 || timeline.Views[0].OnDutyArbitrator != "B" ||
+		timeline.Views[0].Offset != 1 {
+		t.Errorf("unexpected views %+v", timeline.Views)
+	}
+	if len(timeline.Proposals) != 2 || timeline.Proposals[0].Sponsor != "A" ||
+		timeline.Proposals[1].Sponsor != "B" || timeline.Proposals[1].ViewOffset != 1 {
+		t.Errorf("unexpected proposals %+v", timeline.Proposals)
+	}
+	if len(timeline.Votes) != 4 {
+		t.Fatalf("got %d votes, expected 4", len(timeline.Votes))
+	}
+	for i, signer := range []string{"B", "C", "A", "C"} {
+		if timeline.Votes[i].Signer != signer || timeline.Votes[i].Accept != (i >= 2) {
+			t.Errorf("unexpected vote %d %+v", i, timeline.Votes[i])
+		}
+	}
+
+	if timelines[1].Consensus.Height != 11 ||
+		!timelines[1].Consensus.StartTime.IsZero() ||
+		len(timelines[1].Proposals) != 1 {
+		t.Errorf("unexpected timeline %+v", timelines[1])
+	}
+}
+
+func TestDposStore_GetEventsByFilter(t *testing.T) {
+	s, cleanup := newQueryTestStore(t)
+	defer cleanup()
+
+	now := time.Now()
+	for height := uint32(1); height <= 3; height++ {
+		p := addQueryTestProposal(t, s, height, "A", 0, now)
+		addQueryTestVote(t, s, p, "B", true, now)
+		addQueryTestVote(t, s, p, "C", height != 2, now)
+	}
+	p := addQueryTestProposal(t, s, 2, "B", 1, now)
+	addQueryTestVote(t, s, p, "C", true, now)
+	if _, err := s.addViewEvent(&log.ViewEvent{OnDutyArbitrator: "B",
+		StartTime: now, Offset: 1, Height: 2}); err != nil {
+		t.Fatal("add view event failed:", err)
+	}
+
+	proposals, err := s.GetProposalEvents(&interfaces.EventFilter{
+		StartHeight: 1, EndHeight: 3, Arbiter: "A"})
+	if err != nil || len(proposals) != 3 {
+		t.Errorf("got %d proposals of A, expected 3, err %v", len(proposals), err)
+	}
+
+	viewOffset := uint32(1)
+	proposals, err = s.GetProposalEvents(&interfaces.EventFilter{
+		StartHeight: 1, EndHeight: 3, ViewOffset: &viewOffset})
+	if err != nil || len(proposals) != 1 || proposals[0].Sponsor != "B" {
+		t.Errorf("unexpected proposals in view 1 %+v, err %v", proposals, err)
+	}
+
+	votes, err := s.GetVoteEvents(&interfaces.EventFilter{
+		StartHeight: 2, EndHeight: 2, Arbiter: "C"})
+	if err != nil || len(votes) != 2 || votes[0].Accept || !votes[1].Accept {
+		t.Errorf("unexpected votes of C at height 2 %+v, err %v", votes, err)
+	}
+
+	views, err := s.GetViewEvents(&interfaces.EventFilter{
+		StartHeight: 1, EndHeight: 3, Arbiter: "B"})
+	if err != nil || len(views) != 1 || views[0].Height != 2 {
+		t.Errorf("unexpected views of B %+v, err %v", views, err)
+	}
+
+	if _, err := s.GetViewEvents(&interfaces.EventFilter{
+		StartHeight: 3, EndHeight: 1}); err == nil {
+		t.Error("expected error for invalid height range")
+	}
+}
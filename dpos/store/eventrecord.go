@@ -55,3 +55,7 @@ func (e *EventRecord) OnConsensusFinished(cons *log.ConsensusEvent) {
 		log.Error("[OnConsensusFinished] err:", err.Error())
 	}
 }
+
+func (e *EventRecord) OnIllegalEvidenceFound(evidence *log.IllegalEvidenceEvent) {
+	// Illegal evidences are recorded by the transactions packed in blocks.
+}
@@ -27,7 +27,7 @@ var ConsensusEventTable = &interfaces.DBTable{
 var ProposalEventTable = &interfaces.DBTable{
 	Name:       "ProposalEvent",
 	PrimaryKey: 7,
-	Indexes:    []uint64{1, 2, 6},
+	Indexes:    []uint64{1, 2, 6, 8},
 	Fields: []string{
 		"Proposal",
 		"BlockHash",
@@ -36,31 +36,34 @@ var ProposalEventTable = &interfaces.DBTable{
 		"Result",
 		"ProposalHash",
 		"RawData",
+		"Height",
 	},
 }
 
 var VoteEventTable = &interfaces.DBTable{
 	Name:       "VoteEvent",
 	PrimaryKey: 0,
-	Indexes:    nil,
+	Indexes:    []uint64{2, 6},
 	Fields: []string{
 		"ProposalID",
 		"Signer",
 		"ReceivedTime",
 		"Result",
 		"RawData",
+		"ProposalHash",
 	},
 }
 
 var ViewEventTable = &interfaces.DBTable{
 	Name:       "ViewEvent",
 	PrimaryKey: 0,
-	Indexes:    nil,
+	Indexes:    []uint64{2, 5},
 	Fields: []string{
 		"ConsensusID",
 		"OnDutyArbitrator",
 		"StartTime",
 		"Offset",
+		"Height",
 	},
 }
 
@@ -286,6 +289,7 @@ func (s *DposStore) addProposalEvent(event *log.ProposalEvent) (uint64, error) {
 		{"Result", event.Result},
 		{"ProposalHash", event.ProposalHash},
 		{"RawData", event.RawData},
+		{"Height", event.Height},
 	})
 }
 func (s *DposStore) UpdateProposalEvent(event interface{}) error {
@@ -352,6 +356,7 @@ func (s *DposStore) addVoteEvent(event *log.VoteEvent) (uint64, error) {
 		{"ReceivedTime", event.ReceivedTime.UnixNano()},
 		{"Result", event.Result},
 		{"RawData", event.RawData},
+		{"ProposalHash", vote.ProposalHash},
 	})
 }
 
@@ -388,5 +393,6 @@ func (s *DposStore) addViewEvent(event *log.ViewEvent) (uint64, error) {
 		{"OnDutyArbitrator", event.OnDutyArbitrator},
 		{"StartTime", event.StartTime.UnixNano()},
 		{"Offset", event.Offset},
+		{"Height", event.Height},
 	})
 }
@@ -24,6 +24,8 @@ const (
 	ErrReturnDepositConsensus ErrCode = 45021
 	ErrProducerProcessing     ErrCode = 45022
 	ErrProducerNodeProcessing ErrCode = 45023
+	ErrTxPoolFull             ErrCode = 45024
+	ErrTooManyAncestors       ErrCode = 45025
 
 	SessionExpired       ErrCode = 41001
 	IllegalDataFormat    ErrCode = 41003
@@ -59,6 +61,8 @@ var ErrMap = map[ErrCode]string{
 	ErrReturnDepositConsensus: "Error return deposit consensus",
 	ErrProducerProcessing:     "Error producer processing",
 	ErrProducerNodeProcessing: "Error producer node processing",
+	ErrTxPoolFull:             "Error transaction pool full",
+	ErrTooManyAncestors:       "Error too many unconfirmed ancestors",
 	ErrInvalidInput:           "INTERNAL ERROR, ErrInvalidInput",
 	ErrInvalidOutput:          "INTERNAL ERROR, ErrInvalidOutput",
 	ErrAssetPrecision:         "INTERNAL ERROR, ErrAssetPrecision",
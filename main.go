@@ -13,6 +13,7 @@ import (
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
 	"github.com/elastos/Elastos.ELA/dpos"
+	"github.com/elastos/Elastos.ELA/dpos/account"
 	"github.com/elastos/Elastos.ELA/dpos/store"
 	"github.com/elastos/Elastos.ELA/node"
 	"github.com/elastos/Elastos.ELA/pow"
@@ -65,12 +66,51 @@ func startConsensus() {
 	}
 }
 
+func saveTxPool(noder protocol.Noder) {
+	if err := noder.SaveFeeEstimator(); err != nil {
+		log.Error("save fee estimator failed:", err)
+	}
+	if config.Parameters.DisableTxPoolPersist {
+		return
+	}
+	if err := noder.SaveTxPool(); err != nil {
+		log.Error("save transaction pool failed:", err)
+	}
+}
+
+// newDposAccount returns the account signs by the remote signer if configured,
+// or by the keystore with the sign ledger otherwise.
+func newDposAccount() (account.DposAccount, *account.SignLedger, error) {
+	if signer := config.Parameters.ArbiterConfiguration.Signer; signer != "" {
+		log.Info("Connect the remote signer ", signer)
+		dposAccount, err := account.NewRemoteAccount(signer, config.Parameters.GetArbiterID())
+		return dposAccount, nil, err
+	}
+
+	pwd, err := password.GetFlagPassword()
+	if err != nil {
+		return nil, nil, err
+	}
+	signLedger, err := account.NewSignLedger(filepath.Join(config.DataPath,
+		config.DataDir, config.SignLedgerDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	dposAccount, err := account.NewDposAccount(pwd, signLedger)
+	if err != nil {
+		signLedger.Close()
+		return nil, nil, err
+	}
+	return dposAccount, signLedger, nil
+}
+
 func main() {
 	//var blockChain *ledger.Blockchain
 	var err error
 	var noder protocol.Noder
-	var pwd []byte
+	var dposAccount account.DposAccount
 	var arbitrator dpos.Arbitrator
+	var signLedger *account.SignLedger
 	var interrupt = signal.NewInterrupt()
 
 	log.Info("Node version: ", config.Version)
@@ -106,15 +146,19 @@ func main() {
 
 	if config.EnableArbiter {
 		log.Info("Start the manager")
-		pwd, err = password.GetFlagPassword()
+		dposAccount, signLedger, err = newDposAccount()
 		if err != nil {
 			goto ERROR
 		}
-		arbitrator, err = dpos.NewArbitrator(pwd,
+		if signLedger != nil {
+			defer signLedger.Close()
+		}
+		arbitrator, err = dpos.NewArbitrator(dposAccount,
 			dpos.ArbitratorConfig{
-				EnableEventLog:    true,
-				EnableEventRecord: true,
-				Store:             dposStore,
+				EnableEventLog:     true,
+				EnableEventRecord:  true,
+				EnableArbiterStats: true,
+				Store:              dposStore,
 			})
 		if err != nil {
 			goto ERROR
@@ -126,8 +170,12 @@ func main() {
 	}
 
 	servers.ServerNode = noder
+	servers.EventQuery = dposStore
+	servers.ArbiterStats = dposStore
 	servers.ServerNode.RegisterTxPoolListener(arbitrator)
 	servers.ServerNode.RegisterTxPoolListener(chainStore)
+	noder.LoadFeeEstimator()
+	noder.LoadTxPool()
 	servers.LocalPow = pow.NewPowService()
 
 	log.Info("Start services")
@@ -140,13 +188,20 @@ func main() {
 
 	noder.WaitForSyncFinish(interrupt.C)
 	if interrupt.Interrupted() {
+		saveTxPool(noder)
 		return
 	}
 	log.Info("Start consensus")
 	startConsensus()
 
 	<-interrupt.C
+	saveTxPool(noder)
 ERROR:
 	log.Error(err)
+	// The deferred functions are not run by os.Exit, so the sign ledger is
+	// closed here to flush and unlock it.
+	if signLedger != nil {
+		signLedger.Close()
+	}
 	os.Exit(-1)
 }
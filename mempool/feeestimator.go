@@ -0,0 +1,309 @@
+package mempool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	. "github.com/elastos/Elastos.ELA/common"
+	. "github.com/elastos/Elastos.ELA/core/types"
+)
+
+const (
+	// EstimateFeeDepth is the maximum number of blocks a fee rate can be
+	// estimated for.
+	EstimateFeeDepth = 25
+
+	// FeeEstimatorFile is the name of the file under the data directory the
+	// fee estimator state is saved to.
+	FeeEstimatorFile = "feeestimates.dat"
+
+	// minBucketFeeRate and maxBucketFeeRate are the fee rates in sela per KB
+	// of the lowest and highest fee rate bucket.
+	minBucketFeeRate = 100
+	maxBucketFeeRate = 1e8
+
+	// bucketSpacing is the ratio between the fee rates of two adjacent
+	// buckets.
+	bucketSpacing = 1.1
+
+	// feeDecay is multiplied to the statistics on every new block, so recent
+	// blocks weigh more than old ones.
+	feeDecay = 0.998
+
+	// minSampleSize is the number of transactions a group of buckets must
+	// have before its confirmation ratio is trusted.
+	minSampleSize = 2
+)
+
+// feeBucket collects the confirmation statistics of transactions with fee
+// rate not lower than feeRate and lower than the fee rate of the next bucket.
+type feeBucket struct {
+	feeRate float64
+
+	// confirmed[i] is the number of transactions confirmed within i+1 blocks.
+	confirmed [EstimateFeeDepth]float64
+
+	// total is the number of transactions confirmed, or still unconfirmed
+	// after EstimateFeeDepth blocks.
+	total float64
+
+	// feeRateSum is the sum of the fee rates of transactions counted in
+	// total.
+	feeRateSum float64
+}
+
+// observedTx is a transaction in pool waiting for confirmation.
+type observedTx struct {
+	feeRate Fixed64
+	height  uint32
+}
+
+// FeeEstimator records the fee rate of pool transactions and how many blocks
+// they took to be confirmed, and estimates the fee rate needed to get a
+// transaction confirmed within a number of blocks.
+type FeeEstimator struct {
+	sync.Mutex
+	buckets  []*feeBucket
+	observed map[Uint256]*observedTx
+}
+
+func NewFeeEstimator() *FeeEstimator {
+	var buckets []*feeBucket
+	for rate := float64(minBucketFeeRate); rate < maxBucketFeeRate; rate *= bucketSpacing {
+		buckets = append(buckets, &feeBucket{feeRate: rate})
+	}
+	return &FeeEstimator{
+		buckets:  buckets,
+		observed: make(map[Uint256]*observedTx),
+	}
+}
+
+// bucketIndex returns the index of the bucket feeRate belongs to.
+func (e *FeeEstimator) bucketIndex(feeRate Fixed64) int {
+	i := sort.Search(len(e.buckets), func(i int) bool {
+		return e.buckets[i].feeRate > float64(feeRate)
+	})
+	if i > 0 {
+		i--
+	}
+	return i
+}
+
+// ObserveTransaction starts waiting for txn to be confirmed, height is the
+// best block height when txn arrived.
+func (e *FeeEstimator) ObserveTransaction(txn *Transaction, height uint32) {
+	e.Lock()
+	defer e.Unlock()
+	if _, ok := e.observed[txn.Hash()]; ok {
+		return
+	}
+	e.observed[txn.Hash()] = &observedTx{feeRate: txn.FeePerKB, height: height}
+}
+
+// RemoveTransaction stops waiting for the transaction, it is called when the
+// transaction has been removed from pool without being confirmed.
+func (e *FeeEstimator) RemoveTransaction(hash Uint256) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.observed, hash)
+}
+
+// RegisterBlock records the confirmation of the observed transactions in
+// block, and counts those waiting longer than EstimateFeeDepth blocks as
+// failed to confirm.
+func (e *FeeEstimator) RegisterBlock(block *Block) {
+	e.Lock()
+	defer e.Unlock()
+
+	for _, b := range e.buckets {
+		for i := range b.confirmed {
+			b.confirmed[i] *= feeDecay
+		}
+		b.total *= feeDecay
+		b.feeRateSum *= feeDecay
+	}
+
+	for _, txn := range block.Transactions {
+		tx, ok := e.observed[txn.Hash()]
+		if !ok {
+			continue
+		}
+		delete(e.observed, txn.Hash())
+		if block.Height <= tx.height {
+			continue
+		}
+		b := e.buckets[e.bucketIndex(tx.feeRate)]
+		for i := int(block.Height-tx.height) - 1; i < EstimateFeeDepth; i++ {
+			b.confirmed[i]++
+		}
+		b.total++
+		b.feeRateSum += float64(tx.feeRate)
+	}
+
+	for hash, tx := range e.observed {
+		if block.Height > tx.height && block.Height-tx.height > EstimateFeeDepth {
+			b := e.buckets[e.bucketIndex(tx.feeRate)]
+			b.total++
+			b.feeRateSum += float64(tx.feeRate)
+			delete(e.observed, hash)
+		}
+	}
+}
+
+// EstimateFee returns the lowest fee rate in sela per KB with which at least
+// confidence (between 0 and 1) of the transactions were confirmed within
+// confirmations blocks. Buckets are grouped from the highest fee rate down
+// until the group has enough transactions, the estimate stops at the first
+// group failing the confidence.
+func (e *FeeEstimator) EstimateFee(confirmations uint32, confidence float64) (Fixed64, error) {
+	if confirmations == 0 || confirmations > EstimateFeeDepth {
+		return 0, fmt.Errorf("confirmations should be between 1 and %d",
+			EstimateFeeDepth)
+	}
+	if confidence <= 0 || confidence > 1 {
+		return 0, errors.New("confidence should be between 0 and 1")
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	var confirmed, total, feeRateSum float64
+	var feeRate Fixed64
+	for i := len(e.buckets) - 1; i >= 0; i-- {
+		b := e.buckets[i]
+		confirmed += b.confirmed[confirmations-1]
+		total += b.total
+		feeRateSum += b.feeRateSum
+		if total < minSampleSize {
+			continue
+		}
+		if confirmed/total < confidence {
+			break
+		}
+		feeRate = Fixed64(feeRateSum / total)
+		confirmed, total, feeRateSum = 0, 0, 0
+	}
+	if feeRate == 0 {
+		return 0, errors.New("insufficient data to estimate fee")
+	}
+	return feeRate, nil
+}
+
+// Save writes the statistics and observed transactions to a temporary file
+// first and then renames it to path, so an interrupted write never leaves a
+// broken file.
+func (e *FeeEstimator) Save(path string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = e.serialize(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Load reads back the statistics and observed transactions saved by Save.
+func (e *FeeEstimator) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	loaded := NewFeeEstimator()
+	if err := loaded.deserialize(bufio.NewReader(file)); err != nil {
+		return err
+	}
+	e.Lock()
+	e.buckets = loaded.buckets
+	e.observed = loaded.observed
+	e.Unlock()
+	return nil
+}
+
+func (e *FeeEstimator) serialize(w io.Writer) error {
+	if err := WriteVarUint(w, uint64(len(e.buckets))); err != nil {
+		return err
+	}
+	for _, b := range e.buckets {
+		values := append(b.confirmed[:], b.total, b.feeRateSum)
+		for _, v := range values {
+			if err := WriteUint64(w, math.Float64bits(v)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := WriteVarUint(w, uint64(len(e.observed))); err != nil {
+		return err
+	}
+	for hash, tx := range e.observed {
+		if err := hash.Serialize(w); err != nil {
+			return err
+		}
+		if err := WriteElements(w, tx.feeRate, tx.height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *FeeEstimator) deserialize(r io.Reader) error {
+	count, err := ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count != uint64(len(e.buckets)) {
+		return fmt.Errorf("fee estimator has %d buckets, %d saved",
+			len(e.buckets), count)
+	}
+	for _, b := range e.buckets {
+		var values [EstimateFeeDepth + 2]float64
+		for i := range values {
+			v, err := ReadUint64(r)
+			if err != nil {
+				return err
+			}
+			values[i] = math.Float64frombits(v)
+		}
+		copy(b.confirmed[:], values[:EstimateFeeDepth])
+		b.total = values[EstimateFeeDepth]
+		b.feeRateSum = values[EstimateFeeDepth+1]
+	}
+
+	count, err = ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		var hash Uint256
+		if err := hash.Deserialize(r); err != nil {
+			return err
+		}
+		tx := new(observedTx)
+		if err := ReadElements(r, &tx.feeRate, &tx.height); err != nil {
+			return err
+		}
+		e.observed[hash] = tx
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package mempool
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeEstimator_EstimateFee(t *testing.T) {
+	estimator := NewFeeEstimator()
+	_, err := estimator.EstimateFee(1, 0.95)
+	assert.Error(t, err)
+	_, err = estimator.EstimateFee(EstimateFeeDepth+1, 0.95)
+	assert.Error(t, err)
+
+	newTx := func(feeRate common.Fixed64) *types.Transaction {
+		var nonce [32]byte
+		rand.Read(nonce[:])
+		tx := new(types.Transaction)
+		tx.TxType = types.TransferAsset
+		tx.Payload = &payload.PayloadTransferAsset{}
+		tx.Attributes = []*types.Attribute{{Usage: types.Nonce, Data: nonce[:]}}
+		tx.FeePerKB = feeRate
+		return tx
+	}
+
+	// high fee rate transactions are confirmed in the next block, low fee
+	// rate ones are never confirmed
+	block := &types.Block{Header: types.Header{Height: 101}}
+	for i := 0; i < 10; i++ {
+		high := newTx(50000)
+		estimator.ObserveTransaction(high, 100)
+		block.Transactions = append(block.Transactions, high)
+		estimator.ObserveTransaction(newTx(1000), 100)
+	}
+	estimator.RegisterBlock(block)
+	for h := uint32(102); h <= 101+EstimateFeeDepth; h++ {
+		estimator.RegisterBlock(&types.Block{Header: types.Header{Height: h}})
+	}
+	assert.Equal(t, 0, len(estimator.observed))
+
+	feeRate, err := estimator.EstimateFee(1, 0.95)
+	assert.NoError(t, err)
+	assert.Equal(t, common.Fixed64(50000), feeRate)
+
+	// the statistics should survive restarts
+	path := filepath.Join(os.TempDir(), FeeEstimatorFile)
+	defer os.Remove(path)
+	estimator.ObserveTransaction(newTx(2000), 200)
+	assert.NoError(t, estimator.Save(path))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+	loaded := NewFeeEstimator()
+	assert.NoError(t, loaded.Load(path))
+	assert.Equal(t, 1, len(loaded.observed))
+	feeRate, err = loaded.EstimateFee(1, 0.95)
+	assert.NoError(t, err)
+	assert.Equal(t, common.Fixed64(50000), feeRate)
+}
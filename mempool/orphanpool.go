@@ -0,0 +1,170 @@
+package mempool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/blockchain"
+	. "github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/common/log"
+	. "github.com/elastos/Elastos.ELA/core/types"
+	. "github.com/elastos/Elastos.ELA/errors"
+)
+
+const (
+	// maxOrphanTxSize is the maximum size of an orphan transaction kept in
+	// pool.
+	maxOrphanTxSize = 100000
+
+	// orphanTTL is how long an orphan transaction is kept waiting for its
+	// parents.
+	orphanTTL = time.Minute * 15
+)
+
+// orphanTx is a transaction spending outputs of transactions neither in pool
+// nor in the ledger.
+type orphanTx struct {
+	txn        *Transaction
+	expiration time.Time
+}
+
+// missingInputs returns the inputs of txn referring to transactions neither
+// in parents nor in the ledger.
+func (pool *TxPool) missingInputs(txn *Transaction, parents map[Uint256]*Transaction) []*Input {
+	var missing []*Input
+	for _, input := range txn.Inputs {
+		if _, ok := parents[input.Previous.TxID]; ok {
+			continue
+		}
+		_, _, err := blockchain.DefaultLedger.Store.GetTransaction(input.Previous.TxID)
+		if err != nil {
+			missing = append(missing, input)
+		}
+	}
+	return missing
+}
+
+// addOrphan keeps txn in the orphan pool, indexed by the missing outpoints it
+// is waiting for. A random orphan is evicted if the orphan pool is full.
+func (pool *TxPool) addOrphan(txn *Transaction, missing []*Input) error {
+	maxCount := config.Parameters.MaxOrphanTxs
+	if maxCount <= 0 {
+		return fmt.Errorf("orphan transaction %s rejected, orphan pool "+
+			"is disabled", txn.Hash())
+	}
+	if size := txn.GetSize(); size > maxOrphanTxSize {
+		return fmt.Errorf("orphan transaction %s size %d, more than %d",
+			txn.Hash(), size, maxOrphanTxSize)
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+	txHash := txn.Hash()
+	if _, ok := pool.orphans[txHash]; ok {
+		return nil
+	}
+	for len(pool.orphans) >= maxCount {
+		// map iteration order is random
+		for _, orphan := range pool.orphans {
+			log.Debug("evict orphan transaction, txid=", orphan.txn.Hash())
+			pool.delOrphan(orphan.txn)
+			break
+		}
+	}
+	pool.orphans[txHash] = &orphanTx{
+		txn:        txn,
+		expiration: time.Now().Add(orphanTTL),
+	}
+	for _, input := range missing {
+		key := input.ReferKey()
+		if _, ok := pool.orphansByPrev[key]; !ok {
+			pool.orphansByPrev[key] = make(map[Uint256]*Transaction)
+		}
+		pool.orphansByPrev[key][txHash] = txn
+	}
+	log.Debugf("add orphan transaction %s, total %d", txHash, len(pool.orphans))
+	return nil
+}
+
+// delOrphan removes txn from the orphan pool. The pool lock must be held by
+// the caller.
+func (pool *TxPool) delOrphan(txn *Transaction) {
+	txHash := txn.Hash()
+	if _, ok := pool.orphans[txHash]; !ok {
+		return
+	}
+	delete(pool.orphans, txHash)
+	for _, input := range txn.Inputs {
+		key := input.ReferKey()
+		orphans, ok := pool.orphansByPrev[key]
+		if !ok {
+			continue
+		}
+		delete(orphans, txHash)
+		if len(orphans) == 0 {
+			delete(pool.orphansByPrev, key)
+		}
+	}
+}
+
+func (pool *TxPool) removeOrphan(txn *Transaction) {
+	pool.Lock()
+	defer pool.Unlock()
+	pool.delOrphan(txn)
+}
+
+// takeOrphansSpending removes the orphan transactions spending outputs of txn
+// from the orphan pool and returns them.
+func (pool *TxPool) takeOrphansSpending(txn *Transaction) []*Transaction {
+	pool.Lock()
+	defer pool.Unlock()
+	var orphans []*Transaction
+	txHash := txn.Hash()
+	for i := range txn.Outputs {
+		input := Input{
+			Previous: OutPoint{
+				TxID:  txHash,
+				Index: uint16(i),
+			},
+		}
+		for _, orphan := range pool.orphansByPrev[input.ReferKey()] {
+			pool.delOrphan(orphan)
+			orphans = append(orphans, orphan)
+		}
+	}
+	return orphans
+}
+
+// processOrphans appends the orphan transactions spending outputs of txn to
+// pool, and then the ones spending outputs of those appended. Orphans still
+// missing other parents are put back into the orphan pool.
+func (pool *TxPool) processOrphans(txn *Transaction) {
+	queue := []*Transaction{txn}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, orphan := range pool.takeOrphansSpending(parent) {
+			if errCode := pool.appendToTxnPool(orphan); errCode != Success {
+				log.Debugf("orphan transaction %s not accepted: %s",
+					orphan.Hash(), errCode.Message())
+				continue
+			}
+			log.Debug("accept orphan transaction, txid=", orphan.Hash())
+			queue = append(queue, orphan)
+		}
+	}
+}
+
+// expireOrphans removes the orphan transactions which have been waiting for
+// their parents too long.
+func (pool *TxPool) expireOrphans(now time.Time) {
+	pool.Lock()
+	defer pool.Unlock()
+	for _, orphan := range pool.orphans {
+		if now.After(orphan.expiration) {
+			log.Debug("expire orphan transaction, txid=", orphan.txn.Hash())
+			pool.delOrphan(orphan.txn)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package mempool
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/types"
+	. "github.com/elastos/Elastos.ELA/errors"
+)
+
+const (
+	// TxPoolFile is the name of the file under the data directory the
+	// transaction pool is saved to.
+	TxPoolFile = "mempool.dat"
+
+	// saveTxPoolInterval is the interval used to save the transaction pool
+	// to file.
+	saveTxPoolInterval = time.Minute * 10
+)
+
+func txPoolFilePath() string {
+	return filepath.Join(config.DataPath, config.DataDir, TxPoolFile)
+}
+
+func feeEstimatorFilePath() string {
+	return filepath.Join(config.DataPath, config.DataDir, FeeEstimatorFile)
+}
+
+// savedTx is a transaction saved in the transaction pool file with the time
+// it arrived in pool, so it expires at the same time after loaded back.
+type savedTx struct {
+	txn     *types.Transaction
+	arrival time.Time
+}
+
+// SaveTxPool writes the transactions in pool to the transaction pool file, so
+// they can be loaded back by LoadTxPool at next start.
+func (pool *TxPool) SaveTxPool() error {
+	if config.Parameters.DisableTxPoolPersist {
+		return errors.New("transaction pool persistence is disabled")
+	}
+	txs := pool.GetTransactionsForBlock(0, 0)
+	saved := make([]savedTx, 0, len(txs))
+	pool.RLock()
+	for _, txn := range txs {
+		saved = append(saved, savedTx{txn: txn, arrival: pool.txnTime[txn.Hash()]})
+	}
+	pool.RUnlock()
+	if err := writeTxPoolFile(txPoolFilePath(), saved); err != nil {
+		return err
+	}
+	log.Infof("saved %d transactions in pool", len(txs))
+	return nil
+}
+
+// LoadTxPool appends the transactions saved in the transaction pool file to
+// pool with their saved arrival times, transactions no longer valid or
+// expired are dropped.
+func (pool *TxPool) LoadTxPool() {
+	if config.Parameters.DisableTxPoolPersist {
+		return
+	}
+	path := txPoolFilePath()
+	txs, err := readTxPoolFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("load transaction pool file %s failed: %v", path, err)
+		}
+		return
+	}
+	var count int
+	for _, saved := range txs {
+		txn := saved.txn
+		if errCode := pool.AppendToTxnPool(txn); errCode != Success {
+			log.Debugf("drop saved transaction %s: %s", txn.Hash(), errCode.Message())
+			continue
+		}
+		pool.restoreArrivalTime(txn.Hash(), saved.arrival)
+		count++
+	}
+	pool.expireTransactions(time.Now())
+	log.Infof("loaded %d of %d saved transactions into pool", count, len(txs))
+}
+
+// restoreArrivalTime sets the arrival time of the transaction in pool back to
+// the saved one, if it is earlier.
+func (pool *TxPool) restoreArrivalTime(hash common.Uint256, arrival time.Time) {
+	pool.Lock()
+	defer pool.Unlock()
+	if current, ok := pool.txnTime[hash]; ok && arrival.Before(current) {
+		pool.txnTime[hash] = arrival
+	}
+}
+
+// SaveFeeEstimator writes the fee estimator state to file, so the estimates
+// survive restarts.
+func (pool *TxPool) SaveFeeEstimator() error {
+	return pool.feeEstimator.Save(feeEstimatorFilePath())
+}
+
+// LoadFeeEstimator reads back the fee estimator state saved by
+// SaveFeeEstimator.
+func (pool *TxPool) LoadFeeEstimator() {
+	path := feeEstimatorFilePath()
+	if err := pool.feeEstimator.Load(path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("load fee estimator file %s failed: %v", path, err)
+	}
+}
+
+// PersistHandler saves the transaction pool and the fee estimator state to
+// file periodically. It must be run as a goroutine.
+func (pool *TxPool) PersistHandler() {
+	ticker := time.NewTicker(saveTxPoolInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !config.Parameters.DisableTxPoolPersist {
+			if err := pool.SaveTxPool(); err != nil {
+				log.Warn("save transaction pool failed:", err)
+			}
+		}
+		if err := pool.SaveFeeEstimator(); err != nil {
+			log.Warn("save fee estimator failed:", err)
+		}
+	}
+}
+
+// writeTxPoolFile writes the transactions to a temporary file first and then
+// renames it to path, so an interrupted write never leaves a broken file.
+func writeTxPoolFile(path string, txs []savedTx) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = common.WriteVarUint(w, uint64(len(txs)))
+	for i := 0; err == nil && i < len(txs); i++ {
+		if err = txs[i].txn.Serialize(w); err == nil {
+			err = common.WriteUint64(w, uint64(txs[i].arrival.Unix()))
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func readTxPoolFile(path string) ([]savedTx, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	var txs []savedTx
+	for i := uint64(0); i < count; i++ {
+		txn := new(types.Transaction)
+		if err := txn.Deserialize(r); err != nil {
+			return nil, err
+		}
+		arrival, err := common.ReadUint64(r)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, savedTx{txn: txn, arrival: time.Unix(int64(arrival), 0)})
+	}
+	return txs, nil
+}
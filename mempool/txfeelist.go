@@ -0,0 +1,53 @@
+package mempool
+
+import (
+	"sort"
+
+	"github.com/elastos/Elastos.ELA/core/types"
+)
+
+// txFeeList keeps the pool transactions ordered by fee per KB from high to
+// low, transactions with the same fee rate are ordered by hash so the order
+// never depends on map iteration.
+type txFeeList []*types.Transaction
+
+func feeRateHigher(a, b *types.Transaction) bool {
+	if a.FeePerKB != b.FeePerKB {
+		return a.FeePerKB > b.FeePerKB
+	}
+	return a.Hash().Compare(b.Hash()) < 0
+}
+
+// search returns the position where tx is, or should be inserted.
+func (l txFeeList) search(tx *types.Transaction) int {
+	return sort.Search(len(l), func(i int) bool {
+		return !feeRateHigher(l[i], tx)
+	})
+}
+
+func (l *txFeeList) insert(tx *types.Transaction) {
+	i := l.search(tx)
+	*l = append(*l, nil)
+	copy((*l)[i+1:], (*l)[i:])
+	(*l)[i] = tx
+}
+
+func (l *txFeeList) remove(tx *types.Transaction) bool {
+	i := l.search(tx)
+	if i >= len(*l) || !(*l)[i].Hash().IsEqual(tx.Hash()) {
+		return false
+	}
+	copy((*l)[i:], (*l)[i+1:])
+	(*l)[len(*l)-1] = nil
+	*l = (*l)[:len(*l)-1]
+	return true
+}
+
+// lowest returns the transaction with the lowest fee rate, nil if the list is
+// empty.
+func (l txFeeList) lowest() *types.Transaction {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[len(l)-1]
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/elastos/Elastos.ELA/blockchain"
 	. "github.com/elastos/Elastos.ELA/common"
@@ -18,15 +20,25 @@ import (
 	"github.com/elastos/Elastos.ELA/protocol"
 )
 
+// expireInterval is the interval used to evict the expired transactions from
+// pool.
+const expireInterval = time.Minute
+
 type TxPool struct {
 	sync.RWMutex
-	txnCnt  uint64                   // count
-	txnList map[Uint256]*Transaction // transaction which have been verifyed will put into this map
+	txnCnt     uint64                   // count
+	txnList    map[Uint256]*Transaction // transaction which have been verifyed will put into this map
+	txnFeeList txFeeList                // transactions in txnList ordered by fee rate
+	txnSize    int                      // total serialized size of transactions in txnList
+	txnTime    map[Uint256]time.Time    // arrival time of transactions in txnList
 	//issueSummary  map[Uint256]Fixed64           // transaction which pass the verify will summary the amout to this map
 	inputUTXOList     map[string]*Transaction // transaction which pass the verify will add the UTXO to this map
 	producerList      map[string]struct{}
 	nodePublicKeyList map[string]struct{}
 	sidechainTxList   map[Uint256]*Transaction // sidechain tx pool
+	orphans           map[Uint256]*orphanTx    // transactions waiting for their parents
+	orphansByPrev     map[string]map[Uint256]*Transaction
+	feeEstimator      *FeeEstimator
 	Listeners         map[protocol.TxnPoolListener]interface{}
 }
 
@@ -37,15 +49,30 @@ func (pool *TxPool) Init() {
 	pool.inputUTXOList = make(map[string]*Transaction)
 	//pool.issueSummary = make(map[Uint256]Fixed64)
 	pool.txnList = make(map[Uint256]*Transaction)
+	pool.txnFeeList = nil
+	pool.txnSize = 0
+	pool.txnTime = make(map[Uint256]time.Time)
 	pool.producerList = make(map[string]struct{})
 	pool.nodePublicKeyList = make(map[string]struct{})
 	pool.sidechainTxList = make(map[Uint256]*Transaction)
+	pool.orphans = make(map[Uint256]*orphanTx)
+	pool.orphansByPrev = make(map[string]map[Uint256]*Transaction)
+	pool.feeEstimator = NewFeeEstimator()
 	pool.Listeners = make(map[protocol.TxnPoolListener]interface{})
 }
 
 //append transaction to txnpool when check ok.
 //1.check  2.check with ledger(db) 3.check with pool
 func (pool *TxPool) AppendToTxnPool(txn *Transaction) ErrCode {
+	if errCode := pool.appendToTxnPool(txn); errCode != Success {
+		return errCode
+	}
+	// orphan transactions waiting for txn can be appended now
+	pool.processOrphans(txn)
+	return Success
+}
+
+func (pool *TxPool) appendToTxnPool(txn *Transaction) ErrCode {
 
 	if txn.IsCoinBaseTx() {
 		log.Warn("coinbase cannot be added into transaction pool", txn.Hash().String())
@@ -57,25 +84,47 @@ func (pool *TxPool) AppendToTxnPool(txn *Transaction) ErrCode {
 		log.Warn("[TxPool CheckTransactionSanity] failed", txn.Hash().String())
 		return errCode
 	}
-	if errCode := blockchain.CheckTransactionContext(blockchain.DefaultLedger.Blockchain.BlockHeight+1, txn); errCode != Success {
-		log.Warn("[TxPool CheckTransactionContext] failed", txn.Hash().String())
-		return errCode
+	// transactions in pool spent by txn, their outputs are not in ledger yet
+	parents := pool.getParents(txn)
+	// keep txn as orphan if any of its parents has not arrived yet
+	if missing := pool.missingInputs(txn, parents); len(missing) > 0 {
+		if err := pool.addOrphan(txn, missing); err != nil {
+			log.Warn(err)
+		}
+		return ErrUnknownReferredTx
 	}
-	//verify transaction by pool with lock
-	if errCode := pool.verifyTransactionWithTxnPool(txn); errCode != Success {
-		log.Warn("[TxPool verifyTransactionWithTxnPool] failed", txn.Hash())
+	if errCode := blockchain.CheckTransactionContextWithParents(blockchain.DefaultLedger.Blockchain.BlockHeight+1, txn, parents); errCode != Success {
+		log.Warn("[TxPool CheckTransactionContext] failed", txn.Hash().String())
 		return errCode
 	}
 
-	txn.Fee = blockchain.GetTxFee(txn, blockchain.DefaultLedger.Blockchain.AssetID)
+	txn.Fee = blockchain.GetTxFeeWithParents(txn, blockchain.DefaultLedger.Blockchain.AssetID, parents)
 	buf := new(bytes.Buffer)
 	txn.Serialize(buf)
 	txn.FeePerKB = txn.Fee * 1000 / Fixed64(len(buf.Bytes()))
-	//add the transaction to process scope
-	if ok := pool.addToTxList(txn); !ok {
-		// reject duplicated transaction
-		log.Debugf("Transaction duplicate %s", txn.Hash().String())
-		return ErrTransactionDuplicate
+
+	// limit the unconfirmed chain txn builds on
+	if err := pool.verifyAncestors(txn, buf.Len()); err != nil {
+		log.Warn(err)
+		return ErrTooManyAncestors
+	}
+
+	// reject the transaction if the pool is full and there are not enough
+	// transactions paying a lower fee rate to be evicted for it
+	if pool.isFullFor(txn, buf.Len()) {
+		log.Warn("[TxPool isFullFor] transaction fee rate too low", txn.Hash())
+		return ErrTxPoolFull
+	}
+
+	// find the conflicting transactions in pool to be replaced, they are only
+	// removed after txn passed all the checks below
+	replaced, err := pool.getReplacedTransactions(txn, parents)
+	if err != nil {
+		log.Warn(err)
+		return ErrDoubleSpend
+	}
+	if errCode := pool.addReplacement(txn, replaced); errCode != Success {
+		return errCode
 	}
 
 	if pool.Listeners != nil && txn.IsIllegalBlockTx() {
@@ -84,38 +133,104 @@ func (pool *TxPool) AppendToTxnPool(txn *Transaction) ErrCode {
 		}
 	}
 
+	pool.feeEstimator.ObserveTransaction(txn,
+		blockchain.DefaultLedger.Blockchain.BlockHeight)
+
 	return Success
 }
 
-//get the transaction in txnpool
+// get the transaction in txnpool, if hasMaxCount is true only the
+// MaxTxsInBlock transactions with highest fee rate will be returned. The
+// returned map is unordered, use GetTransactionsForBlock to get transactions in
+// the order they should be packed into a block.
 func (pool *TxPool) GetTransactionPool(hasMaxCount bool) map[Uint256]*Transaction {
 	pool.RLock()
 	count := config.Parameters.MaxTxsInBlock
 	if count <= 0 {
 		hasMaxCount = false
 	}
-	if len(pool.txnList) < count || !hasMaxCount {
-		count = len(pool.txnList)
+	if len(pool.txnFeeList) < count || !hasMaxCount {
+		count = len(pool.txnFeeList)
 	}
-	var num int
-	txnMap := make(map[Uint256]*Transaction)
-	for txnID, tx := range pool.txnList {
-		txnMap[txnID] = tx
-		num++
-		if num >= count {
-			break
-		}
+	txnMap := make(map[Uint256]*Transaction, count)
+	for _, tx := range pool.txnFeeList[:count] {
+		txnMap[tx.Hash()] = tx
 	}
 	pool.RUnlock()
 	return txnMap
 }
 
+// GetTransactionsForBlock returns transactions in pool ordered by the fee
+// rate of the package each one forms with its unconfirmed ancestors, from high
+// to low, so a high fee child pulls in its low fee parents. Parents always come
+// before their children. Packages that would take the total size above maxSize
+// are skipped so smaller ones can still fill up the block, and at most maxCount
+// transactions will be returned. A non-positive limit means no limit.
+func (pool *TxPool) GetTransactionsForBlock(maxSize, maxCount int) []*Transaction {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	type txPackage struct {
+		txn     *Transaction
+		feeRate Fixed64
+	}
+	packages := make([]txPackage, 0, len(pool.txnFeeList))
+	for _, txn := range pool.txnFeeList {
+		feeRate := packageFeeRate(txn, pool.ancestorsOf(txn, nil))
+		packages = append(packages, txPackage{txn: txn, feeRate: feeRate})
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].feeRate > packages[j].feeRate
+	})
+
+	var totalSize int
+	included := make(map[Uint256]struct{})
+	txs := make([]*Transaction, 0, len(packages))
+	for _, p := range packages {
+		if maxCount > 0 && len(txs) >= maxCount {
+			break
+		}
+		if _, ok := included[p.txn.Hash()]; ok {
+			continue
+		}
+		pkg := append(pool.ancestorsOf(p.txn, included), p.txn)
+		var size int
+		for _, tx := range pkg {
+			size += tx.GetSize()
+		}
+		if maxCount > 0 && len(txs)+len(pkg) > maxCount {
+			continue
+		}
+		if maxSize > 0 && totalSize+size > maxSize {
+			continue
+		}
+		for _, tx := range pkg {
+			included[tx.Hash()] = struct{}{}
+		}
+		totalSize += size
+		txs = append(txs, pkg...)
+	}
+	return txs
+}
+
+// EstimateFee returns the fee rate in sela per KB needed for a transaction to
+// be confirmed within the given number of blocks with the given confidence.
+func (pool *TxPool) EstimateFee(confirmations uint32, confidence float64) (Fixed64, error) {
+	return pool.feeEstimator.EstimateFee(confirmations, confidence)
+}
+
 //clean the trasaction Pool with committed block.
 func (pool *TxPool) CleanSubmittedTransactions(block *Block) error {
+	pool.feeEstimator.RegisterBlock(block)
 	pool.cleanTransactions(block.Transactions)
 	pool.cleanSidechainTx(block.Transactions)
 	pool.cleanSideChainPowTx()
 	pool.cleanCanceledProducer(block.Transactions)
+	pool.expireTransactions(time.Now())
+	for _, txn := range block.Transactions {
+		pool.removeOrphan(txn)
+		pool.processOrphans(txn)
+	}
 
 	return nil
 }
@@ -149,49 +264,13 @@ func (pool *TxPool) cleanTransactions(blockTxs []*Transaction) error {
 						"block transaction hash: %x, transaction hash: %x, the same input: %s, index: %d",
 						blockTx.Hash(), tx.Hash(), input.Previous.TxID, input.Previous.Index)
 				}
-				//1.remove from txnList
-				pool.delFromTxList(tx.Hash())
-				//2.remove from UTXO list map
-				for _, input := range tx.Inputs {
-					pool.delInputUTXOList(input)
-				}
-
-				//delete sidechain tx list
-				if tx.TxType == WithdrawFromSideChain {
-					payload, ok := tx.Payload.(*PayloadWithdrawFromSideChain)
-					if !ok {
-						log.Error("type cast failed when clean sidechain tx:", tx.Hash())
-					}
-					for _, hash := range payload.SideChainTransactionHashes {
-						pool.delSidechainTx(hash)
-					}
-				}
-
-				// delete producer
-				if tx.TxType == RegisterProducer {
-					rpPayload, ok := tx.Payload.(*PayloadRegisterProducer)
-					if !ok {
-						log.Error("register producer payload cast failed, tx:", tx.Hash())
-					}
-					pool.delProducer(BytesToHexString(rpPayload.OwnerPublicKey))
-					pool.delProducerNode(BytesToHexString(rpPayload.NodePublicKey))
-				}
-				if tx.TxType == UpdateProducer {
-					upPayload, ok := tx.Payload.(*PayloadUpdateProducer)
-					if !ok {
-						log.Error("update producer payload cast failed, tx:", tx.Hash())
-					}
-					pool.delProducer(BytesToHexString(upPayload.OwnerPublicKey))
-					pool.delProducerNode(BytesToHexString(upPayload.NodePublicKey))
-				}
-				if tx.TxType == CancelProducer {
-					cpPayload, ok := tx.Payload.(*PayloadCancelProducer)
-					if !ok {
-						log.Error("cancel producer payload cast failed, tx:", tx.Hash())
-					}
-					pool.delProducer(BytesToHexString(cpPayload.OwnerPublicKey))
+				if tx.Hash() == blockTx.Hash() {
+					pool.doRemoveTransaction(tx)
+				} else {
+					// descendants of the double spent transaction are invalid,
+					// they will never be packed in a block
+					pool.notifyEvicted(pool.removeTransactionWithDescendants(tx))
 				}
-
 				deleteCount++
 			}
 		}
@@ -202,6 +281,53 @@ func (pool *TxPool) cleanTransactions(blockTxs []*Transaction) error {
 	return nil
 }
 
+// doRemoveTransaction removes the transaction and everything it registered
+// in the pool, including inputs, sidechain hashes and producer keys.
+func (pool *TxPool) doRemoveTransaction(tx *Transaction) {
+	//1.remove from txnList
+	pool.delFromTxList(tx.Hash())
+	//2.remove from UTXO list map
+	for _, input := range tx.Inputs {
+		pool.delInputUTXOList(input)
+	}
+
+	//delete sidechain tx list
+	if tx.TxType == WithdrawFromSideChain {
+		payload, ok := tx.Payload.(*PayloadWithdrawFromSideChain)
+		if !ok {
+			log.Error("type cast failed when clean sidechain tx:", tx.Hash())
+		}
+		for _, hash := range payload.SideChainTransactionHashes {
+			pool.delSidechainTx(hash)
+		}
+	}
+
+	// delete producer
+	if tx.TxType == RegisterProducer {
+		rpPayload, ok := tx.Payload.(*PayloadRegisterProducer)
+		if !ok {
+			log.Error("register producer payload cast failed, tx:", tx.Hash())
+		}
+		pool.delProducer(BytesToHexString(rpPayload.OwnerPublicKey))
+		pool.delProducerNode(BytesToHexString(rpPayload.NodePublicKey))
+	}
+	if tx.TxType == UpdateProducer {
+		upPayload, ok := tx.Payload.(*PayloadUpdateProducer)
+		if !ok {
+			log.Error("update producer payload cast failed, tx:", tx.Hash())
+		}
+		pool.delProducer(BytesToHexString(upPayload.OwnerPublicKey))
+		pool.delProducerNode(BytesToHexString(upPayload.NodePublicKey))
+	}
+	if tx.TxType == CancelProducer {
+		cpPayload, ok := tx.Payload.(*PayloadCancelProducer)
+		if !ok {
+			log.Error("cancel producer payload cast failed, tx:", tx.Hash())
+		}
+		pool.delProducer(BytesToHexString(cpPayload.OwnerPublicKey))
+	}
+}
+
 func (pool *TxPool) cleanCanceledProducer(txs []*Transaction) error {
 	for _, txn := range txs {
 		if txn.TxType == CancelProducer {
@@ -219,8 +345,13 @@ func (pool *TxPool) cleanCanceledProducer(txs []*Transaction) error {
 }
 
 func (pool *TxPool) cleanVoteAndUpdateProducer(ownerPublicKey []byte) error {
-	for _, txn := range pool.txnList {
+	for _, txn := range pool.copyTxList() {
+		if pool.GetTransaction(txn.Hash()) == nil {
+			// removed as a descendant of another transaction already
+			continue
+		}
 		if txn.TxType == TransferAsset {
+		outputs:
 			for _, output := range txn.Outputs {
 				if output.OutputType == VoteOutput {
 					opPayload, ok := output.OutputPayload.(*outputpayload.VoteOutput)
@@ -231,7 +362,8 @@ func (pool *TxPool) cleanVoteAndUpdateProducer(ownerPublicKey []byte) error {
 						if content.VoteType == outputpayload.Delegate {
 							for _, pubKey := range content.Candidates {
 								if bytes.Equal(ownerPublicKey, pubKey) {
-									pool.removeTransaction(txn)
+									pool.notifyEvicted(pool.removeTransactionWithDescendants(txn))
+									break outputs
 								}
 							}
 						}
@@ -244,9 +376,7 @@ func (pool *TxPool) cleanVoteAndUpdateProducer(ownerPublicKey []byte) error {
 				return errors.New("invalid update producer payload")
 			}
 			if bytes.Equal(upPayload.OwnerPublicKey, ownerPublicKey) {
-				pool.removeTransaction(txn)
-				pool.delProducer(BytesToHexString(upPayload.OwnerPublicKey))
-				pool.delProducerNode(BytesToHexString(upPayload.NodePublicKey))
+				pool.notifyEvicted(pool.removeTransactionWithDescendants(txn))
 			}
 		}
 	}
@@ -318,29 +448,10 @@ func (pool *TxPool) verifyTransactionWithTxnPool(txn *Transaction) ErrCode {
 	return Success
 }
 
-//remove from associated map
-func (pool *TxPool) removeTransaction(txn *Transaction) {
-	//1.remove from txnList
-	pool.delFromTxList(txn.Hash())
-	//2.remove from UTXO list map
-	result, err := blockchain.DefaultLedger.Store.GetTxReference(txn)
-	if err != nil {
-		log.Info(fmt.Sprintf("Transaction =%x not Exist in Pool when delete.", txn.Hash()))
-		return
-	}
-	for UTXOTxInput := range result {
-		pool.delInputUTXOList(UTXOTxInput)
-	}
-}
-
 //check and add to utxo list pool
 func (pool *TxPool) verifyDoubleSpend(txn *Transaction) error {
-	reference, err := blockchain.DefaultLedger.Store.GetTxReference(txn)
-	if err != nil {
-		return err
-	}
 	inputs := []*Input{}
-	for k := range reference {
+	for _, k := range txn.Inputs {
 		if txn := pool.getInputUTXOList(k); txn != nil {
 			return errors.New(fmt.Sprintf("double spent UTXO inputs detected, "+
 				"transaction hash: %x, input: %s, index: %d",
@@ -459,7 +570,7 @@ func (pool *TxPool) replaceDuplicateSideChainPowTx(txn *Transaction) {
 	for _, txn := range replaceList {
 		txid := txn.Hash()
 		log.Info("replace sidechainpow transaction, txid=", txid.String())
-		pool.removeTransaction(txn)
+		pool.notifyEvicted(pool.removeTransactionWithDescendants(txn))
 	}
 }
 
@@ -471,20 +582,9 @@ func (pool *TxPool) cleanSidechainTx(txs []*Transaction) {
 			for _, hash := range withPayload.SideChainTransactionHashes {
 				poolTx := pool.sidechainTxList[hash]
 				if poolTx != nil {
-					// delete tx
-					pool.delFromTxList(poolTx.Hash())
-					//delete utxo map
-					for _, input := range poolTx.Inputs {
-						pool.delInputUTXOList(input)
-					}
-					//delete sidechain tx map
-					payload, ok := poolTx.Payload.(*PayloadWithdrawFromSideChain)
-					if !ok {
-						log.Error("type cast failed when clean sidechain tx:", poolTx.Hash())
-					}
-					for _, hash := range payload.SideChainTransactionHashes {
-						pool.delSidechainTx(hash)
-					}
+					// delete tx with the sidechain hashes and the
+					// transactions spending its outputs
+					pool.notifyEvicted(pool.removeTransactionWithDescendants(poolTx))
 				}
 			}
 		}
@@ -502,6 +602,9 @@ func (pool *TxPool) cleanSideChainPowTx() {
 			if err := blockchain.CheckSideChainPowConsensus(txn, arbitrator); err != nil {
 				// delete tx
 				delete(pool.txnList, hash)
+				delete(pool.txnTime, hash)
+				pool.txnFeeList.remove(txn)
+				pool.txnSize -= txn.GetSize()
 				//delete utxo map
 				for _, input := range txn.Inputs {
 					delete(pool.inputUTXOList, input.ReferKey())
@@ -519,6 +622,9 @@ func (pool *TxPool) addToTxList(txn *Transaction) bool {
 		return false
 	}
 	pool.txnList[txnHash] = txn
+	pool.txnTime[txnHash] = time.Now()
+	pool.txnFeeList.insert(txn)
+	pool.txnSize += txn.GetSize()
 	blockchain.DefaultLedger.Blockchain.BCEvents.Notify(events.EventNewTransactionPutInPool, txn)
 	return true
 }
@@ -526,13 +632,493 @@ func (pool *TxPool) addToTxList(txn *Transaction) bool {
 func (pool *TxPool) delFromTxList(txID Uint256) bool {
 	pool.Lock()
 	defer pool.Unlock()
-	if _, ok := pool.txnList[txID]; !ok {
+	txn, ok := pool.txnList[txID]
+	if !ok {
 		return false
 	}
 	delete(pool.txnList, txID)
+	delete(pool.txnTime, txID)
+	pool.txnFeeList.remove(txn)
+	pool.txnSize -= txn.GetSize()
 	return true
 }
 
+// isFullFor returns if there is no room for the transaction of the given size
+// in pool, even after evicting all transactions which can be evicted for it,
+// see evictionCandidates.
+func (pool *TxPool) isFullFor(txn *Transaction, size int) bool {
+	pool.RLock()
+	defer pool.RUnlock()
+	maxSize := config.Parameters.MaxTxPoolSize
+	if maxSize > 0 && size > maxSize {
+		return true
+	}
+	_, ok := pool.evictionPlan(txn, 1, size)
+	return !ok
+}
+
+// isOverLimits returns if the pool has more transactions, or more bytes of
+// transactions, than configured.
+func (pool *TxPool) isOverLimits() bool {
+	pool.RLock()
+	defer pool.RUnlock()
+	maxCount := config.Parameters.MaxTxsInPool
+	maxSize := config.Parameters.MaxTxPoolSize
+	return (maxCount > 0 && len(pool.txnList) > maxCount) ||
+		(maxSize > 0 && pool.txnSize > maxSize)
+}
+
+// getParents returns the transactions in pool spent by txn.
+func (pool *TxPool) getParents(txn *Transaction) map[Uint256]*Transaction {
+	pool.RLock()
+	defer pool.RUnlock()
+	parents := make(map[Uint256]*Transaction)
+	for _, input := range txn.Inputs {
+		if parent, ok := pool.txnList[input.Previous.TxID]; ok {
+			parents[parent.Hash()] = parent
+		}
+	}
+	return parents
+}
+
+// ancestorsOf returns the unconfirmed ancestors of txn in pool, leaving out
+// those in skip. Parents always come before their children. The pool lock
+// must be held by the caller.
+func (pool *TxPool) ancestorsOf(txn *Transaction, skip map[Uint256]struct{}) []*Transaction {
+	var ancestors []*Transaction
+	visited := make(map[Uint256]struct{})
+	var visit func(tx *Transaction)
+	visit = func(tx *Transaction) {
+		for _, input := range tx.Inputs {
+			parent, ok := pool.txnList[input.Previous.TxID]
+			if !ok {
+				continue
+			}
+			hash := parent.Hash()
+			if _, ok := visited[hash]; ok {
+				continue
+			}
+			if _, ok := skip[hash]; ok {
+				continue
+			}
+			visited[hash] = struct{}{}
+			visit(parent)
+			ancestors = append(ancestors, parent)
+		}
+	}
+	visit(txn)
+	return ancestors
+}
+
+// packageFeeRate returns the fee per KB of txn together with its ancestors.
+func packageFeeRate(txn *Transaction, ancestors []*Transaction) Fixed64 {
+	if len(ancestors) == 0 {
+		return txn.FeePerKB
+	}
+	fee := txn.Fee
+	size := txn.GetSize()
+	for _, tx := range ancestors {
+		fee += tx.Fee
+		size += tx.GetSize()
+	}
+	return fee * 1000 / Fixed64(size)
+}
+
+// verifyAncestors checks the number and total size of the unconfirmed
+// ancestors of txn against the configured limits.
+func (pool *TxPool) verifyAncestors(txn *Transaction, size int) error {
+	pool.RLock()
+	ancestors := pool.ancestorsOf(txn, nil)
+	pool.RUnlock()
+
+	maxCount := config.Parameters.MaxTxAncestorCount
+	if maxCount > 0 && len(ancestors) > maxCount {
+		return fmt.Errorf("transaction %s has %d unconfirmed ancestors, "+
+			"more than %d", txn.Hash(), len(ancestors), maxCount)
+	}
+	maxSize := config.Parameters.MaxTxAncestorSize
+	if maxSize > 0 {
+		totalSize := size
+		for _, tx := range ancestors {
+			totalSize += tx.GetSize()
+		}
+		if totalSize > maxSize {
+			return fmt.Errorf("transaction %s and its unconfirmed ancestors "+
+				"size %d, more than %d", txn.Hash(), totalSize, maxSize)
+		}
+	}
+	return nil
+}
+
+// trimToLimits evicts the transactions which can be evicted for keep, see
+// evictionCandidates, together with their descendants in pool, until the pool
+// is back within its limits. Nothing is evicted and false is returned if the
+// pool would still exceed the limits after evicting all of them.
+func (pool *TxPool) trimToLimits(keep *Transaction) bool {
+	pool.RLock()
+	evicted, ok := pool.evictionPlan(keep, 0, 0)
+	pool.RUnlock()
+	if !ok {
+		return false
+	}
+	for _, tx := range evicted {
+		// it may have been removed as a descendant of another evicted one
+		if pool.GetTransaction(tx.Hash()) == nil {
+			continue
+		}
+		log.Info("evict transaction from pool, txid=", tx.Hash().String())
+		pool.notifyEvicted(pool.removeTransactionWithDescendants(tx))
+	}
+	return pool.GetTransaction(keep.Hash()) != nil
+}
+
+// evictionCandidates returns the transactions in pool which can be evicted
+// for txn, ordered by the fee rate of the package each one forms with its
+// unconfirmed ancestors, from low to high. Only the transactions whose
+// package pays a lower fee rate than the package of txn are candidates, and
+// the ancestors of txn are left out, because evicting a parent evicts its
+// children too. The pool lock must be held by the caller.
+func (pool *TxPool) evictionCandidates(txn *Transaction) []*Transaction {
+	ancestors := pool.ancestorsOf(txn, nil)
+	skip := map[Uint256]struct{}{txn.Hash(): {}}
+	for _, tx := range ancestors {
+		skip[tx.Hash()] = struct{}{}
+	}
+	feeRate := packageFeeRate(txn, ancestors)
+
+	var candidates []*Transaction
+	feeRates := make(map[Uint256]Fixed64)
+	for i := len(pool.txnFeeList) - 1; i >= 0; i-- {
+		tx := pool.txnFeeList[i]
+		if _, ok := skip[tx.Hash()]; ok {
+			continue
+		}
+		rate := packageFeeRate(tx, pool.ancestorsOf(tx, nil))
+		if rate >= feeRate {
+			continue
+		}
+		feeRates[tx.Hash()] = rate
+		candidates = append(candidates, tx)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return feeRates[candidates[i].Hash()] < feeRates[candidates[j].Hash()]
+	})
+	return candidates
+}
+
+// evictionPlan returns the transactions to be evicted for txn, including the
+// descendants of the candidates, for the pool to be within its limits after
+// adding count transactions of size bytes. A candidate with any descendant
+// which is not a candidate is not evicted. Parents always come before their
+// children. False is returned if the pool would still exceed the limits after
+// evicting all candidates. The pool lock must be held by the caller.
+func (pool *TxPool) evictionPlan(txn *Transaction, count, size int) ([]*Transaction, bool) {
+	maxCount := config.Parameters.MaxTxsInPool
+	maxSize := config.Parameters.MaxTxPoolSize
+	count += len(pool.txnList)
+	size += pool.txnSize
+	withinLimits := func() bool {
+		return (maxCount <= 0 || count <= maxCount) &&
+			(maxSize <= 0 || size <= maxSize)
+	}
+
+	if withinLimits() {
+		return nil, true
+	}
+
+	candidates := pool.evictionCandidates(txn)
+	isCandidate := make(map[Uint256]struct{}, len(candidates))
+	for _, tx := range candidates {
+		isCandidate[tx.Hash()] = struct{}{}
+	}
+
+	var evicted []*Transaction
+	visited := make(map[Uint256]struct{})
+	for _, tx := range candidates {
+		if withinLimits() {
+			break
+		}
+		if _, ok := visited[tx.Hash()]; ok {
+			continue
+		}
+		// the descendants are evicted together, so none of them may pay a
+		// higher package fee rate than txn
+		descendants := pool.descendantsOf(tx, make(map[Uint256]struct{}))
+		evictable := true
+		for _, child := range descendants {
+			if _, ok := isCandidate[child.Hash()]; !ok {
+				evictable = false
+				break
+			}
+		}
+		if !evictable {
+			continue
+		}
+
+		for _, tx := range append([]*Transaction{tx}, descendants...) {
+			if _, ok := visited[tx.Hash()]; ok {
+				continue
+			}
+			visited[tx.Hash()] = struct{}{}
+			count--
+			size -= tx.GetSize()
+			evicted = append(evicted, tx)
+		}
+	}
+	return evicted, withinLimits()
+}
+
+// ExpireHandler evicts the expired transactions and orphan transactions from
+// pool periodically, so they do not stay in pool while no block is coming. It
+// must be run as a goroutine.
+func (pool *TxPool) ExpireHandler() {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		pool.expireTransactions(now)
+	}
+}
+
+// expireTransactions evicts the transactions which have stayed in pool longer
+// than the configured expiry, together with their descendants, and the orphan
+// transactions which have expired.
+func (pool *TxPool) expireTransactions(now time.Time) {
+	pool.expireOrphans(now)
+
+	expiry := config.Parameters.TxPoolExpiry * time.Second
+	if expiry <= 0 {
+		return
+	}
+	var expired []*Transaction
+	pool.RLock()
+	for hash, arrival := range pool.txnTime {
+		if now.Sub(arrival) > expiry {
+			expired = append(expired, pool.txnList[hash])
+		}
+	}
+	pool.RUnlock()
+
+	for _, txn := range expired {
+		// it may have been removed as a descendant of another expired one
+		if pool.GetTransaction(txn.Hash()) == nil {
+			continue
+		}
+		log.Info("expire transaction from pool, txid=", txn.Hash().String())
+		pool.notifyEvicted(pool.removeTransactionWithDescendants(txn))
+	}
+}
+
+// removeTransactionWithDescendants removes the transaction and all
+// transactions in pool that spend its outputs, and returns the removed ones.
+func (pool *TxPool) removeTransactionWithDescendants(txn *Transaction) []*Transaction {
+	txHash := txn.Hash()
+	pool.doRemoveTransaction(txn)
+	removed := []*Transaction{txn}
+	for i := range txn.Outputs {
+		input := Input{
+			Previous: OutPoint{
+				TxID:  txHash,
+				Index: uint16(i),
+			},
+		}
+		if child := pool.getInputUTXOList(&input); child != nil {
+			removed = append(removed, pool.removeTransactionWithDescendants(child)...)
+		}
+	}
+	return removed
+}
+
+// descendantsOf returns the transactions in pool spending outputs of txn,
+// directly or indirectly, leaving out those in visited. The pool lock must be
+// held by the caller.
+func (pool *TxPool) descendantsOf(txn *Transaction, visited map[Uint256]struct{}) []*Transaction {
+	var descendants []*Transaction
+	txHash := txn.Hash()
+	for i := range txn.Outputs {
+		input := Input{
+			Previous: OutPoint{
+				TxID:  txHash,
+				Index: uint16(i),
+			},
+		}
+		child, ok := pool.inputUTXOList[input.ReferKey()]
+		if !ok {
+			continue
+		}
+		if _, ok := visited[child.Hash()]; ok {
+			continue
+		}
+		visited[child.Hash()] = struct{}{}
+		descendants = append(descendants, child)
+		descendants = append(descendants, pool.descendantsOf(child, visited)...)
+	}
+	return descendants
+}
+
+// getConflicts returns the transactions in pool spending any input of txn.
+func (pool *TxPool) getConflicts(txn *Transaction) []*Transaction {
+	pool.RLock()
+	defer pool.RUnlock()
+	var conflicts []*Transaction
+	found := make(map[Uint256]struct{})
+	for _, input := range txn.Inputs {
+		tx, ok := pool.inputUTXOList[input.ReferKey()]
+		if !ok {
+			continue
+		}
+		if _, ok := found[tx.Hash()]; ok {
+			continue
+		}
+		found[tx.Hash()] = struct{}{}
+		conflicts = append(conflicts, tx)
+	}
+	return conflicts
+}
+
+// getReplacedTransactions returns the transactions in pool conflicting with txn
+// on inputs, together with their descendants, which txn will replace.
+// Replacement is only allowed when every conflicting transaction signals
+// replace-by-fee, txn pays a higher fee rate than each of them and a higher fee
+// than all replaced transactions in total by at least MinTxFee per KB of its
+// size, and txn does not spend outputs of the replaced transactions. Nothing is
+// removed from pool here.
+func (pool *TxPool) getReplacedTransactions(txn *Transaction,
+	parents map[Uint256]*Transaction) ([]*Transaction, error) {
+	conflicts := pool.getConflicts(txn)
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+
+	pool.RLock()
+	var replaced []*Transaction
+	visited := make(map[Uint256]struct{})
+	for _, tx := range conflicts {
+		if !tx.IsReplaceable() {
+			pool.RUnlock()
+			return nil, fmt.Errorf("double spent UTXO inputs detected, "+
+				"transaction hash: %x, conflicting transaction %x is not "+
+				"replaceable", txn.Hash(), tx.Hash())
+		}
+		if txn.FeePerKB <= tx.FeePerKB {
+			pool.RUnlock()
+			return nil, fmt.Errorf("replacement transaction %x fee rate %d is "+
+				"not higher than %d of transaction %x", txn.Hash(), txn.FeePerKB,
+				tx.FeePerKB, tx.Hash())
+		}
+		if _, ok := visited[tx.Hash()]; ok {
+			continue
+		}
+		visited[tx.Hash()] = struct{}{}
+		replaced = append(replaced, tx)
+		replaced = append(replaced, pool.descendantsOf(tx, visited)...)
+	}
+	pool.RUnlock()
+
+	var fee Fixed64
+	for _, tx := range replaced {
+		if _, ok := parents[tx.Hash()]; ok {
+			return nil, fmt.Errorf("replacement transaction %x spends outputs "+
+				"of transaction %x it replaces", txn.Hash(), tx.Hash())
+		}
+		fee += tx.Fee
+	}
+	// txn pays for its own relay on top of the replaced ones, at MinTxFee per
+	// KB, otherwise transactions could be replaced over and over for almost
+	// nothing
+	bump := (Fixed64(txn.GetSize())*Fixed64(
+		config.Parameters.PowConfiguration.MinTxFee) + 999) / 1000
+	if txn.Fee < fee+bump {
+		return nil, fmt.Errorf("replacement transaction %x fee %d is less "+
+			"than %d of the transactions it replaces plus %d for its size",
+			txn.Hash(), txn.Fee, fee, bump)
+	}
+	return replaced, nil
+}
+
+// addReplacement adds txn to pool in place of the replaced transactions. The
+// replaced transactions are taken out of pool before txn is verified with
+// pool, and put back as they were if txn is not accepted, so they are only
+// evicted once txn is in pool. Neither are other transactions evicted for a
+// replacement not accepted, as trimToLimits evicts nothing unless it makes
+// room for txn.
+func (pool *TxPool) addReplacement(txn *Transaction, replaced []*Transaction) ErrCode {
+	arrivals := pool.takeTransactions(replaced)
+
+	//verify transaction by pool with lock
+	if errCode := pool.verifyTransactionWithTxnPool(txn); errCode != Success {
+		log.Warn("[TxPool verifyTransactionWithTxnPool] failed", txn.Hash())
+		pool.restoreTransactions(replaced, arrivals)
+		return errCode
+	}
+
+	//add the transaction to process scope
+	if ok := pool.addToTxList(txn); !ok {
+		// reject duplicated transaction
+		log.Debugf("Transaction duplicate %s", txn.Hash().String())
+		pool.restoreTransactions(replaced, arrivals)
+		return ErrTransactionDuplicate
+	}
+
+	// evict transactions with lowest fee rate if pool exceeds the limits
+	if ok := pool.trimToLimits(txn); !ok {
+		pool.doRemoveTransaction(txn)
+		pool.restoreTransactions(replaced, arrivals)
+		return ErrTxPoolFull
+	}
+
+	for _, tx := range replaced {
+		log.Info("replace transaction by fee, txid=", tx.Hash().String())
+	}
+	pool.notifyEvicted(replaced)
+	return Success
+}
+
+// takeTransactions removes the transactions from pool and returns their
+// arrival times, so they can be put back by restoreTransactions.
+func (pool *TxPool) takeTransactions(txs []*Transaction) map[Uint256]time.Time {
+	arrivals := make(map[Uint256]time.Time, len(txs))
+	pool.RLock()
+	for _, tx := range txs {
+		arrivals[tx.Hash()] = pool.txnTime[tx.Hash()]
+	}
+	pool.RUnlock()
+	for _, tx := range txs {
+		pool.doRemoveTransaction(tx)
+	}
+	return arrivals
+}
+
+// restoreTransactions puts the transactions removed by takeTransactions back
+// to pool with their arrival times. Parents must come before their children.
+func (pool *TxPool) restoreTransactions(txs []*Transaction,
+	arrivals map[Uint256]time.Time) {
+	for _, tx := range txs {
+		if errCode := pool.verifyTransactionWithTxnPool(tx); errCode != Success {
+			log.Warn("restore transaction to pool failed, txid=", tx.Hash().String())
+			continue
+		}
+		pool.Lock()
+		pool.txnList[tx.Hash()] = tx
+		pool.txnTime[tx.Hash()] = arrivals[tx.Hash()]
+		pool.txnFeeList.insert(tx)
+		pool.txnSize += tx.GetSize()
+		pool.Unlock()
+	}
+}
+
+// notifyEvicted tells the listeners that the transactions have been removed
+// from pool without being packed in a block.
+func (pool *TxPool) notifyEvicted(txs []*Transaction) {
+	for _, tx := range txs {
+		pool.feeEstimator.RemoveTransaction(tx.Hash())
+		for k := range pool.Listeners {
+			if listener, ok := k.(protocol.TxnEvictedListener); ok {
+				listener.OnTxnEvicted(tx)
+			}
+		}
+	}
+}
+
 func (pool *TxPool) copyTxList() map[Uint256]*Transaction {
 	pool.RLock()
 	defer pool.RUnlock()
@@ -634,7 +1220,7 @@ func (pool *TxPool) RemoveTransaction(txn *Transaction) {
 
 		txn := pool.getInputUTXOList(&input)
 		if txn != nil {
-			pool.removeTransaction(txn)
+			pool.notifyEvicted(pool.removeTransactionWithDescendants(txn))
 		}
 	}
 }
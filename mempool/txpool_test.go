@@ -6,7 +6,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/elastos/Elastos.ELA/auxpow"
 	"github.com/elastos/Elastos.ELA/blockchain"
@@ -14,7 +16,9 @@ import (
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
 	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
 	"github.com/elastos/Elastos.ELA/core/types/payload"
 	dplog "github.com/elastos/Elastos.ELA/dpos/log"
 	"github.com/elastos/Elastos.ELA/errors"
@@ -25,6 +29,29 @@ import (
 
 var txPool TxPool
 
+// newTestTx returns a unique transfer transaction paying the given fee and
+// spending the given inputs.
+func newTestTx(fee common.Fixed64, inputs ...*types.Input) *types.Transaction {
+	var nonce [32]byte
+	rand.Read(nonce[:])
+	tx := new(types.Transaction)
+	tx.TxType = types.TransferAsset
+	tx.Payload = &payload.PayloadTransferAsset{}
+	tx.Attributes = []*types.Attribute{{Usage: types.Nonce, Data: nonce[:]}}
+	tx.Inputs = inputs
+	tx.Outputs = []*types.Output{{Value: 100,
+		OutputPayload: &outputpayload.DefaultOutput{}}}
+	tx.Programs = []*program.Program{}
+	tx.Fee = fee
+	tx.FeePerKB = fee * 1000 / common.Fixed64(tx.GetSize())
+	return tx
+}
+
+// spendOf returns an input spending the first output of the transaction.
+func spendOf(parent *types.Transaction) *types.Input {
+	return &types.Input{Previous: types.OutPoint{TxID: parent.Hash()}}
+}
+
 func TestTxPoolInit(t *testing.T) {
 	log.Init(
 		config.Parameters.PrintLevel,
@@ -342,8 +369,14 @@ func TestTxPool_CleanSubmittedTransactions(t *testing.T) {
 	newBLock.Height = 221
 	newBLock.AuxPow = blockAuxpow
 	newBLock.Transactions = []*types.Transaction{tx2}
+	tx1.Outputs = []*types.Output{{Value: 100}}
 	txPool.addToTxList(tx1)
 	txPool.addInputUTXOList(tx1, input)
+	child := newTestTx(0, spendOf(tx1))
+	txPool.addToTxList(child)
+	txPool.addInputUTXOList(child, child.Inputs[0])
+	listener := &evictedListener{}
+	txPool.Listeners[listener] = nil
 
 	txPool.CleanSubmittedTransactions(&newBLock)
 
@@ -351,6 +384,10 @@ func TestTxPool_CleanSubmittedTransactions(t *testing.T) {
 	if tx != nil {
 		t.Error("Should delete double spent utxo transaction")
 	}
+	// the double spent transaction and its descendants are evicted
+	assert.Nil(t, txPool.GetTransaction(child.Hash()))
+	assert.Equal(t, []*types.Transaction{tx1, child}, listener.evicted)
+	delete(txPool.Listeners, listener)
 
 	for _, input := range tx1.Inputs {
 		utxoInput := txPool.inputUTXOList[input.ReferKey()]
@@ -500,3 +537,407 @@ func TestTxPool_CleanSubmittedTransactions(t *testing.T) {
 		t.Error("should have transaction: tx6", err)
 	}
 }
+
+func TestTxPool_FeeRateOrderAndEviction(t *testing.T) {
+	txPool.Init()
+	maxTxsInPool := config.Parameters.MaxTxsInPool
+	defer func() { config.Parameters.MaxTxsInPool = maxTxsInPool }()
+
+	tx1 := newTestTx(100)
+	tx2 := newTestTx(300)
+	// the input makes tx3 larger than the others
+	tx3 := newTestTx(200, &types.Input{})
+	txPool.addToTxList(tx1)
+	txPool.addToTxList(tx2)
+	txPool.addToTxList(tx3)
+
+	// transactions should be selected from highest fee rate to lowest
+	txs := txPool.GetTransactionsForBlock(0, 0)
+	assert.Equal(t, []*types.Transaction{tx2, tx3, tx1}, txs)
+	txs = txPool.GetTransactionsForBlock(0, 2)
+	assert.Equal(t, []*types.Transaction{tx2, tx3}, txs)
+	txs = txPool.GetTransactionsForBlock(tx2.GetSize()+tx3.GetSize()-1, 0)
+	assert.Equal(t, []*types.Transaction{tx2, tx1}, txs)
+
+	// a full pool only accepts transactions paying a higher fee rate
+	config.Parameters.MaxTxsInPool = 3
+	assert.True(t, txPool.isFullFor(newTestTx(50), 0))
+	assert.False(t, txPool.isFullFor(newTestTx(150), 0))
+
+	// the lowest fee rate transaction should be evicted
+	tx4 := newTestTx(150)
+	txPool.addToTxList(tx4)
+	assert.True(t, txPool.trimToLimits(tx4))
+	assert.Nil(t, txPool.GetTransaction(tx1.Hash()))
+	assert.Equal(t, 3, txPool.GetTransactionCount())
+	txs = txPool.GetTransactionsForBlock(0, 0)
+	assert.Equal(t, []*types.Transaction{tx2, tx4, tx3}, txs)
+
+	// the pool map holds the highest fee rate transactions, but unordered
+	maxTxsInBlock := config.Parameters.MaxTxsInBlock
+	defer func() { config.Parameters.MaxTxsInBlock = maxTxsInBlock }()
+	config.Parameters.MaxTxsInBlock = 2
+	assert.Equal(t, map[common.Uint256]*types.Transaction{
+		tx2.Hash(): tx2, tx4.Hash(): tx4}, txPool.GetTransactionPool(true))
+	assert.Equal(t, 3, len(txPool.GetTransactionPool(false)))
+
+	// the kept transaction should never be evicted
+	config.Parameters.MaxTxsInPool = 1
+	assert.False(t, txPool.trimToLimits(tx4))
+	assert.NotNil(t, txPool.GetTransaction(tx4.Hash()))
+}
+
+func TestTxPool_AncestorPackageSelection(t *testing.T) {
+	txPool.Init()
+
+	parent := newTestTx(10)
+	child := newTestTx(10000, spendOf(parent))
+	other := newTestTx(1000)
+	txPool.addToTxList(parent)
+	txPool.addToTxList(child)
+	txPool.addInputUTXOList(child, child.Inputs[0])
+	txPool.addToTxList(other)
+
+	// the high fee child pulls in its low fee parent before the others
+	txs := txPool.GetTransactionsForBlock(0, 0)
+	assert.Equal(t, []*types.Transaction{parent, child, other}, txs)
+
+	// a package is skipped as a whole if it does not fit
+	txs = txPool.GetTransactionsForBlock(0, 1)
+	assert.Equal(t, []*types.Transaction{other}, txs)
+
+	// the ancestor limits should be checked
+	maxCount := config.Parameters.MaxTxAncestorCount
+	defer func() { config.Parameters.MaxTxAncestorCount = maxCount }()
+	grandchild := newTestTx(100, spendOf(child))
+	config.Parameters.MaxTxAncestorCount = 2
+	assert.NoError(t, txPool.verifyAncestors(grandchild, grandchild.GetSize()))
+	config.Parameters.MaxTxAncestorCount = 1
+	assert.Error(t, txPool.verifyAncestors(grandchild, grandchild.GetSize()))
+
+	// removing the parent removes its descendants
+	txPool.removeTransactionWithDescendants(parent)
+	assert.Nil(t, txPool.GetTransaction(child.Hash()))
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+}
+
+func TestTxPool_EvictPackages(t *testing.T) {
+	txPool.Init()
+	listener := &evictedListener{}
+	txPool.Listeners[listener] = nil
+	maxTxsInPool := config.Parameters.MaxTxsInPool
+	maxTxPoolSize := config.Parameters.MaxTxPoolSize
+	defer func() {
+		config.Parameters.MaxTxsInPool = maxTxsInPool
+		config.Parameters.MaxTxPoolSize = maxTxPoolSize
+	}()
+	addTx := func(tx *types.Transaction) {
+		txPool.addToTxList(tx)
+		for _, input := range tx.Inputs {
+			txPool.addInputUTXOList(tx, input)
+		}
+	}
+
+	// the low fee parent is not evicted for its high fee child, the other
+	// transaction paying a lower package fee rate is evicted instead
+	parent := newTestTx(10)
+	other := newTestTx(1000)
+	addTx(parent)
+	addTx(other)
+	child := newTestTx(10000, spendOf(parent))
+	config.Parameters.MaxTxsInPool = 2
+	assert.False(t, txPool.isFullFor(child, child.GetSize()))
+	addTx(child)
+	assert.True(t, txPool.trimToLimits(child))
+	assert.NotNil(t, txPool.GetTransaction(parent.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(child.Hash()))
+	assert.Nil(t, txPool.GetTransaction(other.Hash()))
+	assert.Equal(t, []*types.Transaction{other}, listener.evicted)
+
+	// a transaction paying a lower fee rate than the package can not evict it
+	poor := newTestTx(1000)
+	config.Parameters.MaxTxsInPool = 1
+	assert.True(t, txPool.isFullFor(poor, poor.GetSize()))
+	addTx(poor)
+	assert.False(t, txPool.trimToLimits(poor))
+	assert.Equal(t, 3, txPool.GetTransactionCount())
+	assert.Equal(t, 1, len(listener.evicted))
+	txPool.removeTransactionWithDescendants(poor)
+
+	// the package is evicted as a whole for a higher fee rate transaction
+	listener.evicted = nil
+	rich := newTestTx(100000)
+	assert.False(t, txPool.isFullFor(rich, rich.GetSize()))
+	addTx(rich)
+	assert.True(t, txPool.trimToLimits(rich))
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+	assert.Equal(t, []*types.Transaction{parent, child}, listener.evicted)
+
+	// the size of all transactions paying a lower fee rate counts
+	txPool.Init()
+	config.Parameters.MaxTxsInPool = 0
+	small1 := newTestTx(100)
+	small2 := newTestTx(100)
+	small3 := newTestTx(100)
+	for _, tx := range []*types.Transaction{small1, small2, small3} {
+		addTx(tx)
+	}
+	config.Parameters.MaxTxPoolSize = 3 * small1.GetSize()
+	big := newTestTx(10000, &types.Input{})
+	assert.True(t, big.GetSize() > small1.GetSize())
+	assert.True(t, big.GetSize() <= 2*small1.GetSize())
+	assert.False(t, txPool.isFullFor(big, big.GetSize()))
+	txPool.doRemoveTransaction(small2)
+	txPool.doRemoveTransaction(small3)
+	addTx(newTestTx(100000))
+	addTx(newTestTx(100000))
+	assert.True(t, txPool.isFullFor(big, big.GetSize()))
+}
+
+type evictedListener struct {
+	evicted []*types.Transaction
+}
+
+func (l *evictedListener) OnIllegalBlockTxnReceived(txn *types.Transaction) {}
+
+func (l *evictedListener) OnTxnEvicted(txn *types.Transaction) {
+	l.evicted = append(l.evicted, txn)
+}
+
+func TestTxPool_ReplaceByFee(t *testing.T) {
+	txPool.Init()
+	listener := &evictedListener{}
+	txPool.Listeners[listener] = nil
+
+	var prevHash common.Uint256
+	rand.Read(prevHash[:])
+	addTx := func(tx *types.Transaction) {
+		txPool.addToTxList(tx)
+		txPool.addInputUTXOList(tx, tx.Inputs[0])
+	}
+
+	// transactions not signalling replace-by-fee can not be replaced
+	original := newTestTx(100, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	addTx(original)
+	replacement := newTestTx(1000, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	_, err := txPool.getReplacedTransactions(replacement, nil)
+	assert.Error(t, err)
+	txPool.doRemoveTransaction(original)
+
+	original = newTestTx(100, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}, Sequence: types.ReplaceableSequence})
+	child := newTestTx(200, spendOf(original))
+	addTx(original)
+	addTx(child)
+
+	// the replacement should pay more than the original and its descendants
+	replacement = newTestTx(250, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	_, err = txPool.getReplacedTransactions(replacement, nil)
+	assert.Error(t, err)
+
+	// and pay for its own size on top of that
+	replacement = newTestTx(301, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	_, err = txPool.getReplacedTransactions(replacement, nil)
+	assert.Error(t, err)
+
+	// the replaced transactions are kept in pool until the replacement is
+	// accepted
+	replacement = newTestTx(1000, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	replaced, err := txPool.getReplacedTransactions(replacement, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.Transaction{original, child}, replaced)
+	assert.Equal(t, 2, txPool.GetTransactionCount())
+	assert.Equal(t, 0, len(listener.evicted))
+
+	assert.Equal(t, errors.Success, txPool.addReplacement(replacement, replaced))
+	assert.Equal(t, 1, txPool.GetTransactionCount())
+	assert.Nil(t, txPool.GetTransaction(original.Hash()))
+	assert.Equal(t, replacement, txPool.getInputUTXOList(original.Inputs[0]))
+	assert.Equal(t, []*types.Transaction{original, child}, listener.evicted)
+}
+
+func TestTxPool_ReplaceByFeeRestore(t *testing.T) {
+	txPool.Init()
+	listener := &evictedListener{}
+	txPool.Listeners[listener] = nil
+	maxTxsInPool := config.Parameters.MaxTxsInPool
+	defer func() { config.Parameters.MaxTxsInPool = maxTxsInPool }()
+
+	var prevHash common.Uint256
+	rand.Read(prevHash[:])
+	original := newTestTx(100, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}, Sequence: types.ReplaceableSequence})
+	child := newTestTx(200, spendOf(original))
+	cheap := newTestTx(1)
+	other := newTestTx(100000)
+	for _, tx := range []*types.Transaction{original, child, cheap, other} {
+		txPool.addToTxList(tx)
+		for _, input := range tx.Inputs {
+			txPool.addInputUTXOList(tx, input)
+		}
+	}
+	arrival := txPool.txnTime[original.Hash()]
+
+	// the replacement passes the replace-by-fee rules, but the pool is full
+	// and only the cheap transaction pays a lower fee rate than it after the
+	// originals are taken out, evicting it alone does not make enough room
+	config.Parameters.MaxTxsInPool = 1
+	replacement := newTestTx(1000, &types.Input{
+		Previous: types.OutPoint{TxID: prevHash}})
+	replaced, err := txPool.getReplacedTransactions(replacement, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, errors.ErrTxPoolFull, txPool.addReplacement(replacement, replaced))
+
+	// the originals are restored as they were, nothing is evicted
+	assert.Nil(t, txPool.GetTransaction(replacement.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(cheap.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(other.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(original.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(child.Hash()))
+	assert.Equal(t, original, txPool.getInputUTXOList(original.Inputs[0]))
+	assert.Equal(t, child, txPool.getInputUTXOList(child.Inputs[0]))
+	assert.Equal(t, arrival, txPool.txnTime[original.Hash()])
+	assert.Equal(t, 0, len(listener.evicted))
+}
+
+func TestTxPool_SaveAndLoadFile(t *testing.T) {
+	var txs []savedTx
+	for i := 0; i < 3; i++ {
+		txs = append(txs, savedTx{txn: newTestTx(0),
+			arrival: time.Unix(int64(1000*i), 0)})
+	}
+
+	path := filepath.Join(os.TempDir(), TxPoolFile)
+	defer os.Remove(path)
+	assert.NoError(t, writeTxPoolFile(path, txs))
+
+	loaded, err := readTxPoolFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(txs), len(loaded))
+	for i := range txs {
+		assert.Equal(t, txs[i].txn.Hash(), loaded[i].txn.Hash())
+		assert.True(t, txs[i].arrival.Equal(loaded[i].arrival))
+	}
+
+	// the saved arrival time is restored, so the transaction expires as if
+	// it was never unloaded
+	txPool.Init()
+	expiry := config.Parameters.TxPoolExpiry
+	defer func() { config.Parameters.TxPoolExpiry = expiry }()
+	config.Parameters.TxPoolExpiry = 60
+	txPool.addToTxList(txs[0].txn)
+	txPool.addToTxList(txs[1].txn)
+	txPool.restoreArrivalTime(txs[0].txn.Hash(), time.Now().Add(-time.Hour))
+	txPool.restoreArrivalTime(txs[1].txn.Hash(), time.Now().Add(time.Hour))
+	txPool.expireTransactions(time.Now())
+	assert.Nil(t, txPool.GetTransaction(txs[0].txn.Hash()))
+	assert.NotNil(t, txPool.GetTransaction(txs[1].txn.Hash()))
+
+	// a broken file should not be loaded
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Truncate(10))
+	file.Close()
+	_, err = readTxPoolFile(path)
+	assert.Error(t, err)
+}
+
+func TestTxPool_OrphanAndExpiry(t *testing.T) {
+	txPool.Init()
+
+	maxOrphans := config.Parameters.MaxOrphanTxs
+	expiry := config.Parameters.TxPoolExpiry
+	defer func() {
+		config.Parameters.MaxOrphanTxs = maxOrphans
+		config.Parameters.TxPoolExpiry = expiry
+	}()
+
+	// orphans are rejected if the orphan pool is disabled
+	parent := newTestTx(0)
+	orphan := newTestTx(0, spendOf(parent))
+	config.Parameters.MaxOrphanTxs = 0
+	assert.Error(t, txPool.addOrphan(orphan, orphan.Inputs))
+
+	// orphans are taken out when their parent arrives
+	config.Parameters.MaxOrphanTxs = 2
+	assert.NoError(t, txPool.addOrphan(orphan, orphan.Inputs))
+	assert.Equal(t, 1, len(txPool.orphans))
+	assert.Equal(t, 0, len(txPool.takeOrphansSpending(newTestTx(0))))
+	assert.Equal(t, []*types.Transaction{orphan}, txPool.takeOrphansSpending(parent))
+	assert.Equal(t, 0, len(txPool.orphans))
+	assert.Equal(t, 0, len(txPool.orphansByPrev))
+
+	// the orphan pool is bounded and orphans expire
+	for i := 0; i < 3; i++ {
+		orphan := newTestTx(0, spendOf(newTestTx(0)))
+		assert.NoError(t, txPool.addOrphan(orphan, orphan.Inputs))
+	}
+	assert.Equal(t, 2, len(txPool.orphans))
+	txPool.expireOrphans(time.Now())
+	assert.Equal(t, 2, len(txPool.orphans))
+	txPool.expireOrphans(time.Now().Add(orphanTTL + time.Second))
+	assert.Equal(t, 0, len(txPool.orphans))
+	assert.Equal(t, 0, len(txPool.orphansByPrev))
+
+	// transactions expire together with their descendants
+	config.Parameters.TxPoolExpiry = 60
+	child := newTestTx(0, spendOf(parent))
+	txPool.addToTxList(parent)
+	txPool.addToTxList(child)
+	txPool.addInputUTXOList(child, child.Inputs[0])
+	txPool.expireTransactions(time.Now())
+	assert.Equal(t, 2, txPool.GetTransactionCount())
+	txPool.expireTransactions(time.Now().Add(time.Minute * 2))
+	assert.Equal(t, 0, txPool.GetTransactionCount())
+	assert.Equal(t, 0, len(txPool.txnTime))
+}
+
+func TestTxPool_RemoveDescendants(t *testing.T) {
+	txPool.Init()
+	listener := &evictedListener{}
+	txPool.Listeners[listener] = nil
+	addTx := func(tx *types.Transaction) {
+		txPool.addToTxList(tx)
+		for _, input := range tx.Inputs {
+			txPool.addInputUTXOList(tx, input)
+		}
+	}
+
+	// the spenders of a rolled back transaction are removed with their
+	// descendants
+	rollback := newTestTx(0)
+	spender := newTestTx(10, spendOf(rollback))
+	child := newTestTx(10, spendOf(spender))
+	addTx(spender)
+	addTx(child)
+	txPool.RemoveTransaction(rollback)
+	assert.Equal(t, 0, txPool.GetTransactionCount())
+	assert.Equal(t, []*types.Transaction{spender, child}, listener.evicted)
+
+	// the withdraw transaction is removed with its descendants once the
+	// sidechain transaction is withdrawn in a block
+	var sidechainHash common.Uint256
+	rand.Read(sidechainHash[:])
+	withdraw := newTestTx(10, &types.Input{})
+	withdraw.TxType = types.WithdrawFromSideChain
+	withdraw.Payload = &payload.PayloadWithdrawFromSideChain{
+		SideChainTransactionHashes: []common.Uint256{sidechainHash},
+	}
+	child = newTestTx(10, spendOf(withdraw))
+	addTx(withdraw)
+	addTx(child)
+	txPool.addSidechainTx(withdraw)
+	blockTx := newTestTx(10)
+	blockTx.TxType = types.WithdrawFromSideChain
+	blockTx.Payload = withdraw.Payload
+	listener.evicted = nil
+	txPool.cleanSidechainTx([]*types.Transaction{blockTx})
+	assert.Equal(t, 0, txPool.GetTransactionCount())
+	assert.False(t, txPool.IsDuplicateSidechainTx(sidechainHash))
+	assert.Equal(t, []*types.Transaction{withdraw, child}, listener.evicted)
+}
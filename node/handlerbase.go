@@ -8,6 +8,7 @@ import (
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/types"
 	"github.com/elastos/Elastos.ELA/p2p"
 	"github.com/elastos/Elastos.ELA/p2p/msg"
 	"github.com/elastos/Elastos.ELA/protocol"
@@ -47,6 +48,14 @@ func (h *HandlerBase) MakeEmptyMessage(cmd string) (message p2p.Message, err err
 	case p2p.CmdPong:
 		message = &msg.Pong{}
 
+	case p2p.CmdGetHeaders:
+		message = &msg.GetHeaders{}
+
+	case p2p.CmdHeaders:
+		message = msg.NewEmptyHeaders(func() common.Serializable {
+			return &types.Header{}
+		})
+
 	default:
 		err = errors.New("unknown message type")
 	}
@@ -74,6 +83,12 @@ func (h *HandlerBase) HandleMessage(message p2p.Message) {
 	case *msg.Pong:
 		h.onPong(m)
 
+	case *msg.GetHeaders:
+		h.onGetHeaders(m)
+
+	case *msg.Headers:
+		h.onHeaders(m)
+
 	default:
 		log.Warnf("unknown handled message %s", m.CMD())
 	}
@@ -169,6 +184,36 @@ func (h *HandlerBase) onPong(pong *msg.Pong) {
 	h.node.SetLastActive(time.Now())
 }
 
+func (h *HandlerBase) onGetHeaders(req *msg.GetHeaders) {
+	LocalNode.AcqSyncBlkReqSem()
+	defer LocalNode.RelSyncBlkReqSem()
+
+	// Reply even if no header found, so the peer knows headers are synced.
+	var headers []common.Serializable
+	start := chain.DefaultLedger.Blockchain.LatestLocatorHash(req.Locator)
+	hashes, err := GetBlockHashes(*start, req.HashStop, msg.MaxHeadersPerMsg)
+	if err != nil {
+		log.Debug("get block hashes failed: ", err)
+	}
+	for _, hash := range hashes {
+		header, err := chain.DefaultLedger.Store.GetHeader(*hash)
+		if err != nil {
+			log.Errorf("can't get header from hash %s: %s", hash, err)
+			break
+		}
+		headers = append(headers, header)
+	}
+	h.node.SendMessage(msg.NewHeaders(headers))
+}
+
+func (h *HandlerBase) onHeaders(m *msg.Headers) {
+	headers := make([]*types.Header, 0, len(m.Headers))
+	for _, header := range m.Headers {
+		headers = append(headers, header.(*types.Header))
+	}
+	LocalNode.headersSync.onHeaders(h.node, headers)
+}
+
 func (h *HandlerBase) onGetAddr(getAddr *msg.GetAddr) {
 	var addrs []*p2p.NetAddress
 	// Only send addresses that enabled SPV service
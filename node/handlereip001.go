@@ -285,6 +285,12 @@ func (h *HandlerEIP001) onBlock(msgBlock *msg.Block) {
 	LocalNode.syncTimer.update()
 	LocalNode.DeleteRequestedBlock(hash)
 
+	// Blocks requested by headers first sync are processed in order
+	if LocalNode.headersSync.onBlock(node,
+		&types.DposBlock{BlockFlag: true, Block: block}) {
+		return
+	}
+
 	_, isOrphan, err := chain.DefaultLedger.Blockchain.AddBlock(block)
 	if err != nil {
 		reject := msg.NewReject(msgBlock.CMD(), msg.RejectInvalid, err.Error())
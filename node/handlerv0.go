@@ -218,6 +218,11 @@ func (h *HandlerV0) onBlock(msg *msg.Block) error {
 		LocalNode.syncTimer.update()
 		LocalNode.DeleteRequestedBlock(blockHash)
 
+		// Blocks requested by headers first sync are processed in order
+		if LocalNode.headersSync.onBlock(node, dposBlock) {
+			return nil
+		}
+
 		var err error
 		_, isOrphan, err = chain.DefaultLedger.HeightVersions.AddDposBlock(dposBlock)
 		if err != nil {
@@ -0,0 +1,519 @@
+package node
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/auxpow"
+	chain "github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/p2p"
+	"github.com/elastos/Elastos.ELA/p2p/msg"
+	"github.com/elastos/Elastos.ELA/p2p/msg/v0"
+	"github.com/elastos/Elastos.ELA/protocol"
+)
+
+const (
+	// blockDownloadWindow is the max number of blocks after the last
+	// processed one that can be requested or waiting to be processed.
+	blockDownloadWindow = 1024
+
+	// maxBlocksInFlightPerPeer is the max number of blocks requested from a
+	// peer and not received yet.
+	maxBlocksInFlightPerPeer = 16
+
+	// blockStallTimeout is the time limit for a peer to reply a requested
+	// block, the block is requested again from other peers after it.
+	blockStallTimeout = 15 * time.Second
+
+	// headersTimeout is the time limit for the headers peer to reply a
+	// getheaders request.
+	headersTimeout = 30 * time.Second
+
+	// maxPeerStalls is the number of stalls after which a peer is no longer
+	// used by the current sync.
+	maxPeerStalls = 3
+
+	// stallCheckInterval is the interval to check stalled requests.
+	stallCheckInterval = time.Second
+
+	// maxPendingHeaders is the max number of synced headers whose blocks
+	// are not processed yet, more headers are requested after the blocks
+	// are processed.
+	maxPendingHeaders = 4 * msg.MaxHeadersPerMsg
+)
+
+type blockRequest struct {
+	peer protocol.Noder
+	time time.Time
+}
+
+type receivedBlock struct {
+	peer  protocol.Noder
+	block *types.DposBlock
+}
+
+// headersSync syncs the header chain from one peer first, and then downloads
+// the blocks of the header chain from several peers in parallel with a sliding
+// window, processing them in order. The headers are checked for proof of
+// work and difficulty only, the blocks are fully validated when they are
+// processed.
+type headersSync struct {
+	sync.Mutex
+	running     bool
+	quit        chan struct{}
+	headersPeer protocol.Noder
+	headersTime time.Time
+	headersDone bool
+	// headersPaused is set when maxPendingHeaders is reached.
+	headersPaused bool
+
+	// hashes is the header chain to download, the height of hashes[i] is
+	// baseHeight+1+i.
+	hashes      []common.Uint256
+	index       map[common.Uint256]int
+	baseHeight  uint32
+	lastHash    common.Uint256
+	nextProcess int
+
+	// tip is the node of lastHash, the latest header nodes are kept linked
+	// to their parents to calculate the difficulty of the next header.
+	tip   *chain.BlockNode
+	nodes []*chain.BlockNode
+
+	requested map[common.Uint256]*blockRequest
+	received  map[common.Uint256]*receivedBlock
+	inFlight  map[protocol.Noder]int
+	stalls    map[protocol.Noder]int
+}
+
+// start begins a headers first sync, it returns false if no peer serves
+// headers. It does nothing if the sync has been started.
+func (s *headersSync) start() bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.running {
+		return true
+	}
+
+	s.stalls = make(map[protocol.Noder]int)
+	peer := s.bestHeadersPeer()
+	if peer == nil {
+		return false
+	}
+
+	lastHash := chain.DefaultLedger.Store.GetCurrentBlockHash()
+	tip, ok := chain.DefaultLedger.Blockchain.LookupNodeInIndex(&lastHash)
+	if !ok {
+		return false
+	}
+
+	s.running = true
+	s.quit = make(chan struct{})
+	s.headersDone = false
+	s.headersPaused = false
+	s.hashes = nil
+	s.index = make(map[common.Uint256]int)
+	s.baseHeight = chain.DefaultLedger.Store.GetHeight()
+	s.lastHash = lastHash
+	s.nextProcess = 0
+	s.tip = tip
+	s.nodes = nil
+	s.requested = make(map[common.Uint256]*blockRequest)
+	s.received = make(map[common.Uint256]*receivedBlock)
+	s.inFlight = make(map[protocol.Noder]int)
+	LocalNode.SetSyncHeaders(true)
+
+	log.Infof("start headers first sync from height %d with peer %s",
+		s.baseHeight, peer.Addr())
+	s.requestHeaders(peer)
+	go s.stallHandler(s.quit)
+	return true
+}
+
+// abort stops the sync, blocks not processed yet are dropped.
+func (s *headersSync) abort() {
+	s.Lock()
+	defer s.Unlock()
+	if s.running {
+		s.stop()
+	}
+}
+
+// stop must be called with the lock held.
+func (s *headersSync) stop() {
+	s.running = false
+	close(s.quit)
+	s.requested = nil
+	s.received = nil
+	s.inFlight = nil
+	s.tip = nil
+	s.nodes = nil
+	LocalNode.SetSyncHeaders(false)
+}
+
+// bestHeadersPeer returns the highest internal peer serving headers which is
+// higher than the local chain.
+func (s *headersSync) bestHeadersPeer() protocol.Noder {
+	var best protocol.Noder
+	height := uint64(chain.DefaultLedger.Store.GetHeight())
+	for _, peer := range LocalNode.GetNeighborNodes() {
+		if peer.IsExternal() || peer.Services()&protocol.HeadersService == 0 ||
+			s.stalls[peer] >= maxPeerStalls || peer.Height() <= height {
+			continue
+		}
+		if best == nil || peer.Height() > best.Height() {
+			best = peer
+		}
+	}
+	return best
+}
+
+func (s *headersSync) requestHeaders(peer protocol.Noder) {
+	s.headersPeer = peer
+	s.headersTime = time.Now()
+	lastHash := s.lastHash
+	var locator []*common.Uint256
+	if len(s.hashes) == 0 {
+		locator = chain.DefaultLedger.Blockchain.BlockLocatorFromHash(&lastHash)
+	} else {
+		locator = []*common.Uint256{&lastHash}
+	}
+	peer.SendMessage(msg.NewGetHeaders(locator, common.EmptyHash))
+}
+
+// rotateHeadersPeer switches the headers peer after the current one stalled
+// or sent invalid headers. The sync is stopped if no other peer serves
+// headers.
+func (s *headersSync) rotateHeadersPeer() {
+	s.stalls[s.headersPeer] = maxPeerStalls
+	peer := s.bestHeadersPeer()
+	if peer == nil {
+		log.Warn("no peer to sync headers from, stop headers first sync")
+		s.stop()
+		return
+	}
+	log.Infof("sync headers from peer %s", peer.Addr())
+	s.requestHeaders(peer)
+}
+
+func (s *headersSync) onHeaders(peer protocol.Noder, headers []*types.Header) {
+	s.Lock()
+	defer s.Unlock()
+	if !s.running || s.headersDone || peer != s.headersPeer {
+		return
+	}
+
+	powLimit := config.Parameters.ChainParam.PowLimit
+	for _, header := range headers {
+		hash := header.Hash()
+		if _, ok := s.index[hash]; ok {
+			continue
+		}
+
+		// The locator may match an earlier block than the local tip, skip
+		// the headers already in ledger.
+		if len(s.hashes) == 0 && chain.DefaultLedger.BlockInLedger(hash) {
+			if !s.resetTip(hash) {
+				log.Warnf("header %s from peer %s too deep in local chain",
+					hash, peer.Addr())
+				s.rotateHeadersPeer()
+				return
+			}
+			continue
+		}
+
+		// The peer may be on a fork of the local chain, follow it from the
+		// fork point and let the blocks be processed as a side chain.
+		if len(s.hashes) == 0 && !header.Previous.IsEqual(s.lastHash) &&
+			!s.resetTip(header.Previous) {
+			log.Warnf("headers from peer %s not connected to local chain",
+				peer.Addr())
+			s.rotateHeadersPeer()
+			return
+		}
+
+		if !header.Previous.IsEqual(s.lastHash) ||
+			header.Height != s.baseHeight+uint32(len(s.hashes))+1 {
+			log.Warnf("headers from peer %s not in sequence", peer.Addr())
+			s.rotateHeadersPeer()
+			return
+		}
+		if !header.AuxPow.Check(&hash, auxpow.AuxPowChainID) {
+			log.Warnf("header %s from peer %s check aux pow failed", hash,
+				peer.Addr())
+			s.rotateHeadersPeer()
+			return
+		}
+		if err := chain.CheckProofOfWork(header, powLimit); err != nil {
+			log.Warnf("header %s from peer %s check proof of work failed: %s",
+				hash, peer.Addr(), err)
+			s.rotateHeadersPeer()
+			return
+		}
+		if err := s.connectHeader(header, hash); err != nil {
+			log.Warnf("header %s from peer %s check difficulty failed: %s",
+				hash, peer.Addr(), err)
+			s.rotateHeadersPeer()
+			return
+		}
+
+		s.index[hash] = len(s.hashes)
+		s.hashes = append(s.hashes, hash)
+		s.lastHash = hash
+	}
+
+	if len(headers) < msg.MaxHeadersPerMsg {
+		s.headersDone = true
+		log.Infof("headers synced to height %d",
+			s.baseHeight+uint32(len(s.hashes)))
+	} else if len(s.hashes)-s.nextProcess >= maxPendingHeaders {
+		s.headersPaused = true
+	} else {
+		s.requestHeaders(peer)
+	}
+
+	s.requestBlocks()
+	s.checkFinished()
+}
+
+// resetTip moves the tip to a block in the local chain, it returns false if
+// the block is not in the block index.
+func (s *headersSync) resetTip(hash common.Uint256) bool {
+	tip, ok := chain.DefaultLedger.Blockchain.LookupNodeInIndex(&hash)
+	if !ok {
+		return false
+	}
+	s.baseHeight = tip.Height
+	s.lastHash = hash
+	s.tip = tip
+	s.nodes = nil
+	return true
+}
+
+// connectHeader checks the difficulty of a header against the previous
+// headers and links the node of it to the tip.
+func (s *headersSync) connectHeader(header *types.Header,
+	hash common.Uint256) error {
+	bits, err := chain.CalcNextRequiredDifficulty(s.tip,
+		time.Unix(int64(header.Timestamp), 0))
+	if err != nil {
+		return err
+	}
+	if header.Bits != bits {
+		return fmt.Errorf("difficulty %08x is not the expected %08x",
+			header.Bits, bits)
+	}
+
+	node := chain.NewBlockNode(header, &hash)
+	node.Parent = s.tip
+	s.tip = node
+	s.nodes = append(s.nodes, node)
+
+	// Only the nodes of a retarget interval are needed to calculate the
+	// difficulty, unlink the older ones so they can be released.
+	params := config.Parameters.ChainParam
+	if len(s.nodes) > int(params.TargetTimespan/params.TargetTimePerBlock) {
+		s.nodes[0].Parent = nil
+		s.nodes = s.nodes[1:]
+	}
+	return nil
+}
+
+// onBlock takes a block of the header chain received from a peer, and returns
+// false if the block is not one the sync is waiting for.
+func (s *headersSync) onBlock(peer protocol.Noder, block *types.DposBlock) bool {
+	s.Lock()
+	defer s.Unlock()
+	if !s.running {
+		return false
+	}
+	hash := block.Block.Hash()
+	i, ok := s.index[hash]
+	if !ok || i < s.nextProcess {
+		return false
+	}
+
+	if req, ok := s.requested[hash]; ok {
+		s.inFlight[req.peer]--
+		delete(s.requested, hash)
+	}
+	s.received[hash] = &receivedBlock{peer: peer, block: block}
+
+	s.processBlocks()
+	if !s.running {
+		return true
+	}
+	if s.headersPaused && len(s.hashes)-s.nextProcess < maxPendingHeaders {
+		s.headersPaused = false
+		s.requestHeaders(s.headersPeer)
+	}
+	s.requestBlocks()
+	s.checkFinished()
+	return true
+}
+
+// processBlocks processes the received blocks in the order of the header
+// chain until a block not received yet.
+func (s *headersSync) processBlocks() {
+	defer s.compact()
+	for s.nextProcess < len(s.hashes) {
+		hash := s.hashes[s.nextProcess]
+		received, ok := s.received[hash]
+		if !ok {
+			return
+		}
+		delete(s.received, hash)
+
+		if !chain.DefaultLedger.BlockInLedger(hash) {
+			_, _, err := chain.DefaultLedger.HeightVersions.AddDposBlock(received.block)
+			if err != nil {
+				// The block matches the header but the content is invalid,
+				// request it again from other peers.
+				log.Warnf("process block %s from peer %s failed: %s", hash,
+					received.peer.Addr(), err)
+				s.stalls[received.peer] = maxPeerStalls
+				return
+			}
+		}
+		s.nextProcess++
+	}
+}
+
+// compact drops the hashes of the processed blocks once they fill a headers
+// message, so the header chain kept is bounded by maxPendingHeaders.
+func (s *headersSync) compact() {
+	n := s.nextProcess
+	if n < msg.MaxHeadersPerMsg {
+		return
+	}
+	for _, hash := range s.hashes[:n] {
+		delete(s.index, hash)
+	}
+	s.hashes = append([]common.Uint256(nil), s.hashes[n:]...)
+	for i, hash := range s.hashes {
+		s.index[hash] = i
+	}
+	s.baseHeight += uint32(n)
+	s.nextProcess = 0
+}
+
+// requestBlocks requests the blocks in the download window which are neither
+// requested nor received, from the peers with the fewest blocks in flight.
+func (s *headersSync) requestBlocks() {
+	var peers []protocol.Noder
+	for _, peer := range LocalNode.GetNeighborNodes() {
+		if !peer.IsExternal() && s.stalls[peer] < maxPeerStalls {
+			peers = append(peers, peer)
+		}
+	}
+
+	end := s.nextProcess + blockDownloadWindow
+	if end > len(s.hashes) {
+		end = len(s.hashes)
+	}
+	for i := s.nextProcess; i < end; i++ {
+		hash := s.hashes[i]
+		if _, ok := s.requested[hash]; ok {
+			continue
+		}
+		if _, ok := s.received[hash]; ok {
+			continue
+		}
+
+		height := uint64(s.baseHeight) + uint64(i) + 1
+		var best protocol.Noder
+		for _, peer := range peers {
+			if s.inFlight[peer] >= maxBlocksInFlightPerPeer ||
+				peer.Height() < height {
+				continue
+			}
+			if best == nil || s.inFlight[peer] < s.inFlight[best] {
+				best = peer
+			}
+		}
+		if best == nil {
+			return
+		}
+
+		requestBlock(best, hash)
+		s.requested[hash] = &blockRequest{peer: best, time: time.Now()}
+		s.inFlight[best]++
+	}
+}
+
+func requestBlock(peer protocol.Noder, hash common.Uint256) {
+	if peer.Version() < p2p.EIP001Version {
+		peer.SendMessage(v0.NewGetData(hash))
+		return
+	}
+	getData := msg.NewGetData()
+	getData.AddInvVect(msg.NewInvVect(msg.InvTypeBlock, &hash))
+	peer.SendMessage(getData)
+}
+
+func (s *headersSync) checkFinished() {
+	if s.headersDone && s.nextProcess == len(s.hashes) {
+		log.Infof("headers first sync finished at height %d",
+			chain.DefaultLedger.Store.GetHeight())
+		s.stop()
+	}
+}
+
+// stallHandler checks stalled requests periodically. It must be run as a
+// goroutine.
+func (s *headersSync) stallHandler(quit chan struct{}) {
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkStalls()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// checkStalls switches the headers peer if it stalled, and releases the
+// stalled block requests so they are requested from other peers.
+func (s *headersSync) checkStalls() {
+	s.Lock()
+	defer s.Unlock()
+	if !s.running {
+		return
+	}
+
+	now := time.Now()
+	if !s.headersDone && !s.headersPaused &&
+		now.Sub(s.headersTime) > headersTimeout {
+		log.Warnf("headers peer %s stalled", s.headersPeer.Addr())
+		s.rotateHeadersPeer()
+		if !s.running {
+			return
+		}
+	}
+
+	for hash, req := range s.requested {
+		if now.Sub(req.time) > blockStallTimeout {
+			log.Debugf("request block %s from peer %s stalled", hash,
+				req.peer.Addr())
+			s.stalls[req.peer]++
+			s.inFlight[req.peer]--
+			delete(s.requested, hash)
+		}
+	}
+
+	s.requestBlocks()
+	if s.headersDone && len(s.requested) == 0 &&
+		s.nextProcess < len(s.hashes) {
+		if _, ok := s.received[s.hashes[s.nextProcess]]; !ok {
+			log.Warn("no peer to download blocks from, stop headers first sync")
+			s.stop()
+		}
+	}
+}
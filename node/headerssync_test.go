@@ -0,0 +1,289 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA/auxpow"
+	chain "github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/blockchain/mock"
+	"github.com/elastos/Elastos.ELA/common/config"
+	"github.com/elastos/Elastos.ELA/common/log"
+	"github.com/elastos/Elastos.ELA/core/types"
+	"github.com/elastos/Elastos.ELA/p2p"
+	"github.com/elastos/Elastos.ELA/p2p/msg"
+	"github.com/elastos/Elastos.ELA/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNoder is a neighbor which records the messages sent to it.
+type fakeNoder struct {
+	protocol.Noder
+	id       uint64
+	height   uint64
+	services uint64
+	external bool
+	sent     []p2p.Message
+}
+
+func (n *fakeNoder) ID() uint64                { return n.id }
+func (n *fakeNoder) Addr() string              { return "fake" }
+func (n *fakeNoder) Version() uint32           { return p2p.EIP001Version }
+func (n *fakeNoder) Height() uint64            { return n.height }
+func (n *fakeNoder) Services() uint64          { return n.services }
+func (n *fakeNoder) IsExternal() bool          { return n.external }
+func (n *fakeNoder) State() protocol.State     { return protocol.ESTABLISHED }
+func (n *fakeNoder) SendMessage(m p2p.Message) { n.sent = append(n.sent, m) }
+
+func (n *fakeNoder) count(cmd string) int {
+	var count int
+	for _, m := range n.sent {
+		if m.CMD() == cmd {
+			count++
+		}
+	}
+	return count
+}
+
+// setupHeadersSync initializes a ledger with the genesis block only and a
+// local node with the given neighbors, it returns a function to clean up.
+func setupHeadersSync(t *testing.T, peers ...*fakeNoder) func() {
+	log.Init(0, 20, 100)
+	dir, err := ioutil.TempDir("", "headerssync")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	store, err := chain.NewChainStore(dir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	originLedger, originNode := chain.DefaultLedger, LocalNode
+	if !assert.NoError(t, chain.Init(store, mock.NewBlockHeightMock())) {
+		t.FailNow()
+	}
+
+	LocalNode = &node{}
+	LocalNode.neighbours.init()
+	for _, peer := range peers {
+		LocalNode.AddNeighborNode(peer)
+	}
+	return func() {
+		LocalNode.headersSync.abort()
+		store.Close()
+		os.RemoveAll(dir)
+		chain.DefaultLedger, LocalNode = originLedger, originNode
+	}
+}
+
+func genesisHeader(t *testing.T) *types.Header {
+	genesis, err := chain.GetGenesisBlock()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &genesis.Header
+}
+
+// newHeaders creates a header chain of n headers after prev solved with the
+// given difficulty.
+func newHeaders(prev *types.Header, n int, bits uint32) []*types.Header {
+	headers := make([]*types.Header, 0, n)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			Previous:  prev.Hash(),
+			Timestamp: prev.Timestamp + 1,
+			Bits:      bits,
+			Height:    prev.Height + 1,
+		}
+		hash := header.Hash()
+		aux := auxpow.GenerateAuxPow(hash)
+		target := chain.CompactToBig(bits)
+		for nonce := uint32(0); ; nonce++ {
+			aux.ParBlockHeader.Nonce = nonce
+			parHash := aux.ParBlockHeader.Hash()
+			if chain.HashToBig(&parHash).Cmp(target) <= 0 {
+				break
+			}
+		}
+		header.AuxPow = *aux
+		headers = append(headers, header)
+		prev = header
+	}
+	return headers
+}
+
+func newDposBlock(header *types.Header) *types.DposBlock {
+	return &types.DposBlock{
+		BlockFlag: true,
+		Block:     &types.Block{Header: *header},
+	}
+}
+
+func TestHeadersSync_OnHeaders(t *testing.T) {
+	peer1 := &fakeNoder{id: 1, height: 10, services: protocol.HeadersService}
+	peer2 := &fakeNoder{id: 2, height: 5, services: protocol.HeadersService}
+	external := &fakeNoder{id: 3, height: 20,
+		services: protocol.HeadersService, external: true}
+	defer setupHeadersSync(t, peer1, peer2, external)()
+	s := &LocalNode.headersSync
+
+	// the highest internal peer is used to sync headers
+	assert.True(t, s.start())
+	assert.Equal(t, protocol.Noder(peer1), s.headersPeer)
+	assert.Equal(t, 1, peer1.count(p2p.CmdGetHeaders))
+	assert.True(t, LocalNode.IsSyncHeaders())
+
+	// headers with a difficulty other than the one calculated from the
+	// previous headers are rejected, and the next peer is used
+	bits := uint32(config.Parameters.ChainParam.PowLimitBits)
+	harder := newHeaders(genesisHeader(t), 2, bits-0x01000000)
+	s.onHeaders(peer1, harder)
+	assert.Equal(t, 0, len(s.hashes))
+	assert.Equal(t, maxPeerStalls, s.stalls[peer1])
+	assert.Equal(t, protocol.Noder(peer2), s.headersPeer)
+	assert.Equal(t, 1, peer2.count(p2p.CmdGetHeaders))
+
+	// headers from a peer other than the headers peer are ignored
+	headers := newHeaders(genesisHeader(t), 5, bits)
+	s.onHeaders(peer1, headers)
+	assert.Equal(t, 0, len(s.hashes))
+
+	// headers not in sequence are rejected
+	s.onHeaders(peer2, []*types.Header{headers[1]})
+	assert.Equal(t, 0, len(s.hashes))
+	assert.False(t, s.running)
+	assert.False(t, LocalNode.IsSyncHeaders())
+
+	// valid headers are accepted and the blocks requested from the peers
+	// having them
+	peer1.height = 3
+	assert.True(t, s.start())
+	s.onHeaders(peer2, headers)
+	assert.True(t, s.headersDone)
+	assert.Equal(t, 5, len(s.hashes))
+	assert.Equal(t, headers[4].Hash(), s.lastHash)
+	assert.Equal(t, headers[4].Height, s.tip.Height)
+	assert.Equal(t, 5, len(s.requested))
+	assert.Equal(t, 5, peer1.count(p2p.CmdGetData)+peer2.count(p2p.CmdGetData))
+	assert.True(t, peer2.count(p2p.CmdGetData) >= 2)
+	assert.Equal(t, 0, external.count(p2p.CmdGetData))
+}
+
+func TestHeadersSync_OnBlock(t *testing.T) {
+	peer := &fakeNoder{id: 1, height: 3, services: protocol.HeadersService}
+	defer setupHeadersSync(t, peer)()
+	s := &LocalNode.headersSync
+
+	bits := uint32(config.Parameters.ChainParam.PowLimitBits)
+	headers := newHeaders(genesisHeader(t), 3, bits)
+	assert.True(t, s.start())
+	s.onHeaders(peer, headers)
+	assert.Equal(t, 3, len(s.requested))
+	assert.Equal(t, 3, s.inFlight[peer])
+
+	// blocks not in the header chain are not taken
+	other := *headers[1]
+	other.Timestamp++
+	assert.False(t, s.onBlock(peer, newDposBlock(&other)))
+
+	// blocks are processed in the order of the header chain
+	assert.True(t, s.onBlock(peer, newDposBlock(headers[1])))
+	assert.Equal(t, 0, s.nextProcess)
+	assert.Equal(t, 1, len(s.received))
+	assert.Equal(t, 2, s.inFlight[peer])
+
+	assert.True(t, s.onBlock(peer, newDposBlock(headers[0])))
+	assert.Equal(t, 2, s.nextProcess)
+	assert.Equal(t, 0, len(s.received))
+
+	// processed blocks are not taken again
+	assert.False(t, s.onBlock(peer, newDposBlock(headers[0])))
+
+	// the sync finishes after the last block is processed
+	assert.True(t, s.onBlock(peer, newDposBlock(headers[2])))
+	assert.False(t, s.running)
+	assert.False(t, LocalNode.IsSyncHeaders())
+}
+
+func TestHeadersSync_PendingHeaders(t *testing.T) {
+	peer := &fakeNoder{id: 1, height: maxPendingHeaders + 1,
+		services: protocol.HeadersService}
+	defer setupHeadersSync(t, peer)()
+	s := &LocalNode.headersSync
+
+	bits := uint32(config.Parameters.ChainParam.PowLimitBits)
+	headers := newHeaders(genesisHeader(t), maxPendingHeaders+1, bits)
+	assert.True(t, s.start())
+	for i := 0; i < maxPendingHeaders; i += msg.MaxHeadersPerMsg {
+		s.onHeaders(peer, headers[i:i+msg.MaxHeadersPerMsg])
+	}
+
+	// no more headers are requested until the pending blocks are processed
+	assert.True(t, s.headersPaused)
+	assert.Equal(t, maxPendingHeaders/msg.MaxHeadersPerMsg,
+		peer.count(p2p.CmdGetHeaders))
+
+	// a paused headers peer is not stalled
+	s.headersTime = time.Now().Add(-2 * headersTimeout)
+	s.checkStalls()
+	assert.Equal(t, protocol.Noder(peer), s.headersPeer)
+	assert.Equal(t, 0, s.stalls[peer])
+
+	// the processed hashes are dropped and the headers are requested again
+	for _, header := range headers[:msg.MaxHeadersPerMsg] {
+		assert.True(t, s.onBlock(peer, newDposBlock(header)))
+	}
+	assert.False(t, s.headersPaused)
+	assert.Equal(t, maxPendingHeaders/msg.MaxHeadersPerMsg+1,
+		peer.count(p2p.CmdGetHeaders))
+	assert.Equal(t, 0, s.nextProcess)
+	assert.Equal(t, maxPendingHeaders-msg.MaxHeadersPerMsg, len(s.hashes))
+	assert.Equal(t, uint32(msg.MaxHeadersPerMsg), s.baseHeight)
+	assert.Equal(t, 0, s.index[headers[msg.MaxHeadersPerMsg].Hash()])
+	_, ok := s.index[headers[0].Hash()]
+	assert.False(t, ok)
+
+	// the header chain continues after the compaction
+	s.onHeaders(peer, headers[maxPendingHeaders:])
+	assert.True(t, s.headersDone)
+	assert.Equal(t, maxPendingHeaders-msg.MaxHeadersPerMsg+1, len(s.hashes))
+	assert.Equal(t, headers[maxPendingHeaders].Height, s.tip.Height)
+}
+
+func TestHeadersSync_CheckStalls(t *testing.T) {
+	peer1 := &fakeNoder{id: 1, height: 3, services: protocol.HeadersService}
+	peer2 := &fakeNoder{id: 2, height: 3, services: protocol.HeadersService}
+	defer setupHeadersSync(t, peer1, peer2)()
+	s := &LocalNode.headersSync
+
+	// the headers peer is rotated after it stalled
+	assert.True(t, s.start())
+	first := s.headersPeer.(*fakeNoder)
+	second := peer2
+	if first == peer2 {
+		second = peer1
+	}
+	s.headersTime = time.Now().Add(-2 * headersTimeout)
+	s.checkStalls()
+	assert.Equal(t, maxPeerStalls, s.stalls[first])
+	assert.Equal(t, protocol.Noder(second), s.headersPeer)
+	assert.Equal(t, 1, second.count(p2p.CmdGetHeaders))
+
+	// stalled block requests are released and requested from the peers
+	// which did not stall
+	bits := uint32(config.Parameters.ChainParam.PowLimitBits)
+	headers := newHeaders(genesisHeader(t), 3, bits)
+	s.onHeaders(second, headers)
+	assert.Equal(t, 3, second.count(p2p.CmdGetData))
+	for _, req := range s.requested {
+		req.time = time.Now().Add(-2 * blockStallTimeout)
+	}
+	s.checkStalls()
+	assert.Equal(t, 3, s.stalls[second])
+	assert.Equal(t, 0, len(s.requested))
+
+	// the sync stops when no peer is left to download blocks from
+	assert.False(t, s.running)
+	assert.False(t, LocalNode.IsSyncHeaders())
+}
@@ -66,6 +66,9 @@ func (node *node) SyncBlocks() {
 	bc := chain.DefaultLedger.Blockchain
 	log.Info("[", len(bc.Index), len(bc.BlockCache), len(bc.Orphans), "]")
 	if needSync {
+		if config.Parameters.HeadersFirstSync && LocalNode.headersSync.start() {
+			return
+		}
 		syncNode := LocalNode.GetSyncNode()
 		if syncNode == nil {
 			LocalNode.ResetRequestedBlock()
@@ -122,6 +125,7 @@ func (node *node) SyncBlocks() {
 func stopSyncing() {
 	// Stop sync timer
 	LocalNode.syncTimer.stop()
+	LocalNode.headersSync.abort()
 	LocalNode.SetSyncHeaders(false)
 	LocalNode.SetStartHash(EmptyHash)
 	LocalNode.SetStopHash(EmptyHash)
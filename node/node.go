@@ -76,6 +76,7 @@ type node struct {
 	DefaultMaxPeers    uint
 	RequestedBlockList map[Uint256]time.Time
 	syncTimer          *syncTimer
+	headersSync        headersSync
 	SyncBlkReqSem      Semaphore
 	StartHash          Uint256
 	StopHash           Uint256
@@ -138,7 +139,7 @@ func InitLocalNode() protocol.Noder {
 	LocalNode = &node{
 		id:                 rand.New(rand.NewSource(time.Now().Unix())).Uint64(),
 		version:            protocol.ProtocolVersion,
-		services:           protocol.FlagNode | protocol.OpenService,
+		services:           protocol.FlagNode | protocol.OpenService | protocol.HeadersService,
 		relay:              true,
 		SyncBlkReqSem:      MakeSemaphore(protocol.MaxSyncHdrReq),
 		RequestedBlockList: make(map[Uint256]time.Time),
@@ -176,6 +177,8 @@ func InitLocalNode() protocol.Noder {
 	}()
 
 	go LocalNode.nodeHeartBeat()
+	go LocalNode.TxPool.PersistHandler()
+	go LocalNode.TxPool.ExpireHandler()
 	go monitorNodeState()
 	return LocalNode
 }
@@ -507,6 +510,10 @@ func (node *node) GetStopHash() Uint256 {
 }
 
 func (node *node) RegisterTxPoolListener(listener protocol.TxnPoolListener) {
+	// arbitrator is nil when arbiter is not enabled
+	if listener == nil {
+		return
+	}
 	node.TxPool.Listeners[listener] = nil
 }
 
@@ -25,6 +25,8 @@ const (
 	CmdGetAddr     = "getaddr"
 	CmdAddr        = "addr"
 	CmdGetBlocks   = "getblocks"
+	CmdGetHeaders  = "getheaders"
+	CmdHeaders     = "headers"
 	CmdInv         = "inv"
 	CmdGetData     = "getdata"
 	CmdNotFound    = "notfound"
@@ -0,0 +1,26 @@
+package msg
+
+import (
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+// Ensure GetHeaders implement p2p.Message interface.
+var _ p2p.Message = (*GetHeaders)(nil)
+
+// GetHeaders has the same payload as GetBlocks, but requests block headers
+// instead of block hashes.
+type GetHeaders struct {
+	GetBlocks
+}
+
+func NewGetHeaders(locator []*common.Uint256, hashStop common.Uint256) *GetHeaders {
+	msg := new(GetHeaders)
+	msg.Locator = locator
+	msg.HashStop = hashStop
+	return msg
+}
+
+func (msg *GetHeaders) CMD() string {
+	return p2p.CmdGetHeaders
+}
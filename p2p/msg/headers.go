@@ -0,0 +1,85 @@
+package msg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/p2p"
+)
+
+// MaxHeadersPerMsg is the maximum number of block headers allowed per message.
+const MaxHeadersPerMsg = 2000
+
+// Ensure Headers implement p2p.Message interface.
+var _ p2p.Message = (*Headers)(nil)
+
+type Headers struct {
+	Headers []common.Serializable
+
+	// newHeader creates the empty header to deserialize into, like Block the
+	// header type is not referenced here to avoid an import cycle.
+	newHeader func() common.Serializable
+}
+
+func NewHeaders(headers []common.Serializable) *Headers {
+	return &Headers{Headers: headers}
+}
+
+// NewEmptyHeaders creates a Headers message to deserialize into, newHeader
+// returns an empty block header.
+func NewEmptyHeaders(newHeader func() common.Serializable) *Headers {
+	return &Headers{newHeader: newHeader}
+}
+
+func (msg *Headers) CMD() string {
+	return p2p.CmdHeaders
+}
+
+func (msg *Headers) MaxLength() uint32 {
+	return p2p.MaxMessagePayload
+}
+
+func (msg *Headers) Serialize(w io.Writer) error {
+	count := len(msg.Headers)
+	if count > MaxHeadersPerMsg {
+		str := fmt.Sprintf("too many headers in message [count %v, max %v]",
+			count, MaxHeadersPerMsg)
+		return common.FuncError("Headers.Serialize", str)
+	}
+
+	if err := common.WriteUint32(w, uint32(count)); err != nil {
+		return err
+	}
+
+	for _, header := range msg.Headers {
+		if err := header.Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (msg *Headers) Deserialize(r io.Reader) error {
+	count, err := common.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	if count > MaxHeadersPerMsg {
+		str := fmt.Sprintf("too many headers in message [count %v, max %v]",
+			count, MaxHeadersPerMsg)
+		return common.FuncError("Headers.Deserialize", str)
+	}
+
+	msg.Headers = make([]common.Serializable, 0, count)
+	for i := uint32(0); i < count; i++ {
+		header := msg.newHeader()
+		if err := header.Deserialize(r); err != nil {
+			return err
+		}
+		msg.Headers = append(msg.Headers, header)
+	}
+
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"math"
 	"math/rand"
-	"sort"
 	"sync"
 	"time"
 
@@ -123,12 +122,6 @@ func CreateCoinbaseTx(minerAddr string) (*Transaction, error) {
 	return txn, nil
 }
 
-type byFeeDesc []*Transaction
-
-func (s byFeeDesc) Len() int           { return len(s) }
-func (s byFeeDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-func (s byFeeDesc) Less(i, j int) bool { return s[i].FeePerKB > s[j].FeePerKB }
-
 func (pow *PowService) GenerateBlock(minerAddr string, bestChain *BlockNode) (*Block, error) {
 	nextBlockHeight := bestChain.Height + 1
 	coinBaseTx, err := CreateCoinbaseTx(minerAddr)
@@ -152,40 +145,35 @@ func (pow *PowService) GenerateBlock(minerAddr string, bestChain *BlockNode) (*B
 	}
 
 	msgBlock.Transactions = append(msgBlock.Transactions, coinBaseTx)
-	totalTxsSize := coinBaseTx.GetSize()
-	txCount := 1
 	totalTxFee := common.Fixed64(0)
-	var txsByFeeDesc byFeeDesc
-	txsInPool := node.LocalNode.GetTransactionPool(false)
-	txsByFeeDesc = make([]*Transaction, 0, len(txsInPool))
-	for _, v := range txsInPool {
-		txsByFeeDesc = append(txsByFeeDesc, v)
+	txsByFeeDesc := node.LocalNode.GetTransactionsForBlock(
+		config.Parameters.MaxBlockSize-coinBaseTx.GetSize(),
+		config.Parameters.MaxTxsInBlock-len(msgBlock.Transactions))
+
+	// transactions may spend outputs of the transactions before them in block
+	// since InBlockSpendHeight, before that the ones spending outputs of
+	// unconfirmed transactions fail the context check and are left in pool
+	var parents map[common.Uint256]*Transaction
+	if IsInBlockSpendAllowed(nextBlockHeight) {
+		parents = make(map[common.Uint256]*Transaction)
 	}
-	sort.Sort(txsByFeeDesc)
-
 	for _, tx := range txsByFeeDesc {
-		totalTxsSize = totalTxsSize + tx.GetSize()
-		if totalTxsSize > config.Parameters.MaxBlockSize {
-			break
-		}
-		if txCount >= config.Parameters.MaxTxsInBlock {
-			break
-		}
-
 		if !IsFinalizedTransaction(tx, nextBlockHeight) {
 			continue
 		}
-		if errCode := CheckTransactionContext(nextBlockHeight, tx); errCode != Success {
+		if errCode := CheckTransactionContextWithParents(nextBlockHeight, tx, parents); errCode != Success {
 			log.Warn("check transaction context failed, wrong transaction:", tx.Hash().String())
 			continue
 		}
-		fee := GetTxFee(tx, DefaultLedger.Blockchain.AssetID)
+		fee := GetTxFeeWithParents(tx, DefaultLedger.Blockchain.AssetID, parents)
 		if fee != tx.Fee {
 			continue
 		}
 		msgBlock.Transactions = append(msgBlock.Transactions, tx)
 		totalTxFee += fee
-		txCount++
+		if parents != nil {
+			parents[tx.Hash()] = tx
+		}
 	}
 
 	blockReward := RewardAmountPerBlock
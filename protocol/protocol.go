@@ -28,6 +28,9 @@ const (
 	FlagNode = 1
 
 	OpenService = 1 << 2
+
+	// HeadersService indicates node replies getheaders requests.
+	HeadersService = 1 << 3
 )
 
 type State int32
@@ -62,6 +65,13 @@ type TxnPoolListener interface {
 	OnIllegalBlockTxnReceived(txn *types.Transaction)
 }
 
+// TxnEvictedListener can be implemented by a TxnPoolListener to be told about
+// the transactions removed from pool without being packed in a block, such as
+// the ones evicted when pool is full, replaced by fee or expired.
+type TxnEvictedListener interface {
+	OnTxnEvicted(txn *types.Transaction)
+}
+
 // Handler is the P2P message handler interface.
 type Handler interface {
 	MakeEmptyMessage(cmd string) (p2p.Message, error)
@@ -98,6 +108,11 @@ type Noder interface {
 	AppendToTxnPool(*types.Transaction) errors.ErrCode
 	RegisterTxPoolListener(listener TxnPoolListener)
 	UnregisterTxPoolListener(listener TxnPoolListener)
+	SaveTxPool() error
+	LoadTxPool()
+	SaveFeeEstimator() error
+	LoadFeeEstimator()
+	EstimateFee(confirmations uint32, confidence float64) (common.Fixed64, error)
 	IsDuplicateSidechainTx(sidechainTxHash common.Uint256) bool
 	ExistedID(id common.Uint256) bool
 	RequireNeighbourList()
@@ -69,6 +69,23 @@ type TransactionInfo struct {
 	Programs       []ProgramInfo      `json:"programs"`
 }
 
+type ReferenceInfo struct {
+	Address string `json:"address"`
+	Value   string `json:"value"`
+}
+
+type SignStatusInfo struct {
+	HaveSign int `json:"havesign"`
+	NeedSign int `json:"needsign"`
+}
+
+type DecodedTransactionInfo struct {
+	*TransactionInfo
+	References []*ReferenceInfo  `json:"references"`
+	SignStatus []*SignStatusInfo `json:"signstatus"`
+	Fee        string            `json:"fee,omitempty"`
+}
+
 type BlockInfo struct {
 	Hash              string        `json:"hash"`
 	Confirmations     uint32        `json:"confirmations"`
@@ -187,3 +204,77 @@ type UTXOInfo struct {
 	OutputLock    uint32 `json:"outputlock"`
 	Confirmations uint32 `json:"confirmations"`
 }
+
+type SpendInfo struct {
+	TxID   string `json:"txid"`
+	VIn    uint16 `json:"vin"`
+	Height uint32 `json:"height"`
+}
+
+type AddressHistoryInfo struct {
+	Height    uint32 `json:"height"`
+	TxID      string `json:"txid"`
+	Direction string `json:"direction"`
+	Index     uint16 `json:"index"`
+	Value     string `json:"value"`
+}
+
+type ConsensusTimelineInfo struct {
+	Height      uint32              `json:"height"`
+	StartTime   int64               `json:"starttime"`
+	EndTime     int64               `json:"endtime"`
+	Duration    int64               `json:"duration"`
+	ViewChanges int                 `json:"viewchanges"`
+	Views       []ViewEventInfo     `json:"views"`
+	Proposals   []ProposalEventInfo `json:"proposals"`
+}
+
+type ViewEventInfo struct {
+	Height           uint32 `json:"height"`
+	OnDutyArbitrator string `json:"ondutyarbitrator"`
+	Offset           uint32 `json:"offset"`
+	StartTime        int64  `json:"starttime"`
+}
+
+type ProposalEventInfo struct {
+	Height       uint32          `json:"height"`
+	Sponsor      string          `json:"sponsor"`
+	BlockHash    string          `json:"blockhash"`
+	ProposalHash string          `json:"proposalhash"`
+	ViewOffset   uint32          `json:"viewoffset"`
+	ReceivedTime int64           `json:"receivedtime"`
+	EndTime      int64           `json:"endtime"`
+	Result       bool            `json:"result"`
+	Votes        []VoteEventInfo `json:"votes,omitempty"`
+}
+
+type VoteEventInfo struct {
+	Height       uint32 `json:"height"`
+	Signer       string `json:"signer"`
+	ProposalHash string `json:"proposalhash"`
+	Accept       bool   `json:"accept"`
+	ReceivedTime int64  `json:"receivedtime"`
+}
+
+type ConsensusEventsInfo struct {
+	Proposals []ProposalEventInfo `json:"proposals"`
+	Votes     []VoteEventInfo     `json:"votes"`
+	Views     []ViewEventInfo     `json:"views"`
+}
+
+type ArbiterRoundStatsInfo struct {
+	StartHeight uint32             `json:"startheight"`
+	EndHeight   uint32             `json:"endheight"`
+	Arbiters    []ArbiterStatsInfo `json:"arbiters"`
+}
+
+type ArbiterStatsInfo struct {
+	Arbiter          string `json:"arbiter"`
+	DutySlots        uint32 `json:"dutyslots"`
+	Proposals        uint32 `json:"proposals"`
+	MissedDuties     uint32 `json:"missedduties"`
+	ExpectedVotes    uint32 `json:"expectedvotes"`
+	Votes            uint32 `json:"votes"`
+	RejectedVotes    uint32 `json:"rejectedvotes"`
+	IllegalEvidences uint32 `json:"illegalevidences"`
+}
@@ -42,10 +42,12 @@ func StartRPCServer() {
 	mainMux["getblockhash"] = GetBlockHash
 	mainMux["getconnectioncount"] = GetConnectionCount
 	mainMux["getrawmempool"] = GetTransactionPool
+	mainMux["savemempool"] = SaveMemPool
 	mainMux["getrawtransaction"] = GetRawTransaction
 	mainMux["getneighbors"] = GetNeighbors
 	mainMux["getnodestate"] = GetNodeState
 	mainMux["sendrawtransaction"] = SendRawTransaction
+	mainMux["decoderawtransaction"] = DecodeRawTransaction
 	mainMux["getarbitratorgroupbyheight"] = GetArbitratorGroupByHeight
 	mainMux["getbestblockhash"] = GetBestBlockHash
 	mainMux["getblockcount"] = GetBlockCount
@@ -53,6 +55,11 @@ func StartRPCServer() {
 	mainMux["getexistwithdrawtransactions"] = GetExistWithdrawTransactions
 	mainMux["listunspent"] = ListUnspent
 	mainMux["getreceivedbyaddress"] = GetReceivedByAddress
+	mainMux["getaddresshistory"] = GetAddressHistory
+	mainMux["getspendinginfo"] = GetSpendingInfo
+	mainMux["getconsensustimeline"] = GetConsensusTimeline
+	mainMux["getconsensusevents"] = GetConsensusEvents
+	mainMux["getarbiterstats"] = GetArbiterStats
 	// aux interfaces
 	mainMux["help"] = AuxHelp
 	mainMux["submitauxblock"] = SubmitAuxBlock
@@ -271,14 +278,26 @@ func convertParams(method string, params []interface{}) Params {
 		return FromArray(params, "count")
 	case "sendrawtransaction":
 		return FromArray(params, "data")
+	case "decoderawtransaction":
+		return FromArray(params, "data")
 	case "listunspent":
 		return FromArray(params, "addresses")
 	case "getreceivedbyaddress":
 		return FromArray(params, "address")
 	case "getblockbyheight":
 		return FromArray(params, "height")
+	case "getaddresshistory":
+		return FromArray(params, "addr", "skip", "count")
+	case "getspendinginfo":
+		return FromArray(params, "txid", "vout")
 	case "estimatesmartfee":
 		return FromArray(params, "confirmations")
+	case "getconsensustimeline":
+		return FromArray(params, "start", "end")
+	case "getconsensusevents":
+		return FromArray(params, "start", "end", "arbiter", "viewoffset")
+	case "getarbiterstats":
+		return FromArray(params, "height")
 	default:
 		return Params{}
 	}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	chain "github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/servers"
 )
@@ -22,6 +23,7 @@ type Info struct {
 	HttpLocalPort int
 	NodePort      uint16
 	NodeID        string
+	ArbiterStats  *interfaces.ArbiterRoundStats
 }
 
 type NgbNodeInfo struct {
@@ -55,6 +57,10 @@ func viewHandler(w http.ResponseWriter, r *http.Request) {
 		NodeID:       fmt.Sprintf("0x%x", node.ID()),
 	}
 
+	if servers.ArbiterStats != nil {
+		pageInfo.ArbiterStats, _ = servers.ArbiterStats.GetArbiterStats(pageInfo.BlockHeight)
+	}
+
 	err := templates.ExecuteTemplate(w, "info", pageInfo)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -72,6 +72,33 @@ const page = `
 </td>
 </tr>
 </table>
+{{if .ArbiterStats}}
+<br><br><br><br>
+
+<table class="bt" width="80%">
+	<tr><th>Arbiter Statistics</th></tr>
+</table>
+<br>
+
+<table class="bd" width="80%">
+<tr>
+<td width="20%" >
+	<table class="font" width="100%">
+	<tr><th>Election Round</th></tr>
+	<tr><td align="center"><b>{{.ArbiterStats.StartHeight}} - {{.ArbiterStats.EndHeight}}</b></td></tr>
+	</table>
+</td>
+<td width="80%">
+	<table class="font" width="100%">
+	<tr><th>Arbiter</th><th>Duty Slots</th><th>Proposals</th><th>Missed Duties</th><th>Votes / Expected</th><th>Rejected Votes</th><th>Illegal Evidences</th></tr>
+	{{range .ArbiterStats.Arbiters}}
+	<tr><td class="pk">{{.Arbiter}}</td><td align="center">{{.DutySlots}}</td><td align="center">{{.Proposals}}</td><td align="center">{{.MissedDuties}}</td><td align="center">{{.Votes}} / {{.ExpectedVotes}}</td><td align="center">{{.RejectedVotes}}</td><td align="center">{{.IllegalEvidences}}</td></tr>
+	{{end}}
+	</table>
+</td>
+</tr>
+</table>
+{{end}}
 <br><br><br><br><br><br>
 
 <table class="font" border="0" width="80%">
@@ -31,6 +31,9 @@ const (
 	ApiGetBalanceByAsset   = "/api/v1/asset/balance/:addr/:assetid"
 	ApiGetUTXOByAsset      = "/api/v1/asset/utxo/:addr/:assetid"
 	ApiGetUTXOByAddr       = "/api/v1/asset/utxos/:addr"
+	ApiGetAddressHistory   = "/api/v1/address/history/:addr"
+	ApiGetSpendingInfo     = "/api/v1/output/spending/:hash/:index"
+	ApiGetConsensus        = "/api/v1/dpos/consensus/:height"
 	ApiSendRawTransaction  = "/api/v1/transaction"
 	ApiGetTransactionPool  = "/api/v1/transactionpool"
 	ApiRestart             = "/api/v1/restart"
@@ -112,6 +115,9 @@ func (rt *restServer) initializeMethod() {
 		ApiGetUTXOByAsset:      {name: "getutxobyasset", handler: servers.GetUnspendOutput},
 		ApiGetBalanceByAddr:    {name: "getbalancebyaddr", handler: servers.GetBalanceByAddr},
 		ApiGetBalanceByAsset:   {name: "getbalancebyasset", handler: servers.GetBalanceByAsset},
+		ApiGetAddressHistory:   {name: "getaddresshistory", handler: servers.GetAddressHistory},
+		ApiGetSpendingInfo:     {name: "getspendinginfo", handler: servers.GetSpendingInfo},
+		ApiGetConsensus:        {name: "getconsensustimeline", handler: servers.GetConsensusTimeline},
 		ApiRestart:             {name: "restart", handler: rt.Restart},
 	}
 
@@ -144,6 +150,12 @@ func (rt *restServer) getPath(url string) string {
 		return ApiGetUTXOByAsset
 	} else if strings.Contains(url, strings.TrimRight(ApiGetAsset, ":hash")) {
 		return ApiGetAsset
+	} else if strings.Contains(url, strings.TrimRight(ApiGetAddressHistory, ":addr")) {
+		return ApiGetAddressHistory
+	} else if strings.Contains(url, strings.TrimRight(ApiGetSpendingInfo, ":hash/:index")) {
+		return ApiGetSpendingInfo
+	} else if strings.Contains(url, strings.TrimRight(ApiGetConsensus, ":height")) {
+		return ApiGetConsensus
 	}
 	return url
 }
@@ -190,6 +202,25 @@ func (rt *restServer) getParams(r *http.Request, url string, req map[string]inte
 		req["addr"] = getParam(r, "addr")
 		req["assetid"] = getParam(r, "assetid")
 
+	case ApiGetAddressHistory:
+		req["addr"] = getParam(r, "addr")
+		if skip := r.FormValue("skip"); skip != "" {
+			req["skip"] = skip
+		}
+		if count := r.FormValue("count"); count != "" {
+			req["count"] = count
+		}
+
+	case ApiGetSpendingInfo:
+		req["txid"] = getParam(r, "hash")
+		req["vout"] = getParam(r, "index")
+
+	case ApiGetConsensus:
+		req["start"] = getParam(r, "height")
+		if end := r.FormValue("end"); end != "" {
+			req["end"] = end
+		}
+
 	case ApiRestart:
 
 	case ApiSendRawTransaction:
@@ -11,6 +11,7 @@ import (
 
 	aux "github.com/elastos/Elastos.ELA/auxpow"
 	chain "github.com/elastos/Elastos.ELA/blockchain"
+	"github.com/elastos/Elastos.ELA/blockchain/interfaces"
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/common/config"
 	"github.com/elastos/Elastos.ELA/common/log"
@@ -18,10 +19,13 @@ import (
 	. "github.com/elastos/Elastos.ELA/core/types"
 	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
 	. "github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
 	. "github.com/elastos/Elastos.ELA/errors"
-	"github.com/elastos/Elastos.ELA/p2p/msg"
+	"github.com/elastos/Elastos.ELA/mempool"
 	"github.com/elastos/Elastos.ELA/pow"
 	. "github.com/elastos/Elastos.ELA/protocol"
+
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 const (
@@ -30,10 +34,28 @@ const (
 	MixedUTXO  utxoType = 0x00
 	VoteUTXO   utxoType = 0x01
 	NormalUTXO utxoType = 0x02
+
+	// MinFeeRate is the basic fee rate in sela per KB returned when there is
+	// not enough data to estimate fee.
+	MinFeeRate common.Fixed64 = 10000
+
+	// DefaultFeeConfidence is the percentage of transactions expected to be
+	// confirmed in time when estimating fee.
+	DefaultFeeConfidence = 95
+
+	// MaxAddressHistoryCount is the maximum number of address history records
+	// returned in one request.
+	MaxAddressHistoryCount = 1000
+
+	// MaxConsensusEventHeights is the maximum number of blocks whose
+	// consensus events are returned in one request.
+	MaxConsensusEventHeights = 100
 )
 
 var ServerNode Noder
 var LocalPow *pow.PowService
+var EventQuery interfaces.IEventQuery
+var ArbiterStats interfaces.IArbiterStatsRecord
 
 var preChainHeight uint64
 var preTime int64
@@ -117,6 +139,48 @@ func GetTransactionInfo(header *Header, tx *Transaction) *TransactionInfo {
 	}
 }
 
+// GetDecodedTransactionInfo returns the transaction info with the outputs
+// referenced by the inputs, the sign status of each program and the fee. A nil
+// reference is unknown, and the fee is shown only if all references are known.
+func GetDecodedTransactionInfo(tx *Transaction, references []*Output) *DecodedTransactionInfo {
+	info := &DecodedTransactionInfo{
+		TransactionInfo: GetTransactionInfo(nil, tx),
+		References:      make([]*ReferenceInfo, len(tx.Inputs)),
+		SignStatus:      make([]*SignStatusInfo, len(tx.Programs)),
+	}
+
+	fee := common.Fixed64(0)
+	resolved := len(references) == len(tx.Inputs)
+	for i := range tx.Inputs {
+		if i >= len(references) || references[i] == nil {
+			resolved = false
+			continue
+		}
+		address, _ := references[i].ProgramHash.ToAddress()
+		info.References[i] = &ReferenceInfo{
+			Address: address,
+			Value:   references[i].Value.String(),
+		}
+		fee += references[i].Value
+	}
+	if resolved && !tx.IsCoinBaseTx() {
+		for _, output := range tx.Outputs {
+			fee -= output.Value
+		}
+		info.Fee = fee.String()
+	}
+
+	for i, program := range tx.Programs {
+		haveSign, needSign, err := crypto.GetSignStatus(program.Code, program.Parameter)
+		if err != nil {
+			continue
+		}
+		info.SignStatus[i] = &SignStatusInfo{HaveSign: haveSign, NeedSign: needSign}
+	}
+
+	return info
+}
+
 // Input JSON string examples for getblock method as following:
 func GetRawTransaction(param Params) map[string]interface{} {
 	str, ok := param.String("txid")
@@ -416,6 +480,13 @@ func GetTransactionPool(param Params) map[string]interface{} {
 	return ResponsePack(Success, txs)
 }
 
+func SaveMemPool(param Params) map[string]interface{} {
+	if err := ServerNode.SaveTxPool(); err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	return ResponsePack(Success, true)
+}
+
 func GetBlockInfo(block *Block, verbose bool) BlockInfo {
 	var txs []interface{}
 	if verbose {
@@ -525,6 +596,38 @@ func SendRawTransaction(param Params) map[string]interface{} {
 	return ResponsePack(Success, ToReversedString(txn.Hash()))
 }
 
+func DecodeRawTransaction(param Params) map[string]interface{} {
+	str, ok := param.String("data")
+	if !ok {
+		return ResponsePack(InvalidParams, "need a string parameter named data")
+	}
+
+	bys, err := common.HexStringToBytes(str)
+	if err != nil {
+		return ResponsePack(InvalidParams, "hex string to bytes error")
+	}
+	var txn Transaction
+	if err := txn.Deserialize(bytes.NewReader(bys)); err != nil {
+		return ResponsePack(InvalidTransaction, err.Error())
+	}
+
+	// resolve the references from blocks and the transaction pool
+	references := make([]*Output, len(txn.Inputs))
+	for i, input := range txn.Inputs {
+		tx, _, err := chain.DefaultLedger.Store.GetTransaction(input.Previous.TxID)
+		if err != nil {
+			if tx = ServerNode.GetTransaction(input.Previous.TxID); tx == nil {
+				continue
+			}
+		}
+		if int(input.Previous.Index) < len(tx.Outputs) {
+			references[i] = tx.Outputs[input.Previous.Index]
+		}
+	}
+
+	return ResponsePack(Success, GetDecodedTransactionInfo(&txn, references))
+}
+
 func GetBlockHeight(param Params) map[string]interface{} {
 	return ResponsePack(Success, chain.DefaultLedger.Blockchain.BlockHeight)
 }
@@ -682,6 +785,85 @@ func GetBalanceByAddr(param Params) map[string]interface{} {
 	return ResponsePack(Success, balance.String())
 }
 
+func GetAddressHistory(param Params) map[string]interface{} {
+	if !config.Parameters.EnableAddressIndex {
+		return ResponsePack(InternalError, "address index is not enabled")
+	}
+	addr, ok := param.String("addr")
+	if !ok {
+		return ResponsePack(InvalidParams, "need a param called addr")
+	}
+	programHash, err := common.Uint168FromAddress(addr)
+	if err != nil {
+		return ResponsePack(InvalidParams, "invalid address, "+err.Error())
+	}
+	skip, ok := param.Uint("skip")
+	if !ok {
+		skip = 0
+	}
+	count, ok := param.Uint("count")
+	if !ok || count > MaxAddressHistoryCount {
+		count = MaxAddressHistoryCount
+	}
+
+	history, err := chain.DefaultLedger.Store.GetAddressHistory(*programHash,
+		int(skip), int(count))
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	result := make([]AddressHistoryInfo, 0, len(history))
+	for _, h := range history {
+		direction := "received"
+		if h.Direction == chain.HistorySent {
+			direction = "sent"
+		}
+		result = append(result, AddressHistoryInfo{
+			Height:    h.Height,
+			TxID:      ToReversedString(h.TxID),
+			Direction: direction,
+			Index:     h.Index,
+			Value:     h.Value.String(),
+		})
+	}
+	return ResponsePack(Success, result)
+}
+
+func GetSpendingInfo(param Params) map[string]interface{} {
+	if !config.Parameters.EnableSpendIndex {
+		return ResponsePack(InternalError, "spend index is not enabled")
+	}
+	str, ok := param.String("txid")
+	if !ok {
+		return ResponsePack(InvalidParams, "need a param called txid")
+	}
+	bys, err := FromReversedString(str)
+	if err != nil {
+		return ResponsePack(InvalidParams, "invalid txid")
+	}
+	txID, err := common.Uint256FromBytes(bys)
+	if err != nil {
+		return ResponsePack(InvalidParams, "invalid txid")
+	}
+	index, ok := param.Uint("vout")
+	if !ok || index > math.MaxUint16 {
+		return ResponsePack(InvalidParams, "need a param called vout")
+	}
+
+	info, err := chain.DefaultLedger.Store.GetSpendInfo(*txID, uint16(index))
+	if err == leveldb.ErrNotFound || err == chain.ErrBoltNotFound {
+		// the output does not exist or has not been spent
+		return ResponsePack(Success, nil)
+	}
+	if err != nil {
+		return ResponsePack(InternalError, "get spend info failed: "+err.Error())
+	}
+	return ResponsePack(Success, SpendInfo{
+		TxID:   ToReversedString(info.TxID),
+		VIn:    info.Index,
+		Height: info.Height,
+	})
+}
+
 func GetBalanceByAsset(param Params) map[string]interface{} {
 	addr, ok := param.String("addr")
 	if !ok {
@@ -1097,6 +1279,13 @@ func VoteStatus(param Params) map[string]interface{} {
 	})
 }
 
+type DepositCoin struct {
+	Available    string `json:"available"`
+	Deducted     string `json:"deducted"`
+	Canceled     bool   `json:"canceled"`
+	CancelHeight uint32 `json:"cancelheight"`
+}
+
 func GetDepositCoin(param Params) map[string]interface{} {
 	pk, ok := param.String("ownerpublickey")
 	if !ok {
@@ -1120,13 +1309,15 @@ func GetDepositCoin(param Params) map[string]interface{} {
 	var deducted common.Fixed64 = 0
 	//todo get deducted coin
 
-	type depositCoin struct {
-		Available string `json:"available"`
-		Deducted  string `json:"deducted"`
-	}
-	return ResponsePack(Success, &depositCoin{
-		Available: balance.String(),
-		Deducted:  deducted.String(),
+	// the deposit can be returned DepositLockupBlocks after canceled
+	cancelHeight, err := chain.DefaultLedger.Store.GetCancelProducerHeight(pkBytes)
+	canceled := err == nil
+
+	return ResponsePack(Success, &DepositCoin{
+		Available:    balance.String(),
+		Deducted:     deducted.String(),
+		Canceled:     canceled,
+		CancelHeight: cancelHeight,
 	})
 }
 
@@ -1135,32 +1326,27 @@ func EstimateSmartFee(param Params) map[string]interface{} {
 	if !ok {
 		return ResponsePack(InvalidParams, "need a param called confirmations")
 	}
-	if confirm > 25 {
-		return ResponsePack(InvalidParams, "support only 25 confirmations at most")
+	if confirm < 1 || confirm > mempool.EstimateFeeDepth {
+		return ResponsePack(InvalidParams, fmt.Sprintf("confirmations "+
+			"should be between 1 and %d", mempool.EstimateFeeDepth))
 	}
-	currentHeight := chain.DefaultLedger.GetLocalBlockChainHeight()
-	var FeeRate = 10000 //basic fee rate 10000 sela per KB
-	var count = 0
-	// 6 confirmations at most
-	for i := currentHeight; i == 0; i-- {
-		b, _ := chain.DefaultLedger.GetBlockWithHeight(i)
-		if b.GetSize() < msg.MaxBlockSize {
-			break
-		} else {
-			//how many full blocks continuously before?
-			count++
-		}
+	confidence, ok := param.Float("confidence")
+	if !ok {
+		confidence = DefaultFeeConfidence
+	}
+	if confidence <= 0 || confidence > 100 {
+		return ResponsePack(InvalidParams, "confidence should be between 0 and 100")
 	}
 
-	return ResponsePack(Success, GetFeeRate(count, int(confirm))*FeeRate)
-}
-
-func GetFeeRate(count int, confirm int) int {
-	gap := count - confirm
-	if gap < 0 {
-		gap = -1
+	feeRate, err := ServerNode.EstimateFee(uint32(confirm), confidence/100)
+	if err != nil {
+		// not enough transactions observed yet, use the basic fee rate
+		return ResponsePack(Success, MinFeeRate)
+	}
+	if feeRate < MinFeeRate {
+		feeRate = MinFeeRate
 	}
-	return gap + 2
+	return ResponsePack(Success, feeRate)
 }
 
 func getPayloadInfo(p Payload) PayloadInfo {
@@ -1271,3 +1457,184 @@ func ResponsePack(errCode ErrCode, result interface{}) map[string]interface{} {
 	}
 	return map[string]interface{}{"Result": result, "Error": errCode}
 }
+
+func GetConsensusTimeline(param Params) map[string]interface{} {
+	if EventQuery == nil {
+		return ResponsePack(InternalError, "consensus events are not recorded")
+	}
+	start, end, errCode, desc := getHeightRange(param)
+	if errCode != Success {
+		return ResponsePack(errCode, desc)
+	}
+
+	timelines, err := EventQuery.GetConsensusTimelines(start, end)
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	result := make([]ConsensusTimelineInfo, 0, len(timelines))
+	for _, t := range timelines {
+		info := ConsensusTimelineInfo{
+			Height:      t.Consensus.Height,
+			StartTime:   unixMilli(t.Consensus.StartTime),
+			EndTime:     unixMilli(t.Consensus.EndTime),
+			ViewChanges: len(t.Views),
+			Views:       make([]ViewEventInfo, 0, len(t.Views)),
+			Proposals:   make([]ProposalEventInfo, 0, len(t.Proposals)),
+		}
+		if !t.Consensus.StartTime.IsZero() && !t.Consensus.EndTime.IsZero() {
+			info.Duration = int64(t.Consensus.EndTime.Sub(t.Consensus.StartTime) / time.Millisecond)
+		}
+		for _, v := range t.Views {
+			info.Views = append(info.Views, getViewEventInfo(v))
+		}
+		for _, p := range t.Proposals {
+			proposal := getProposalEventInfo(p)
+			for _, v := range t.Votes {
+				if v.ProposalHash.IsEqual(p.ProposalHash) {
+					proposal.Votes = append(proposal.Votes, getVoteEventInfo(v))
+				}
+			}
+			info.Proposals = append(info.Proposals, proposal)
+		}
+		result = append(result, info)
+	}
+	return ResponsePack(Success, result)
+}
+
+func GetConsensusEvents(param Params) map[string]interface{} {
+	if EventQuery == nil {
+		return ResponsePack(InternalError, "consensus events are not recorded")
+	}
+	start, end, errCode, desc := getHeightRange(param)
+	if errCode != Success {
+		return ResponsePack(errCode, desc)
+	}
+	filter := &interfaces.EventFilter{StartHeight: start, EndHeight: end}
+	filter.Arbiter, _ = param.String("arbiter")
+	if viewOffset, ok := param.Uint("viewoffset"); ok {
+		filter.ViewOffset = &viewOffset
+	}
+
+	proposals, err := EventQuery.GetProposalEvents(filter)
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	votes, err := EventQuery.GetVoteEvents(filter)
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	views, err := EventQuery.GetViewEvents(filter)
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+
+	result := ConsensusEventsInfo{
+		Proposals: make([]ProposalEventInfo, 0, len(proposals)),
+		Votes:     make([]VoteEventInfo, 0, len(votes)),
+		Views:     make([]ViewEventInfo, 0, len(views)),
+	}
+	for _, p := range proposals {
+		result.Proposals = append(result.Proposals, getProposalEventInfo(p))
+	}
+	for _, v := range votes {
+		result.Votes = append(result.Votes, getVoteEventInfo(v))
+	}
+	for _, v := range views {
+		result.Views = append(result.Views, getViewEventInfo(v))
+	}
+	return ResponsePack(Success, result)
+}
+
+func GetArbiterStats(param Params) map[string]interface{} {
+	if ArbiterStats == nil {
+		return ResponsePack(InternalError, "arbiter stats are not recorded")
+	}
+	height, ok := param.Uint("height")
+	if !ok {
+		height = chain.DefaultLedger.Blockchain.GetBestHeight()
+	}
+
+	round, err := ArbiterStats.GetArbiterStats(height)
+	if err != nil {
+		return ResponsePack(InternalError, err.Error())
+	}
+	result := ArbiterRoundStatsInfo{
+		StartHeight: round.StartHeight,
+		EndHeight:   round.EndHeight,
+		Arbiters:    make([]ArbiterStatsInfo, 0, len(round.Arbiters)),
+	}
+	for _, a := range round.Arbiters {
+		result.Arbiters = append(result.Arbiters, ArbiterStatsInfo{
+			Arbiter:          a.Arbiter,
+			DutySlots:        a.DutySlots,
+			Proposals:        a.Proposals,
+			MissedDuties:     a.MissedDuties,
+			ExpectedVotes:    a.ExpectedVotes,
+			Votes:            a.Votes,
+			RejectedVotes:    a.RejectedVotes,
+			IllegalEvidences: a.IllegalEvidences,
+		})
+	}
+	return ResponsePack(Success, result)
+}
+
+// getHeightRange returns the height range from the start and the optional
+// end params, the end is the start if not specified.
+func getHeightRange(param Params) (uint32, uint32, ErrCode, string) {
+	start, ok := param.Uint("start")
+	if !ok {
+		return 0, 0, InvalidParams, "need a param called start"
+	}
+	end, ok := param.Uint("end")
+	if !ok {
+		end = start
+	}
+	if end < start {
+		return 0, 0, InvalidParams, "end should not be less than start"
+	}
+	if end-start >= MaxConsensusEventHeights {
+		return 0, 0, InvalidParams, fmt.Sprintf("at most %d blocks in one request",
+			MaxConsensusEventHeights)
+	}
+	return start, end, Success, ""
+}
+
+func getProposalEventInfo(p *interfaces.ProposalRecord) ProposalEventInfo {
+	return ProposalEventInfo{
+		Height:       p.Height,
+		Sponsor:      p.Sponsor,
+		BlockHash:    ToReversedString(p.BlockHash),
+		ProposalHash: ToReversedString(p.ProposalHash),
+		ViewOffset:   p.ViewOffset,
+		ReceivedTime: unixMilli(p.ReceivedTime),
+		EndTime:      unixMilli(p.EndTime),
+		Result:       p.Result,
+	}
+}
+
+func getVoteEventInfo(v *interfaces.VoteRecord) VoteEventInfo {
+	return VoteEventInfo{
+		Height:       v.Height,
+		Signer:       v.Signer,
+		ProposalHash: ToReversedString(v.ProposalHash),
+		Accept:       v.Accept,
+		ReceivedTime: unixMilli(v.ReceivedTime),
+	}
+}
+
+func getViewEventInfo(v *interfaces.ViewRecord) ViewEventInfo {
+	return ViewEventInfo{
+		Height:           v.Height,
+		OnDutyArbitrator: v.OnDutyArbitrator,
+		Offset:           v.Offset,
+		StartTime:        unixMilli(v.StartTime),
+	}
+}
+
+// unixMilli returns the time in unix milliseconds, or zero for the zero time.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/elastos/Elastos.ELA/account"
+	"github.com/elastos/Elastos.ELA/cli/password"
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/common/config"
+	dposaccount "github.com/elastos/Elastos.ELA/dpos/account"
+
+	"github.com/urfave/cli"
+)
+
+var Version string
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "ela-signer"
+	app.Version = Version
+	app.HelpName = "ela-signer"
+	app.Usage = "reference remote signer for ELA arbiters"
+	app.UsageText = "ela-signer [global options]"
+	app.Description = "ela-signer holds the arbiter key in its own process, and signs the proposals, " +
+		"votes and peer nonces requested by the arbiter configured with ArbiterConfiguration.Signer. " +
+		"Conflicting proposals or votes at the same height and view offset are refused by its sign ledger."
+	app.HideHelp = false
+	app.HideVersion = false
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen, l",
+			Usage: "the unix socket to listen, unix:path/to/socket or unix:///path/to/socket, the directory of the socket must be accessible by the owner only",
+			Value: "unix:ela-signer/signer.sock",
+		},
+		cli.StringFlag{
+			Name:  "wallet, w",
+			Usage: "the keystore file of the arbiter",
+			Value: account.KeystoreFileName,
+		},
+		cli.StringFlag{
+			Name:  "password, p",
+			Usage: "the password of the keystore, input it interactively if not specified",
+		},
+		cli.StringFlag{
+			Name:  "ledger",
+			Usage: "the sign ledger directory",
+			Value: filepath.Join(config.DataPath, config.DataDir, config.SignLedgerDir),
+		},
+	}
+	app.Action = runSigner
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println("error:", err)
+		os.Exit(1)
+	}
+}
+
+func runSigner(c *cli.Context) error {
+	// Check the address before asking for the password.
+	_, _, err := dposaccount.ParseSignerAddress(c.String("listen"))
+	if err != nil {
+		return err
+	}
+
+	pwd := []byte(c.String("password"))
+	if len(pwd) == 0 {
+		pwd, err = password.GetPassword()
+		if err != nil {
+			return err
+		}
+	}
+	client, err := account.Open(c.String("wallet"), pwd)
+	if err != nil {
+		return err
+	}
+	acc, err := client.GetDefaultAccount()
+	if err != nil {
+		return err
+	}
+	publicKey, err := acc.PubKey().EncodePoint(true)
+	if err != nil {
+		return err
+	}
+
+	ledger, err := dposaccount.NewSignLedger(c.String("ledger"))
+	if err != nil {
+		return errors.New("open sign ledger failed, " + err.Error())
+	}
+	defer ledger.Close()
+
+	listener, err := dposaccount.ListenSigner(c.String("listen"))
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		close(stopped)
+		listener.Close()
+	}()
+
+	fmt.Println("Public key:", common.BytesToHexString(publicKey))
+	fmt.Println("Listening on", c.String("listen"))
+	err = dposaccount.ServeSigner(listener,
+		dposaccount.NewDposAccountFromExisting(acc, ledger))
+	select {
+	case <-stopped:
+	default:
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("Signer stopped")
+	return nil
+}